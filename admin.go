@@ -0,0 +1,330 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const auditPageSize = 200
+
+// signablePaths are the export routes that accept signed urls
+var signablePaths = []string{"/admin/audit", "/admin/export/jobs.csv", "/admin/export/jobs.jsonl", "/export/sqlite"}
+
+// isAdmin will return true when the request carries the admin credentials
+func isAdmin(r *http.Request) bool {
+	user, pass, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(user), []byte(cfg.AdminUser)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(pass), []byte(cfg.AdminPassword)) == 1
+}
+
+// requireAdmin will only call next when the request carries the admin
+// credentials. Admin pages are not found when no admin password is configured.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.AdminPassword == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if !isAdmin(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="who is hiring admin"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// requireAdminOrSigned will call next for admin requests or requests with a
+// valid signed url, so exports can be downloaded without the admin credentials.
+func requireAdminOrSigned(next http.HandlerFunc) http.HandlerFunc {
+	admin := requireAdmin(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if validSignature(r) {
+			next(w, r)
+			return
+		}
+		admin(w, r)
+	}
+}
+
+// requireSameOrigin will answer a 403 and return false for requests sent from
+// another site. Admin forms post with it, as browsers send the basic auth
+// credentials along with requests from any site. Clients not sending
+// Sec-Fetch-Site, like curl, are let through.
+func requireSameOrigin(w http.ResponseWriter, r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// signHandler will return a signed url for the path and query in the "url" param
+func signHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.SigningKey == "" {
+		http.Error(w, "signed urls are not configured", http.StatusNotFound)
+		return
+	}
+
+	u, err := url.Parse(r.URL.Query().Get("url"))
+	if err != nil || getIndex(signablePaths, u.Path) == -1 {
+		http.Error(w, "url must be an export path on this site", http.StatusBadRequest)
+		return
+	}
+	ttl := cfg.SignedUrlTTL
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		ttl, err = time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			http.Error(w, "invalid ttl", http.StatusBadRequest)
+			return
+		}
+	}
+
+	signed := cfg.PublicBaseUrl + signUrl(u.Path, u.Query(), ttl)
+	if err := RecordAudit(cfg.AdminUser, "export.sign", u.String(), "ttl "+ttl.String()); err != nil {
+		log.Println("failed to record audit entry.", err)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, signed)
+}
+
+// dateParam will parse a YYYY-MM-DD value or return the zero time
+func dateParam(v string) time.Time {
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// exportAuditLog will stream the audit entries matching f as csv
+func exportAuditLog(w http.ResponseWriter, r *http.Request, f AuditFilter) {
+	rows, err := QueryAuditLog(f)
+	if err != nil {
+		log.Println("failed to query audit log.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit_log.csv"`)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "created_at", "actor", "action", "target", "detail"})
+	var written int
+	for rows.Next() {
+		if err := r.Context().Err(); err != nil {
+			log.Println("audit log export canceled.", err)
+			return
+		}
+
+		var e AuditEntry
+		if err := rows.StructScan(&e); err != nil {
+			log.Println("failed to scan audit log row.", err)
+			return
+		}
+		cw.Write([]string{
+			strconv.FormatUint(e.Id, 10),
+			e.Created().Format(time.RFC3339),
+			e.Actor,
+			e.Action,
+			e.Target,
+			e.Detail,
+		})
+		written++
+		flushRows(w, written, cw.Flush)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("failed to read audit log.", err)
+	}
+	cw.Flush()
+}
+
+func auditLogHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := AuditFilter{
+		Actor:  q.Get("actor"),
+		Action: q.Get("action"),
+		From:   dateParam(q.Get("from")),
+		To:     dateParam(q.Get("to")),
+	}
+	// The "to" date is inclusive
+	if !f.To.IsZero() {
+		f.To = f.To.AddDate(0, 0, 1)
+	}
+
+	if q.Get("format") == "csv" {
+		exportAuditLog(w, r, f)
+		return
+	}
+
+	f.Limit = auditPageSize
+	entries, err := SelectAuditLog(f)
+	if err != nil {
+		log.Println("failed to select audit log.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	actors, err := SelectAuditActors()
+	if err != nil {
+		log.Println("failed to select audit actors.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	actions, err := SelectAuditActions()
+	if err != nil {
+		log.Println("failed to select audit actions.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	csvQuery := r.URL.Query()
+	csvQuery.Set("format", "csv")
+	var signedCsvUrl string
+	if cfg.SigningKey != "" {
+		signedCsvUrl = "/admin/sign?" + url.Values{"url": {r.URL.Path + "?" + csvQuery.Encode()}}.Encode()
+	}
+	data := struct {
+		Entries  []AuditEntry
+		Actors   []string
+		Actions  []string
+		Actor    string
+		Action   string
+		From     string
+		To       string
+		CsvQuery string
+		SignUrl  string
+		Limit    int
+	}{
+		Entries:  entries,
+		Actors:   actors,
+		Actions:  actions,
+		Actor:    f.Actor,
+		Action:   f.Action,
+		From:     q.Get("from"),
+		To:       q.Get("to"),
+		CsvQuery: csvQuery.Encode(),
+		SignUrl:  signedCsvUrl,
+		Limit:    auditPageSize,
+	}
+	renderTemplate(w, "admin_audit.html", data)
+}
+
+// jobAttributeRow is a job attribute shown on the job debug page
+type jobAttributeRow struct {
+	Field     string
+	Value     any
+	Evidence  *FieldEvidence
+	Uncertain bool
+}
+
+// jobDebugHandler will show the attributes derived from a job with their
+// confidence and what they were derived from
+func jobDebugHandler(w http.ResponseWriter, r *http.Request) {
+	hnId := paramValue(strings.TrimPrefix(r.URL.Path, "/admin/job/"), 0)
+	attrs, err := SelectJobAttributeMap(hnId)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Println("failed to select job attributes.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	evidence, err := SelectJobEvidence(hnId)
+	if err != nil {
+		log.Println("failed to select job evidence.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var rows []jobAttributeRow
+	for _, col := range strings.Split(hiringJobColumns, ",") {
+		col = strings.TrimSpace(col)
+		if getIndex([]string{"hn_id", "hiring_story_id", "text", "time"}, col) != -1 {
+			continue
+		}
+		row := jobAttributeRow{Field: col, Value: attrs[col], Uncertain: evidence.Uncertain(col)}
+		if b, ok := row.Value.([]byte); ok {
+			row.Value = string(b)
+		}
+		if e, ok := evidence[col]; ok {
+			row.Evidence = &e
+		}
+		rows = append(rows, row)
+	}
+
+	data := struct {
+		HnId uint64
+		Text template.HTML
+		Rows []jobAttributeRow
+	}{
+		HnId: hnId,
+		Text: sanitizeJobHTML(fmt.Sprint(attrs["text"])),
+		Rows: rows,
+	}
+	renderTemplate(w, "admin_job.html", data)
+}
+
+// companyMergeHandler will merge the source company into the target one on
+// POST, both given by slug, and show the merge form on GET
+func companyMergeHandler(w http.ResponseWriter, r *http.Request) {
+	var message string
+	if r.Method == http.MethodPost {
+		if !requireSameOrigin(w, r) {
+			return
+		}
+
+		source, err := GetCompanyBySlug(companyKey(r.PostFormValue("source")))
+		if err == nil {
+			var target *Company
+			target, err = GetCompanyBySlug(companyKey(r.PostFormValue("target")))
+			if err == nil {
+				err = MergeCompanies(source.Id, target.Id)
+			}
+			if err == nil {
+				detail := fmt.Sprintf("merged %s (%d) into %s (%d)", source.Name, source.Id, target.Name, target.Id)
+				if err := RecordAudit(cfg.AdminUser, "company.merge", target.Slug, detail); err != nil {
+					log.Println("failed to record audit entry.", err)
+				}
+				bus.Publish(event{Topic: eventCompaniesMerged})
+				http.Redirect(w, r, "/admin/companies?merged="+url.QueryEscape(source.Name), http.StatusSeeOther)
+				return
+			}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			message = "Unknown company, pick the source and target companies from the suggestions."
+		} else {
+			log.Println("failed to merge companies.", err)
+			message = "Failed to merge companies: " + err.Error()
+		}
+	} else if merged := r.URL.Query().Get("merged"); merged != "" {
+		message = merged + " was merged. Its name is now an alias of the target company."
+	}
+
+	data := struct {
+		Message string
+		Source  string
+		Target  string
+	}{
+		Message: message,
+		Source:  r.PostFormValue("source"),
+		Target:  r.PostFormValue("target"),
+	}
+	renderTemplate(w, "admin_companies.html", data)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequireSameOrigin(t *testing.T) {
+	cases := []struct {
+		site string
+		want bool
+	}{
+		{"", true},
+		{"same-origin", true},
+		{"same-site", false},
+		{"cross-site", false},
+		{"none", false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/admin/tags", nil)
+		if c.site != "" {
+			r.Header.Set("Sec-Fetch-Site", c.site)
+		}
+		w := httptest.NewRecorder()
+		if got := requireSameOrigin(w, r); got != c.want {
+			t.Errorf("requireSameOrigin with Sec-Fetch-Site %q = %t, want %t", c.site, got, c.want)
+		}
+		if !c.want && w.Code != http.StatusForbidden {
+			t.Errorf("Sec-Fetch-Site %q answered %d, want 403", c.site, w.Code)
+		}
+	}
+}
+
+// insertAuditEntries will insert audit entries created at the given times,
+// as RecordAudit stamps them with the current time
+func insertAuditEntries(t *testing.T, entries []AuditEntry) {
+	t.Helper()
+	for _, e := range entries {
+		sql := `INSERT INTO audit_log (actor, action, target, detail, created_at) VALUES (?, ?, ?, ?, ?)`
+		if _, err := db.Exec(sql, e.Actor, e.Action, e.Target, e.Detail, e.CreatedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// auditDay will return the unix time of an hour of a day of october 2026
+func auditDay(day, hour int) int64 {
+	return time.Date(2026, 10, day, hour, 0, 0, 0, time.UTC).Unix()
+}
+
+func TestAuditLogFilters(t *testing.T) {
+	useTestDB(t)
+	insertAuditEntries(t, []AuditEntry{
+		{Actor: "admin", Action: "company.merge", Target: "acme", CreatedAt: auditDay(1, 9)},
+		{Actor: "admin", Action: "tag.rule", Target: "golang", CreatedAt: auditDay(2, 23)},
+		{Actor: "system", Action: "report.send", Target: "2026-09", CreatedAt: auditDay(3, 8)},
+		{Actor: "admin", Action: "company.merge", Target: "initech", CreatedAt: auditDay(5, 12)},
+	})
+
+	cases := []struct {
+		name string
+		q    string
+		want []string
+	}{
+		{"all, newest first", "", []string{"initech", "2026-09", "golang", "acme"}},
+		{"actor", "actor=system", []string{"2026-09"}},
+		{"action", "action=company.merge", []string{"initech", "acme"}},
+		{"actor and action", "actor=admin&action=tag.rule", []string{"golang"}},
+		// the to date is inclusive, up to the end of its day
+		{"date range", "from=2026-10-02&to=2026-10-03", []string{"2026-09", "golang"}},
+		{"from", "from=2026-10-04", []string{"initech"}},
+		{"invalid dates are ignored", "from=yesterday", []string{"initech", "2026-09", "golang", "acme"}},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		auditLogHandler(w, httptest.NewRequest(http.MethodGet, "/admin/audit?"+c.q+"&format=csv", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: answered %d", c.name, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+			t.Errorf("%s: Content-Type = %q, want text/csv", c.name, ct)
+		}
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) == 0 || strings.Join(records[0], ",") != "id,created_at,actor,action,target,detail" {
+			t.Fatalf("%s: csv header = %q", c.name, records)
+		}
+		var targets []string
+		for _, r := range records[1:] {
+			targets = append(targets, r[4])
+		}
+		if strings.Join(targets, " ") != strings.Join(c.want, " ") {
+			t.Errorf("%s: csv targets = %q, want %q", c.name, targets, c.want)
+		}
+
+		// the page lists the same entries
+		w = httptest.NewRecorder()
+		auditLogHandler(w, httptest.NewRequest(http.MethodGet, "/admin/audit?"+c.q, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: page answered %d", c.name, w.Code)
+		}
+		body := w.Body.String()
+		for _, target := range []string{"acme", "golang", "2026-09", "initech"} {
+			listed := strings.Contains(body, `<td class="pr-2">`+target+"</td>")
+			if want := getIndex(c.want, target) != -1; listed != want {
+				t.Errorf("%s: page lists %s = %t, want %t", c.name, target, listed, want)
+			}
+		}
+	}
+}
+
+func TestAuditLogCsvFields(t *testing.T) {
+	useTestDB(t)
+	insertAuditEntries(t, []AuditEntry{
+		{Actor: "admin", Action: "sql.query", Target: "playground", Detail: "SELECT \"a, b\"\nFROM hiring_job", CreatedAt: auditDay(1, 9)},
+	})
+	w := httptest.NewRecorder()
+	auditLogHandler(w, httptest.NewRequest(http.MethodGet, "/admin/audit?format=csv", nil))
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1", "2026-10-01T09:00:00Z", "admin", "sql.query", "playground", "SELECT \"a, b\"\nFROM hiring_job"}
+	if len(records) != 2 || strings.Join(records[1], "|") != strings.Join(want, "|") {
+		t.Errorf("csv rows = %q, want the header and %q", records, want)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "audit_log.csv") {
+		t.Errorf("Content-Disposition = %q, want an audit_log.csv attachment", cd)
+	}
+}
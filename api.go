@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// companySuggestions is the max number of companies returned by the autocomplete api
+	companySuggestions = 10
+	// tagSuggestions is the max number of tags returned by the autocomplete api
+	tagSuggestions = 10
+)
+
+// companiesApiHandler will return the companies whose name or alias starts
+// with the prefix param, for autocompletes
+func companiesApiHandler(w http.ResponseWriter, r *http.Request) {
+	matches, err := SelectCompaniesByPrefix(r.URL.Query().Get("prefix"), companySuggestions)
+	if err != nil {
+		log.Println("failed to select companies.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(matches); err != nil {
+		log.Println("failed to encode companies.", err)
+	}
+}
+
+// tagsApiHandler will return the tags starting with the prefix param along
+// with their number of jobs in the current story, the most frequent first
+func tagsApiHandler(w http.ResponseWriter, r *http.Request) {
+	hs, err := GetCurrentHiringStory()
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println("failed to get current story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	counts, err := SelectStoryTagCounts(hs.HnId)
+	if err != nil {
+		log.Println("failed to count tags.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	matches := tagsByPrefix(r.URL.Query().Get("prefix"), counts)
+	if len(matches) > tagSuggestions {
+		matches = matches[:tagSuggestions]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(matches); err != nil {
+		log.Println("failed to encode tags.", err)
+	}
+}
+
+// searchApiResult is a job matching a search
+type searchApiResult struct {
+	Id         uint64 `json:"id"`
+	StoryId    uint64 `json:"story_id"`
+	StoryTitle string `json:"story_title"`
+	Company    string `json:"company"`
+	Role       string `json:"role"`
+	Location   string `json:"location"`
+	Remote     string `json:"remote"`
+	Visa       string `json:"visa"`
+	Link       string `json:"link"`
+	// Snippet is the escaped html of the text around the matched terms, which
+	// are marked with <mark> tags
+	Snippet string `json:"snippet"`
+}
+
+// searchApiHandler will return the jobs of a story, or of every story with
+// story=all, matching the q param like the search page, best matches first
+func searchApiHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	all := q.Get("story") == searchAllStories
+	var hsId uint64
+	var err error
+	if !all {
+		hsId, err = uintParam(q, "story")
+	}
+	filter, filterErr := parseFilterState(q)
+	if filter.Query == "" {
+		err = errors.Join(err, invalidParamError{Param: "q", Value: q.Get("q"), Expected: searchSyntaxHint})
+	} else {
+		err = errors.Join(err, searchSyntaxError(filter.Query))
+	}
+	if all && isRegexSearch(filter.Query) {
+		err = errors.Join(err, invalidParamError{Param: "story", Value: searchAllStories, Expected: "a story id", Reason: errRegexAllStories.Error()})
+	}
+	filter = withSavedExclusions(w, r, filter)
+	if err := errors.Join(err, filterErr); err != nil {
+		apiError(w, http.StatusBadRequest, "invalid query params", err)
+		return
+	}
+
+	hs, err := searchStory(hsId)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(w, http.StatusNotFound, "story not found", nil)
+		return
+	}
+	if err != nil {
+		log.Println("failed to get story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	scope := jobScope{StoryId: hs.HnId}
+	if all {
+		scope = jobScope{}
+	}
+	res, err := searchQuery(scope, filter)
+	var page listPage
+	if err == nil {
+		matched := filter
+		matched.Query = res.Query
+		page, err = selectJobPage(scope, matched)
+	}
+	if len(paramErrors(err)) > 0 {
+		apiError(w, http.StatusBadRequest, "invalid query params", err)
+		return
+	}
+	if err != nil {
+		log.Println("failed to search hiring jobs.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if len(res.Spellings) > 0 {
+		w.Header().Set(closeSpellingsHeader, strings.Join(res.Spellings, ", "))
+	}
+	recordSearchMiss(filter, page.Total)
+	var extra url.Values
+	if v := q.Get("story"); v != "" {
+		extra = url.Values{"story": {v}}
+	}
+	prevUrl, nextUrl := page.pageUrls("/api/search", filter, extra)
+	setLinkHeaders(w, prevUrl, nextUrl)
+	jobs := page.Jobs
+	if didYouMean, err := searchSuggestion(filter.Query, page.Total); err != nil {
+		log.Println("failed to suggest a search.", err)
+	} else if didYouMean != "" {
+		w.Header().Set(didYouMeanHeader, didYouMean)
+	}
+
+	snippets, err := searchSnippets(res.Query, jobs)
+	if err != nil {
+		log.Println("failed to select search snippets.", err)
+	}
+	results := make([]searchApiResult, len(jobs))
+	for i, hj := range jobs {
+		h := parseJobHeadline(hj.Text)
+		results[i] = searchApiResult{
+			Id:         hj.HnId,
+			StoryId:    hj.HiringStoryId,
+			StoryTitle: hj.StoryTitle,
+			Company:    h.Company,
+			Role:       h.Role,
+			Location:   hj.Location,
+			Remote:     hj.Remote,
+			Visa:       hj.Visa,
+			Link:       canonicalUrl(fmt.Sprintf("/job/%d", hj.HnId)),
+			Snippet:    string(highlightSnippet(snippets[hj.HnId])),
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Println("failed to encode search results.", err)
+	}
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiV1Path is the route prefix of the versioned json api, meant for the
+// clients and scripts built on the ingested jobs
+const apiV1Path = "/api/v1/"
+
+// apiStory is a hiring story as returned by the v1 api
+type apiStory struct {
+	Id    uint64 `json:"id"`
+	Title string `json:"title"`
+	Month string `json:"month"`
+	HnUrl string `json:"hn_url"`
+}
+
+// apiStorySync is the state of the ingestion of a story as of its last sync
+type apiStorySync struct {
+	// SyncedAt is the time of the last sync, empty for stories not synced yet
+	SyncedAt string `json:"synced_at"`
+	// Comments are the top level comments of the story on hacker news
+	Comments int `json:"comments"`
+	Ingested int `json:"ingested"`
+	Skipped  int `json:"skipped"`
+	// Missing are the comments neither ingested nor skipped yet
+	Missing  int  `json:"missing"`
+	Complete bool `json:"complete"`
+}
+
+// apiStorySummary is a hiring story as listed by the v1 api, with its counts
+type apiStorySummary struct {
+	apiStory
+	Time string `json:"time"`
+	// Jobs are the live jobs of the story
+	Jobs   uint64       `json:"jobs"`
+	Pinned bool         `json:"pinned"`
+	Sync   apiStorySync `json:"sync"`
+}
+
+// apiSalary is the salary range of a job as posted and converted to USD
+type apiSalary struct {
+	Min      uint64 `json:"min"`
+	Max      uint64 `json:"max"`
+	Currency string `json:"currency"`
+	MinUsd   uint64 `json:"min_usd"`
+	MaxUsd   uint64 `json:"max_usd"`
+}
+
+// apiJobFields are the fields parsed from the text of a job
+type apiJobFields struct {
+	Company         string   `json:"company"`
+	Role            string   `json:"role"`
+	Headline        string   `json:"headline"`
+	CompanyDomain   string   `json:"company_domain"`
+	Levels          []string `json:"levels"`
+	EmploymentTypes []string `json:"employment_types"`
+	Language        string   `json:"language"`
+	Location        string   `json:"location"`
+	Latitude        *float64 `json:"latitude,omitempty"`
+	Longitude       *float64 `json:"longitude,omitempty"`
+	Remote          []string `json:"remote"`
+	Timezone        string   `json:"timezone"`
+	Visa            string   `json:"visa"`
+	Funding         string   `json:"funding"`
+	Employees       int      `json:"employees"`
+	CompanySize     string   `json:"company_size"`
+	YCBatch         string   `json:"yc_batch"`
+	Interview       []string `json:"interview"`
+	InterviewRounds int      `json:"interview_rounds"`
+	// Deadline is the date applications close on, like 2024-06-15
+	Deadline   string     `json:"deadline"`
+	Tags       []string   `json:"tags"`
+	Benefits   []string   `json:"benefits"`
+	Equity     string     `json:"equity"`
+	Salary     *apiSalary `json:"salary"`
+	ApplyEmail string     `json:"apply_email"`
+	ApplyUrl   string     `json:"apply_url"`
+	// DuplicateOf is the id of the job this one reposts, 0 for original posts
+	DuplicateOf uint64 `json:"duplicate_of"`
+}
+
+// apiJob is a job as returned by the v1 api: the post as fetched from hacker
+// news along with the fields parsed from it
+type apiJob struct {
+	Id      uint64 `json:"id"`
+	StoryId uint64 `json:"story_id"`
+	Poster  string `json:"poster"`
+	Time    string `json:"time"`
+	// Text is the html of the post as served by the hacker news api
+	Text   string       `json:"text"`
+	Url    string       `json:"url"`
+	HnUrl  string       `json:"hn_url"`
+	Parsed apiJobFields `json:"parsed"`
+}
+
+// apiJobStatuses are the names of the job statuses returned by the v1 api
+var apiJobStatuses = map[uint8]string{
+	jobStatusOk:      "ok",
+	jobStatusDead:    "dead",
+	jobStatusDeleted: "deleted",
+}
+
+// commaList will split a comma separated field, returning an empty list for
+// empty fields so clients always get an array
+func commaList(v string) []string {
+	if v == "" {
+		return []string{}
+	}
+	return strings.Split(v, ",")
+}
+
+// newApiStory will return the api representation of a story
+func newApiStory(hs HiringStory) apiStory {
+	month, _ := storyTitleParts(hs.Title)
+	return apiStory{Id: hs.HnId, Title: hs.Title, Month: month, HnUrl: fmt.Sprintf("%s%d", hnItemUrl, hs.HnId)}
+}
+
+// newApiStorySummary will return the api representation of a listed story
+func newApiStorySummary(hs HiringStorySummary) apiStorySummary {
+	s := apiStorySummary{
+		apiStory: newApiStory(hs.HiringStory),
+		Time:     time.Unix(int64(hs.Time), 0).UTC().Format(time.RFC3339),
+		Jobs:     hs.Jobs,
+		Pinned:   hs.Pinned,
+		Sync: apiStorySync{
+			Comments: hs.Comments,
+			Ingested: hs.Ingested,
+			Skipped:  hs.Skipped,
+			Missing:  hs.Missing(),
+			Complete: hs.SyncedAt > 0 && hs.Missing() == 0,
+		},
+	}
+	if hs.SyncedAt > 0 {
+		s.Sync.SyncedAt = time.Unix(int64(hs.SyncedAt), 0).UTC().Format(time.RFC3339)
+	}
+	return s
+}
+
+// newApiJob will return the api representation of a job
+func newApiJob(hj HiringJob) apiJob {
+	h := parseJobHeadline(hj.Text)
+	j := apiJob{
+		Id:      hj.HnId,
+		StoryId: hj.HiringStoryId,
+		Poster:  hj.Poster,
+		Time:    time.Unix(int64(hj.Time), 0).UTC().Format(time.RFC3339),
+		Text:    hj.Text,
+		Url:     canonicalUrl(fmt.Sprintf("/job/%d", hj.HnId)),
+		HnUrl:   fmt.Sprintf("%s%d", hnItemUrl, hj.HnId),
+		Parsed: apiJobFields{
+			Company:         h.Company,
+			Role:            h.Role,
+			Headline:        hj.Headline(),
+			CompanyDomain:   hj.CompanyDomain,
+			Levels:          commaList(hj.Level),
+			EmploymentTypes: commaList(hj.EmploymentType),
+			Language:        hj.Language,
+			Location:        hj.Location,
+			Remote:          commaList(hj.Remote),
+			Timezone:        hj.Timezone,
+			Visa:            hj.Visa,
+			Funding:         hj.Funding,
+			Employees:       hj.Employees,
+			CompanySize:     hj.CompanySize,
+			YCBatch:         hj.YCBatch,
+			Interview:       commaList(hj.Interview),
+			InterviewRounds: hj.InterviewRounds,
+			Deadline:        hj.Deadline,
+			Tags:            commaList(hj.Tags),
+			Benefits:        commaList(hj.Benefits),
+			Equity:          hj.Equity,
+			ApplyEmail:      hj.ApplyEmail,
+			ApplyUrl:        hj.ApplyUrl,
+			DuplicateOf:     hj.DuplicateOf,
+		},
+	}
+	if hj.Latitude != 0 || hj.Longitude != 0 {
+		lat, lng := hj.Latitude, hj.Longitude
+		j.Parsed.Latitude, j.Parsed.Longitude = &lat, &lng
+	}
+	if hj.SalaryCurrency != "" {
+		j.Parsed.Salary = &apiSalary{
+			Min:      hj.SalaryMin,
+			Max:      hj.SalaryMax,
+			Currency: hj.SalaryCurrency,
+			MinUsd:   hj.SalaryMinUsd,
+			MaxUsd:   hj.SalaryMaxUsd,
+		}
+	}
+	return j
+}
+
+// writeApiJSON will send v as the json body of an api response
+func writeApiJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("failed to encode api response.", err)
+	}
+}
+
+// apiV1Handler will route the requests of the v1 api by path
+func apiV1Handler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiV1Path), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "stories":
+		storiesApiHandler(w, r)
+	case len(parts) == 3 && parts[0] == "stories" && parts[2] == "jobs":
+		storyJobsApiHandler(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "jobs":
+		jobApiHandler(w, r, parts[1])
+	default:
+		apiError(w, http.StatusNotFound, "unknown api path", nil)
+	}
+}
+
+// storiesApiHandler will list every ingested hiring story at /api/v1/stories,
+// newest first, with its job counts and sync status, so clients can find the
+// months available
+func storiesApiHandler(w http.ResponseWriter, r *http.Request) {
+	stories, err := SelectHiringStories(storyKindHiring)
+	if err != nil {
+		log.Println("failed to select stories.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+
+	body := struct {
+		Stories []apiStorySummary `json:"stories"`
+	}{
+		Stories: make([]apiStorySummary, len(stories)),
+	}
+	for i, hs := range stories {
+		body.Stories[i] = newApiStorySummary(hs)
+	}
+	writeApiJSON(w, body)
+}
+
+// apiCursor is the position a page of the jobs of a story starts from: the
+// jobs after the job of the cursor in the sort order of the listing, or before
+// it when backward. Pages are selected from the sort key of the job rather
+// than its place in the listing, so walking a story stays reliable while a
+// sync adds, edits or removes jobs.
+type apiCursor struct {
+	StoryId  uint64
+	JobId    uint64
+	Backward bool
+}
+
+// encode will return the cursor as an opaque url safe string
+func (c apiCursor) encode() string {
+	dir := "a"
+	if c.Backward {
+		dir = "b"
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s:%d", c.StoryId, dir, c.JobId)))
+}
+
+// decodeApiCursor will parse a cursor returned by encode
+func decodeApiCursor(v string) (apiCursor, error) {
+	var c apiCursor
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return c, errInvalidApiCursor
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 3 || (parts[1] != "a" && parts[1] != "b") {
+		return c, errInvalidApiCursor
+	}
+	if c.StoryId, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return c, errInvalidApiCursor
+	}
+	if c.JobId, err = strconv.ParseUint(parts[2], 10, 64); err != nil {
+		return c, errInvalidApiCursor
+	}
+	c.Backward = parts[1] == "b"
+	return c, nil
+}
+
+// errInvalidApiCursor is returned for cursors not returned by the api
+var errInvalidApiCursor = errors.New("invalid cursor")
+
+// storyJobsApiHandler will return a page of the live jobs of a story, at
+// /api/v1/stories/<hn id>/jobs, narrowed down and sorted by the reader filter
+// params. The pages around it are linked in the next and prev fields of the
+// body and in Link headers, with an opaque cursor param. The after and before
+// params of the reader are taken as cursors too.
+func storyJobsApiHandler(w http.ResponseWriter, r *http.Request, id string) {
+	filter, err := parseFilterState(r.URL.Query())
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid query params", err)
+		return
+	}
+	filter = withSavedExclusions(w, r, filter)
+	hs, err := GetHiringStory(paramValue(id, 0))
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(w, http.StatusNotFound, "story not found", nil)
+		return
+	}
+	if err != nil {
+		log.Println("failed to get story.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+	latest, err := GetLatestHiringStory()
+	if err != nil {
+		log.Println("failed to get latest story.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+
+	cursor := apiCursor{StoryId: hs.HnId, JobId: filter.After}
+	if filter.Before > 0 {
+		cursor.JobId, cursor.Backward = filter.Before, true
+	}
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		if cursor, err = decodeApiCursor(v); err != nil {
+			apiError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		if cursor.StoryId != hs.HnId {
+			apiError(w, http.StatusBadRequest, "cursor of another story", nil)
+			return
+		}
+	}
+
+	scope := jobScope{StoryId: hs.HnId}
+	total, err := CountJobList(scope, filter)
+	if len(paramErrors(err)) > 0 {
+		apiError(w, http.StatusBadRequest, "invalid query params", err)
+		return
+	}
+	if err != nil {
+		log.Println("failed to count hiring jobs.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+	size := filter.pageSize()
+	// one more job than a page tells whether there is a page past it
+	page, err := selectJobs(scope, filter, jobPage{Cursor: cursor.JobId, Backward: cursor.Backward, Limit: size + 1})
+	if err != nil {
+		log.Println("failed to select hiring jobs page.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+	more := len(page) > size
+	if more && cursor.Backward {
+		page = page[1:]
+	} else if more {
+		page = page[:size]
+	}
+	var prev, next apiCursor
+	if len(page) > 0 {
+		if (cursor.Backward && more) || (!cursor.Backward && cursor.JobId > 0) {
+			prev = apiCursor{StoryId: hs.HnId, JobId: page[0].HnId, Backward: true}
+		}
+		if (!cursor.Backward && more) || cursor.Backward {
+			next = apiCursor{StoryId: hs.HnId, JobId: page[len(page)-1].HnId}
+		}
+	}
+	pageUrl := func(c apiCursor) string {
+		if c.JobId == 0 {
+			return ""
+		}
+		q := filter.query()
+		q.Del("after")
+		q.Del("before")
+		q.Set("cursor", c.encode())
+		return r.URL.Path + "?" + q.Encode()
+	}
+	prevUrl, nextUrl := pageUrl(prev), pageUrl(next)
+	setLinkHeaders(w, prevUrl, nextUrl)
+
+	body := struct {
+		Story apiStory `json:"story"`
+		// Total is the number of jobs matching the filter, across the pages
+		Total int      `json:"total"`
+		Jobs  []apiJob `json:"jobs"`
+		// Next and Prev are the urls of the pages around this one, empty at the ends
+		Next string `json:"next"`
+		Prev string `json:"prev"`
+	}{
+		Story: newApiStory(*hs),
+		Total: total,
+		Jobs:  make([]apiJob, len(page)),
+		Next:  nextUrl,
+		Prev:  prevUrl,
+	}
+	for i, hj := range page {
+		body.Jobs[i] = newApiJob(hj.HiringJob)
+	}
+	if hs.HnId != latest.HnId {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", archiveMaxAge))
+	}
+	writeApiJSON(w, body)
+}
+
+// jobApiHandler will return a job at /api/v1/jobs/<hn id>, whatever its
+// status: ok, or dead and deleted for the jobs hacker news took down, whose
+// fields are kept as last fetched. The story of the job is returned along.
+func jobApiHandler(w http.ResponseWriter, r *http.Request, id string) {
+	hj, status, err := GetHiringJobWithStatus(paramValue(id, 0))
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(w, http.StatusNotFound, "job not found", nil)
+		return
+	}
+	if err != nil {
+		log.Println("failed to get hiring job.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+	hs, err := GetHiringStory(hj.HiringStoryId)
+	if err != nil {
+		log.Println("failed to get story.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+	latest, err := GetLatestHiringStory()
+	if err != nil {
+		log.Println("failed to get latest story.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+
+	body := struct {
+		apiJob
+		Status string   `json:"status"`
+		Story  apiStory `json:"story"`
+	}{
+		apiJob: newApiJob(*hj),
+		Status: apiJobStatuses[status],
+		Story:  newApiStory(*hs),
+	}
+	if hs.HnId != latest.HnId {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", archiveMaxAge))
+	}
+	writeApiJSON(w, body)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditEntry struct {
+	Id        uint64
+	Actor     string
+	Action    string
+	Target    string
+	Detail    string
+	CreatedAt int64 `db:"created_at"`
+}
+
+// Created will return the entry creation time
+func (ae AuditEntry) Created() time.Time {
+	return time.Unix(ae.CreatedAt, 0).UTC()
+}
+
+// AuditFilter narrows down the audit log entries returned by SelectAuditLog.
+// Zero values are ignored.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+func RecordAudit(actor, action, target, detail string) error {
+	sql := `INSERT INTO audit_log (actor, action, target, detail, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.Exec(sql, actor, action, target, detail, time.Now().Unix())
+	return err
+}
+
+// auditLogQuery will build the sql and args selecting the entries matching f
+func auditLogQuery(f AuditFilter) (string, []any) {
+	var where []string
+	var args []any
+	if f.Actor != "" {
+		where = append(where, "actor=?")
+		args = append(args, f.Actor)
+	}
+	if f.Action != "" {
+		where = append(where, "action=?")
+		args = append(args, f.Action)
+	}
+	if !f.From.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, f.From.Unix())
+	}
+	if !f.To.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, f.To.Unix())
+	}
+
+	sql := `SELECT id, actor, action, target, detail, created_at FROM audit_log`
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	sql += " ORDER BY created_at DESC, id DESC"
+	if f.Limit > 0 {
+		sql += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+	return sql, args
+}
+
+func SelectAuditLog(f AuditFilter) ([]AuditEntry, error) {
+	sql, args := auditLogQuery(f)
+	var entries []AuditEntry
+	if err := db.Select(&entries, sql, args...); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func QueryAuditLog(f AuditFilter) (*sqlx.Rows, error) {
+	sql, args := auditLogQuery(f)
+	return db.Queryx(sql, args...)
+}
+
+func SelectAuditActors() ([]string, error) {
+	var actors []string
+	err := db.Select(&actors, `SELECT DISTINCT actor FROM audit_log ORDER BY actor`)
+	return actors, err
+}
+
+func SelectAuditActions() ([]string, error) {
+	var actions []string
+	err := db.Select(&actions, `SELECT DISTINCT action FROM audit_log ORDER BY action`)
+	return actions, err
+}
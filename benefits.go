@@ -0,0 +1,90 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	benefitEquity     = "equity"
+	benefit401k       = "401k"
+	benefitHealth     = "health"
+	benefitFourDay    = "4day"
+	benefitPTO        = "pto"
+	benefitParental   = "parental"
+	benefitLearning   = "learning"
+	benefitRetirement = "pension"
+)
+
+var benefits = []string{
+	benefitEquity, benefit401k, benefitRetirement, benefitHealth, benefitFourDay,
+	benefitPTO, benefitParental, benefitLearning,
+}
+
+// benefitNames are the display names of the benefits
+var benefitNames = map[string]string{
+	benefitEquity:     "Equity",
+	benefit401k:       "401k",
+	benefitRetirement: "Pension",
+	benefitHealth:     "Health insurance",
+	benefitFourDay:    "4-day week",
+	benefitPTO:        "Unlimited PTO",
+	benefitParental:   "Parental leave",
+	benefitLearning:   "Learning budget",
+}
+
+var benefitPatterns = map[string]*regexp.Regexp{
+	benefitEquity:     regexp.MustCompile(`(?i)\b(equity|stock options?|rsus?|esop)\b`),
+	benefit401k:       regexp.MustCompile(`(?i)\b401\s?\(?k\)?`),
+	benefitRetirement: regexp.MustCompile(`(?i)\b(pension|retirement plan|superannuation)\b`),
+	benefitHealth:     regexp.MustCompile(`(?i)\b(health|medical|dental|vision)\s+(insurance|care|coverage|benefits|plans?)\b`),
+	benefitFourDay:    regexp.MustCompile(`(?i)\b(4|four)[- ]day (work )?(week|workweek)\b|\b32[- ]hour (work )?week\b`),
+	benefitPTO:        regexp.MustCompile(`(?i)\b(unlimited|flexible) (pto|vacation|time off|holidays?)\b`),
+	benefitParental:   regexp.MustCompile(`(?i)\b(parental|maternity|paternity) leave\b`),
+	benefitLearning:   regexp.MustCompile(`(?i)\b(learning|education|conference|training) (budget|stipend|allowance)\b`),
+}
+
+// equityRangePattern matches equity ranges like "0.1%-0.5%" or "0.25% - 1% equity"
+var equityRangePattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%?\s*(?:-|–|to)\s*(\d+(?:\.\d+)?)\s*%`)
+var equityPercentPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%\s*(?:equity|of equity|stock|options)`)
+
+// jobBenefits will return the comma separated benefit flags found in a job text
+func jobBenefits(text string) string {
+	plain := jobPlainText(text)
+	var found []string
+	for _, b := range benefits {
+		if benefitPatterns[b].MatchString(plain) {
+			found = append(found, b)
+		}
+	}
+	return strings.Join(found, ",")
+}
+
+// jobEquity will return the equity range offered in a job text, like
+// "0.1%-0.5%". Only ranges close to an equity mention are considered.
+func jobEquity(text string) string {
+	plain := jobPlainText(text)
+	for _, loc := range benefitPatterns[benefitEquity].FindAllStringIndex(plain, -1) {
+		// Look for the percentages around the equity mention
+		start, end := loc[0]-60, loc[1]+60
+		if start < 0 {
+			start = 0
+		}
+		if end > len(plain) {
+			end = len(plain)
+		}
+		window := plain[start:end]
+		if m := equityRangePattern.FindStringSubmatch(window); m != nil {
+			return m[1] + "%-" + m[2] + "%"
+		}
+		if m := equityPercentPattern.FindStringSubmatch(window); m != nil {
+			return m[1] + "%"
+		}
+	}
+	return ""
+}
+
+// isBenefit will return true when v is a known benefit flag
+func isBenefit(v string) bool {
+	return getIndex(benefits, v) != -1
+}
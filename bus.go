@@ -0,0 +1,52 @@
+package main
+
+import "sync"
+
+const (
+	// eventSyncCompleted is published after a sync stored new data
+	eventSyncCompleted = "sync.completed"
+	// eventCompaniesMerged is published after an admin merged two companies
+	eventCompaniesMerged = "companies.merged"
+	// eventTagsChanged is published after the tag rules were applied to the stored jobs
+	eventTagsChanged = "tags.changed"
+	// eventStoryPinned is published after an admin pinned or unpinned the story served on /
+	eventStoryPinned = "story.pinned"
+)
+
+// event is a message published on the bus
+type event struct {
+	Topic   string
+	StoryId uint64
+}
+
+// eventBus dispatches events to the handlers subscribed to their topic.
+// Handlers run synchronously in the publishing goroutine, in subscription order.
+type eventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]func(event)
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{handlers: make(map[string][]func(event))}
+}
+
+// bus is the application wide event bus. Caches subscribe to it to be
+// invalidated when the data they hold changes.
+var bus = newEventBus()
+
+// Subscribe will call fn for every event published on topic
+func (b *eventBus) Subscribe(topic string, fn func(event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], fn)
+}
+
+// Publish will dispatch e to the handlers subscribed to its topic
+func (b *eventBus) Publish(e event) {
+	b.mu.RLock()
+	handlers := b.handlers[e.Topic]
+	b.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(e)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+	"strings"
+)
+
+// changelogText are the release notes, embedded so self-hosted builds show
+// the notes of the code they run
+//
+//go:embed CHANGELOG.md
+var changelogText string
+
+// releaseNote are the notes of a release, identified by its date
+type releaseNote struct {
+	Date  string
+	Notes []string
+}
+
+// parseChangelog will parse release notes made of "## date" headings followed
+// by "- note" lines. Indented lines continue the previous note, other lines are ignored.
+func parseChangelog(text string) []releaseNote {
+	var releases []releaseNote
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "## "):
+			releases = append(releases, releaseNote{Date: trimmed[3:]})
+		case len(releases) == 0 || trimmed == "":
+		case strings.HasPrefix(line, "- "):
+			r := &releases[len(releases)-1]
+			r.Notes = append(r.Notes, trimmed[2:])
+		case line != trimmed:
+			r := &releases[len(releases)-1]
+			if n := len(r.Notes); n > 0 {
+				r.Notes[n-1] += " " + trimmed
+			}
+		}
+	}
+	return releases
+}
+
+var releaseNotes = parseChangelog(changelogText)
+
+// latestRelease will return the date of the latest release. Pages link to the
+// release notes with it, so returning readers are told about new releases.
+func latestRelease() string {
+	if len(releaseNotes) == 0 {
+		return ""
+	}
+	return releaseNotes[0].Date
+}
+
+// whatsNewHandler will serve the release notes, newest first
+func whatsNewHandler(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Releases  []releaseNote
+		Canonical string
+	}{
+		Releases:  releaseNotes,
+		Canonical: canonicalUrl("/whatsnew"),
+	}
+	renderTemplate(w, "whatsnew.html", data)
+}
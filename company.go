@@ -0,0 +1,249 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Company struct {
+	Id     uint64
+	Name   string
+	Slug   string
+	Domain string
+}
+
+var (
+	parenPattern     = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+	nonAlnumPattern  = regexp.MustCompile(`[^a-z0-9]+`)
+	domainishPattern = regexp.MustCompile(`^[a-z0-9\-]+(\.[a-z0-9\-]+)*\.[a-z]{2,}$`)
+)
+
+// companySuffixes are legal entity suffixes ignored when comparing company names
+var companySuffixes = []string{
+	"inc", "incorporated", "llc", "ltd", "limited", "gmbh", "corp", "corporation", "co",
+	"company", "plc", "ag", "sa", "sas", "bv", "ab", "oy", "pty", "srl", "pvt", "lp",
+}
+
+// companyKey will normalize a company name, or domain, into the key used
+// to match the same company across posts. "Stripe", "Stripe, Inc." and
+// "stripe.com" all become "stripe".
+func companyKey(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = parenPattern.ReplaceAllString(name, " ")
+	if domainishPattern.MatchString(name) {
+		if d := normalizeDomain(name); d != "" {
+			name = d[:strings.Index(d, ".")]
+		}
+	}
+
+	words := strings.Fields(nonAlnumPattern.ReplaceAllString(name, " "))
+	for len(words) > 1 && getIndex(companySuffixes, words[len(words)-1]) != -1 {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, "-")
+}
+
+// companyDisplayName will clean up a company name as written in a post
+func companyDisplayName(name string) string {
+	name = strings.TrimSpace(parenPattern.ReplaceAllString(name, " "))
+	name = strings.Join(strings.Fields(name), " ")
+	return strings.TrimRight(name, " ,.-–")
+}
+
+// jobCompanyName will return the company name from the "Company | Role | ..."
+// headline convention, or an empty string for free form posts.
+func jobCompanyName(text string) string {
+	return companyDisplayName(parseJobHeadline(text).Company)
+}
+
+// ResolveCompany will return the id of the company matching name or domain,
+// creating the company when it is not known yet.
+func ResolveCompany(name, domain string) (uint64, error) {
+	key := companyKey(name)
+	if key == "" {
+		key = companyKey(domain)
+		name = domain
+	}
+	if key == "" {
+		return 0, nil
+	}
+
+	var c Company
+	err := db.Get(&c, `SELECT id, name, slug, domain FROM companies WHERE slug=?`, key)
+	if err == nil {
+		if c.Domain == "" && domain != "" {
+			_, err = db.Exec(`UPDATE companies SET domain=? WHERE id=?`, domain, c.Id)
+		}
+		return c.Id, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	var aliasOf uint64
+	err = db.Get(&aliasOf, `SELECT company_id FROM company_alias WHERE alias_key=?`, key)
+	if err == nil {
+		return aliasOf, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	if domain != "" {
+		err = db.Get(&c, `SELECT id, name, slug, domain FROM companies WHERE domain=? ORDER BY id LIMIT 1`, domain)
+		if err == nil {
+			// the company is known under another name on the same domain
+			_, err = db.Exec(`INSERT OR IGNORE INTO company_alias (alias_key, company_id, name) VALUES (?, ?, ?)`,
+				key, c.Id, companyDisplayName(name))
+			return c.Id, err
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+	}
+
+	res, err := db.Exec(`INSERT INTO companies (name, slug, domain, created_at) VALUES (?, ?, ?, ?)`,
+		companyDisplayName(name), key, domain, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(id), nil
+}
+
+func GetCompany(id uint64) (*Company, error) {
+	var c Company
+	if err := db.Get(&c, `SELECT id, name, slug, domain FROM companies WHERE id=?`, id); err != nil {
+		return &c, err
+	}
+
+	return &c, nil
+}
+
+// CompanyMatch is a company found by prefix, through its name or one of its aliases
+type CompanyMatch struct {
+	Id     uint64 `json:"id"`
+	Name   string `json:"name"`
+	Slug   string `json:"slug"`
+	Domain string `json:"domain"`
+	// Alias is the alias name that matched, empty when the company name matched
+	Alias string `json:"alias"`
+	Jobs  int    `json:"jobs"`
+}
+
+// companyPrefixKey will normalize a typed prefix like companyKey, keeping a
+// trailing separator so "new " only matches names with more words
+func companyPrefixKey(prefix string) string {
+	key := nonAlnumPattern.ReplaceAllString(strings.ToLower(prefix), "-")
+	return strings.TrimLeft(key, "-")
+}
+
+// SelectCompaniesByPrefix will return up to limit companies whose name or
+// alias starts with prefix, the ones with most jobs first
+func SelectCompaniesByPrefix(prefix string, limit int) ([]CompanyMatch, error) {
+	key := companyPrefixKey(prefix)
+	if key == "" {
+		return []CompanyMatch{}, nil
+	}
+
+	// keys only hold [a-z0-9-], so every key with the prefix sorts before prefix+"~"
+	var rows []CompanyMatch
+	sql := `SELECT c.id, c.name, c.slug, c.domain, '' AS alias,
+            (SELECT COUNT(*) FROM job_attribute ja WHERE ja.company_id = c.id) AS jobs
+            FROM companies c WHERE c.slug >= ? AND c.slug < ?
+            UNION ALL
+            SELECT c.id, c.name, c.slug, c.domain, a.name AS alias,
+            (SELECT COUNT(*) FROM job_attribute ja WHERE ja.company_id = c.id) AS jobs
+            FROM company_alias a JOIN companies c ON c.id = a.company_id
+            WHERE a.alias_key >= ? AND a.alias_key < ?
+            ORDER BY jobs DESC, name ASC`
+	if err := db.Select(&rows, sql, key, key+"~", key, key+"~"); err != nil {
+		return nil, err
+	}
+
+	matches := []CompanyMatch{}
+	seen := map[uint64]int{}
+	for _, m := range rows {
+		if i, ok := seen[m.Id]; ok {
+			if m.Alias == "" {
+				matches[i].Alias = ""
+			}
+			continue
+		}
+		if len(matches) == limit {
+			continue
+		}
+		seen[m.Id] = len(matches)
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// MergeCompanies will move the jobs and aliases of the source company to the
+// target one, keeping the source name as an alias of the target
+func MergeCompanies(sourceId, targetId uint64) error {
+	if sourceId == targetId {
+		return errors.New("cannot merge a company into itself")
+	}
+	source, err := GetCompany(sourceId)
+	if err != nil {
+		return err
+	}
+	if _, err := GetCompany(targetId); err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmts := []struct {
+		sql  string
+		args []any
+	}{
+		{`INSERT OR REPLACE INTO job_field_provenance (hn_id, field, source, version, derived_at)
+            SELECT hn_id, 'company_id', ?, 0, ? FROM job_attribute WHERE company_id=?`, []any{sourceCompanyMerge, time.Now().Unix(), sourceId}},
+		{`UPDATE job_attribute SET company_id=?, updated_at=? WHERE company_id=?`, []any{targetId, time.Now().Unix(), sourceId}},
+		{`UPDATE company_alias SET company_id=? WHERE company_id=?`, []any{targetId, sourceId}},
+		{`INSERT OR REPLACE INTO company_alias (alias_key, company_id, name) VALUES (?, ?, ?)`, []any{source.Slug, targetId, source.Name}},
+		{`UPDATE companies SET domain=? WHERE id=? and domain=''`, []any{source.Domain, targetId}},
+		{`DELETE FROM companies WHERE id=?`, []any{sourceId}},
+	}
+	for _, st := range stmts {
+		if _, err := tx.Exec(st.sql, st.args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetCompanyBySlug will return the company known by slug or by an alias with that key
+func GetCompanyBySlug(slug string) (*Company, error) {
+	var c Company
+	sql := `SELECT id, name, slug, domain FROM companies
+            WHERE slug=? OR id=(SELECT company_id FROM company_alias WHERE alias_key=?)
+            LIMIT 1`
+	if err := db.Get(&c, sql, slug, slug); err != nil {
+		return &c, err
+	}
+
+	return &c, nil
+}
+
+// SelectCompanyNames will select the names of the companies and of their aliases
+func SelectCompanyNames() ([]string, error) {
+	var names []string
+	sql := `SELECT name FROM companies UNION SELECT name FROM company_alias`
+	if err := db.Select(&names, sql); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
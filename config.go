@@ -0,0 +1,235 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// config holds the deployment specific settings read from the environment.
+type config struct {
+	// AdminUser and AdminPassword protect the /admin pages with basic auth.
+	// Admin pages are disabled when no password is set.
+	AdminUser     string
+	AdminPassword string
+
+	// SyncWorkers is the number of concurrent hacker news item requests during a sync
+	SyncWorkers int
+
+	// SigningKey signs time limited export urls. Signed urls are disabled when empty.
+	SigningKey    string
+	SignedUrlTTL  time.Duration
+	PublicBaseUrl string
+
+	// CSP sources allowed in addition to 'self' and per request nonces
+	CSPScriptSrc string
+	CSPStyleSrc  string
+	CSPImgSrc    string
+	// FrameAncestors controls who may frame our pages. Routes under
+	// /embed/ use EmbedFrameAncestors instead.
+	FrameAncestors      string
+	EmbedFrameAncestors string
+	ReferrerPolicy      string
+
+	// TruncateAt is the number of characters after which long job posts are
+	// cut at the next paragraph, with a link to show the rest. 0 disables it.
+	TruncateAt int
+
+	// Enrichers are the names of the enrichers run on jobs, in order. Empty runs all of them.
+	Enrichers []string
+
+	// MapTilesUrl is the tile url template of the jobs map, like
+	// https://tile.openstreetmap.org/{z}/{x}/{y}.png, credited with MapAttribution
+	MapTilesUrl    string
+	MapAttribution string
+
+	// ExchangeRates are the units of a currency worth one USD, like "EUR=0.92",
+	// used to compare salaries. Rates fetched from ExchangeRatesUrl on every sync
+	// replace them.
+	ExchangeRates    []string
+	ExchangeRatesUrl string
+
+	// SearchEngine is the name of the engine jobs are searched with, like fts5, scan or bleve
+	SearchEngine string
+	// SearchPostgresUrl is the connection url of the database the postgres
+	// engine keeps its index in
+	SearchPostgresUrl string
+
+	// Experiments are the enabled experiments with the percent of sessions
+	// shown their variant, like "reader_layout=20"
+	Experiments []string
+
+	// SMTPAddr is the host:port of the smtp server operator notifications are
+	// sent through, to the NotifyTo addresses. Notifications are printed when
+	// either is unset.
+	SMTPAddr     string
+	SMTPUser     string
+	SMTPPassword string
+	NotifyFrom   string
+	NotifyTo     []string
+
+	// PanelTimeout is how long pages wait for the data of an auxiliary panel,
+	// like the story stats, before rendering without it
+	PanelTimeout time.Duration
+
+	// HotHalfLife is how long the engagement of a job takes to count half as
+	// much in the hot sort
+	HotHalfLife time.Duration
+
+	// Synonyms are the search aliases added to defaultSynonyms, like
+	// "k8s=kubernetes", searched along with each other
+	Synonyms []string
+
+	// GeminiAddr is the address the gemini mirror of the site listens on,
+	// like :1965, empty to not serve it. GeminiCert and GeminiKey are the
+	// files of its TLS certificate, self signed when missing.
+	GeminiAddr string
+	GeminiCert string
+	GeminiKey  string
+
+	// RegexSearch enables the regex searches, like re:/senior (go|rust)/i,
+	// which match the posts of a month one by one
+	RegexSearch bool
+
+	// SyncSchedule, CheckSchedule, ReportSchedule, BackupSchedule and
+	// PruneSchedule are the cron specs, like "0 3 * * *" or "@every 1h", the
+	// scheduler runs each job on, "off" to not run it
+	SyncSchedule   string
+	CheckSchedule  string
+	ReportSchedule string
+	BackupSchedule string
+	PruneSchedule  string
+	// ScheduleJitter is the max random delay added to each scheduled run, so
+	// instances started together don't hit hacker news at once
+	ScheduleJitter time.Duration
+	// BackupDir is where scheduled backups are written, keeping the BackupKeep newest
+	BackupDir  string
+	BackupKeep int
+	// LinkCheckAge is how old the last check of an apply link gets before a
+	// sync checks it again, 0 to not check links after syncs
+	LinkCheckAge time.Duration
+}
+
+var cfg = loadConfig()
+
+// envOr will return the value of the environment variable k or d when unset
+func envOr(k, d string) string {
+	if v, ok := os.LookupEnv(k); ok && v != "" {
+		return v
+	}
+	return d
+}
+
+// envInt will parse the environment variable k as an int or return d
+func envInt(k string, d int) int {
+	v := envOr(k, "")
+	if v == "" {
+		return d
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("invalid number %q for %s, using %d", v, k, d)
+		return d
+	}
+	return i
+}
+
+// envDuration will parse the environment variable k as a duration or return d
+func envDuration(k string, d time.Duration) time.Duration {
+	v := envOr(k, "")
+	if v == "" {
+		return d
+	}
+	pd, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("invalid duration %q for %s, using %s", v, k, d)
+		return d
+	}
+	return pd
+}
+
+// envBool will parse the environment variable k as a bool, like 1 or true, or return d
+func envBool(k string, d bool) bool {
+	v := envOr(k, "")
+	if v == "" {
+		return d
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("invalid bool %q for %s, using %t", v, k, d)
+		return d
+	}
+	return b
+}
+
+// envList will split the environment variable k on commas, dropping empty items
+func envList(k string) []string {
+	var items []string
+	for _, v := range strings.Split(envOr(k, ""), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			items = append(items, v)
+		}
+	}
+	return items
+}
+
+// loadConfig will build a config from the environment
+func loadConfig() config {
+	return config{
+		AdminUser:     envOr("WIH_ADMIN_USER", "admin"),
+		AdminPassword: envOr("WIH_ADMIN_PASSWORD", ""),
+
+		SyncWorkers: envInt("WIH_SYNC_WORKERS", 8),
+
+		SigningKey:    envOr("WIH_SIGNING_KEY", ""),
+		SignedUrlTTL:  envDuration("WIH_SIGNED_URL_TTL", 24*time.Hour),
+		PublicBaseUrl: strings.TrimSuffix(envOr("WIH_PUBLIC_BASE_URL", "http://localhost:8080"), "/"),
+
+		CSPScriptSrc:        envOr("WIH_CSP_SCRIPT_SRC", "https://cdn.tailwindcss.com https://unpkg.com"),
+		CSPStyleSrc:         envOr("WIH_CSP_STYLE_SRC", "'unsafe-inline'"),
+		CSPImgSrc:           envOr("WIH_CSP_IMG_SRC", "data: https://icons.duckduckgo.com"),
+		FrameAncestors:      envOr("WIH_FRAME_ANCESTORS", "'none'"),
+		EmbedFrameAncestors: envOr("WIH_EMBED_FRAME_ANCESTORS", "*"),
+		ReferrerPolicy:      envOr("WIH_REFERRER_POLICY", "strict-origin-when-cross-origin"),
+
+		TruncateAt: envInt("WIH_TRUNCATE_AT", 1500),
+		Enrichers:  envList("WIH_ENRICHERS"),
+
+		MapTilesUrl:    envOr("WIH_MAP_TILES_URL", "https://tile.openstreetmap.org/{z}/{x}/{y}.png"),
+		MapAttribution: envOr("WIH_MAP_ATTRIBUTION", "© OpenStreetMap contributors"),
+
+		ExchangeRates:    envList("WIH_EXCHANGE_RATES"),
+		ExchangeRatesUrl: envOr("WIH_EXCHANGE_RATES_URL", ""),
+
+		SearchEngine:      envOr("WIH_SEARCH_ENGINE", "fts5"),
+		SearchPostgresUrl: envOr("WIH_SEARCH_POSTGRES_URL", ""),
+
+		Experiments: envList("WIH_EXPERIMENTS"),
+
+		SMTPAddr:     envOr("WIH_SMTP_ADDR", ""),
+		SMTPUser:     envOr("WIH_SMTP_USER", ""),
+		SMTPPassword: envOr("WIH_SMTP_PASSWORD", ""),
+		NotifyFrom:   envOr("WIH_NOTIFY_FROM", ""),
+		NotifyTo:     envList("WIH_NOTIFY_TO"),
+
+		PanelTimeout: envDuration("WIH_PANEL_TIMEOUT", 500*time.Millisecond),
+		HotHalfLife:  envDuration("WIH_HOT_HALF_LIFE", 72*time.Hour),
+		Synonyms:     envList("WIH_SYNONYMS"),
+		GeminiAddr:   envOr("WIH_GEMINI_ADDR", ""),
+		GeminiCert:   envOr("WIH_GEMINI_CERT", "gemini.crt"),
+		GeminiKey:    envOr("WIH_GEMINI_KEY", "gemini.key"),
+		RegexSearch:  envBool("WIH_REGEX_SEARCH", false),
+
+		SyncSchedule:   envOr("WIH_SYNC_SCHEDULE", "@hourly"),
+		CheckSchedule:  envOr("WIH_CHECK_SCHEDULE", "off"),
+		ReportSchedule: envOr("WIH_REPORT_SCHEDULE", "off"),
+		BackupSchedule: envOr("WIH_BACKUP_SCHEDULE", "off"),
+		PruneSchedule:  envOr("WIH_PRUNE_SCHEDULE", "@daily"),
+		ScheduleJitter: envDuration("WIH_SCHEDULE_JITTER", time.Minute),
+		BackupDir:      envOr("WIH_BACKUP_DIR", "backups"),
+		BackupKeep:     envInt("WIH_BACKUP_KEEP", 7),
+		LinkCheckAge:   envDuration("WIH_LINK_CHECK_AGE", 24*time.Hour),
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	issueStoryCount    = "story.count"
+	issueStoryMissing  = "story.missing"
+	issueStoryUnlisted = "story.unlisted"
+	issueStorySweep    = "story.sweep"
+	issueOrphan        = "orphan"
+	// consistencyChecksKept is the number of checks whose issues are kept
+	consistencyChecksKept = 30
+	// issueExamplesMax is the max number of ids listed as examples of an issue
+	issueExamplesMax = 5
+)
+
+// ConsistencyIssue is a discrepancy found by a consistency check
+type ConsistencyIssue struct {
+	Kind   string
+	Target string
+	Detail string
+}
+
+// ConsistencyCheck is a run of the consistency checker
+type ConsistencyCheck struct {
+	Id        uint64
+	CheckedAt int64 `db:"checked_at"`
+	Issues    int
+}
+
+// Checked will return the time of the check
+func (c ConsistencyCheck) Checked() time.Time {
+	return time.Unix(c.CheckedAt, 0).UTC()
+}
+
+// orphanCheck selects the ids of the rows of a table left without the row they belong to
+type orphanCheck struct {
+	Table       string
+	Description string
+	Sql         string
+}
+
+// orphanChecks are the rows that must belong to a stored job, story or company
+var orphanChecks = []orphanCheck{
+	{"hiring_job", "jobs of no stored story", `SELECT hn_id FROM hiring_job WHERE hiring_story_id NOT IN (SELECT hn_id FROM hiring_story)`},
+	{"job_attribute", "attributes of no stored job", `SELECT hn_id FROM job_attribute WHERE hn_id NOT IN (SELECT hn_id FROM hiring_job)`},
+	{"job_attribute", "jobs marked as reposts of no stored job", `SELECT hn_id FROM job_attribute WHERE duplicate_of != 0 AND duplicate_of NOT IN (SELECT hn_id FROM hiring_job)`},
+	{"job_attribute", "jobs of no stored company", `SELECT hn_id FROM job_attribute WHERE company_id != 0 AND company_id NOT IN (SELECT id FROM companies)`},
+	{"job_attribute_evidence", "evidence of no stored job", `SELECT DISTINCT hn_id FROM job_attribute_evidence WHERE hn_id NOT IN (SELECT hn_id FROM hiring_job)`},
+	{"job_field_provenance", "provenance of no stored job", `SELECT DISTINCT hn_id FROM job_field_provenance WHERE hn_id NOT IN (SELECT hn_id FROM hiring_job)`},
+	{"job_tag", "tags of no stored job", `SELECT DISTINCT hn_id FROM job_tag WHERE hn_id NOT IN (SELECT hn_id FROM hiring_job)`},
+	{"job_engagement", "engagement of no stored job", `SELECT hn_id FROM job_engagement WHERE hn_id NOT IN (SELECT hn_id FROM hiring_job)`},
+	{"job_mark", "read and favorite marks of no stored job", `SELECT hn_id FROM job_mark WHERE hn_id NOT IN (SELECT hn_id FROM hiring_job)`},
+	{"seen_job", "jobs seen by readers of no stored job", `SELECT DISTINCT hn_id FROM seen_job WHERE hn_id NOT IN (SELECT hn_id FROM hiring_job)`},
+	{"hiring_job_revision", "revisions of no stored job", `SELECT DISTINCT hn_id FROM hiring_job_revision WHERE hn_id NOT IN (SELECT hn_id FROM hiring_job)`},
+	{"company_alias", "aliases of no stored company", `SELECT company_id FROM company_alias WHERE company_id NOT IN (SELECT id FROM companies)`},
+}
+
+// fts5OrphanCheck is checked when jobs are searched with the fts5 engine
+var fts5OrphanCheck = orphanCheck{"hiring_job_fts", "indexed texts of no stored job", `SELECT rowid FROM hiring_job_fts WHERE rowid NOT IN (SELECT hn_id FROM hiring_job)`}
+
+// idExamples will describe a few ids of an issue
+func idExamples(ids []uint64) string {
+	var parts []string
+	for i, id := range ids {
+		if i == issueExamplesMax {
+			parts = append(parts, fmt.Sprintf("and %d more", len(ids)-i))
+			break
+		}
+		parts = append(parts, strconv.FormatUint(id, 10))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkOrphans will find the rows left without the job, story or company they belong to
+func checkOrphans() ([]ConsistencyIssue, error) {
+	checks := orphanChecks
+	if jobSearch.Name() == "fts5" {
+		checks = append(checks, fts5OrphanCheck)
+	}
+	var issues []ConsistencyIssue
+	for _, c := range checks {
+		var ids []uint64
+		if err := db.Select(&ids, c.Sql); err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", c.Table, searchError(err))
+		}
+		if len(ids) > 0 {
+			issues = append(issues, ConsistencyIssue{
+				Kind:   issueOrphan,
+				Target: c.Table,
+				Detail: fmt.Sprintf("%d %s, like %s", len(ids), c.Description, idExamples(ids)),
+			})
+		}
+	}
+	return issues, nil
+}
+
+// checkSweeps will find the stories last synced before the next story was
+// posted, which miss the posts made at the end of their month
+func checkSweeps(stories []HiringStorySummary) []ConsistencyIssue {
+	var issues []ConsistencyIssue
+	// stories are sorted newest first, the latest one is synced on every run
+	for i := 1; i < len(stories); i++ {
+		hs, next := stories[i], stories[i-1]
+		if hs.SyncedAt >= next.Time {
+			continue
+		}
+		synced := "never synced"
+		if hs.SyncedAt > 0 {
+			synced = "last synced on " + time.Unix(int64(hs.SyncedAt), 0).UTC().Format(time.DateOnly)
+		}
+		issues = append(issues, ConsistencyIssue{
+			Kind:   issueStorySweep,
+			Target: strconv.FormatUint(hs.HnId, 10),
+			Detail: fmt.Sprintf("%s, %s before the next story was posted on %s", hs.Title, synced,
+				time.Unix(int64(next.Time), 0).UTC().Format(time.DateOnly)),
+		})
+	}
+	return issues
+}
+
+// checkStoryCounts will compare the jobs ingested from each story with its
+// comments on hacker news as of now
+func checkStoryCounts(ctx context.Context, stories []HiringStorySummary) ([]ConsistencyIssue, error) {
+	ids := make([]uint64, len(stories))
+	titles := map[uint64]string{}
+	for i, hs := range stories {
+		ids[i] = hs.HnId
+		titles[hs.HnId] = hs.Title
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var issues []ConsistencyIssue
+	for res := range fetchHnItems(ctx, ids, cfg.SyncWorkers) {
+		if res.Err != nil {
+			return nil, fmt.Errorf("failed to get hiring story item %d: %w", res.Id, res.Err)
+		}
+		var stored []uint64
+		if err := db.Select(&stored, `SELECT hn_id FROM hiring_job WHERE hiring_story_id=? ORDER BY hn_id`, res.Id); err != nil {
+			return nil, err
+		}
+		skipped, err := SelectSkippedItemIds(res.Id)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, compareStoryJobs(titles[res.Id], res.Item, stored, skipped)...)
+	}
+	return issues, nil
+}
+
+// compareStoryJobs will compare the ids of the jobs stored for a story with
+// the comments of its hacker news item. Items skipped at ingest are not missing.
+func compareStoryJobs(title string, hs *hnItem, stored, skipped []uint64) []ConsistencyIssue {
+	target := strconv.FormatUint(hs.Id, 10)
+	kids := map[uint64]bool{}
+	for _, id := range hs.Kids {
+		kids[id] = true
+	}
+	for _, id := range skipped {
+		delete(kids, id)
+	}
+	var unlisted []uint64
+	for _, id := range stored {
+		if !kids[id] {
+			unlisted = append(unlisted, id)
+		}
+		delete(kids, id)
+	}
+	var missing []uint64
+	for _, id := range hs.Kids {
+		if kids[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	var issues []ConsistencyIssue
+	if len(missing) > 0 {
+		issues = append(issues, ConsistencyIssue{
+			Kind:   issueStoryMissing,
+			Target: target,
+			Detail: fmt.Sprintf("%s: %d of %d posts not ingested, like %s", title, len(missing), len(hs.Kids), idExamples(missing)),
+		})
+	}
+	if len(unlisted) > 0 {
+		issues = append(issues, ConsistencyIssue{
+			Kind:   issueStoryUnlisted,
+			Target: target,
+			Detail: fmt.Sprintf("%s: %d stored jobs are not posts of the story anymore, like %s", title, len(unlisted), idExamples(unlisted)),
+		})
+	}
+	if len(stored) > hs.Descendants {
+		issues = append(issues, ConsistencyIssue{
+			Kind:   issueStoryCount,
+			Target: target,
+			Detail: fmt.Sprintf("%s: %d jobs stored but hacker news counts %d comments", title, len(stored), hs.Descendants),
+		})
+	}
+	return issues
+}
+
+// runConsistencyCheck will run every check and return the issues found
+func runConsistencyCheck(ctx context.Context) ([]ConsistencyIssue, error) {
+	var issues []ConsistencyIssue
+	for _, kind := range storyKinds {
+		// each thread is followed by the next thread of its kind
+		stories, err := SelectHiringStories(kind)
+		if err != nil {
+			return nil, err
+		}
+		counts, err := checkStoryCounts(ctx, stories)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, counts...)
+		issues = append(issues, checkSweeps(stories)...)
+	}
+	orphans, err := checkOrphans()
+	if err != nil {
+		return nil, err
+	}
+	return append(issues, orphans...), nil
+}
+
+// SaveConsistencyCheck will save the issues found by a check, dropping the
+// issues of older checks past the last consistencyChecksKept
+func SaveConsistencyCheck(issues []ConsistencyIssue) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`INSERT INTO consistency_check (checked_at, issues) VALUES (?, ?)`, time.Now().Unix(), len(issues))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, i := range issues {
+		sql := `INSERT INTO consistency_issue (check_id, kind, target, detail) VALUES (?, ?, ?, ?)`
+		if _, err := tx.Exec(sql, id, i.Kind, i.Target, i.Detail); err != nil {
+			return err
+		}
+	}
+	sql := `DELETE FROM consistency_issue WHERE check_id NOT IN (SELECT id FROM consistency_check ORDER BY id DESC LIMIT ?)`
+	if _, err := tx.Exec(sql, consistencyChecksKept); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SelectConsistencyChecks will select the most recent checks, newest first
+func SelectConsistencyChecks(limit int) ([]ConsistencyCheck, error) {
+	var checks []ConsistencyCheck
+	if err := db.Select(&checks, `SELECT id, checked_at, issues FROM consistency_check ORDER BY id DESC LIMIT ?`, limit); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// SelectConsistencyIssues will select the issues found by a check
+func SelectConsistencyIssues(checkId uint64) ([]ConsistencyIssue, error) {
+	var issues []ConsistencyIssue
+	sql := `SELECT kind, target, detail FROM consistency_issue WHERE check_id=? ORDER BY kind, target`
+	if err := db.Select(&issues, sql, checkId); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// latestConsistencyCheck will return the latest check with its issues, or a
+// zero check when none ran yet
+func latestConsistencyCheck() (ConsistencyCheck, []ConsistencyIssue, error) {
+	checks, err := SelectConsistencyChecks(1)
+	if err != nil || len(checks) == 0 {
+		return ConsistencyCheck{}, nil, err
+	}
+	issues, err := SelectConsistencyIssues(checks[0].Id)
+	return checks[0], issues, err
+}
+
+// consistencyMetrics will count the issues of the latest check by kind, for
+// the metrics
+func consistencyMetrics() any {
+	check, issues, err := latestConsistencyCheck()
+	if err != nil {
+		log.Println("failed to select the latest consistency check.", err)
+		return nil
+	}
+	counts := map[string]int{}
+	for _, i := range issues {
+		counts[i.Kind]++
+	}
+	return map[string]any{"checked_at": check.CheckedAt, "issues": counts}
+}
+
+// sweepStories will sync the stories again, like the final sweep a story
+// gets when the next one is posted
+func sweepStories(ctx context.Context, issues []ConsistencyIssue) error {
+	for _, i := range issues {
+		if i.Kind != issueStorySweep {
+			continue
+		}
+		hsId, err := strconv.ParseUint(i.Target, 10, 64)
+		if err != nil {
+			return err
+		}
+		if err := processJobPosts(ctx, hsId); err != nil {
+			return err
+		}
+		if err := RecordAudit("system", "story.sweep", i.Target, "swept by the consistency check"); err != nil {
+			log.Println("failed to record audit entry.", err)
+		}
+	}
+	return nil
+}
+
+// checkCommand will cross-check the stored stories and jobs with hacker news
+// and with each other, and save the issues found for the admin page and the
+// metrics. It is meant to run nightly from cron.
+func checkCommand(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	sweep := fs.Bool("sweep", false, "sync the stories missing their final sweep again before checking")
+	fs.Parse(args)
+
+	issues, err := checkConsistency(context.Background(), *sweep)
+	if err != nil {
+		return err
+	}
+	for _, i := range issues {
+		fmt.Printf("%s %s: %s\n", i.Kind, i.Target, i.Detail)
+	}
+	fmt.Printf("%d issues found\n", len(issues))
+	return nil
+}
+
+// checkConsistency will run the consistency check, after syncing the stories
+// missing their final sweep again when sweep is set, and save its issues
+func checkConsistency(ctx context.Context, sweep bool) ([]ConsistencyIssue, error) {
+	issues, err := runConsistencyCheck(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sweep {
+		if err := sweepStories(ctx, issues); err != nil {
+			return nil, err
+		}
+		if issues, err = runConsistencyCheck(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := SaveConsistencyCheck(issues); err != nil {
+		return nil, err
+	}
+	if err := RecordAudit("system", "consistency.check", "", fmt.Sprintf("%d issues", len(issues))); err != nil {
+		log.Println("failed to record audit entry.", err)
+	}
+	return issues, nil
+}
+
+// consistencyHandler will show the issues found by the latest consistency
+// check along with the recent checks
+func consistencyHandler(w http.ResponseWriter, r *http.Request) {
+	checks, err := SelectConsistencyChecks(consistencyChecksKept)
+	if err != nil {
+		log.Println("failed to select consistency checks.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var issues []ConsistencyIssue
+	if len(checks) > 0 {
+		if issues, err = SelectConsistencyIssues(checks[0].Id); err != nil {
+			log.Println("failed to select consistency issues.", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	data := struct {
+		Checks []ConsistencyCheck
+		Issues []ConsistencyIssue
+	}{
+		Checks: checks,
+		Issues: issues,
+	}
+	renderTemplate(w, "admin_consistency.html", data)
+}
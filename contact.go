@@ -0,0 +1,67 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}\b`)
+	urlPattern   = regexp.MustCompile(`(?i)\bhttps?://[^\s"'<>]+`)
+	// Obfuscated "name [at] domain [dot] com" style addresses
+	atPattern  = regexp.MustCompile(`(?i)\s*[\[\(\{]\s*at\s*[\]\)\}]\s*`)
+	dotPattern = regexp.MustCompile(`(?i)\s*[\[\(\{]\s*dot\s*[\]\)\}]\s*`)
+)
+
+// applyUrlHints are url fragments that usually point to an application form
+var applyUrlHints = []string{
+	"lever.co", "greenhouse.io", "ashbyhq.com", "workable.com", "bamboohr.com",
+	"recruitee.com", "smartrecruiters.com", "workatastartup.com", "jobs", "careers",
+	"apply", "hiring", "join",
+}
+
+// jobApplyEmail will return the first contact email found in a job text
+func jobApplyEmail(text string) string {
+	plain := jobPlainText(text)
+	plain = atPattern.ReplaceAllString(plain, "@")
+	plain = dotPattern.ReplaceAllString(plain, ".")
+	return strings.ToLower(emailPattern.FindString(plain))
+}
+
+// jobUrls will return the unique urls found in a job text, in order
+func jobUrls(text string) []string {
+	var urls []string
+	seen := map[string]bool{}
+	for _, u := range urlPattern.FindAllString(html.UnescapeString(text), -1) {
+		u = strings.TrimRight(u, ".,;:)!?")
+		pu, err := url.Parse(u)
+		if err != nil || pu.Host == "" || pu.Host == "news.ycombinator.com" {
+			continue
+		}
+		if !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// jobApplyUrl will return the url in a job text that most likely leads to
+// an application, falling back to the first url found.
+func jobApplyUrl(text string) string {
+	urls := jobUrls(text)
+	for _, u := range urls {
+		lu := strings.ToLower(u)
+		for _, h := range applyUrlHints {
+			if strings.Contains(lu, h) {
+				return u
+			}
+		}
+	}
+	if len(urls) > 0 {
+		return urls[0]
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// baseCurrency is the currency salaries are converted to for comparison
+const baseCurrency = "USD"
+
+// defaultExchangeRates are the units of each currency worth one USD, used
+// when no rates are configured or fetched
+var defaultExchangeRates = map[string]float64{
+	"USD": 1, "EUR": 0.92, "GBP": 0.79, "CAD": 1.36, "AUD": 1.52, "CHF": 0.88,
+}
+
+// exchangeRateTable holds the units of each currency worth one USD. Rates are
+// replaced when fetched during syncs, while jobs may be enriched concurrently.
+type exchangeRateTable struct {
+	mu    sync.RWMutex
+	rates map[string]float64
+}
+
+var exchangeRates = newExchangeRateTable(cfg.ExchangeRates)
+
+var exchangeRatesClient = &http.Client{Timeout: 30 * time.Second}
+
+// newExchangeRateTable will build a table from rates like "EUR=0.92,GBP=0.79",
+// falling back to the default rate of the currencies not listed
+func newExchangeRateTable(items []string) *exchangeRateTable {
+	rates := map[string]float64{}
+	for k, v := range defaultExchangeRates {
+		rates[k] = v
+	}
+	for _, item := range items {
+		code, v, _ := strings.Cut(item, "=")
+		rate, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || rate <= 0 {
+			log.Printf("invalid exchange rate %q, skipping", item)
+			continue
+		}
+		rates[strings.ToUpper(strings.TrimSpace(code))] = rate
+	}
+	return &exchangeRateTable{rates: rates}
+}
+
+// rate will return the units of currency worth one USD
+func (t *exchangeRateTable) rate(currency string) (float64, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rate, ok := t.rates[currency]
+	return rate, ok
+}
+
+// toBase will convert an amount in currency to USD
+func (t *exchangeRateTable) toBase(amount uint64, currency string) (uint64, bool) {
+	rate, ok := t.rate(currency)
+	if !ok {
+		return 0, false
+	}
+	return uint64(float64(amount)/rate + 0.5), true
+}
+
+// update will replace the rates of the currencies in rates
+func (t *exchangeRateTable) update(rates map[string]float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for code, rate := range rates {
+		if rate > 0 {
+			t.rates[strings.ToUpper(code)] = rate
+		}
+	}
+}
+
+// fetchExchangeRates will update the rates from cfg.ExchangeRatesUrl, which
+// must return USD based rates like {"rates": {"EUR": 0.92}}
+func fetchExchangeRates(ctx context.Context) error {
+	if cfg.ExchangeRatesUrl == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.ExchangeRatesUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := exchangeRatesClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("exchange rates request failed with %s", resp.Status)
+	}
+
+	var body struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	if body.Base != "" && !strings.EqualFold(body.Base, baseCurrency) {
+		return fmt.Errorf("exchange rates are based on %s, not %s", body.Base, baseCurrency)
+	}
+	exchangeRates.update(body.Rates)
+	return nil
+}
+
+// formatBaseSalary will format a salary range converted to USD, like "≈ USD 130k-160k".
+// Salaries already in USD are not repeated.
+func formatBaseSalary(lo, hi uint64, currency string) string {
+	if currency == "" || currency == baseCurrency {
+		return ""
+	}
+	return "≈ " + formatSalary(lo, hi, baseCurrency)
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"html/template"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 const (
@@ -16,35 +18,275 @@ const (
 	jobStatusDeleted = 3
 )
 
-var db = sqlx.MustConnect("sqlite3", "whoishiring.db")
+// dbPath is the sqlite database file of the app
+const dbPath = "whoishiring.db"
+
+var db = sqlx.MustConnect(sqliteDriver(), dbPath)
 
 type HiringStory struct {
 	HnId  uint64 `db:"hn_id"`
 	Title string
+	// Kind is the thread of the story, storyKindHiring or storyKindFreelancer
+	Kind string
 }
 
-type HiringJob struct {
-	HnId uint64 `db:"hn_id"`
-	Text string
+// HiringStorySummary is a hiring story listed with its number of jobs
+type HiringStorySummary struct {
+	HiringStory
+	StoryIngestion
 	Time uint64
+	Jobs uint64
+	// Pinned is set for the story served on / in place of the latest one
+	Pinned bool
+}
+
+// StoryIngestion compares the comments of a story on hacker news, as of its
+// last sync, with the jobs we ingested from it
+type StoryIngestion struct {
+	Score       int
+	Descendants int
+	// Comments are the top level comments of the story, each one a job post
+	Comments int
+	// Ingested are the jobs saved from the story, including dead and deleted ones
+	Ingested int
+	// Skipped are the top level items of the story that are not job posts, like polls
+	Skipped  int
+	SyncedAt uint64 `db:"synced_at"`
+}
+
+// Missing will return the number of top level comments not ingested or skipped yet
+func (si StoryIngestion) Missing() int {
+	if si.Ingested+si.Skipped >= si.Comments {
+		return 0
+	}
+	return si.Comments - si.Ingested - si.Skipped
+}
+
+// hiringJobColumns are the hiring_job_view columns scanned into a HiringJob.
+// The view joins the jobs as fetched from hacker news with their attributes.
+const hiringJobColumns = `hn_id, hiring_story_id, text, time, poster, level, apply_email, apply_url, company_domain, company_id,
+            simhash, duplicate_of, language, employment_type, equity, benefits,
+            salary_min, salary_max, salary_currency, salary_min_usd, salary_max_usd, tags, location, remote, timezone, tz_min, tz_max, visa, deadline, interview, interview_rounds,
+            funding, employees, company_size, yc_batch, latitude, longitude,
+            enrichers, parser_version, updated_at`
+
+type HiringJob struct {
+	HnId          uint64 `db:"hn_id"`
+	HiringStoryId uint64 `db:"hiring_story_id"`
+
+	Text       string
+	Time       uint64
+	Level      string
+	ApplyEmail string `db:"apply_email"`
+	ApplyUrl   string `db:"apply_url"`
+
+	// Poster is the hacker news account that posted the job
+	Poster string
+
+	CompanyDomain string `db:"company_domain"`
+	CompanyId     uint64 `db:"company_id"`
+
+	Simhash     int64  `db:"simhash"`
+	DuplicateOf uint64 `db:"duplicate_of"`
+	Language    string
+
+	EmploymentType string `db:"employment_type"`
+	Equity         string
+	Benefits       string
+
+	SalaryMin      uint64 `db:"salary_min"`
+	SalaryMax      uint64 `db:"salary_max"`
+	SalaryCurrency string `db:"salary_currency"`
+	// SalaryMinUsd and SalaryMaxUsd are the salary range converted to USD
+	SalaryMinUsd uint64 `db:"salary_min_usd"`
+	SalaryMaxUsd uint64 `db:"salary_max_usd"`
+	Tags         string
+	Location     string
+	Remote       string
+	// Timezone is the range of UTC offsets the job requires overlap with,
+	// from TzMin to TzMax minutes. Empty when the job does not mention any.
+	Timezone string
+	TzMin    int `db:"tz_min"`
+	TzMax    int `db:"tz_max"`
+	// Visa is yes when the job sponsors visas and no when it rules
+	// sponsorship out, empty when the post does not say
+	Visa string
+	// Deadline is the date the job stops taking applications on, as
+	// deadlineLayout, empty when the post does not mention one
+	Deadline string
+	// Interview are the interview process flags of the job, one of
+	// interviewFlags, and InterviewRounds its number of rounds, 0 when unknown
+	Interview       string
+	InterviewRounds int `db:"interview_rounds"`
+	// Funding is the funding stage of the company, one of fundingStages, and
+	// Employees its headcount, bucketed into CompanySize, 0 when unknown
+	Funding     string
+	Employees   int
+	CompanySize string `db:"company_size"`
+	// YCBatch is the Y Combinator batch of the company, like "YC S24", or
+	// "YC" for companies backed by it in a batch the post does not name
+	YCBatch   string `db:"yc_batch"`
+	Latitude  float64
+	Longitude float64
+	// Enrichers are the names of the enrichers and ParserVersion the parsers
+	// version that derived the fields above
+	Enrichers     string
+	ParserVersion int    `db:"parser_version"`
+	UpdatedAt     uint64 `db:"updated_at"`
+}
+
+// Salary will return the job salary range for display
+func (hj HiringJob) Salary() string {
+	return formatSalary(hj.SalaryMin, hj.SalaryMax, hj.SalaryCurrency)
+}
+
+// BaseSalary will return the job salary range converted to USD for display,
+// empty for salaries in USD
+func (hj HiringJob) BaseSalary() string {
+	return formatBaseSalary(hj.SalaryMinUsd, hj.SalaryMaxUsd, hj.SalaryCurrency)
+}
+
+// TagList will return the technology tags of the job
+func (hj HiringJob) TagList() []string {
+	if hj.Tags == "" {
+		return nil
+	}
+	return strings.Split(hj.Tags, ",")
+}
+
+// RemotePolicies will return the display names of the job remote policies
+func (hj HiringJob) RemotePolicies() []string {
+	var names []string
+	for _, p := range strings.Split(hj.Remote, ",") {
+		if n, ok := remotePolicyNames[p]; ok {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// BenefitNames will return the display names of the job benefits
+func (hj HiringJob) BenefitNames() []string {
+	var names []string
+	for _, b := range strings.Split(hj.Benefits, ",") {
+		if n, ok := benefitNames[b]; ok {
+			if b == benefitEquity && hj.Equity != "" {
+				n += " " + hj.Equity
+			}
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// VisaName will return the display name of the job visa sponsorship
+func (hj HiringJob) VisaName() string {
+	return visaNames[hj.Visa]
+}
+
+// InterviewNames will return the display names of the job interview process
+// flags, with the number of rounds in place of interviewFewRounds
+func (hj HiringJob) InterviewNames() []string {
+	var names []string
+	for _, f := range strings.Split(hj.Interview, ",") {
+		if f == interviewFewRounds {
+			continue
+		}
+		if n, ok := interviewNames[f]; ok {
+			names = append(names, n)
+		}
+	}
+	if hj.InterviewRounds == 1 {
+		names = append(names, "1 round")
+	} else if hj.InterviewRounds > 1 {
+		names = append(names, fmt.Sprintf("%d rounds", hj.InterviewRounds))
+	}
+	return names
+}
+
+// FundingName will return the display name of the company funding stage
+func (hj HiringJob) FundingName() string {
+	return fundingNames[hj.Funding]
+}
+
+// YCBatchPath will return the path of the listing of the YC batch of the
+// job, empty when the batch is not known
+func (hj HiringJob) YCBatchPath() string {
+	if slug := ycBatchSlug(hj.YCBatch); slug != "" {
+		return "/yc/" + slug
+	}
+	return ""
 }
 
-// transformedText will parse the job text and return
-// a string with updated html and styles
-func (hj HiringJob) transformedText() string {
-	var s string
-	var l []string
-	st := strings.Split(hj.Text, "\n")
-	for _, v := range st {
-		sl := strings.Split(v, "<p>")
-		for _, slv := range sl {
-			l = append(l, slv)
+// DeadlineStatus will return whether the apply deadline of the job passed or
+// is closing soon, empty otherwise
+func (hj HiringJob) DeadlineStatus() string {
+	return deadlineStatus(hj.Deadline, time.Now().UTC())
+}
+
+// DeadlineDate will return the apply deadline of the job for display, like "Jun 15"
+func (hj HiringJob) DeadlineDate() string {
+	d, err := time.Parse(deadlineLayout, hj.Deadline)
+	if err != nil {
+		return ""
+	}
+	return d.Format("Jan 2")
+}
+
+// EmploymentTypes will return the display names of the job employment types
+func (hj HiringJob) EmploymentTypes() []string {
+	var names []string
+	for _, t := range strings.Split(hj.EmploymentType, ",") {
+		if n, ok := employmentTypeNames[t]; ok {
+			names = append(names, n)
 		}
 	}
-	for _, v := range l {
-		s = s + fmt.Sprintf(`<p class="my-2">%s</p>`, v)
+	return names
+}
+
+// LanguageName will return the display name of the job language
+func (hj HiringJob) LanguageName() string {
+	return languageNames[hj.Language]
+}
+
+// HiringJobListItem is a hiring job listed along with its story
+type HiringJobListItem struct {
+	HiringJob
+	StoryTitle string `db:"story_title"`
+}
+
+// StoryMonth will return the month of the story of the job, like "June 2024"
+func (hj HiringJobListItem) StoryMonth() string {
+	month, _ := storyTitleParts(hj.StoryTitle)
+	return month
+}
+
+// Headline will return the first line of the job as plain text
+func (hj HiringJob) Headline() string {
+	return strings.TrimSpace(jobPlainText(jobRoleText(hj.Text)))
+}
+
+// FaviconUrl will return the favicon url for the company domain
+func (hj HiringJob) FaviconUrl() string {
+	return fmt.Sprintf(faviconUrl, hj.CompanyDomain)
+}
+
+// PosterUrl will return the hacker news profile url of the job poster
+func (hj HiringJob) PosterUrl() string {
+	return hnUserUrl + url.QueryEscape(hj.Poster)
+}
+
+// Levels will return the seniority levels of the job
+func (hj HiringJob) Levels() []string {
+	if hj.Level == "" {
+		return nil
 	}
-	return s
+	return strings.Split(hj.Level, ",")
+}
+
+// Body will return the job text as sanitized html
+func (hj HiringJob) Body() template.HTML {
+	return sanitizeJobHTML(hj.Text)
 }
 
 func HiringJobStatus(dead bool, deleted bool) uint8 {
@@ -59,9 +301,9 @@ func HiringJobStatus(dead bool, deleted bool) uint8 {
 	return jobStatusOk
 }
 
-func CreateHiringStory(hnId uint64, title string, time uint64) (uint64, error) {
-	sql := `INSERT INTO hiring_story (hn_id, title, time) VALUES (?, ?, ?)`
-	res := db.MustExec(sql, hnId, title, time)
+func CreateHiringStory(hnId uint64, kind, title string, time uint64) (uint64, error) {
+	sql := `INSERT INTO hiring_story (hn_id, kind, title, time) VALUES (?, ?, ?, ?)`
+	res := db.MustExec(sql, hnId, kind, title, time)
 	_, err := res.LastInsertId()
 	if err != nil {
 		return 0, err
@@ -70,26 +312,174 @@ func CreateHiringStory(hnId uint64, title string, time uint64) (uint64, error) {
 	return hnId, nil
 }
 
-func CreateHiringJob(hjId, hsId uint64, hjText string, hjTime uint64, hjStatus uint8) (uint64, error) {
-	sql := `INSERT INTO hiring_job (hn_id, hiring_story_id, text, time, status) VALUES (?, ?, ?, ?, ?)`
-	res := db.MustExec(sql, hjId, hsId, hjText, hjTime, hjStatus)
+// CreateHiringJob will insert a job as fetched from hacker news. Fields derived
+// from its text are saved separately by SaveJobAttributes.
+func CreateHiringJob(hsId uint64, hjStatus uint8, hj HiringJob) (uint64, error) {
+	sql := `INSERT INTO hiring_job (hn_id, hiring_story_id, text, time, poster, status) VALUES (?, ?, ?, ?, ?, ?)`
+	res := db.MustExec(sql, hj.HnId, hsId, hj.Text, hj.Time, hj.Poster, hjStatus)
 	_, err := res.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
+	if err := recordProvenance(db, hj.HnId, sourceHN, 0, hnFields...); err != nil {
+		return 0, err
+	}
+	if err := jobSearch.Index(db, hj.HnId, hj.Text); err != nil {
+		return 0, err
+	}
+
+	return hj.HnId, nil
+}
 
-	return hjId, nil
+// UpdateHiringJobPoster will set the hacker news account that posted a job
+func UpdateHiringJobPoster(hnId uint64, poster string) error {
+	if _, err := db.Exec(`UPDATE hiring_job SET poster=? WHERE hn_id=?`, poster, hnId); err != nil {
+		return err
+	}
+	return recordProvenance(db, hnId, sourceHN, 0, "poster")
 }
 
+// GetLatestHiringStory will return the latest "Who is hiring?" story
 func GetLatestHiringStory() (*HiringStory, error) {
+	return GetLatestStory(storyKindHiring)
+}
+
+// GetLatestStory will return the latest story of a kind
+func GetLatestStory(kind string) (*HiringStory, error) {
+	var hs HiringStory
+	if err := db.Get(&hs, "SELECT hn_id, title, kind FROM hiring_story WHERE kind=? ORDER BY time DESC LIMIT 1", kind); err != nil {
+		return &hs, err
+	}
+
+	return &hs, nil
+}
+
+// GetMonthStory will return the story of a kind posted for a month, like "October 2026"
+func GetMonthStory(kind, month string) (*HiringStory, error) {
+	var hs HiringStory
+	sql := `SELECT hn_id, title, kind FROM hiring_story WHERE kind=? and title LIKE ? ESCAPE '\' ORDER BY time DESC LIMIT 1`
+	if err := db.Get(&hs, sql, kind, likeContains("("+month+")")); err != nil {
+		return &hs, err
+	}
+
+	return &hs, nil
+}
+
+// GetCurrentHiringStory will return the story served on /, the pinned story
+// when an admin pinned one and the latest story otherwise
+func GetCurrentHiringStory() (*HiringStory, error) {
 	var hs HiringStory
-	if err := db.Get(&hs, "SELECT hn_id, title FROM hiring_story ORDER BY time DESC LIMIT 1"); err != nil {
+	sql := "SELECT hn_id, title, kind FROM hiring_story WHERE kind=? ORDER BY pinned DESC, time DESC LIMIT 1"
+	if err := db.Get(&hs, sql, storyKindHiring); err != nil {
 		return &hs, err
 	}
 
 	return &hs, nil
 }
 
+func GetHiringStory(hnId uint64) (*HiringStory, error) {
+	var hs HiringStory
+	if err := db.Get(&hs, "SELECT hn_id, title, kind FROM hiring_story WHERE hn_id=?", hnId); err != nil {
+		return &hs, err
+	}
+
+	return &hs, nil
+}
+
+// GetStoryIngestion will return the hacker news counts of a story along with its ingested jobs
+func GetStoryIngestion(hsId uint64) (StoryIngestion, error) {
+	var si StoryIngestion
+	sql := `SELECT hs.score, hs.descendants, hs.comments, hs.synced_at,
+            (SELECT COUNT(*) FROM hiring_job hj WHERE hj.hiring_story_id = hs.hn_id) AS ingested,
+            (SELECT COUNT(*) FROM skipped_item si WHERE si.hiring_story_id = hs.hn_id) AS skipped
+            FROM hiring_story hs
+            WHERE hs.hn_id=?`
+	err := db.Get(&si, sql, hsId)
+	return si, err
+}
+
+// UpdateHiringStoryMetadata will save the counts and raw item of a story as of a sync
+func UpdateHiringStoryMetadata(hnId uint64, score, descendants, comments int, raw []byte) error {
+	sql := `UPDATE hiring_story SET score=?, descendants=?, comments=?, raw=?, synced_at=? WHERE hn_id=?`
+	_, err := db.Exec(sql, score, descendants, comments, string(raw), time.Now().Unix(), hnId)
+	return err
+}
+
+// SelectHiringStories will select the stories of a kind, newest first
+func SelectHiringStories(kind string) ([]HiringStorySummary, error) {
+	var stories []HiringStorySummary
+	sql := `SELECT hs.hn_id, hs.title, hs.kind, hs.time, hs.score, hs.descendants, hs.comments, hs.synced_at, hs.pinned,
+            (SELECT COUNT(*) FROM hiring_job hj WHERE hj.hiring_story_id = hs.hn_id and hj.status=?) AS jobs,
+            (SELECT COUNT(*) FROM hiring_job hj WHERE hj.hiring_story_id = hs.hn_id) AS ingested,
+            (SELECT COUNT(*) FROM skipped_item si WHERE si.hiring_story_id = hs.hn_id) AS skipped
+            FROM hiring_story hs
+            WHERE hs.kind=?
+            ORDER BY hs.time DESC`
+	if err := db.Select(&stories, sql, jobStatusOk, kind); err != nil {
+		return nil, err
+	}
+
+	return stories, nil
+}
+
+// StoryStats are the counts summarizing the jobs of a story
+type StoryStats struct {
+	Jobs      int
+	Companies int
+	Remote    int
+	TopTags   []string `db:"-"`
+}
+
+// SelectStoryStats will count the live, non duplicate jobs of a story, their
+// companies and remote jobs, along with the most common tags
+func SelectStoryStats(hsId uint64) (StoryStats, error) {
+	var stats StoryStats
+	sql := `SELECT COUNT(*) AS jobs,
+            COUNT(DISTINCT NULLIF(company_id, 0)) AS companies,
+            COALESCE(SUM(instr(',' || remote || ',', ',' || ? || ',') > 0), 0) AS remote
+            FROM hiring_job_view
+            WHERE hiring_story_id=? and status=? and duplicate_of=0`
+	if err := db.Get(&stats, sql, remoteFull, hsId, jobStatusOk); err != nil {
+		return stats, err
+	}
+
+	counts, err := SelectStoryTagCounts(hsId)
+	if err != nil {
+		return stats, err
+	}
+	for tag := range counts {
+		stats.TopTags = append(stats.TopTags, tag)
+	}
+	sort.Slice(stats.TopTags, func(i, j int) bool {
+		a, b := stats.TopTags[i], stats.TopTags[j]
+		if counts[a] != counts[b] {
+			return counts[a] > counts[b]
+		}
+		return a < b
+	})
+	if len(stats.TopTags) > storyTopTags {
+		stats.TopTags = stats.TopTags[:storyTopTags]
+	}
+	return stats, nil
+}
+
+// SelectStoryTagCounts will count the live, non duplicate jobs of a story by tag
+func SelectStoryTagCounts(hsId uint64) (map[string]int, error) {
+	var tags []TagCount
+	sql := `SELECT jt.tag, COUNT(*) AS count FROM job_tag jt
+            JOIN hiring_job_view hj ON hj.hn_id = jt.hn_id
+            WHERE hj.hiring_story_id=? and hj.status=? and hj.duplicate_of=0
+            GROUP BY jt.tag`
+	if err := db.Select(&tags, sql, hsId, jobStatusOk); err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, t := range tags {
+		counts[t.Tag] = t.Count
+	}
+	return counts, nil
+}
+
 func SelectHiringJobIds(hsId int) (*sql.Rows, error) {
 	sql := `SELECT hn_id FROM hiring_job WHERE hiring_story_id=?`
 	rows, err := db.Query(sql, hsId)
@@ -100,33 +490,544 @@ func SelectHiringJobIds(hsId int) (*sql.Rows, error) {
 	return rows, nil
 }
 
-func SelectNextHiringJob(hsId uint64, hnTime uint64) (*HiringJob, error) {
-	var hj HiringJob
-	sql := `SELECT hn_id, text, time
-            FROM hiring_job
-            WHERE hiring_story_id=? and status=? and time < ?
-            ORDER BY time Desc
-            Limit 1`
-	if hnTime == 0 {
-		hnTime = uint64(time.Now().Unix())
-	}
-	if err := db.Get(&hj, sql, hsId, jobStatusOk, hnTime); err != nil {
-		return &hj, err
+// SkippedItem is a top level item of a story that is not a job post
+type SkippedItem struct {
+	HnId          uint64 `db:"hn_id"`
+	HiringStoryId uint64 `db:"hiring_story_id"`
+	Type          string
+	Reason        string
+}
+
+// SaveSkippedItem will record an item skipped at ingest, so it is not fetched again
+func SaveSkippedItem(item SkippedItem) error {
+	sql := `INSERT OR REPLACE INTO skipped_item (hn_id, hiring_story_id, type, reason, skipped_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.Exec(sql, item.HnId, item.HiringStoryId, item.Type, item.Reason, time.Now().Unix())
+	return err
+}
+
+// SelectSkippedItemIds will select the ids of the items of a story skipped at ingest
+func SelectSkippedItemIds(hsId uint64) ([]uint64, error) {
+	var ids []uint64
+	if err := db.Select(&ids, `SELECT hn_id FROM skipped_item WHERE hiring_story_id=? ORDER BY hn_id`, hsId); err != nil {
+		return nil, err
 	}
+	return ids, nil
+}
 
-	return &hj, nil
+// SelectHiringJobTextsBetween will return the live jobs posted from one unix
+// time up to another, with their text, company and apply link
+func SelectHiringJobTextsBetween(from, to int64) ([]HiringJob, error) {
+	var jobs []HiringJob
+	sql := `SELECT hn_id, text, company_id, apply_url FROM hiring_job_view WHERE time >= ? and time < ? and status=? ORDER BY time`
+	if err := db.Select(&jobs, sql, from, to, jobStatusOk); err != nil {
+		return nil, err
+	}
+	return jobs, nil
 }
 
-func SelectPreviousHiringJob(hsId uint64, hnTime uint64) (*HiringJob, error) {
+// GetHiringJob will return a live job by its hn id
+func GetHiringJob(hnId uint64) (*HiringJob, error) {
 	var hj HiringJob
-	sql := `SELECT hn_id, text, time
-            FROM hiring_job
-            WHERE hiring_story_id=? and status=? and time > ?
-            ORDER BY time ASC
-            Limit 1`
-	if err := db.Get(&hj, sql, hsId, jobStatusOk, hnTime); err != nil {
+	sql := `SELECT ` + hiringJobColumns + ` FROM hiring_job_view WHERE hn_id=? and status=?`
+	if err := db.Get(&hj, sql, hnId, jobStatusOk); err != nil {
 		return &hj, err
 	}
 
 	return &hj, nil
 }
+
+// GetHiringJobWithStatus will return a job whatever its status, along with
+// the status, as dead and deleted jobs are kept
+func GetHiringJobWithStatus(hnId uint64) (*HiringJob, uint8, error) {
+	var row struct {
+		HiringJob
+		Status uint8
+	}
+	sql := `SELECT ` + hiringJobColumns + `, status FROM hiring_job_view WHERE hn_id=?`
+	if err := db.Get(&row, sql, hnId); err != nil {
+		return &row.HiringJob, 0, err
+	}
+
+	return &row.HiringJob, row.Status, nil
+}
+
+// SelectNextHiringJob will return the job listed after the cursor job in
+// the sort order of the filter, or the first job when the cursor is empty
+func SelectNextHiringJob(hsId uint64, cursor HiringJob, f FilterState) (*HiringJob, error) {
+	return selectAdjacentJob(hsId, cursor, f, false)
+}
+
+// SelectPreviousHiringJob will return the job listed before the cursor job
+func SelectPreviousHiringJob(hsId uint64, cursor HiringJob, f FilterState) (*HiringJob, error) {
+	return selectAdjacentJob(hsId, cursor, f, true)
+}
+
+// selectAdjacentJob will return the job next to the cursor job, before it
+// when backward is set. It is sql.ErrNoRows when there is none.
+func selectAdjacentJob(hsId uint64, cursor HiringJob, f FilterState, backward bool) (*HiringJob, error) {
+	jobs, err := selectJobs(jobScope{StoryId: hsId}, f, jobPage{Cursor: cursor.HnId, Backward: backward, Limit: 1})
+	if err != nil {
+		return &HiringJob{}, err
+	}
+	if len(jobs) == 0 {
+		return &HiringJob{}, sql.ErrNoRows
+	}
+	return &jobs[0].HiringJob, nil
+}
+
+// jobScope narrows the jobs of a listing down before its filter applies
+type jobScope struct {
+	// StoryId keeps the jobs of a story, 0 keeps the jobs of every hiring story
+	StoryId uint64
+	// StoryIds keep the jobs of any of the stories, like the threads of a month
+	StoryIds []uint64
+	Domain   string
+	Poster   string
+	// CompanyId keeps the jobs of a company, under any of its names
+	CompanyId uint64
+	// YCBatch keeps the jobs of companies of a YC batch, like "YC S24"
+	YCBatch string
+}
+
+// conds will return the conditions on hiring_job_view hj keeping the live
+// jobs of the scope, with their args
+func (scope jobScope) conds() ([]string, []any) {
+	conds := []string{"hj.status=?"}
+	args := []any{jobStatusOk}
+	switch {
+	case scope.StoryId > 0:
+		conds = append(conds, "hj.hiring_story_id=?")
+		args = append(args, scope.StoryId)
+	case len(scope.StoryIds) > 0:
+		conds = append(conds, "hj.hiring_story_id IN (?"+strings.Repeat(", ?", len(scope.StoryIds)-1)+")")
+		for _, id := range scope.StoryIds {
+			args = append(args, id)
+		}
+	default:
+		// freelancer threads are only listed along with their hiring thread
+		conds = append(conds, "hj.hiring_story_id IN (SELECT hn_id FROM hiring_story WHERE kind=?)")
+		args = append(args, storyKindHiring)
+	}
+	if scope.Domain != "" {
+		conds = append(conds, "hj.company_domain=?")
+		args = append(args, scope.Domain)
+	}
+	if scope.Poster != "" {
+		conds = append(conds, "hj.poster=?")
+		args = append(args, scope.Poster)
+	}
+	if scope.CompanyId > 0 {
+		conds = append(conds, "hj.company_id=?")
+		args = append(args, scope.CompanyId)
+	}
+	if scope.YCBatch != "" {
+		conds = append(conds, "hj.yc_batch=?")
+		args = append(args, scope.YCBatch)
+	}
+	return conds, args
+}
+
+// selectHotJobIds will select the ids of the jobs matching conds on
+// hiring_job_view hj from the hottest one
+func selectHotJobIds(conds []string, args []any) ([]uint64, error) {
+	var jobs []jobEngagement
+	sql := `SELECT hj.hn_id, hj.time, COALESCE(je.replies, 0) AS replies, COALESCE(je.views, 0) AS views
+            FROM hiring_job_view hj LEFT JOIN job_engagement je ON je.hn_id = hj.hn_id
+            WHERE ` + strings.Join(conds, " and ")
+	if err := db.Select(&jobs, sql, args...); err != nil {
+		return nil, err
+	}
+	return rankHotJobs(jobs, time.Now()), nil
+}
+
+// jobPage is the part of a listing selected: the jobs after, or before when
+// Backward is set, the Cursor job in the sort order. A Cursor of 0 starts
+// from the first job and a Limit of 0 selects every job.
+type jobPage struct {
+	Cursor   uint64
+	Backward bool
+	Limit    int
+}
+
+// SelectJobList will select the live jobs of a scope matching a filter, in
+// the sort order of the filter. Listings, the api and feeds select jobs
+// through it, so every surface takes the same filter params. A limit of 0
+// selects every job.
+func SelectJobList(scope jobScope, f FilterState, limit int) ([]HiringJobListItem, error) {
+	return selectJobs(scope, f, jobPage{Limit: limit})
+}
+
+// CountJobList will count the live jobs of a scope matching a filter, the jobs
+// SelectJobList selects without a limit
+func CountJobList(scope jobScope, f FilterState) (int, error) {
+	return countJobs(scope, f, jobPage{})
+}
+
+// jobsQuery are the clauses selecting a page of the jobs of a scope matching
+// a filter, from hiring_job_view hj
+type jobsQuery struct {
+	from    string
+	where   string
+	orderBy string
+	args    []any
+}
+
+// newJobsQuery will build the query of a page of the live jobs of a scope
+// matching a filter. The limit of the page is left to the select.
+func newJobsQuery(scope jobScope, f FilterState, page jobPage) (jobsQuery, error) {
+	sort := f.sortOrder()
+	key, desc := sortKey(sort)
+	q := jobsQuery{from: "hiring_job_view hj", orderBy: orderBy(key, desc != page.Backward)}
+	scopeConds, scopeArgs := scope.conds()
+	var rankedIds string
+	switch sort {
+	case sortRelevance:
+		// the query is ranked by the search engine, the jobs keep its order
+		ranked, err := jobSearch.Rank(scope.StoryId, searchExpr(f.Query))
+		if err != nil {
+			return q, err
+		}
+		rankedIds = jsonIds(ranked)
+		f.Query = ""
+	case sortHot:
+		ranked, err := selectHotJobIds(scopeConds, scopeArgs)
+		if err != nil {
+			return q, err
+		}
+		rankedIds = jsonIds(ranked)
+	}
+	if rankedIds != "" {
+		q.from = "json_each(?) ranked JOIN hiring_job_view hj ON hj.hn_id = ranked.value"
+		q.args = append(q.args, rankedIds)
+	}
+
+	conds := scopeConds
+	q.args = append(q.args, scopeArgs...)
+	if page.Cursor > 0 {
+		// jobs after the cursor are past it in the direction of the key
+		op := ">"
+		if desc != page.Backward {
+			op = "<"
+		}
+		if rankedIds != "" {
+			// cursors outside the results start from the first ranked job
+			conds = append(conds, "ranked.key "+op+" COALESCE((SELECT c.key FROM json_each(?) c WHERE c.value=?), -1)")
+			q.args = append(q.args, rankedIds, page.Cursor)
+		} else {
+			cols := strings.Join(key, ", ")
+			conds = append(conds, "("+cols+") "+op+" (SELECT "+cols+" FROM hiring_job_view hj WHERE hj.hn_id=?)")
+			q.args = append(q.args, page.Cursor)
+		}
+	}
+	where, fArgs, err := f.where()
+	if err != nil {
+		return q, err
+	}
+	q.where = strings.Join(conds, " and ") + where
+	q.args = append(q.args, fArgs...)
+	return q, nil
+}
+
+// selectJobs will select a page of the live jobs of a scope matching a filter
+func selectJobs(scope jobScope, f FilterState, page jobPage) ([]HiringJobListItem, error) {
+	var jobs []HiringJobListItem
+	q, err := newJobsQuery(scope, f, page)
+	if err != nil {
+		return nil, err
+	}
+	sql := `SELECT ` + hiringJobColumns + `,
+            (SELECT title FROM hiring_story hs WHERE hs.hn_id = hj.hiring_story_id) AS story_title
+            FROM ` + q.from + `
+            WHERE ` + q.where + `
+            ORDER BY ` + q.orderBy
+	args := q.args
+	if page.Limit > 0 {
+		sql += " LIMIT ?"
+		args = append(args, page.Limit)
+	}
+	if err := selectFiltered(&jobs, f, sql, args...); err != nil {
+		return nil, err
+	}
+	if page.Backward {
+		for i, j := 0, len(jobs)-1; i < j; i, j = i+1, j-1 {
+			jobs[i], jobs[j] = jobs[j], jobs[i]
+		}
+	}
+
+	return jobs, nil
+}
+
+// countJobs will count the live jobs of a scope matching a filter past the
+// cursor of page, like the jobs before the first job of a page
+func countJobs(scope jobScope, f FilterState, page jobPage) (int, error) {
+	q, err := newJobsQuery(scope, f, page)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	sql := `SELECT COUNT(*) FROM ` + q.from + ` WHERE ` + q.where
+	if err := getFiltered(&count, f, sql, q.args...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// selectJobFields will select some columns of every live job of a scope
+// matching a filter into dest, for the summaries of listings selecting a page
+// of jobs
+func selectJobFields(dest any, scope jobScope, f FilterState, columns string) error {
+	q, err := newJobsQuery(scope, f, jobPage{})
+	if err != nil {
+		return err
+	}
+	return selectFiltered(dest, f, `SELECT `+columns+` FROM `+q.from+` WHERE `+q.where, q.args...)
+}
+
+// storyJobCount is the number of jobs of a story matching a filter
+type storyJobCount struct {
+	StoryId    uint64 `db:"hiring_story_id"`
+	StoryTitle string `db:"story_title"`
+	Jobs       int
+}
+
+// CountJobsByStory will count the live jobs of a scope matching a filter in
+// each story, newest stories first
+func CountJobsByStory(scope jobScope, f FilterState) ([]storyJobCount, error) {
+	q, err := newJobsQuery(scope, f, jobPage{})
+	if err != nil {
+		return nil, err
+	}
+	var counts []storyJobCount
+	// story ids grow with time, like every hacker news id
+	sql := `SELECT hj.hiring_story_id, COUNT(*) AS jobs,
+            (SELECT title FROM hiring_story hs WHERE hs.hn_id = hj.hiring_story_id) AS story_title
+            FROM ` + q.from + `
+            WHERE ` + q.where + `
+            GROUP BY hj.hiring_story_id
+            ORDER BY hj.hiring_story_id DESC`
+	if err := selectFiltered(&counts, f, sql, q.args...); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func SelectHiringJobHashes(hsId uint64) ([]HiringJob, error) {
+	var jobs []HiringJob
+	sql := `SELECT hn_id, time, simhash, duplicate_of
+            FROM hiring_job_view
+            WHERE hiring_story_id=? and status=?
+            ORDER BY time ASC`
+	if err := db.Select(&jobs, sql, hsId, jobStatusOk); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func SelectHiringJobDuplicateIds(hnId uint64) ([]uint64, error) {
+	var ids []uint64
+	sql := `SELECT hn_id FROM hiring_job_view WHERE duplicate_of=? and status=? ORDER BY time ASC`
+	if err := db.Select(&ids, sql, hnId, jobStatusOk); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// UpdateHiringJobDuplicateOf will mark a job and its duplicates as duplicates of newId
+func UpdateHiringJobDuplicateOf(hnId, newId uint64) error {
+	now := time.Now().Unix()
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	sql := `UPDATE job_field_provenance SET source='duplicate', version=?, derived_at=?
+            WHERE field='duplicate_of' and hn_id IN (SELECT hn_id FROM job_attribute WHERE hn_id=? or duplicate_of=?)`
+	if _, err := tx.Exec(sql, parserVersion, now, hnId, hnId); err != nil {
+		return err
+	}
+	sql = `UPDATE job_attribute SET duplicate_of=?, updated_at=? WHERE hn_id=? or duplicate_of=?`
+	if _, err := tx.Exec(sql, newId, now, hnId, hnId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func SelectHiringJobLanguages(hsId uint64) ([]string, error) {
+	var langs []string
+	sql := `SELECT DISTINCT language FROM hiring_job_view
+            WHERE hiring_story_id=? and status=? and language != ''
+            ORDER BY language`
+	if err := db.Select(&langs, sql, hsId, jobStatusOk); err != nil {
+		return nil, err
+	}
+
+	return langs, nil
+}
+
+// SelectHiringJobBatch will return up to limit live jobs with an id greater
+// than afterId ordered by id, optionally limited to one story.
+func SelectHiringJobBatch(hsId, afterId uint64, limit int) ([]HiringJob, error) {
+	var jobs []HiringJob
+	sql := `SELECT ` + hiringJobColumns + `
+            FROM hiring_job_view
+            WHERE hn_id > ? and status=?`
+	args := []any{afterId, jobStatusOk}
+	if hsId > 0 {
+		sql += " AND hiring_story_id=?"
+		args = append(args, hsId)
+	}
+	sql += " ORDER BY hn_id LIMIT ?"
+	args = append(args, limit)
+	if err := db.Select(&jobs, sql, args...); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// SelectStaleHiringJobs will return the jobs of a story not enriched by the
+// given enricher chain or by an older version of the parsers
+func SelectStaleHiringJobs(hsId uint64, enrichers string, version int) ([]HiringJob, error) {
+	var jobs []HiringJob
+	sql := `SELECT ` + hiringJobColumns + `
+            FROM hiring_job_view
+            WHERE hiring_story_id=? and status=? and (enrichers != ? or parser_version != ?)
+            ORDER BY time ASC`
+	if err := db.Select(&jobs, sql, hsId, jobStatusOk, enrichers, version); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// SelectJobAttributeMap will return the columns of a job by name
+func SelectJobAttributeMap(hnId uint64) (map[string]any, error) {
+	attrs := map[string]any{}
+	sql := `SELECT ` + hiringJobColumns + ` FROM hiring_job_view WHERE hn_id=?`
+	if err := db.QueryRowx(sql, hnId).MapScan(attrs); err != nil {
+		return nil, err
+	}
+
+	return attrs, nil
+}
+
+// SaveJobAttributes will insert or update the derived fields of a job along
+// with their evidence and provenance. The field names must be job_attribute columns. The
+// update time of the attributes only changes when a field other than the
+// enrichers and parser version changes value.
+func SaveJobAttributes(hnId uint64, fields jobFields) error {
+	values, evidences, enrichers := fields.unwrap()
+	delete(evidences, "enrichers")
+	delete(evidences, "parser_version")
+
+	cols := []string{"hn_id"}
+	args := []any{hnId}
+	var sets, changed []string
+	for col, v := range values {
+		cols = append(cols, col)
+		args = append(args, v)
+		sets = append(sets, col+"=excluded."+col)
+		if col != "enrichers" && col != "parser_version" {
+			changed = append(changed, "job_attribute."+col+" IS NOT excluded."+col)
+		}
+	}
+	if len(changed) == 0 {
+		changed = append(changed, "0")
+	}
+	cols = append(cols, "updated_at")
+	args = append(args, time.Now().Unix())
+	sets = append(sets, "updated_at=CASE WHEN "+strings.Join(changed, " OR ")+" THEN excluded.updated_at ELSE job_attribute.updated_at END")
+
+	sql := `INSERT INTO job_attribute (` + strings.Join(cols, ", ") + `)
+            VALUES (?` + strings.Repeat(", ?", len(cols)-1) + `)
+            ON CONFLICT (hn_id) DO UPDATE SET ` + strings.Join(sets, ", ")
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(sql, args...); err != nil {
+		return err
+	}
+	if err := saveJobEvidence(tx, hnId, evidences); err != nil {
+		return err
+	}
+	for field, enricher := range enrichers {
+		if err := recordProvenance(tx, hnId, enricher, parserVersion, field); err != nil {
+			return err
+		}
+	}
+	if tags, ok := values["tags"].(string); ok {
+		if err := saveJobTags(tx, hnId, tags); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// saveJobTags will replace the rows of the tags of a job in job_tag, which
+// tag filters match against
+func saveJobTags(tx *sqlx.Tx, hnId uint64, tags string) error {
+	if _, err := tx.Exec(`DELETE FROM job_tag WHERE hn_id=?`, hnId); err != nil {
+		return err
+	}
+	for _, t := range strings.Split(tags, ",") {
+		if t == "" {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO job_tag (hn_id, tag) VALUES (?, ?)`, hnId, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SelectStorySitemaps will return every story with the last update time of its jobs
+func SelectStorySitemaps() ([]HiringStorySummary, error) {
+	var stories []HiringStorySummary
+	sql := `SELECT hs.hn_id, hs.title,
+            MAX(hs.time, COALESCE((SELECT MAX(hj.updated_at) FROM hiring_job_view hj WHERE hj.hiring_story_id = hs.hn_id), 0)) AS time,
+            (SELECT COUNT(*) FROM hiring_job hj WHERE hj.hiring_story_id = hs.hn_id and hj.status=?) AS jobs
+            FROM hiring_story hs
+            ORDER BY hs.time DESC`
+	if err := db.Select(&stories, sql, jobStatusOk); err != nil {
+		return nil, err
+	}
+
+	return stories, nil
+}
+
+// SelectGeocodedHiringJobs will return the live jobs of a story with a
+// geocoded location matching the filter
+func SelectGeocodedHiringJobs(hsId uint64, f FilterState) ([]HiringJob, error) {
+	var jobs []HiringJob
+	where, args, err := f.where()
+	if err != nil {
+		return nil, err
+	}
+	sql := `SELECT ` + hiringJobColumns + `
+            FROM hiring_job_view
+            WHERE hiring_story_id=? and status=? and (latitude != 0 or longitude != 0)` + where + `
+            ORDER BY time DESC, hn_id DESC`
+	args = append([]any{hsId, jobStatusOk}, args...)
+	if err := selectFiltered(&jobs, f, sql, args...); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// SelectHiringJobUpdates will return the ids and update times of the live jobs of a story
+func SelectHiringJobUpdates(hsId uint64) ([]HiringJob, error) {
+	var jobs []HiringJob
+	sql := `SELECT hn_id, time, updated_at FROM hiring_job_view
+            WHERE hiring_story_id=? and status=?
+            ORDER BY time DESC, hn_id DESC`
+	if err := db.Select(&jobs, sql, hsId, jobStatusOk); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
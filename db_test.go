@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// useTestDB will point db at an in-memory database with the migrations
+// applied, for the length of the test
+func useTestDB(t testing.TB) {
+	t.Helper()
+	mem, err := sqlx.Open(sqliteDriverName, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would open its own empty in-memory database
+	mem.SetMaxOpenConns(1)
+	files, err := filepath.Glob("migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(b), "-- +goose Down")
+		if _, err := mem.Exec(up); err != nil {
+			if strings.Contains(err.Error(), "no such module: fts5") {
+				t.Skip("the database tests need the sqlite_fts5 tag, like make test")
+			}
+			t.Fatalf("failed to apply %s. %s", f, err)
+		}
+	}
+	prev := db
+	db = mem
+	t.Cleanup(func() {
+		db = prev
+		mem.Close()
+	})
+}
+
+func TestCountJobList(t *testing.T) {
+	useTestDB(t)
+	for _, hsId := range []uint64{1, 2} {
+		if _, err := CreateHiringStory(hsId, storyKindHiring, "Ask HN: Who is hiring?", 1700000000+hsId); err != nil {
+			t.Fatal(err)
+		}
+	}
+	texts := []string{"Acme | Golang Engineer | Remote", "Initech | Rust Engineer | Berlin", "Globex | Golang SRE | Onsite"}
+	for i, text := range texts {
+		for _, hsId := range []uint64{1, 2} {
+			hj := HiringJob{HnId: hsId*100 + uint64(i), Text: text, Time: 1700000000 + uint64(i)}
+			if _, err := CreateHiringJob(hsId, jobStatusOk, hj); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := CreateHiringJob(1, jobStatusDead, HiringJob{HnId: 199, Text: "Spam | Golang", Time: 1700000009}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		scope jobScope
+		f     FilterState
+		want  int
+	}{
+		{jobScope{StoryId: 1}, FilterState{}, 3},
+		{jobScope{StoryId: 1}, FilterState{Query: "golang"}, 2},
+		{jobScope{StoryId: 1}, FilterState{Query: "golang", Sort: sortRelevance}, 2},
+		{jobScope{}, FilterState{Query: "golang"}, 4},
+		{jobScope{StoryId: 2}, FilterState{Query: "haskell"}, 0},
+	}
+	for _, c := range cases {
+		count, err := CountJobList(c.scope, c.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs, err := SelectJobList(c.scope, c.f, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != c.want || len(jobs) != c.want {
+			t.Errorf("%+v %+v: CountJobList = %d and SelectJobList selected %d, want %d", c.scope, c.f, count, len(jobs), c.want)
+		}
+	}
+}
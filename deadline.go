@@ -0,0 +1,103 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// deadlineLayout is the layout of the deadline column, which sorts as text
+	deadlineLayout = "2006-01-02"
+	// deadlineSoon is how long before its deadline a job is closing soon
+	deadlineSoon = 7 * 24 * time.Hour
+
+	deadlineExpired     = "expired"
+	deadlineClosingSoon = "closing soon"
+)
+
+// deadlineMonth matches month names and their abbreviations
+const deadlineMonth = `(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.?`
+
+// deadlinePattern matches explicit deadlines like "apply by June 15", "deadline:
+// 15th of June 2024" or "applications close on 2024-06-15", capturing the
+// month and day, the day and month, or the iso date, and the year
+var deadlinePattern = regexp.MustCompile(`(?i)\b(?:apply (?:by|before|until|no later than)|applications? (?:close|closes|closing|due|deadline)|(?:application )?deadline(?: to apply)?|closing date|open until)` +
+	`(?:\s+(?:is|on|by|date|of))?\s*:?\s*(?:` +
+	deadlineMonth + `\s+(\d{1,2})(?:st|nd|rd|th)?` +
+	`|(\d{1,2})(?:st|nd|rd|th)?(?:\s+of)?\s+` + deadlineMonth +
+	`|(\d{4})-(\d{2})-(\d{2}))` +
+	`(?:,?\s+(\d{4}))?\b`)
+
+// deadlineMonths are the months by the first letters of their names
+var deadlineMonths = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// jobDeadline will return the date, as deadlineLayout, a job stops taking
+// applications on, along with the matched terms. Dates without a year are
+// the next one after the job was posted, at posted. Posts without an explicit
+// deadline return empty.
+func jobDeadline(text string, posted time.Time) (string, string) {
+	plain := jobPlainText(text)
+	m := deadlinePattern.FindStringSubmatch(plain)
+	if m == nil {
+		return "", ""
+	}
+	var month time.Month
+	var day, year int
+	switch {
+	case m[1] != "":
+		month, day = deadlineMonths[strings.ToLower(m[1][:3])], deadlineNumber(m[2])
+	case m[4] != "":
+		month, day = deadlineMonths[strings.ToLower(m[4][:3])], deadlineNumber(m[3])
+	default:
+		year, month, day = deadlineNumber(m[5]), time.Month(deadlineNumber(m[6])), deadlineNumber(m[7])
+	}
+	if m[8] != "" && year == 0 {
+		year = deadlineNumber(m[8])
+	}
+
+	posted = posted.UTC()
+	postedDay := time.Date(posted.Year(), posted.Month(), posted.Day(), 0, 0, 0, 0, time.UTC)
+	inferred := year == 0
+	if inferred {
+		year = posted.Year()
+	}
+	d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	// dates like february 30 are normalized into the next month
+	if month < time.January || month > time.December || d.Day() != day || d.Month() != month {
+		return "", ""
+	}
+	if inferred && d.Before(postedDay) {
+		d = d.AddDate(1, 0, 0)
+	}
+	return d.Format(deadlineLayout), matchedTerms(deadlinePattern, plain)
+}
+
+// deadlineNumber will parse the digits matched by a pattern, 0 for anything else
+func deadlineNumber(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// deadlineStatus will return whether a deadline passed or is closing soon at
+// now, empty for jobs still open for a while and jobs without a deadline
+func deadlineStatus(deadline string, now time.Time) string {
+	d, err := time.Parse(deadlineLayout, deadline)
+	if err != nil {
+		return ""
+	}
+	// the deadline day is still open
+	end := d.AddDate(0, 0, 1)
+	switch {
+	case !now.Before(end):
+		return deadlineExpired
+	case end.Sub(now) <= deadlineSoon:
+		return deadlineClosingSoon
+	}
+	return ""
+}
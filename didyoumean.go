@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+)
+
+const (
+	// didYouMeanBelow is the number of results under which searches get a
+	// "did you mean" suggestion
+	didYouMeanBelow = 3
+	// didYouMeanHeader carries the suggestion of api searches, as their body
+	// is the list of results
+	didYouMeanHeader = "X-Wih-Did-You-Mean"
+)
+
+// searchVocabulary will return the known words searches are corrected to:
+// the tags, the words of company names and the full company names
+func searchVocabulary() ([]string, error) {
+	names, err := SelectCompanyNames()
+	if err != nil {
+		return nil, err
+	}
+	vocab := currentTags()
+	for _, n := range names {
+		n = strings.ToLower(strings.Join(strings.Fields(n), " "))
+		vocab = append(vocab, n)
+		if words := strings.Fields(n); len(words) > 1 {
+			vocab = append(vocab, words...)
+		}
+	}
+	return vocab, nil
+}
+
+// maxEdits is the max edit distance a term is corrected by, so short terms
+// are not corrected to unrelated words
+func maxEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 7:
+		return 1
+	}
+	return 2
+}
+
+// closestWord will return the word of the vocabulary closest to a term, and
+// false when the term is known or no word is close enough
+func closestWord(term string, vocab []string) (string, bool) {
+	term = strings.ToLower(term)
+	best, bestDist := "", maxEdits(term)+1
+	for _, w := range vocab {
+		if w == term {
+			return "", false
+		}
+		if d := editDistance(term, w); d < bestDist {
+			best, bestDist = w, d
+		}
+	}
+	return best, best != ""
+}
+
+// didYouMean will return the search with its misspelled terms and phrases
+// corrected to the closest words of the vocabulary, or an empty string when
+// there is nothing to correct
+func didYouMean(q string, vocab []string) string {
+	tokens, err := lexSearch(q)
+	if err != nil {
+		return ""
+	}
+	corrected := false
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && t.Kind != tokenClose && tokens[i-1].Kind != tokenOpen {
+			b.WriteString(" ")
+		}
+		switch t.Kind {
+		case tokenTerm, tokenPhrase:
+			text := t.Text
+			if w, ok := closestWord(text, vocab); ok {
+				text, corrected = w, true
+			}
+			if t.Kind == tokenPhrase || strings.Contains(text, " ") {
+				text = `"` + text + `"`
+			}
+			b.WriteString(text)
+		default:
+			b.WriteString(t.Kind)
+		}
+	}
+	if !corrected {
+		return ""
+	}
+	return b.String()
+}
+
+// searchSuggestion will return the "did you mean" search for a search that
+// found fewer than didYouMeanBelow jobs
+func searchSuggestion(q string, found int) (string, error) {
+	if q == "" || isRegexSearch(q) || found >= didYouMeanBelow {
+		return "", nil
+	}
+	vocab, err := searchVocabulary()
+	if err != nil {
+		return "", err
+	}
+	return didYouMean(q, vocab), nil
+}
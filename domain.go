@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// faviconUrl is the service used to render company favicons
+const faviconUrl = "https://icons.duckduckgo.com/ip3/%s.ico"
+
+// sharedDomains host pages for many companies, so they don't identify one
+var sharedDomains = []string{
+	"lever.co", "greenhouse.io", "ashbyhq.com", "workable.com", "bamboohr.com",
+	"recruitee.com", "smartrecruiters.com", "workatastartup.com", "ycombinator.com",
+	"github.com", "gitlab.com", "linkedin.com", "twitter.com", "x.com", "google.com",
+	"notion.site", "notion.so", "medium.com", "youtube.com", "wellfound.com", "angel.co",
+	"gmail.com", "googlemail.com", "hotmail.com", "outlook.com", "yahoo.com", "protonmail.com",
+	"proton.me", "icloud.com", "bit.ly", "calendly.com", "typeform.com", "forms.gle",
+}
+
+// secondLevelSuffixes are public suffixes made of two labels
+var secondLevelSuffixes = []string{
+	"co.uk", "org.uk", "ac.uk", "com.au", "net.au", "co.nz", "co.jp", "co.in", "co.za",
+	"com.br", "com.mx", "com.sg", "com.cn", "com.tr", "co.il", "co.kr",
+}
+
+// normalizeDomain will lowercase a host name and reduce it to its registrable domain
+func normalizeDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return ""
+	}
+
+	n := 2
+	for _, s := range secondLevelSuffixes {
+		if strings.HasSuffix(host, "."+s) {
+			n = 3
+			break
+		}
+	}
+	if len(labels) < n {
+		return ""
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
+
+// isSharedDomain will return true when d hosts pages for many companies
+func isSharedDomain(d string) bool {
+	return getIndex(sharedDomains, d) != -1
+}
+
+// jobCompanyDomain will return the normalized domain of the first company
+// url in a job text, falling back to the domain of the contact email.
+func jobCompanyDomain(text string) string {
+	for _, u := range jobUrls(text) {
+		pu, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+		if d := normalizeDomain(pu.Host); d != "" && !isSharedDomain(d) {
+			return d
+		}
+	}
+
+	if email := jobApplyEmail(text); email != "" {
+		if d := normalizeDomain(email[strings.LastIndex(email, "@")+1:]); d != "" && !isSharedDomain(d) {
+			return d
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+const (
+	// shingleSize is the number of words hashed together
+	shingleSize = 3
+	// duplicateMaxDistance is the max number of differing simhash bits
+	// for two posts to be considered duplicates. Job posts are short so
+	// small edits flip more bits than they would on long documents.
+	duplicateMaxDistance = 6
+)
+
+// jobSimhash will compute a 64 bit simhash of the word shingles of a job
+// text. Posts with small edits end up with hashes differing by a few bits.
+func jobSimhash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(jobPlainText(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	for i := 0; i+shingleSize <= len(words) || i == 0; i++ {
+		end := i + shingleSize
+		if end > len(words) {
+			end = len(words)
+		}
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:end], " ")))
+		sum := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if sum&(1<<b) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var hash uint64
+	for b := 0; b < 64; b++ {
+		if weights[b] > 0 {
+			hash |= 1 << b
+		}
+	}
+	return hash
+}
+
+// isDuplicateHash will return true when two simhashes are close enough
+func isDuplicateHash(a, b uint64) bool {
+	return bits.OnesCount64(a^b) <= duplicateMaxDistance
+}
+
+// findDuplicateJob will return the hn id of the earliest job of a story with
+// a text similar to the given simhash, or 0 when there is none. When the job
+// being saved is older than the match, it becomes the original: the match
+// and its duplicates are updated to point to it and 0 is returned.
+func findDuplicateJob(hsId, hnId, hnTime, hash uint64) (uint64, error) {
+	if hash == 0 {
+		return 0, nil
+	}
+
+	jobs, err := SelectHiringJobHashes(hsId)
+	if err != nil {
+		return 0, err
+	}
+	for _, j := range jobs {
+		if j.HnId == hnId || j.Simhash == 0 || j.DuplicateOf > 0 || !isDuplicateHash(uint64(j.Simhash), hash) {
+			continue
+		}
+		if j.Time < hnTime {
+			return j.HnId, nil
+		}
+		return 0, UpdateHiringJobDuplicateOf(j.HnId, hnId)
+	}
+	return 0, nil
+}
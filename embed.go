@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// embedSize is the number of newest jobs listed by the embedded widget
+const embedSize = 10
+
+// embedJob is a job listed by the embedded widget
+type embedJob struct {
+	Title   string
+	Details string
+	Url     string
+}
+
+// embedJobsHandler will list the newest jobs of the current story matching
+// the reader filter params, like /embed/jobs?q=golang&remote=1, as a compact
+// page other sites can frame. Its frame-ancestors are WIH_EMBED_FRAME_ANCESTORS
+// and links open outside of the frame.
+func embedJobsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != embedPathPrefix+"jobs" {
+		http.NotFound(w, r)
+		return
+	}
+	filter, err := parseFilterState(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hs, err := GetCurrentHiringStory()
+	if err != nil {
+		log.Println("failed to get current story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	listing := canonicalUrl("/")
+	if params := filter.encode(); params != "" {
+		listing += "?" + params
+	}
+	filter.Sort = sortNewest
+	jobs, err := SelectJobList(jobScope{StoryId: hs.HnId}, filter, embedSize)
+	if err != nil {
+		filterError(w, "failed to select embedded jobs.", err)
+		return
+	}
+
+	entries := make([]embedJob, len(jobs))
+	for i, hj := range jobs {
+		entries[i] = embedJob{
+			Title:   plainJobTitle(hj.HiringJob),
+			Details: plainJobDetails(hj.HiringJob),
+			Url:     canonicalUrl(fmt.Sprintf("/job/%d", hj.HnId)),
+		}
+	}
+	data := struct {
+		Story      HiringStory
+		Jobs       []embedJob
+		ListingUrl string
+	}{*hs, entries, listing}
+	renderTemplate(w, "embed.html", data)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	employmentFullTime   = "fulltime"
+	employmentPartTime   = "parttime"
+	employmentContract   = "contract"
+	employmentInternship = "internship"
+)
+
+var employmentTypes = []string{employmentFullTime, employmentPartTime, employmentContract, employmentInternship}
+
+// employmentTypeNames are the display names of the employment types
+var employmentTypeNames = map[string]string{
+	employmentFullTime:   "Full-time",
+	employmentPartTime:   "Part-time",
+	employmentContract:   "Contract",
+	employmentInternship: "Internship",
+}
+
+// employmentPatterns match the employment type conventions of job headlines
+var employmentPatterns = map[string]*regexp.Regexp{
+	employmentFullTime:   regexp.MustCompile(`(?i)\b(full[- ]?time|fte|permanent|vollzeit|temps plein)\b`),
+	employmentPartTime:   regexp.MustCompile(`(?i)\b(part[- ]?time|teilzeit|temps partiel)\b`),
+	employmentContract:   regexp.MustCompile(`(?i)\b(contract|contractor|contract[- ]to[- ]hire|freelance|freelancer|c2h|1099)\b`),
+	employmentInternship: regexp.MustCompile(`(?i)\b(intern|interns|internships?|co-?op|praktikum)\b`),
+}
+
+// employmentBodyPatterns are stricter patterns used on the post body, where
+// words like "contract" often mean something else.
+var employmentBodyPatterns = map[string]*regexp.Regexp{
+	employmentFullTime:   regexp.MustCompile(`(?i)\b(full[- ]?time (role|position|job|employees?)|permanent (role|position))\b`),
+	employmentPartTime:   regexp.MustCompile(`(?i)\bpart[- ]?time (role|position|job|contract)\b`),
+	employmentContract:   regexp.MustCompile(`(?i)\b(contract (role|position|basis|work)|contract[- ]to[- ]hire|freelance (role|position|basis))\b`),
+	employmentInternship: regexp.MustCompile(`(?i)\b(internships?|summer interns?)\b`),
+}
+
+// jobEmploymentType will classify a job post into employment types using
+// the headline first and the body when the headline doesn't say.
+// Posts offering several types return a comma separated list.
+func jobEmploymentType(text string) string {
+	found := matchEmploymentTypes(employmentPatterns, jobPlainText(jobRoleText(text)))
+	if len(found) == 0 {
+		found = matchEmploymentTypes(employmentBodyPatterns, jobPlainText(text))
+	}
+	return strings.Join(found, ",")
+}
+
+func matchEmploymentTypes(patterns map[string]*regexp.Regexp, text string) []string {
+	var found []string
+	for _, t := range employmentTypes {
+		if patterns[t].MatchString(text) {
+			found = append(found, t)
+		}
+	}
+	return found
+}
+
+// employmentTypeAliases are the other spellings the type param takes
+var employmentTypeAliases = map[string]string{
+	"intern":     employmentInternship,
+	"full-time":  employmentFullTime,
+	"part-time":  employmentPartTime,
+	"contractor": employmentContract,
+	"freelance":  employmentContract,
+}
+
+// isEmploymentType will return true when v is a known employment type
+func isEmploymentType(v string) bool {
+	return getIndex(employmentTypes, v) != -1
+}
+
+// employmentTypeParam will return the employment type of a type param, like
+// internship for intern, or false for unknown types
+func employmentTypeParam(v string) (string, bool) {
+	v = strings.ToLower(v)
+	if t, ok := employmentTypeAliases[v]; ok {
+		return t, true
+	}
+	return v, isEmploymentType(v)
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// parserVersion must be bumped when an enricher changes the fields it derives,
+// so the jobs enriched by older parsers are enriched again after the next sync.
+const parserVersion = 3
+
+// jobFields are derived job fields keyed by their job_attribute column
+type jobFields map[string]any
+
+// Enricher derives fields from the text of a job. Enrichers run in a chain,
+// each one seeing the fields set by the ones before it.
+type Enricher interface {
+	Name() string
+	Enrich(hj HiringJob) (jobFields, error)
+}
+
+// enricherFunc adapts a function to the Enricher interface
+type enricherFunc struct {
+	name string
+	fn   func(hj HiringJob) (jobFields, error)
+}
+
+func (e enricherFunc) Name() string {
+	return e.name
+}
+
+func (e enricherFunc) Enrich(hj HiringJob) (jobFields, error) {
+	return e.fn(hj)
+}
+
+// enrichers are all the available enrichers in their default order.
+// Confidences are rough: values found through keywords in the headline are
+// trusted more than values found in the body or guessed from position.
+var enrichers = []Enricher{
+	enricherFunc{"level", func(hj HiringJob) (jobFields, error) {
+		level := jobLevel(hj.Text)
+		confidence := 0.9
+		if strings.Contains(level, ",") {
+			confidence = 0.7
+		}
+		var terms []string
+		for _, l := range strings.Split(level, ",") {
+			if p, ok := levelPatterns[l]; ok {
+				terms = append(terms, matchedTerms(p, jobPlainText(jobRoleText(hj.Text))))
+			}
+		}
+		return jobFields{"level": scored(level, confidence, "matched "+strings.Join(terms, ", ")+" in headline")}, nil
+	}},
+	enricherFunc{"contact", func(hj HiringJob) (jobFields, error) {
+		email, confidence, source := jobApplyEmail(hj.Text), 0.9, "email address in text"
+		if !strings.Contains(strings.ToLower(jobPlainText(hj.Text)), email) {
+			confidence, source = 0.7, "obfuscated email address in text"
+		}
+		applyUrl := jobApplyUrl(hj.Text)
+		urlConfidence, urlSource := 0.4, "first link in text"
+		for _, h := range applyUrlHints {
+			if strings.Contains(strings.ToLower(applyUrl), h) {
+				urlConfidence, urlSource = 0.8, fmt.Sprintf("link containing %q", h)
+				break
+			}
+		}
+		return jobFields{
+			"apply_email": scored(email, confidence, source),
+			"apply_url":   scored(applyUrl, urlConfidence, urlSource),
+		}, nil
+	}},
+	enricherFunc{"company", func(hj HiringJob) (jobFields, error) {
+		domain := jobCompanyDomain(hj.Text)
+		name := jobCompanyName(hj.Text)
+		companyId, err := ResolveCompany(name, domain)
+		confidence, source := 0.9, fmt.Sprintf("headline segment %q", name)
+		if name == "" {
+			confidence, source = 0.6, fmt.Sprintf("link domain %q", domain)
+		}
+		return jobFields{
+			"company_domain": scored(domain, 0.7, "most linked company domain"),
+			"company_id":     scored(companyId, confidence, source),
+		}, err
+	}},
+	enricherFunc{"duplicate", func(hj HiringJob) (jobFields, error) {
+		simhash := jobSimhash(hj.Text)
+		duplicateOf, err := findDuplicateJob(hj.HiringStoryId, hj.HnId, hj.Time, simhash)
+		source := fmt.Sprintf("text simhash within %d bits of job %d", duplicateMaxDistance, duplicateOf)
+		return jobFields{"simhash": int64(simhash), "duplicate_of": scored(duplicateOf, 0.8, source)}, err
+	}},
+	enricherFunc{"language", func(hj HiringJob) (jobFields, error) {
+		lang, hits, total := detectLanguage(hj.Text)
+		var confidence float64
+		if total > 0 {
+			confidence = float64(hits) / float64(total)
+		}
+		source := fmt.Sprintf("%d of %d stopwords", hits, total)
+		return jobFields{"language": scored(lang, confidence, source)}, nil
+	}},
+	enricherFunc{"employment", func(hj HiringJob) (jobFields, error) {
+		employmentType := jobEmploymentType(hj.Text)
+		confidence, source := 0.6, "keywords in body"
+		if len(matchEmploymentTypes(employmentPatterns, jobPlainText(jobRoleText(hj.Text)))) > 0 {
+			confidence, source = 0.9, "keywords in headline"
+		}
+		return jobFields{"employment_type": scored(employmentType, confidence, source)}, nil
+	}},
+	enricherFunc{"benefits", func(hj HiringJob) (jobFields, error) {
+		plain := jobPlainText(hj.Text)
+		var terms []string
+		for _, b := range strings.Split(jobBenefits(hj.Text), ",") {
+			if p, ok := benefitPatterns[b]; ok {
+				terms = append(terms, matchedTerms(p, plain))
+			}
+		}
+		equity := jobEquity(hj.Text)
+		return jobFields{
+			"equity":   scored(equity, 0.8, fmt.Sprintf("percentage %q near equity", equity)),
+			"benefits": scored(jobBenefits(hj.Text), 0.8, "matched "+strings.Join(terms, ", ")),
+		}, nil
+	}},
+	enricherFunc{"salary", func(hj HiringJob) (jobFields, error) {
+		lo, hi, currency, match, inHeadline := matchSalary(hj.Text)
+		confidence, source := 0.7, fmt.Sprintf("amount %q in body", match)
+		if inHeadline {
+			confidence, source = 0.9, fmt.Sprintf("amount %q in headline", match)
+		}
+		loBase, _ := exchangeRates.toBase(lo, currency)
+		hiBase, _ := exchangeRates.toBase(hi, currency)
+		rate, _ := exchangeRates.rate(currency)
+		baseSource := fmt.Sprintf("%s, converted at %g %s per %s", source, rate, currency, baseCurrency)
+		return jobFields{
+			"salary_min":      scored(lo, confidence, source),
+			"salary_max":      scored(hi, confidence, source),
+			"salary_currency": scored(currency, confidence, source),
+			"salary_min_usd":  scored(loBase, confidence, baseSource),
+			"salary_max_usd":  scored(hiBase, confidence, baseSource),
+		}, nil
+	}},
+	enricherFunc{"tags", func(hj HiringJob) (jobFields, error) {
+		plain := jobPlainText(hj.Text)
+		tags := jobTechTags(hj.Text)
+		var terms []string
+		for _, t := range strings.Split(tags, ",") {
+			if p, ok := tagPatterns[t]; ok {
+				terms = append(terms, matchedTerms(p, plain))
+			}
+		}
+		return jobFields{"tags": scored(tags, 0.8, "matched "+strings.Join(terms, ", "))}, nil
+	}},
+	enricherFunc{"location", func(hj HiringJob) (jobFields, error) {
+		h := parseJobHeadline(hj.Text)
+		confidence, source := 0.9, "headline segment with a place name"
+		if getIndex(h.Guessed, "location") != -1 {
+			confidence, source = 0.5, "headline segment guessed from its position"
+		}
+		return jobFields{"location": scored(jobLocation(hj.Text), confidence, source)}, nil
+	}},
+	enricherFunc{"remote", func(hj HiringJob) (jobFields, error) {
+		h := parseJobHeadline(hj.Text)
+		terms := matchedTerms(remotePattern, h.Remote+" "+h.Location)
+		return jobFields{"remote": scored(jobRemote(hj.Text), 0.9, "matched "+terms+" in headline")}, nil
+	}},
+	enricherFunc{"timezone", func(hj HiringJob) (jobFields, error) {
+		r, terms, confidence, ok := matchTimezone(hj.Text)
+		if !ok {
+			return jobFields{"timezone": "", "tz_min": 0, "tz_max": 0}, nil
+		}
+		source := "matched " + terms
+		return jobFields{
+			"timezone": scored(r.String(), confidence, source),
+			"tz_min":   r.Min,
+			"tz_max":   r.Max,
+		}, nil
+	}},
+	enricherFunc{"visa", func(hj HiringJob) (jobFields, error) {
+		visa, terms := jobVisa(hj.Text)
+		return jobFields{"visa": scored(visa, 0.8, "matched "+terms)}, nil
+	}},
+	enricherFunc{"funding", func(hj HiringJob) (jobFields, error) {
+		funding, terms := jobFunding(hj.Text)
+		employees, match := jobEmployees(hj.Text)
+		batch, batchTerms := jobYCBatch(hj.Text)
+		source := fmt.Sprintf("headcount %q", match)
+		return jobFields{
+			"funding":      scored(funding, 0.8, "matched "+terms),
+			"employees":    scored(employees, 0.7, source),
+			"company_size": scored(companySize(employees), 0.7, source),
+			"yc_batch":     scored(batch, 0.8, "matched "+batchTerms),
+		}, nil
+	}},
+	enricherFunc{"interview", func(hj HiringJob) (jobFields, error) {
+		plain := jobPlainText(hj.Text)
+		interview, rounds := jobInterview(hj.Text)
+		var terms []string
+		for _, f := range strings.Split(interview, ",") {
+			if p, ok := interviewPatterns[f]; ok {
+				terms = append(terms, matchedTerms(p, plain))
+			}
+		}
+		roundsTerms := matchedTerms(interviewRoundsPattern, plain)
+		if rounds > 0 {
+			terms = append(terms, roundsTerms)
+		}
+		return jobFields{
+			"interview":        scored(interview, 0.8, "matched "+strings.Join(terms, ", ")),
+			"interview_rounds": scored(rounds, 0.7, "matched "+roundsTerms),
+		}, nil
+	}},
+	enricherFunc{"deadline", func(hj HiringJob) (jobFields, error) {
+		deadline, terms := jobDeadline(hj.Text, time.Unix(int64(hj.Time), 0))
+		return jobFields{"deadline": scored(deadline, 0.8, "matched "+terms)}, nil
+	}},
+	enricherFunc{"geo", func(hj HiringJob) (jobFields, error) {
+		location := hj.Location
+		if location == "" {
+			location = jobLocation(hj.Text)
+		}
+		place, ok := geocodeLocation(location)
+		if !ok {
+			return jobFields{"latitude": 0.0, "longitude": 0.0}, nil
+		}
+		source := fmt.Sprintf("place %q in location %q", place.Name(), location)
+		return jobFields{
+			"latitude":  scored(place.Latitude, 0.8, source),
+			"longitude": scored(place.Longitude, 0.8, source),
+		}, nil
+	}},
+}
+
+// enricherNames will return the names of the given enrichers
+func enricherNames(es []Enricher) []string {
+	names := make([]string, len(es))
+	for i, e := range es {
+		names[i] = e.Name()
+	}
+	return names
+}
+
+// enrichmentChain is the ordered list of enrichers run on jobs
+type enrichmentChain []Enricher
+
+// newEnrichmentChain will build a chain from enricher names, ignoring unknown ones.
+// No names means every enricher.
+func newEnrichmentChain(names []string) enrichmentChain {
+	if len(names) == 0 {
+		return enrichers
+	}
+	var c enrichmentChain
+	for _, n := range names {
+		idx := getIndex(enricherNames(enrichers), n)
+		if idx == -1 {
+			log.Printf("unknown enricher %q, skipping", n)
+			continue
+		}
+		c = append(c, enrichers[idx])
+	}
+	return c
+}
+
+var enrichment = newEnrichmentChain(cfg.Enrichers)
+
+// signature will return the names of the chain enrichers, stored with each
+// job to know which jobs were enriched by a different chain.
+func (c enrichmentChain) signature() string {
+	return strings.Join(enricherNames(c), ",")
+}
+
+// enrich will run the chain over hj, setting the derived fields on it.
+// Returns all the fields set by the chain.
+func (c enrichmentChain) enrich(hj *HiringJob) (jobFields, error) {
+	all := jobFields{}
+	for _, e := range c {
+		fields, err := e.Enrich(*hj)
+		if err != nil {
+			return nil, fmt.Errorf("enricher %s: %w", e.Name(), err)
+		}
+		if err := fields.applyTo(hj); err != nil {
+			return nil, fmt.Errorf("enricher %s: %w", e.Name(), err)
+		}
+		for k, v := range fields {
+			all[k] = derived{Value: v, Enricher: e.Name()}
+		}
+	}
+	hj.Enrichers = c.signature()
+	hj.ParserVersion = parserVersion
+	all["enrichers"] = hj.Enrichers
+	all["parser_version"] = hj.ParserVersion
+	return all, nil
+}
+
+// applyTo will set the fields on the HiringJob fields mapped to the same columns
+func (f jobFields) applyTo(hj *HiringJob) error {
+	v := reflect.ValueOf(hj).Elem()
+	names := db.Mapper.TypeMap(v.Type()).Names
+	for col, val := range f {
+		if e, ok := val.(evidence); ok {
+			val = e.Value
+		}
+		fi, ok := names[col]
+		if !ok {
+			return fmt.Errorf("unknown job field %s", col)
+		}
+		fv := reflectx.FieldByIndexes(v, fi.Index)
+		rv := reflect.ValueOf(val)
+		if !rv.CanConvert(fv.Type()) {
+			return fmt.Errorf("invalid value %v for job field %s", val, col)
+		}
+		fv.Set(rv.Convert(fv.Type()))
+	}
+	return nil
+}
+
+// enrichStaleJobs will run the chain over the jobs of a story that were
+// enriched by a different chain, like jobs saved before an enricher was added.
+func enrichStaleJobs(ctx context.Context, hsId uint64) error {
+	jobs, err := SelectStaleHiringJobs(hsId, enrichment.signature(), parserVersion)
+	if err != nil {
+		return err
+	}
+	for _, hj := range jobs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fields, err := enrichment.enrich(&hj)
+		if err != nil {
+			return err
+		}
+		if err := SaveJobAttributes(hj.HnId, fields); err != nil {
+			return err
+		}
+	}
+	if len(jobs) > 0 {
+		log.Printf("enriched %d stale jobs of hiring story %d", len(jobs), hsId)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// uncertainConfidence is the confidence under which values are shown as uncertain
+const uncertainConfidence = 0.6
+
+// evidence wraps a derived field value with how confident its enricher is
+// about it, from 0 to 1, and what it was derived from. Fields returned as
+// plain values, like empty ones, have no evidence.
+type evidence struct {
+	Value      any
+	Confidence float64
+	Source     string
+}
+
+// scored will wrap v with its evidence unless v is a zero value
+func scored(v any, confidence float64, source string) any {
+	if reflect.ValueOf(v).IsZero() {
+		return v
+	}
+	return evidence{Value: v, Confidence: confidence, Source: source}
+}
+
+// matchedTerms will describe the distinct matches of p in text, like `"Senior", "Lead"`
+func matchedTerms(p *regexp.Regexp, text string) string {
+	var terms []string
+	for _, m := range p.FindAllString(text, -1) {
+		m = fmt.Sprintf("%q", strings.TrimSpace(m))
+		if getIndex(terms, m) == -1 {
+			terms = append(terms, m)
+		}
+	}
+	return strings.Join(terms, ", ")
+}
+
+// unwrap will return the fields without their evidence and the evidence of the
+// fields that have one, along with the enrichers that derived them. Fields
+// without evidence are returned as nil evidence.
+func (f jobFields) unwrap() (jobFields, map[string]*evidence, map[string]string) {
+	values := jobFields{}
+	evidences := map[string]*evidence{}
+	enrichers := map[string]string{}
+	for k, v := range f {
+		if d, ok := v.(derived); ok {
+			enrichers[k] = d.Enricher
+			v = d.Value
+		}
+		if e, ok := v.(evidence); ok {
+			values[k] = e.Value
+			evidences[k] = &e
+			continue
+		}
+		values[k] = v
+		evidences[k] = nil
+	}
+	return values, evidences, enrichers
+}
+
+// FieldEvidence is the stored evidence of a job attribute
+type FieldEvidence struct {
+	Field      string
+	Confidence float64
+	Source     string
+}
+
+// JobEvidence are the evidences of the attributes of a job by field
+type JobEvidence map[string]FieldEvidence
+
+// Uncertain will return true when the field value has a low confidence
+func (je JobEvidence) Uncertain(field string) bool {
+	e, ok := je[field]
+	return ok && e.Confidence < uncertainConfidence
+}
+
+// saveJobEvidence will store the evidence of the fields of a job, removing
+// the stored evidence of fields without one. Values confirmed by an admin keep
+// their confirmation while they are derived again unchanged.
+func saveJobEvidence(tx *sqlx.Tx, hnId uint64, evidences map[string]*evidence) error {
+	for field, e := range evidences {
+		var err error
+		if e == nil {
+			_, err = tx.Exec(`DELETE FROM job_attribute_evidence WHERE hn_id=? and field=?`, hnId, field)
+		} else {
+			_, err = tx.Exec(`INSERT INTO job_attribute_evidence (hn_id, field, confidence, source) VALUES (?, ?, ?, ?)
+                ON CONFLICT (hn_id, field) DO UPDATE SET
+                confidence=CASE WHEN confirmed_value = CAST(? AS TEXT) THEN confidence ELSE excluded.confidence END,
+                source=CASE WHEN confirmed_value = CAST(? AS TEXT) THEN source ELSE excluded.source END,
+                confirmed_value=CASE WHEN confirmed_value = CAST(? AS TEXT) THEN confirmed_value END`,
+				hnId, field, e.Confidence, e.Source, e.Value, e.Value, e.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func SelectJobEvidence(hnId uint64) (JobEvidence, error) {
+	var rows []FieldEvidence
+	sql := `SELECT field, confidence, source FROM job_attribute_evidence WHERE hn_id=? ORDER BY field`
+	if err := db.Select(&rows, sql, hnId); err != nil {
+		return nil, err
+	}
+
+	je := JobEvidence{}
+	for _, r := range rows {
+		je[r.Field] = r
+	}
+	return je, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// excludeCookie holds the keywords a reader hides posts by on every visit
+	excludeCookie = "wih_exclude"
+	// excludeHeader carries the saved keywords of a request. Pages applying
+	// them vary on it, so cached pages are kept per exclusion list.
+	excludeHeader = "X-Wih-Exclude"
+	// excludeMaxKeywords is the max number of keywords of an exclusion list
+	excludeMaxKeywords = 20
+	// excludeMaxLength is the max length of an excluded keyword
+	excludeMaxLength = 50
+)
+
+// parseExcludeList will parse a comma separated list of keywords, like
+// "blockchain,adtech", lowercased and without duplicates
+func parseExcludeList(v string) []string {
+	var keywords []string
+	for _, k := range strings.Split(v, ",") {
+		k = strings.ToLower(strings.Join(strings.Fields(k), " "))
+		if k == "" || len(k) > excludeMaxLength || getIndex(keywords, k) != -1 {
+			continue
+		}
+		keywords = append(keywords, k)
+		if len(keywords) == excludeMaxKeywords {
+			break
+		}
+	}
+	return keywords
+}
+
+// excludeSearch will return the search matching the jobs with any of the
+// keywords. Keywords of several words match as phrases.
+func excludeSearch(keywords []string) *searchNode {
+	var n *searchNode
+	for _, k := range keywords {
+		n = joinSearch(tokenOr, n, &searchNode{Op: tokenPhrase, Text: k})
+	}
+	return n
+}
+
+// withSavedExclusions will apply the exclusion list saved by the reader to a
+// filter without an exclude param. The response varies on the saved list.
+func withSavedExclusions(w http.ResponseWriter, r *http.Request, f FilterState) FilterState {
+	w.Header().Add("Vary", excludeHeader)
+	if len(f.Exclude) == 0 {
+		f.Exclude = parseExcludeList(r.Header.Get(excludeHeader))
+		f.excludeSaved = len(f.Exclude) > 0
+	}
+	return f
+}
+
+// excludeMiddleware will pass the saved exclusion list of a request to the
+// handlers in the exclude header
+func excludeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the header is only set by us
+		r.Header.Del(excludeHeader)
+		if c, err := r.Cookie(excludeCookie); err == nil && c.Value != "" {
+			r.Header.Set(excludeHeader, strings.Join(parseExcludeList(c.Value), ","))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// excludeHandler will save the exclusion list posted by a reader, or forget
+// it when empty, and send the reader back to the page it was posted from
+func excludeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     excludeCookie,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if keywords := parseExcludeList(r.PostFormValue("exclude")); len(keywords) > 0 {
+		cookie.Value = strings.Join(keywords, ",")
+		cookie.MaxAge = int((365 * 24 * time.Hour).Seconds())
+	} else {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+
+	next := r.PostFormValue("next")
+	// only redirect to pages of this site
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		next = "/"
+	}
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
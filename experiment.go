@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"expvar"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// sessionCookie holds the random id sessions are bucketed by and saved
+	// searches belong to
+	sessionCookie = "wih_session"
+	// sessionMaxAge keeps sessions in the same buckets for a year
+	sessionMaxAge = 365 * 24 * time.Hour
+	// experimentHeader carries the variants assigned to a request. Pages using
+	// an experiment vary on it, so cached pages are kept per variant.
+	experimentHeader = "X-Wih-Experiments"
+	// variantControl is assigned to the sessions compared against a variant
+	variantControl = "control"
+)
+
+// experiment is a UI change rolled out to a fraction of sessions
+type experiment struct {
+	Name     string
+	Variants []string
+}
+
+// experiments are the known experiments, enabled by cfg.Experiments
+var experiments = []experiment{
+	// reader_layout collapses the reader filters into a single toggle
+	{Name: "reader_layout", Variants: []string{"compact"}},
+}
+
+// Exposures count the pages shown with each variant, and sessions the new
+// sessions first shown one, so variants are compared by pages per session.
+var (
+	experimentExposures = expvar.NewMap("experiment_exposures")
+	experimentSessions  = expvar.NewMap("experiment_sessions")
+)
+
+// experimentRollouts will parse rollouts like "reader_layout=20" into the
+// percent of sessions shown a variant of each enabled experiment
+func experimentRollouts(items []string) map[string]int {
+	rollouts := map[string]int{}
+	for _, item := range items {
+		name, v, _ := strings.Cut(item, "=")
+		percent, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || percent <= 0 || percent > 50 || !isExperiment(name) {
+			log.Printf("invalid experiment rollout %q, skipping", item)
+			continue
+		}
+		rollouts[strings.TrimSpace(name)] = percent
+	}
+	return rollouts
+}
+
+var enabledExperiments = experimentRollouts(cfg.Experiments)
+
+// isExperiment will return true when name is a known experiment
+func isExperiment(name string) bool {
+	for _, e := range experiments {
+		if e.Name == strings.TrimSpace(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionBucket will return the bucket from 0 to 99 of a session in an experiment
+func sessionBucket(session, name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + session))
+	return int(h.Sum32() % 100)
+}
+
+// assignVariants will return the variants of a session in the enabled
+// experiments. A rollout of p percent shows a variant to the sessions in the
+// first p buckets and keeps as many sessions as control, the other sessions
+// are not part of the experiment.
+func assignVariants(session string) url.Values {
+	assigned := url.Values{}
+	for _, e := range experiments {
+		percent, ok := enabledExperiments[e.Name]
+		if !ok {
+			continue
+		}
+		switch b := sessionBucket(session, e.Name); {
+		case b < percent:
+			assigned.Set(e.Name, e.Variants[b%len(e.Variants)])
+		case b < 2*percent:
+			assigned.Set(e.Name, variantControl)
+		}
+	}
+	return assigned
+}
+
+// newSessionId will return a random session id
+func newSessionId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Println("failed to generate session id.", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestSession will return the session of a request, starting a new one
+// when there is none, and whether it is new
+func requestSession(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if c, err := r.Cookie(sessionCookie); err == nil && c.Value != "" {
+		return c.Value, false
+	}
+	session := newSessionId()
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session,
+		Path:     "/",
+		MaxAge:   int(sessionMaxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	// later calls for the same request see the new session
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: session})
+	return session, true
+}
+
+// experimentVariant will return the variant of an experiment assigned to the
+// request, or an empty string when the request is not part of it. The
+// response varies on the assigned variants.
+func experimentVariant(w http.ResponseWriter, r *http.Request, name string) string {
+	if _, ok := enabledExperiments[name]; !ok {
+		return ""
+	}
+	w.Header().Add("Vary", experimentHeader)
+	q, _ := url.ParseQuery(r.Header.Get(experimentHeader))
+	return q.Get(name)
+}
+
+// experimentsMiddleware will assign the variants of the enabled experiments
+// to each request by its session, starting a session when there is none.
+// Exposures to the assigned variants are counted for the responses varying on them.
+func experimentsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the header is only set by us
+		r.Header.Del(experimentHeader)
+		if len(enabledExperiments) == 0 || strings.HasPrefix(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session, newSession := requestSession(w, r)
+		assigned := assignVariants(session)
+		r.Header.Set(experimentHeader, assigned.Encode())
+
+		next.ServeHTTP(w, r)
+
+		// cached responses keep the Vary header of the handler that used the experiments
+		if vary, _ := varyHeaders(w.Header()); getIndex(vary, experimentHeader) == -1 {
+			return
+		}
+		for name := range assigned {
+			key := name + "/" + assigned.Get(name)
+			experimentExposures.Add(key, 1)
+			if newSession {
+				experimentSessions.Add(key, 1)
+			}
+		}
+	})
+}
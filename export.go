@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// exportFlushRows is the number of rows written between flushes
+const exportFlushRows = 100
+
+// hiringJobExport is a hiring job row as written by the exports
+type hiringJobExport struct {
+	HnId          uint64 `db:"hn_id" json:"hn_id"`
+	StoryId       uint64 `db:"hiring_story_id" json:"story_id"`
+	Time          uint64 `db:"time" json:"time"`
+	Status        uint8  `db:"status" json:"status"`
+	Level         string `db:"level" json:"level"`
+	ApplyEmail    string `db:"apply_email" json:"apply_email"`
+	ApplyUrl      string `db:"apply_url" json:"apply_url"`
+	CompanyDomain string `db:"company_domain" json:"company_domain"`
+	Language      string `db:"language" json:"language"`
+	Employment    string `db:"employment_type" json:"employment_type"`
+	Text          string `db:"text" json:"text"`
+}
+
+func (e hiringJobExport) csvRecord() []string {
+	return []string{
+		strconv.FormatUint(e.HnId, 10),
+		strconv.FormatUint(e.StoryId, 10),
+		strconv.FormatUint(e.Time, 10),
+		strconv.FormatUint(uint64(e.Status), 10),
+		e.Level,
+		e.ApplyEmail,
+		e.ApplyUrl,
+		e.CompanyDomain,
+		e.Language,
+		e.Employment,
+		e.Text,
+	}
+}
+
+var hiringJobExportHeader = []string{
+	"hn_id", "story_id", "time", "status", "level", "apply_email", "apply_url", "company_domain", "language",
+	"employment_type", "text",
+}
+
+// QueryHiringJobExport will return the rows of all hiring jobs with an id
+// greater than afterId ordered by id, optionally limited to one story.
+func QueryHiringJobExport(hsId, afterId uint64) (*sqlx.Rows, error) {
+	sql := `SELECT hn_id, hiring_story_id, time, COALESCE(status, 0) AS status, level,
+            apply_email, apply_url, company_domain, language, employment_type, text
+            FROM hiring_job_view
+            WHERE hn_id > ?`
+	args := []any{afterId}
+	if hsId > 0 {
+		sql += " AND hiring_story_id=?"
+		args = append(args, hsId)
+	}
+	sql += " ORDER BY hn_id"
+	return db.Queryx(sql, args...)
+}
+
+// flushRows will flush buffered export rows to the client every exportFlushRows rows
+func flushRows(w http.ResponseWriter, rows int, flush func()) {
+	if rows%exportFlushRows != 0 {
+		return
+	}
+	flush()
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// exportJobsHandler streams all hiring jobs as csv or jsonl without
+// buffering the result set. Interrupted downloads are resumed by passing
+// the last received hn_id in the "after_id" param, which continues with the
+// next job and leaves out the csv header. Byte ranges are not served, as the
+// offsets of rows move when syncs update the texts of jobs, so Range headers
+// are ignored and the whole export is sent.
+func exportJobsHandler(w http.ResponseWriter, r *http.Request) {
+	format := strings.TrimPrefix(r.URL.Path, "/admin/export/jobs.")
+	if format != "csv" && format != "jsonl" {
+		http.NotFound(w, r)
+		return
+	}
+
+	hsId, err := uintParam(r.URL.Query(), "story")
+	afterId, afterErr := uintParam(r.URL.Query(), "after_id")
+	if err := errors.Join(err, afterErr); err != nil {
+		apiError(w, http.StatusBadRequest, "invalid query params", err)
+		return
+	}
+	rows, err := QueryHiringJobExport(hsId, afterId)
+	if err != nil {
+		log.Println("failed to query hiring jobs export.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	contentType := "text/csv; charset=utf-8"
+	if format == "jsonl" {
+		contentType = "application/x-ndjson"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="hiring_jobs.%s"`, format))
+
+	cw := csv.NewWriter(w)
+	enc := json.NewEncoder(w)
+	if format == "csv" && afterId == 0 {
+		cw.Write(hiringJobExportHeader)
+	}
+
+	var written int
+	for rows.Next() {
+		if err := r.Context().Err(); err != nil {
+			log.Println("hiring jobs export canceled.", err)
+			return
+		}
+
+		var e hiringJobExport
+		if err := rows.StructScan(&e); err != nil {
+			log.Println("failed to scan hiring job export row.", err)
+			return
+		}
+		if format == "csv" {
+			cw.Write(e.csvRecord())
+		} else if err := enc.Encode(e); err != nil {
+			log.Println("failed to write hiring job export row.", err)
+			return
+		}
+		written++
+		flushRows(w, written, cw.Flush)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println("failed to read hiring jobs export.", err)
+	}
+	cw.Flush()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// feedSize is the number of newest jobs listed by the feeds
+const feedSize = 50
+
+// atomNamespace is the xml namespace of atom feeds
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate"`
+	Guid        rssGuid `xml:"guid"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Id       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Id        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Author    atomAuthor  `xml:"author"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	Uri  string `xml:"uri,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// atomTime will format a unix time as an atom date
+func atomTime(t uint64) string {
+	return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+}
+
+// feedJobs will select the newest jobs of the current story matching a
+// filter, along with the story
+func feedJobs(f FilterState) (*HiringStory, []HiringJobListItem, error) {
+	hs, err := GetCurrentHiringStory()
+	if err != nil {
+		return nil, nil, err
+	}
+	f.Sort = sortNewest
+	jobs, err := SelectJobList(jobScope{StoryId: hs.HnId}, f, feedSize)
+	return hs, jobs, err
+}
+
+// feedUpdated will return the last time one of the jobs was posted or edited
+func feedUpdated(jobs []HiringJobListItem) uint64 {
+	var updated uint64
+	for _, hj := range jobs {
+		if hj.Time > updated {
+			updated = hj.Time
+		}
+		if hj.UpdatedAt > updated {
+			updated = hj.UpdatedAt
+		}
+	}
+	return updated
+}
+
+// rssTime will format a unix time as an rss date
+func rssTime(t uint64) string {
+	return time.Unix(int64(t), 0).UTC().Format(time.RFC1123Z)
+}
+
+// rssFeedHandler will list the newest jobs of the current story as an rss
+// feed, titled by their parsed headline with the full post as description.
+// Jobs are selected on every request, so the feed follows the syncs.
+func rssFeedHandler(w http.ResponseWriter, r *http.Request) {
+	filter, _ := parseFilterState(nil)
+	hs, jobs, err := feedJobs(filter)
+	if err != nil {
+		filterError(w, "failed to select feed jobs.", err)
+		return
+	}
+
+	channel := rssChannel{
+		Title:       hs.Title,
+		Link:        canonicalUrl(fmt.Sprintf("/story/%d", hs.HnId)),
+		Description: "The newest job posts of " + hs.Title,
+	}
+	for _, hj := range jobs {
+		link := canonicalUrl(fmt.Sprintf("/job/%d", hj.HnId))
+		channel.Items = append(channel.Items, rssItem{
+			Title:       plainJobTitle(hj.HiringJob),
+			Link:        link,
+			Description: string(hj.Body()),
+			PubDate:     rssTime(hj.Time),
+			Guid:        rssGuid{IsPermaLink: true, Value: link},
+		})
+	}
+	if built := feedUpdated(jobs); built > 0 {
+		channel.LastBuildDate = rssTime(built)
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	io.WriteString(w, xml.Header)
+	if err := xml.NewEncoder(w).Encode(rssFeed{Version: "2.0", Channel: channel}); err != nil {
+		log.Println("failed to write rss feed.", err)
+	}
+}
+
+// atomFeedHandler will list the newest jobs of the current story matching the
+// reader filter params of the feed url as an atom feed, like
+// /feed.atom?q=golang&remote=1, so every search can be followed from a feed
+// reader without an account.
+func atomFeedHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilterState(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hs, jobs, err := feedJobs(filter)
+	if err != nil {
+		filterError(w, "failed to select feed jobs.", err)
+		return
+	}
+
+	params := filter.encode()
+	self, listing := canonicalUrl("/feed.atom"), canonicalUrl("/")
+	if params != "" {
+		self += "?" + params
+		listing += "?" + params
+	}
+	feed := atomFeed{
+		Xmlns: atomNamespace,
+		Id:    self,
+		Title: hs.Title,
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: self},
+			{Rel: "alternate", Type: "text/html", Href: listing},
+		},
+	}
+	if params != "" {
+		if p, err := url.QueryUnescape(params); err == nil {
+			params = p
+		}
+		feed.Subtitle = "Jobs matching " + strings.ReplaceAll(params, "&", " ")
+	}
+	updated := feedUpdated(jobs)
+	if updated == 0 {
+		updated = uint64(time.Now().Unix())
+	}
+	feed.Updated = atomTime(updated)
+	for _, hj := range jobs {
+		link := canonicalUrl(fmt.Sprintf("/job/%d", hj.HnId))
+		entryUpdated := hj.Time
+		if hj.UpdatedAt > entryUpdated {
+			entryUpdated = hj.UpdatedAt
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Id:        link,
+			Title:     plainJobTitle(hj.HiringJob),
+			Link:      atomLink{Rel: "alternate", Type: "text/html", Href: link},
+			Published: atomTime(hj.Time),
+			Updated:   atomTime(entryUpdated),
+			Author:    atomAuthor{Name: hj.Poster, Uri: hj.PosterUrl()},
+			Content:   atomContent{Type: "html", Value: string(hj.Body())},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	io.WriteString(w, xml.Header)
+	if err := xml.NewEncoder(w).Encode(feed); err != nil {
+		log.Println("failed to write atom feed.", err)
+	}
+}
@@ -0,0 +1,661 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterState is the full state of a jobs listing: the filters narrowing the
+// jobs down and the reader cursor. It is parsed from and serialized to query
+// params, so reader pages, the api and links to them share one encoding.
+type FilterState struct {
+	// Query are the keywords the text of jobs must all contain
+	Query string
+
+	Level          string
+	Language       string
+	EmploymentType string
+	// Benefits are the benefit flags jobs must all offer
+	Benefits []string
+	// Interview are the interview process flags jobs must all have
+	Interview []string
+	// Tags are the technology tags jobs must all have
+	Tags []string
+	// Company is the slug of the company of the jobs, or of one of its aliases
+	Company string
+	// Timezone is the UTC offset, as a tz param, that jobs must require overlap with
+	Timezone string
+	// MinSalary is the yearly salary in USD the salary range of jobs must reach
+	MinSalary uint64
+	// SalaryUnknown keeps the jobs without a parsed salary when filtering by salary
+	SalaryUnknown bool
+	// Location is the place, country or region of the jobs, like berlin or europe
+	Location string
+	// Remote keeps the jobs classified as fully remote
+	Remote bool
+	// Visa keeps the jobs sponsoring visas, or ruling sponsorship out, one of visaOptions
+	Visa string
+	// Funding keeps the jobs of companies at a funding stage, one of fundingStages
+	Funding string
+	// Size keeps the jobs of companies of a size, one of companySizes
+	Size string
+	// YC keeps the jobs of companies backed by Y Combinator
+	YC bool
+	// Open leaves out the jobs whose apply deadline passed
+	Open bool
+	// Exclude are the keywords of the posts hidden from the jobs
+	Exclude []string
+	// excludeSaved is set when Exclude is the list saved by the reader, which
+	// urls leave out as it applies on every visit
+	excludeSaved bool
+	// PostedAfter and PostedBefore are the dates, or times, jobs must be posted from and before
+	PostedAfter  time.Time
+	PostedBefore time.Time
+	// Since keeps the jobs posted in the last days or hours, like 7d or 12h
+	Since string
+	// Fuzzy keeps the jobs whose filtered fields were derived with a low
+	// confidence, which strict filters leave out
+	Fuzzy bool
+	// Typos runs searches finding few jobs again with the close spellings of
+	// their terms, so misspelled terms still find jobs
+	Typos bool
+	// Unseen leaves out the jobs served to the reader in earlier visits
+	Unseen bool
+	// seenBy is the owner of the jobs seen by the reader, set for unseen
+	// listings by withSeenJobs
+	seenBy string
+	// Duplicates includes reposts of the same job, which are collapsed by default
+	Duplicates bool
+	// Sort is the order jobs are listed in, one of jobSorts. Empty lists the
+	// best matches of searches first and the newest jobs otherwise.
+	Sort string
+	// defaultSort is the sort of a listing without a sort param, which urls
+	// leave out as the listing applies it on every visit
+	defaultSort string
+	// After and Before are the job ids the reader moves from to the next or
+	// previous job. Before wins when both are set.
+	After  uint64
+	Before uint64
+	// Limit is the number of jobs a page of a listing shows, 0 shows listPageSize
+	Limit int
+}
+
+// locationMaxLength is the max length of a location filter
+const locationMaxLength = 100
+
+// postedOption is a recent period offered as a filter
+type postedOption struct {
+	Since string
+	Label string
+}
+
+// postedFilters are the recent periods offered as filters
+var postedFilters = []postedOption{
+	{Since: "1d", Label: "last day"},
+	{Since: "7d", Label: "last 7 days"},
+}
+
+// sinceMax is the longest period of a since param
+const sinceMax = 366 * 24 * time.Hour
+
+// parseSince will parse a period of days or hours, like 7d or 12h
+func parseSince(v string) (time.Duration, bool) {
+	if len(v) < 2 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+	if err != nil || n == 0 {
+		return 0, false
+	}
+	var d time.Duration
+	switch v[len(v)-1] {
+	case 'd':
+		d = time.Duration(n) * 24 * time.Hour
+	case 'h':
+		d = time.Duration(n) * time.Hour
+	default:
+		return 0, false
+	}
+	return d, d <= sinceMax
+}
+
+// parsePostedTime will parse a date like 2026-10-01, as UTC midnight, or a time like 2026-10-01T18:30:00Z
+func parsePostedTime(v string) (time.Time, bool) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// formatPostedTime will format a time as a date when it is a UTC midnight, and as RFC 3339 otherwise
+func formatPostedTime(t time.Time) string {
+	if t.Equal(t.Truncate(24 * time.Hour)) {
+		return t.Format(time.DateOnly)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// salaryOption is a minimum salary offered as a filter
+type salaryOption struct {
+	// MinSalary is the yearly salary in USD
+	MinSalary uint64
+	Label     string
+}
+
+// salaryFilters are the minimum salaries offered as filters
+var salaryFilters = func() []salaryOption {
+	var options []salaryOption
+	for _, k := range []uint64{50, 100, 150, 200} {
+		options = append(options, salaryOption{MinSalary: k * 1000, Label: fmt.Sprintf("%dk+ USD", k)})
+	}
+	return options
+}()
+
+// parseFilterState will build a FilterState from query params. Invalid values
+// are left out of the state and reported in the returned error.
+func parseFilterState(q url.Values) (FilterState, error) {
+	var f FilterState
+	var errs []error
+	invalid := func(param, v, expected string) {
+		errs = append(errs, invalidParamError{Param: param, Value: v, Expected: expected})
+	}
+
+	f.Query = strings.Join(strings.Fields(q.Get("q")), " ")
+	if l := q.Get("level"); isJobLevel(l) {
+		f.Level = l
+	} else if l != "" {
+		invalid("level", l, oneOf(jobLevels))
+	}
+	if l := q.Get("lang"); isLanguage(l) {
+		f.Language = l
+	} else if l != "" {
+		invalid("lang", l, oneOf(languageCodes()))
+	}
+	if t, ok := employmentTypeParam(q.Get("type")); ok {
+		f.EmploymentType = t
+	} else if v := q.Get("type"); v != "" {
+		invalid("type", v, oneOf(employmentTypes))
+	}
+	for _, b := range q["benefit"] {
+		if !isBenefit(b) {
+			invalid("benefit", b, oneOf(benefits))
+		} else if !f.HasBenefit(b) {
+			f.Benefits = append(f.Benefits, b)
+		}
+	}
+	for _, v := range q["interview"] {
+		if !isInterviewFlag(v) {
+			invalid("interview", v, oneOf(interviewFlags))
+		} else if !f.HasInterview(v) {
+			f.Interview = append(f.Interview, v)
+		}
+	}
+	for _, v := range q["tag"] {
+		if t := strings.ToLower(v); !isJobTag(t) {
+			invalid("tag", v, oneOf(currentTags()))
+		} else if !f.HasTag(t) {
+			f.Tags = append(f.Tags, t)
+		}
+	}
+	f.Company = companyKey(q.Get("company"))
+	if v := q.Get("tz"); v != "" {
+		if offset, ok := parseUtcOffset(v); ok {
+			f.Timezone = timezoneParam(offset)
+		} else {
+			invalid("tz", v, "a UTC offset like -5 or 5:30")
+		}
+	}
+	// salary is in thousands of USD, kept for the links made before min_salary
+	if v := q.Get("salary"); v != "" {
+		if k, err := strconv.ParseUint(v, 10, 64); err == nil && k > 0 && k <= salaryMax/1000 {
+			f.MinSalary = k * 1000
+		} else {
+			invalid("salary", v, fmt.Sprintf("thousands of USD from 1 to %d", salaryMax/1000))
+		}
+	}
+	if v := q.Get("min_salary"); v != "" {
+		if usd, err := strconv.ParseUint(v, 10, 64); err == nil && usd > 0 && usd <= salaryMax {
+			f.MinSalary = usd
+		} else {
+			invalid("min_salary", v, fmt.Sprintf("a yearly salary in USD from 1 to %d", salaryMax))
+		}
+	}
+	if v := q.Get("salary_unknown"); v == "1" {
+		f.SalaryUnknown = true
+	} else if v != "" {
+		invalid("salary_unknown", v, "1")
+	}
+	if v := strings.ToLower(strings.Join(strings.Fields(q.Get("location")), " ")); len(v) <= locationMaxLength {
+		f.Location = v
+	} else {
+		invalid("location", q.Get("location"), fmt.Sprintf("a place of at most %d characters", locationMaxLength))
+	}
+	if v := q.Get("remote"); v == "1" {
+		f.Remote = true
+	} else if v != "" {
+		invalid("remote", v, "1")
+	}
+	if v := q.Get("visa"); isVisaOption(v) {
+		f.Visa = v
+	} else if v != "" {
+		invalid("visa", v, oneOf(visaOptions))
+	}
+	if v := q.Get("funding"); isFundingStage(v) {
+		f.Funding = v
+	} else if v != "" {
+		invalid("funding", v, oneOf(fundingStages))
+	}
+	if v := q.Get("size"); isCompanySize(v) {
+		f.Size = v
+	} else if v != "" {
+		invalid("size", v, oneOf(companySizes))
+	}
+	if v := q.Get("yc"); v == "1" {
+		f.YC = true
+	} else if v != "" {
+		invalid("yc", v, "1")
+	}
+	if v := q.Get("open"); v == "1" {
+		f.Open = true
+	} else if v != "" {
+		invalid("open", v, "1")
+	}
+	if v := q.Get("exclude"); v != "" {
+		f.Exclude = parseExcludeList(v)
+	}
+	for _, p := range []struct {
+		param string
+		t     *time.Time
+	}{{"posted_after", &f.PostedAfter}, {"posted_before", &f.PostedBefore}} {
+		if v := q.Get(p.param); v != "" {
+			if t, ok := parsePostedTime(v); ok {
+				*p.t = t
+			} else {
+				invalid(p.param, v, "a date like 2026-10-01 or a time like 2026-10-01T18:30:00Z")
+			}
+		}
+	}
+	if !f.PostedAfter.IsZero() && !f.PostedBefore.IsZero() && !f.PostedBefore.After(f.PostedAfter) {
+		errs = append(errs, invalidParamError{Param: "posted_before", Value: q.Get("posted_before"),
+			Expected: "a date after posted_after", Reason: "it is not after posted_after"})
+		f.PostedBefore = time.Time{}
+	}
+	if v := q.Get("since"); v != "" {
+		if _, ok := parseSince(v); ok {
+			f.Since = v
+		} else {
+			invalid("since", v, fmt.Sprintf("days or hours like 7d or 12h, up to %dd", int(sinceMax.Hours()/24)))
+		}
+	}
+	if v := q.Get("fuzzy"); v == "1" {
+		f.Fuzzy = true
+	} else if v != "" {
+		invalid("fuzzy", v, "1")
+	}
+	if v := q.Get("typos"); v == "1" {
+		f.Typos = true
+	} else if v != "" {
+		invalid("typos", v, "1")
+	}
+	if v := q.Get("unseen"); v == "1" {
+		f.Unseen = true
+	} else if v != "" {
+		invalid("unseen", v, "1")
+	}
+	if v := q.Get("dupes"); v == "1" {
+		f.Duplicates = true
+	} else if v != "" {
+		invalid("dupes", v, "1")
+	}
+	if v := q.Get("sort"); isJobSort(v) {
+		f.Sort = v
+	} else if v != "" {
+		invalid("sort", v, oneOf(jobSorts))
+	}
+	var err error
+	if f.After, err = uintParam(q, "after"); err != nil {
+		errs = append(errs, err)
+	}
+	if f.Before, err = uintParam(q, "before"); err != nil {
+		errs = append(errs, err)
+	}
+	if v := q.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= listPageMax {
+			f.Limit = n
+		} else {
+			invalid("limit", v, fmt.Sprintf("a number of jobs from 1 to %d", listPageMax))
+		}
+	}
+	return f, errors.Join(errs...)
+}
+
+// HasInterview will return true when the filter requires interview process flag v
+func (f FilterState) HasInterview(v string) bool {
+	return getIndex(f.Interview, v) != -1
+}
+
+// HasBenefit will return true when the filter requires benefit b
+func (f FilterState) HasBenefit(b string) bool {
+	return getIndex(f.Benefits, b) != -1
+}
+
+// HasTag will return true when the filter requires tag t
+func (f FilterState) HasTag(t string) bool {
+	return getIndex(f.Tags, t) != -1
+}
+
+// withoutTag will return the state without the tag t
+func (f FilterState) withoutTag(t string) FilterState {
+	tags := make([]string, 0, len(f.Tags))
+	for _, ft := range f.Tags {
+		if ft != t {
+			tags = append(tags, ft)
+		}
+	}
+	f.Tags = tags
+	return f
+}
+
+// filterLink is a link to the jobs of a state with one of its filters changed
+type filterLink struct {
+	Label string
+	Url   string
+}
+
+// ExcludeList will return the excluded keywords as the comma separated list they are edited as
+func (f FilterState) ExcludeList() string {
+	return strings.Join(f.Exclude, ", ")
+}
+
+// likeContains will return the LIKE pattern, escaped with \, matching the texts containing s
+func likeContains(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(s) + "%"
+}
+
+// where will return the sql conditions and args for the filter.
+// The conditions are meant to be appended to an existing WHERE clause.
+// Regex searches that do not compile are an invalidParamError of q, and
+// searches the engine fails to match are returned as they are.
+func (f FilterState) where() (string, []any, error) {
+	var conds []string
+	var args []any
+	// strict are the fields filtered on, whose uncertain values leave jobs out
+	var strict []string
+	if isRegexSearch(f.Query) {
+		pattern, err := regexPattern(f.Query)
+		if err != nil {
+			return "", nil, invalidRegexError(f.Query, err)
+		}
+		conds = append(conds, "job_regexp(?, text)")
+		args = append(args, pattern)
+	} else if f.Query != "" {
+		match, matchArgs, err := jobSearch.Match(searchExpr(f.Query))
+		if err != nil {
+			return "", nil, err
+		}
+		conds = append(conds, "hn_id IN ("+match+")")
+		args = append(args, matchArgs...)
+	}
+	if f.Level != "" {
+		conds = append(conds, "(',' || level || ',') LIKE ?")
+		args = append(args, "%,"+f.Level+",%")
+		strict = append(strict, "level")
+	}
+	if f.Language != "" {
+		conds = append(conds, "language=?")
+		args = append(args, f.Language)
+		strict = append(strict, "language")
+	}
+	if f.EmploymentType != "" {
+		conds = append(conds, "(',' || employment_type || ',') LIKE ?")
+		args = append(args, "%,"+f.EmploymentType+",%")
+		strict = append(strict, "employment_type")
+	}
+	for _, b := range f.Benefits {
+		conds = append(conds, "(',' || benefits || ',') LIKE ?")
+		args = append(args, "%,"+b+",%")
+	}
+	if len(f.Benefits) > 0 {
+		strict = append(strict, "benefits")
+	}
+	for _, v := range f.Interview {
+		conds = append(conds, "(',' || interview || ',') LIKE ?")
+		args = append(args, "%,"+v+",%")
+	}
+	if len(f.Interview) > 0 {
+		strict = append(strict, "interview")
+	}
+	for _, t := range f.Tags {
+		conds = append(conds, "hn_id IN (SELECT hn_id FROM job_tag WHERE tag=?)")
+		args = append(args, t)
+	}
+	if len(f.Tags) > 0 {
+		strict = append(strict, "tags")
+	}
+	if f.Company != "" {
+		conds = append(conds, `company_id IN (SELECT id FROM companies WHERE slug=?
+            UNION SELECT company_id FROM company_alias WHERE alias_key=?)`)
+		args = append(args, f.Company, f.Company)
+		strict = append(strict, "company_id")
+	}
+	if offset, ok := parseUtcOffset(f.Timezone); ok && f.Timezone != "" {
+		conds = append(conds, "timezone != '' AND tz_min <= ? AND tz_max >= ?")
+		args = append(args, offset, offset)
+		strict = append(strict, "timezone")
+	}
+	if f.MinSalary > 0 && f.SalaryUnknown {
+		conds = append(conds, "(salary_max_usd >= ? OR (salary_min_usd = 0 AND salary_max_usd = 0))")
+		args = append(args, f.MinSalary)
+		strict = append(strict, "salary_max_usd")
+	} else if f.MinSalary > 0 {
+		conds = append(conds, "salary_max_usd >= ?")
+		args = append(args, f.MinSalary)
+		strict = append(strict, "salary_max_usd")
+	}
+	if f.Location != "" {
+		m := resolveLocation(f.Location)
+		var matches []string
+		for _, p := range m.Places {
+			matches = append(matches, "(latitude = ? AND longitude = ?)")
+			args = append(args, p.Latitude, p.Longitude)
+		}
+		for _, t := range m.Terms {
+			matches = append(matches, `location LIKE ? ESCAPE '\'`)
+			args = append(args, likeContains(t))
+		}
+		conds = append(conds, "("+strings.Join(matches, " OR ")+")")
+		strict = append(strict, "location", "latitude")
+	}
+	if f.Remote {
+		conds = append(conds, "(',' || remote || ',') LIKE ?")
+		args = append(args, "%,"+remoteFull+",%")
+		strict = append(strict, "remote")
+	}
+	if f.Visa != "" {
+		conds = append(conds, "visa=?")
+		args = append(args, f.Visa)
+		strict = append(strict, "visa")
+	}
+	if f.Funding != "" {
+		conds = append(conds, "funding=?")
+		args = append(args, f.Funding)
+		strict = append(strict, "funding")
+	}
+	if f.Size != "" {
+		conds = append(conds, "company_size=?")
+		args = append(args, f.Size)
+		strict = append(strict, "company_size")
+	}
+	if f.YC {
+		conds = append(conds, "yc_batch != ''")
+		strict = append(strict, "yc_batch")
+	}
+	if f.Open {
+		// the deadline day is still open
+		conds = append(conds, "(deadline = '' OR deadline >= ?)")
+		args = append(args, time.Now().UTC().Format(deadlineLayout))
+	}
+	if len(f.Exclude) > 0 {
+		match, matchArgs, err := jobSearch.Match(excludeSearch(f.Exclude))
+		if err != nil {
+			return "", nil, err
+		}
+		conds = append(conds, "hn_id NOT IN ("+match+")")
+		args = append(args, matchArgs...)
+	}
+	if !f.PostedAfter.IsZero() {
+		conds = append(conds, "time >= ?")
+		args = append(args, f.PostedAfter.Unix())
+	}
+	if !f.PostedBefore.IsZero() {
+		conds = append(conds, "time < ?")
+		args = append(args, f.PostedBefore.Unix())
+	}
+	if d, ok := parseSince(f.Since); ok {
+		conds = append(conds, "time >= ?")
+		args = append(args, time.Now().Add(-d).Unix())
+	}
+	if f.Unseen && f.seenBy != "" {
+		conds = append(conds, "hn_id NOT IN (SELECT hn_id FROM seen_job WHERE owner=? AND seen_at < ?)")
+		args = append(args, f.seenBy, time.Now().Add(-seenVisitLength).Unix())
+	}
+	if !f.Duplicates {
+		conds = append(conds, "duplicate_of = 0")
+	}
+	if len(strict) > 0 && !f.Fuzzy {
+		conds = append(conds, "hn_id NOT IN (SELECT hn_id FROM job_attribute_evidence WHERE confidence < ? AND field IN (?"+strings.Repeat(", ?", len(strict)-1)+"))")
+		args = append(args, uncertainConfidence)
+		for _, field := range strict {
+			args = append(args, field)
+		}
+	}
+	if len(conds) == 0 {
+		return "", nil, nil
+	}
+	return " AND " + strings.Join(conds, " AND "), args, nil
+}
+
+// query will return the state encoded as url query params, leaving out
+// defaults so equivalent states get the same params
+func (f FilterState) query() url.Values {
+	q := url.Values{}
+	if f.Query != "" {
+		q.Set("q", f.Query)
+	}
+	if f.Level != "" {
+		q.Set("level", f.Level)
+	}
+	if f.Language != "" {
+		q.Set("lang", f.Language)
+	}
+	if f.EmploymentType != "" {
+		q.Set("type", f.EmploymentType)
+	}
+	for _, b := range f.Benefits {
+		q.Add("benefit", b)
+	}
+	for _, v := range f.Interview {
+		q.Add("interview", v)
+	}
+	for _, t := range f.Tags {
+		q.Add("tag", t)
+	}
+	if f.Company != "" {
+		q.Set("company", f.Company)
+	}
+	if f.Timezone != "" {
+		q.Set("tz", f.Timezone)
+	}
+	if f.MinSalary > 0 {
+		q.Set("min_salary", strconv.FormatUint(f.MinSalary, 10))
+	}
+	if f.SalaryUnknown {
+		q.Set("salary_unknown", "1")
+	}
+	if f.Location != "" {
+		q.Set("location", f.Location)
+	}
+	if f.Remote {
+		q.Set("remote", "1")
+	}
+	if f.Visa != "" {
+		q.Set("visa", f.Visa)
+	}
+	if f.Funding != "" {
+		q.Set("funding", f.Funding)
+	}
+	if f.Size != "" {
+		q.Set("size", f.Size)
+	}
+	if f.YC {
+		q.Set("yc", "1")
+	}
+	if f.Open {
+		q.Set("open", "1")
+	}
+	if len(f.Exclude) > 0 && !f.excludeSaved {
+		q.Set("exclude", strings.Join(f.Exclude, ","))
+	}
+	if !f.PostedAfter.IsZero() {
+		q.Set("posted_after", formatPostedTime(f.PostedAfter))
+	}
+	if !f.PostedBefore.IsZero() {
+		q.Set("posted_before", formatPostedTime(f.PostedBefore))
+	}
+	if f.Since != "" {
+		q.Set("since", f.Since)
+	}
+	if f.Fuzzy {
+		q.Set("fuzzy", "1")
+	}
+	if f.Typos {
+		q.Set("typos", "1")
+	}
+	if f.Unseen {
+		q.Set("unseen", "1")
+	}
+	if f.Duplicates {
+		q.Set("dupes", "1")
+	}
+	if f.Sort != "" {
+		q.Set("sort", f.Sort)
+	}
+	if f.Before > 0 {
+		q.Set("before", strconv.FormatUint(f.Before, 10))
+	} else if f.After > 0 {
+		q.Set("after", strconv.FormatUint(f.After, 10))
+	}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return q
+}
+
+// encode will return the state as a canonical query string, with sorted params
+func (f FilterState) encode() string {
+	return f.query().Encode()
+}
+
+// cursorUrl will return the url of the reader at path for the filters of the
+// state with the cursor param set to v. No cursor is set when the param is empty.
+func (f FilterState) cursorUrl(path, param string, v uint64) string {
+	f.After, f.Before = 0, 0
+	switch param {
+	case "after":
+		f.After = v
+	case "before":
+		f.Before = v
+	}
+	if q := f.encode(); q != "" {
+		return path + "?" + q
+	}
+	return path
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterStateRoundTrip(t *testing.T) {
+	states := []FilterState{
+		{},
+		{Query: "golang rust", Remote: true, Sort: sortNewest},
+		{
+			Query:          `"site reliability" OR sre`,
+			Level:          jobLevels[3],
+			Language:       "en",
+			EmploymentType: employmentTypes[0],
+			Benefits:       []string{benefits[0], benefits[1]},
+			Interview:      []string{interviewFlags[0]},
+			Tags:           []string{"go", "c++"},
+			Company:        "acme",
+			Timezone:       "-5",
+			MinSalary:      150000,
+			SalaryUnknown:  true,
+			Location:       "new york",
+			Visa:           visaOptions[0],
+			Funding:        fundingStages[1],
+			Size:           companySizes[2],
+			YC:             true,
+			Open:           true,
+			Exclude:        []string{"crypto", "web3"},
+			PostedAfter:    time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+			PostedBefore:   time.Date(2026, 10, 1, 18, 30, 0, 0, time.UTC),
+			Since:          "7d",
+			Fuzzy:          true,
+			Typos:          true,
+			Unseen:         true,
+			Duplicates:     true,
+			Sort:           sortSalary,
+			Before:         41234567,
+			Limit:          100,
+		},
+	}
+	for _, f := range states {
+		q, err := url.ParseQuery(f.encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parseFilterState(q)
+		if err != nil {
+			t.Errorf("parseFilterState(%q) failed. %v", f.encode(), err)
+			continue
+		}
+		if !reflect.DeepEqual(got, f) {
+			t.Errorf("parseFilterState(%q) = %+v, want %+v", f.encode(), got, f)
+		}
+	}
+}
+
+func TestFilterStateEncodeCanonical(t *testing.T) {
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"q=++golang+++rust+&remote=1", "q=golang+rust&remote=1"},
+		// the legacy salary param in thousands is written as min_salary
+		{"salary=150", "min_salary=150000"},
+		{"location=New++York&exclude=Crypto,+web3,,crypto", "exclude=crypto%2Cweb3&location=new+york"},
+		// before wins over after
+		{"after=10&before=20", "before=20"},
+		{"tag=go&tag=Go&tag=rust", "tag=go&tag=rust"},
+	}
+	for _, c := range cases {
+		q, err := url.ParseQuery(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parseFilterState(q)
+		if err != nil {
+			t.Errorf("parseFilterState(%q) failed. %v", c.query, err)
+			continue
+		}
+		if got := f.encode(); got != c.want {
+			t.Errorf("parseFilterState(%q).encode() = %q, want %q", c.query, got, c.want)
+		}
+	}
+}
+
+func TestParseFilterStateInvalid(t *testing.T) {
+	q := url.Values{"remote": {"yes"}, "sort": {"best"}, "limit": {"0"}, "q": {"golang"}}
+	f, err := parseFilterState(q)
+	var params []string
+	for _, e := range paramErrors(err) {
+		params = append(params, e.Param)
+	}
+	if !reflect.DeepEqual(params, []string{"remote", "sort", "limit"}) {
+		t.Errorf("invalid params = %q, want [remote sort limit]", params)
+	}
+	// the valid params are kept
+	if f.Query != "golang" || f.Remote || f.Sort != "" || f.Limit != 0 {
+		t.Errorf("parseFilterState(%v) = %+v, want only the golang query", q, f)
+	}
+}
+
+func TestParseFilterStateType(t *testing.T) {
+	f, err := parseFilterState(url.Values{"type": {"Intern"}})
+	if err != nil || f.EmploymentType != employmentInternship {
+		t.Errorf("type=Intern parsed to %q, %v, want %q", f.EmploymentType, err, employmentInternship)
+	}
+	// the error echoes the value as sent
+	_, err = parseFilterState(url.Values{"type": {"Permanent"}})
+	if errs := paramErrors(err); len(errs) != 1 || errs[0].Param != "type" || errs[0].Value != "Permanent" {
+		t.Errorf("type=Permanent errors = %+v, want the Permanent type", errs)
+	}
+}
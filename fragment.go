@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+)
+
+const (
+	// fragmentVersion must be bumped when the markup of cached fragments changes
+	fragmentVersion = 1
+	// fragmentCacheSize is the max number of cached fragments
+	fragmentCacheSize = 5000
+)
+
+// fragmentCache holds rendered fragments of content that never changes,
+// like the job bodies of archived stories.
+var fragmentCache = newLruCache[string, jobBody](fragmentCacheSize)
+
+func init() {
+	bus.Subscribe(eventSyncCompleted, func(event) { fragmentCache.Purge() })
+	bus.Subscribe(eventTagsChanged, func(event) { fragmentCache.Purge() })
+}
+
+// jobBody is the sanitized body of a job, possibly truncated
+type jobBody struct {
+	HTML      template.HTML
+	Truncated bool
+	// MoreUrl is the url showing the full body of a truncated job
+	MoreUrl string
+}
+
+// jobBodyHTML will return the sanitized body of a job truncated at limit.
+// Bodies of jobs from archived stories are cached since they are not updated anymore.
+func jobBodyHTML(hj HiringJob, archived bool, limit int) jobBody {
+	if !archived {
+		html, truncated := truncateJobHTML(hj.Text, limit)
+		return jobBody{HTML: html, Truncated: truncated}
+	}
+
+	key := fmt.Sprintf("job:%d:%d:v%d", hj.HnId, limit, fragmentVersion)
+	if body, ok := fragmentCache.Get(key); ok {
+		return body
+	}
+	html, truncated := truncateJobHTML(hj.Text, limit)
+	body := jobBody{HTML: html, Truncated: truncated}
+	fragmentCache.Add(key, body)
+	return body
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const (
+	// storyKindHiring is the "Who is hiring?" thread the reader serves
+	storyKindHiring = "hiring"
+	// storyKindFreelancer is the "Freelancer? Seeking freelancer?" thread,
+	// only listed along with the hiring thread of its month
+	storyKindFreelancer = "freelancer"
+)
+
+// storyKinds are the threads of the whoishiring account we ingest
+var storyKinds = []string{storyKindHiring, storyKindFreelancer}
+
+// storyPrefixes are the title prefixes of the threads of each kind
+var storyPrefixes = map[string]string{
+	storyKindHiring:     "Ask HN: Who is hiring?",
+	storyKindFreelancer: "Ask HN: Freelancer? Seeking freelancer?",
+}
+
+// hiringStoryPrefix is the title prefix of the hiring threads
+var hiringStoryPrefix = storyPrefixes[storyKindHiring]
+
+// storySources are the badges of the posts of each kind of thread
+var storySources = map[string]string{
+	storyKindHiring:     "Hiring",
+	storyKindFreelancer: "Freelance",
+}
+
+// syncFreelancerStory will ingest the posts of the freelancer thread among
+// the latest stories of the whoishiring account, storing it when new. Months
+// without a freelancer thread are skipped.
+func syncFreelancerStory(ctx context.Context, storyIds []int) error {
+	hs, err := GetLatestStory(storyKindFreelancer)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	hsId := hs.HnId
+	if getIndex(storyIds, int(hs.HnId)) == -1 {
+		hsId, err = newHiringStory(ctx, storyIds, storyKindFreelancer)
+		if err != nil {
+			log.Println("freelancer story not found, skipping it.", err)
+			return nil
+		}
+		// the previous thread is synced one last time, like hiring threads
+		if hs.HnId != 0 {
+			if err := processJobPosts(ctx, hs.HnId); err != nil {
+				log.Printf("failed the final sweep of freelancer story %d. %v", hs.HnId, err)
+			}
+		}
+	}
+
+	if err := processJobPosts(ctx, hsId); err != nil {
+		return err
+	}
+	return enrichStaleJobs(ctx, hsId)
+}
+
+// combinedHandler will list the posts of the hiring and freelancer threads of
+// the month of the current story together, with the thread of each post
+func combinedHandler(w http.ResponseWriter, r *http.Request) {
+	hs, err := GetCurrentHiringStory()
+	if err != nil {
+		log.Println("failed to get current story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	month, _ := storyTitleParts(hs.Title)
+	kinds := map[uint64]string{hs.HnId: hs.Kind}
+	scope := jobScope{StoryIds: []uint64{hs.HnId}}
+	fs, err := GetMonthStory(storyKindFreelancer, month)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println("failed to get freelancer story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		kinds[fs.HnId] = fs.Kind
+		scope.StoryIds = append(scope.StoryIds, fs.HnId)
+	}
+
+	filter, _ := parseFilterState(r.URL.Query())
+	filter = withSavedExclusions(w, r, withPreferences(w, r, filter))
+	if filter.defaultSort == "" {
+		// hundreds of jobs are posted by mid-month, the most engaging ones come first
+		filter.defaultSort = sortHot
+	}
+	page, err := selectJobPage(scope, filter)
+	if err != nil {
+		filterError(w, "failed to select combined hiring jobs.", err)
+		return
+	}
+	entries := make([]jobListEntry, len(page.Jobs))
+	for i, hj := range page.Jobs {
+		entries[i] = jobListEntry{
+			HiringJobListItem: hj,
+			Content:           renderJobBody(r, hj.HiringJob, false),
+			Source:            storySources[kinds[hj.HiringStoryId]],
+		}
+	}
+
+	data := struct {
+		Title     string
+		Jobs      []jobListEntry
+		Canonical string
+		Profile   string
+		Summary   string
+		Path      string
+		Filter    FilterState
+		Sorts     []sortLink
+		AllUrl    string
+		RemoteUrl string
+		DupesUrl  string
+		PrevUrl   string
+		NextUrl   string
+	}{
+		Title:     month + ": hiring and freelance",
+		Jobs:      entries,
+		Canonical: canonicalUrl("/combined"),
+		Path:      "/combined",
+		Filter:    filter,
+	}
+	data.Sorts = filter.sortLinks(data.Path)
+	data.AllUrl, data.RemoteUrl, data.DupesUrl = listToggleUrls(data.Path, filter)
+	data.PrevUrl, data.NextUrl = page.pageUrls(data.Path, filter, nil)
+	setLinkHeaders(w, data.PrevUrl, data.NextUrl)
+	renderTemplate(w, "list.html", data)
+}
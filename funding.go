@@ -0,0 +1,165 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Funding stages, from the least to the most advanced
+const (
+	fundingBootstrapped = "bootstrapped"
+	fundingYC           = "yc"
+	fundingSeed         = "seed"
+	fundingSeriesA      = "series-a"
+	fundingSeriesB      = "series-b"
+	fundingLate         = "late"
+	fundingPublic       = "public"
+)
+
+var fundingStages = []string{
+	fundingBootstrapped, fundingYC, fundingSeed, fundingSeriesA, fundingSeriesB, fundingLate, fundingPublic,
+}
+
+// fundingNames are the display names of the funding stages
+var fundingNames = map[string]string{
+	fundingBootstrapped: "Bootstrapped",
+	fundingYC:           "YC backed",
+	fundingSeed:         "Seed",
+	fundingSeriesA:      "Series A",
+	fundingSeriesB:      "Series B",
+	fundingLate:         "Series C+",
+	fundingPublic:       "Public company",
+}
+
+var fundingPatterns = map[string]*regexp.Regexp{
+	fundingBootstrapped: regexp.MustCompile(`(?i)\b(bootstrapped|self[- ]funded|no (vc|outside) (funding|investors|money))\b`),
+	fundingYC:           regexp.MustCompile(`(?i)\b(yc|y combinator)[ -]?(w|s|f|x|winter|summer|fall|spring)[ ']?(20)?\d{2}\b|\by combinator\b|\byc[- ]backed\b`),
+	fundingSeed:         regexp.MustCompile(`(?i)\b(pre-?seed|seed)[- ](stage|round|funded|funding|backed)\b`),
+	fundingSeriesA:      regexp.MustCompile(`(?i)\bseries[- ]a\b`),
+	fundingSeriesB:      regexp.MustCompile(`(?i)\bseries[- ]b\b`),
+	fundingLate:         regexp.MustCompile(`(?i)\bseries[- ][c-h]\b|\blate[- ]stage\b|\bpre-ipo\b`),
+	fundingPublic:       regexp.MustCompile(`(?i)\b(publicly traded|public company|listed on the (nasdaq|nyse|lse)|(nasdaq|nyse):\s?[a-z]+)\b`),
+}
+
+// ycBatchPattern matches Y Combinator batches like "YC S24", "YC W'20" or
+// "Y Combinator Winter 2021", capturing the season and the year
+var ycBatchPattern = regexp.MustCompile(`(?i)\b(?:yc|y combinator)[ -]?\(?(w|s|f|x|winter|summer|fall|spring)[ ']?((?:20)?\d{2})\b`)
+
+// ycSeasons are the letters YC names the batches of each season with, spring
+// batches being X
+var ycSeasons = map[string]string{"w": "W", "s": "S", "f": "F", "x": "X", "winter": "W", "summer": "S", "fall": "F", "spring": "X"}
+
+// ycBatchSlugPattern matches the batches in listing paths, like s24
+var ycBatchSlugPattern = regexp.MustCompile(`^[wsfx]\d{2}$`)
+
+// Company sizes, as ranges of employees
+const (
+	sizeMicro  = "1-10"
+	sizeSmall  = "11-50"
+	sizeMedium = "51-200"
+	sizeLarge  = "201-1000"
+	sizeHuge   = "1000+"
+)
+
+var companySizes = []string{sizeMicro, sizeSmall, sizeMedium, sizeLarge, sizeHuge}
+
+// companySizeMax are the most employees of each company size, but the largest
+var companySizeMax = map[string]int{sizeMicro: 10, sizeSmall: 50, sizeMedium: 200, sizeLarge: 1000}
+
+// employeesPattern matches headcounts like "500 employees", "1,200 people",
+// "10k+ staff" or "a 12-person team"
+var employeesPattern = regexp.MustCompile(`(?i)\b(\d+(?:,\d{3})*(?:\.\d+)?k?)\+?[ -](employees|people|staff|person (team|company|startup)|team members)\b`)
+
+// employeesHiringPattern matches the words before headcounts of open roles,
+// like "we're hiring 3 people"
+var employeesHiringPattern = regexp.MustCompile(`(?i)\b(hiring|hire|looking for|seeking|adding)\s+(about |around |up to )?$`)
+
+func isFundingStage(v string) bool {
+	return getIndex(fundingStages, v) != -1
+}
+
+func isCompanySize(v string) bool {
+	return getIndex(companySizes, v) != -1
+}
+
+// jobFunding will return the most advanced funding stage mentioned in a job
+// text, like series-b for "YC W20, Series B", along with the matched terms
+func jobFunding(text string) (string, string) {
+	plain := jobPlainText(text)
+	for i := len(fundingStages) - 1; i >= 0; i-- {
+		if p := fundingPatterns[fundingStages[i]]; p.MatchString(plain) {
+			return fundingStages[i], matchedTerms(p, plain)
+		}
+	}
+	return "", ""
+}
+
+// jobYCBatch will return the YC batch of the company of a job text, like
+// "YC S24", along with the matched terms. Companies backed by YC in a batch
+// the post does not name are "YC".
+func jobYCBatch(text string) (string, string) {
+	plain := jobPlainText(text)
+	if m := ycBatchPattern.FindStringSubmatch(plain); m != nil {
+		year := m[2]
+		return "YC " + ycSeasons[strings.ToLower(m[1])] + year[len(year)-2:], matchedTerms(ycBatchPattern, plain)
+	}
+	if p := fundingPatterns[fundingYC]; p.MatchString(plain) {
+		return "YC", matchedTerms(p, plain)
+	}
+	return "", ""
+}
+
+// ycBatchSlug will return the path segment of a YC batch, like s24 for "YC
+// S24", empty for companies of an unknown batch
+func ycBatchSlug(batch string) string {
+	slug := strings.ToLower(strings.TrimPrefix(batch, "YC "))
+	if !ycBatchSlugPattern.MatchString(slug) {
+		return ""
+	}
+	return slug
+}
+
+// ycBatchFromSlug will return the YC batch of a path segment made by
+// ycBatchSlug, empty when it is not one
+func ycBatchFromSlug(slug string) string {
+	slug = strings.ToLower(slug)
+	if !ycBatchSlugPattern.MatchString(slug) {
+		return ""
+	}
+	return "YC " + strings.ToUpper(slug)
+}
+
+// jobEmployees will return the headcount of the company of a job text along
+// with the matched term, leaving out the headcounts of the roles it hires for
+func jobEmployees(text string) (int, string) {
+	plain := jobPlainText(text)
+	for _, loc := range employeesPattern.FindAllStringSubmatchIndex(plain, -1) {
+		if employeesHiringPattern.MatchString(plain[:loc[0]]) {
+			continue
+		}
+		v := strings.ToLower(strings.ReplaceAll(plain[loc[2]:loc[3]], ",", ""))
+		scale := 1.0
+		if n, ok := strings.CutSuffix(v, "k"); ok {
+			v, scale = n, 1000
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if n := int(f * scale); err == nil && n > 0 && n <= 1000000 {
+			return n, strings.TrimSpace(plain[loc[0]:loc[1]])
+		}
+	}
+	return 0, ""
+}
+
+// companySize will return the size range of a company of n employees, empty for 0
+func companySize(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	for _, s := range companySizes {
+		if most, ok := companySizeMax[s]; ok && n <= most {
+			return s
+		}
+	}
+	return sizeHuge
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// geminiRequestMax is the max length of a gemini request line, an url
+	// of up to 1024 bytes and its CRLF
+	geminiRequestMax = 1026
+	// geminiTimeout is how long a gemini connection is kept open
+	geminiTimeout = 30 * time.Second
+)
+
+// geminiResponse is the status, meta and gemtext body of a gemini request
+type geminiResponse struct {
+	Status int
+	Meta   string
+	Body   string
+}
+
+// geminiError will return a response failing with status and the message meta
+func geminiError(status int, meta string) geminiResponse {
+	return geminiResponse{Status: status, Meta: meta}
+}
+
+// geminiNotFound is the response of unknown pages, jobs and stories
+var geminiNotFound = geminiError(51, "Not found")
+
+// geminiFailure is the response of requests failing on our side
+var geminiFailure = geminiError(40, "Temporary failure")
+
+// gemtextLine will escape a line of a post so it is read as text. Lines
+// starting like links, headings or preformatted toggles are indented, list
+// items and quotes are kept as they read the same.
+func gemtextLine(line string) string {
+	for _, marker := range []string{"=>", "#", "```"} {
+		if strings.HasPrefix(line, marker) {
+			return " " + line
+		}
+	}
+	return line
+}
+
+// gemtextJobLink will return the link line of a job in a listing
+func gemtextJobLink(hj HiringJob) string {
+	line := fmt.Sprintf("=> /job/%d %s", hj.HnId, plainJobTitle(hj))
+	if details := plainJobDetails(hj); details != "" {
+		line += " (" + details + ")"
+	}
+	return line + "\n"
+}
+
+// geminiStory will list the jobs of a story, paged by the after and before
+// cursor params like the other listings
+func geminiStory(hs *HiringStory, u *url.URL) geminiResponse {
+	filter, _ := parseFilterState(u.Query())
+	page, err := selectJobPage(jobScope{StoryId: hs.HnId}, filter)
+	if len(paramErrors(err)) > 0 {
+		return geminiError(59, err.Error())
+	}
+	if err != nil {
+		log.Println("failed to select gemini hiring jobs.", err)
+		return geminiFailure
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", hs.Title)
+	fmt.Fprintf(&b, "%s, %s.\n", countNoun(page.Total, "job", "jobs"), filter.SortName())
+	b.WriteString("=> /search Search the jobs\n=> /stories Every month\n\n")
+	for _, hj := range page.Jobs {
+		b.WriteString(gemtextJobLink(hj.HiringJob))
+	}
+	prevUrl, nextUrl := page.pageUrls(u.Path, filter, nil)
+	if prevUrl != "" || nextUrl != "" {
+		b.WriteString("\n")
+	}
+	if prevUrl != "" {
+		fmt.Fprintf(&b, "=> %s Previous page\n", prevUrl)
+	}
+	if nextUrl != "" {
+		fmt.Fprintf(&b, "=> %s Next page\n", nextUrl)
+	}
+	return geminiResponse{Status: 20, Meta: "text/gemini; charset=utf-8", Body: b.String()}
+}
+
+// geminiJob will show a job with the fields derived from it and its post
+func geminiJob(hnId uint64) geminiResponse {
+	hj, err := GetHiringJob(hnId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return geminiNotFound
+	}
+	if err != nil {
+		log.Println("failed to get gemini hiring job.", err)
+		return geminiFailure
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", plainJobTitle(*hj))
+	if details := plainJobDetails(*hj); details != "" {
+		b.WriteString(details + "\n")
+	}
+	fmt.Fprintf(&b, "Posted %s\n\n", time.Unix(int64(hj.Time), 0).UTC().Format(time.DateOnly))
+	for _, line := range strings.Split(jobPlainText(hj.Text), "\n") {
+		b.WriteString(gemtextLine(line) + "\n")
+	}
+	b.WriteString("\n")
+	switch {
+	case hj.ApplyUrl != "":
+		fmt.Fprintf(&b, "=> %s Apply\n", hj.ApplyUrl)
+	case hj.ApplyEmail != "":
+		fmt.Fprintf(&b, "=> mailto:%s Apply by email\n", hj.ApplyEmail)
+	}
+	fmt.Fprintf(&b, "=> %s%d The post on Hacker News\n", hnItemUrl, hj.HnId)
+	fmt.Fprintf(&b, "=> /story/%d Every job of the month\n", hj.HiringStoryId)
+	return geminiResponse{Status: 20, Meta: "text/gemini; charset=utf-8", Body: b.String()}
+}
+
+// geminiStories will list the hiring stories
+func geminiStories() geminiResponse {
+	stories, err := SelectHiringStories(storyKindHiring)
+	if err != nil {
+		log.Println("failed to select gemini hiring stories.", err)
+		return geminiFailure
+	}
+	var b strings.Builder
+	b.WriteString("# Every month\n\n")
+	for _, hs := range stories {
+		fmt.Fprintf(&b, "=> /story/%d %s (%s)\n", hs.HnId, hs.Title, countNoun(int(hs.Jobs), "job", "jobs"))
+	}
+	return geminiResponse{Status: 20, Meta: "text/gemini; charset=utf-8", Body: b.String()}
+}
+
+// geminiSearch will ask for the terms of a search, sent as the query of the
+// url, and list the jobs of the current story matching them
+func geminiSearch(u *url.URL) geminiResponse {
+	q, err := url.QueryUnescape(u.RawQuery)
+	// line breaks would end the heading the query is echoed in
+	q = strings.Join(strings.Fields(q), " ")
+	if err != nil || q == "" {
+		return geminiResponse{Status: 10, Meta: "Search the jobs of the month"}
+	}
+	hs, err := GetCurrentHiringStory()
+	if err != nil {
+		log.Println("failed to get gemini story.", err)
+		return geminiFailure
+	}
+	jobs, err := SelectJobList(jobScope{StoryId: hs.HnId}, FilterState{Query: q}, listPageSize)
+	if len(paramErrors(err)) > 0 {
+		return geminiError(59, err.Error())
+	}
+	if err != nil {
+		log.Println("failed to search gemini hiring jobs.", err)
+		return geminiFailure
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Jobs matching %s\n\n", q)
+	if len(jobs) == 0 {
+		b.WriteString("No jobs found.\n")
+	}
+	for _, hj := range jobs {
+		b.WriteString(gemtextJobLink(hj.HiringJob))
+	}
+	b.WriteString("\n=> /search Search again\n=> / Every job of the month\n")
+	return geminiResponse{Status: 20, Meta: "text/gemini; charset=utf-8", Body: b.String()}
+}
+
+// routeGemini will serve a gemini request of the pages mirrored from the site
+func routeGemini(u *url.URL) geminiResponse {
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	switch {
+	case u.Path == "/":
+		hs, err := GetCurrentHiringStory()
+		if err != nil {
+			log.Println("failed to get gemini story.", err)
+			return geminiFailure
+		}
+		return geminiStory(hs, u)
+	case u.Path == "/stories":
+		return geminiStories()
+	case u.Path == "/search":
+		return geminiSearch(u)
+	case strings.HasPrefix(u.Path, "/story/"):
+		hs, err := GetHiringStory(paramValue(strings.TrimPrefix(u.Path, "/story/"), 0))
+		if errors.Is(err, sql.ErrNoRows) {
+			return geminiNotFound
+		}
+		if err != nil {
+			log.Println("failed to get gemini story.", err)
+			return geminiFailure
+		}
+		return geminiStory(hs, u)
+	case strings.HasPrefix(u.Path, "/job/"):
+		return geminiJob(paramValue(strings.TrimPrefix(u.Path, "/job/"), 0))
+	}
+	return geminiNotFound
+}
+
+// serveGeminiConn will answer the request of a gemini connection
+func serveGeminiConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(geminiTimeout))
+
+	res := geminiError(59, "Bad request")
+	line, err := bufio.NewReaderSize(io.LimitReader(conn, geminiRequestMax), geminiRequestMax).ReadString('\n')
+	if err == nil && strings.HasSuffix(line, "\r\n") {
+		if u, err := url.Parse(strings.TrimSuffix(line, "\r\n")); err == nil && u.Scheme == "gemini" {
+			res = routeGemini(u)
+		}
+	}
+	fmt.Fprintf(conn, "%d %s\r\n%s", res.Status, res.Meta, res.Body)
+}
+
+// geminiCertificate will load the TLS certificate of the gemini server,
+// creating a self signed one on first use. Gemini clients trust the first
+// certificate they see from a host, so it is kept across restarts.
+func geminiCertificate() (tls.Certificate, error) {
+	if _, err := os.Stat(cfg.GeminiCert); errors.Is(err, os.ErrNotExist) {
+		if err := createGeminiCertificate(); err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	return tls.LoadX509KeyPair(cfg.GeminiCert, cfg.GeminiKey)
+}
+
+// createGeminiCertificate will save a self signed certificate for the host of
+// cfg.PublicBaseUrl, valid for ten years
+func createGeminiCertificate() error {
+	host := "localhost"
+	if u, err := url.Parse(cfg.PublicBaseUrl); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(cfg.GeminiKey, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		return err
+	}
+	log.Printf("created a self signed gemini certificate for %s in %s", host, cfg.GeminiCert)
+	return os.WriteFile(cfg.GeminiCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// serveGemini will mirror the story and job pages over the gemini protocol
+// on addr, as gemtext read from the same store as the site
+func serveGemini(addr string) error {
+	cert, err := geminiCertificate()
+	if err != nil {
+		return err
+	}
+	ln, err := tls.Listen("tcp", addr, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		return err
+	}
+	log.Printf("Listening on gemini://localhost%s", addr)
+	// temporary accept errors, like running out of file descriptors, are
+	// retried with a growing delay like net/http does
+	var delay time.Duration
+	for {
+		conn, err := ln.Accept()
+		if ne, ok := err.(net.Error); ok && ne.Temporary() {
+			if delay == 0 {
+				delay = 5 * time.Millisecond
+			} else if delay *= 2; delay > time.Second {
+				delay = time.Second
+			}
+			log.Printf("gemini accept error: %v; retrying in %v", err, delay)
+			time.Sleep(delay)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		delay = 0
+		go serveGeminiConn(conn)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGeminiSearchQueryLine(t *testing.T) {
+	useTestDB(t)
+	if _, err := CreateHiringStory(1, storyKindHiring, "Ask HN: Who is hiring? (October 2026)", 1700000000); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("gemini://localhost/search?golang%0D%0A%3D%3E%20gemini%3A%2F%2Fevil.example%20Apply")
+	res := geminiSearch(u)
+	if res.Status != 20 {
+		t.Fatalf("status %d %s, want 20", res.Status, res.Meta)
+	}
+	heading, _, _ := strings.Cut(res.Body, "\n")
+	if heading != "# Jobs matching golang => gemini://evil.example Apply" {
+		t.Errorf("heading = %q", heading)
+	}
+	for _, line := range strings.Split(res.Body, "\n") {
+		if strings.HasPrefix(line, "=> gemini://evil.example") {
+			t.Errorf("query was echoed as a link line: %q", line)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// geoPlace is a city jobs are geocoded to, known by its names and aliases
+type geoPlace struct {
+	Names     []string
+	Country   string
+	Latitude  float64
+	Longitude float64
+}
+
+// Name will return the display name of the place
+func (p geoPlace) Name() string {
+	return p.Names[0]
+}
+
+// geoPlaces are the cities jobs are commonly located in. Jobs are geocoded
+// offline against this list, so places missing from it are not mapped.
+var geoPlaces = []geoPlace{
+	{[]string{"San Francisco", "SF", "SFBA", "Bay Area"}, "USA", 37.7749, -122.4194},
+	{[]string{"New York", "NYC", "Manhattan"}, "USA", 40.7128, -74.0060},
+	{[]string{"Brooklyn"}, "USA", 40.6782, -73.9442},
+	{[]string{"Los Angeles"}, "USA", 34.0522, -118.2437},
+	{[]string{"Seattle"}, "USA", 47.6062, -122.3321},
+	{[]string{"Boston"}, "USA", 42.3601, -71.0589},
+	{[]string{"Austin"}, "USA", 30.2672, -97.7431},
+	{[]string{"Chicago"}, "USA", 41.8781, -87.6298},
+	{[]string{"Denver"}, "USA", 39.7392, -104.9903},
+	{[]string{"Palo Alto"}, "USA", 37.4419, -122.1430},
+	{[]string{"Mountain View"}, "USA", 37.3861, -122.0839},
+	{[]string{"Menlo Park"}, "USA", 37.4530, -122.1817},
+	{[]string{"San Jose"}, "USA", 37.3382, -121.8863},
+	{[]string{"Oakland"}, "USA", 37.8044, -122.2712},
+	{[]string{"Berkeley"}, "USA", 37.8715, -122.2730},
+	{[]string{"San Diego"}, "USA", 32.7157, -117.1611},
+	{[]string{"Portland"}, "USA", 45.5152, -122.6784},
+	{[]string{"Washington DC", "Washington, DC", "Washington D.C."}, "USA", 38.9072, -77.0369},
+	{[]string{"Atlanta"}, "USA", 33.7490, -84.3880},
+	{[]string{"Miami"}, "USA", 25.7617, -80.1918},
+	{[]string{"Philadelphia"}, "USA", 39.9526, -75.1652},
+	{[]string{"Pittsburgh"}, "USA", 40.4406, -79.9959},
+	{[]string{"Salt Lake City"}, "USA", 40.7608, -111.8910},
+	{[]string{"Toronto"}, "Canada", 43.6532, -79.3832},
+	{[]string{"Vancouver"}, "Canada", 49.2827, -123.1207},
+	{[]string{"Montreal", "Montréal"}, "Canada", 45.5017, -73.5673},
+	{[]string{"London"}, "UK", 51.5074, -0.1278},
+	{[]string{"Edinburgh"}, "UK", 55.9533, -3.1883},
+	{[]string{"Manchester"}, "UK", 53.4808, -2.2426},
+	{[]string{"Dublin"}, "Ireland", 53.3498, -6.2603},
+	{[]string{"Berlin"}, "Germany", 52.5200, 13.4050},
+	{[]string{"Munich", "München", "Muenchen"}, "Germany", 48.1351, 11.5820},
+	{[]string{"Hamburg"}, "Germany", 53.5511, 9.9937},
+	{[]string{"Amsterdam"}, "Netherlands", 52.3676, 4.9041},
+	{[]string{"Paris"}, "France", 48.8566, 2.3522},
+	{[]string{"Stockholm"}, "Sweden", 59.3293, 18.0686},
+	{[]string{"Copenhagen"}, "Denmark", 55.6761, 12.5683},
+	{[]string{"Oslo"}, "Norway", 59.9139, 10.7522},
+	{[]string{"Helsinki"}, "Finland", 60.1699, 24.9384},
+	{[]string{"Zurich", "Zürich"}, "Switzerland", 47.3769, 8.5417},
+	{[]string{"Geneva"}, "Switzerland", 46.2044, 6.1432},
+	{[]string{"Madrid"}, "Spain", 40.4168, -3.7038},
+	{[]string{"Barcelona"}, "Spain", 41.3851, 2.1734},
+	{[]string{"Lisbon"}, "Portugal", 38.7223, -9.1393},
+	{[]string{"Milan"}, "Italy", 45.4642, 9.1900},
+	{[]string{"Vienna"}, "Austria", 48.2082, 16.3738},
+	{[]string{"Prague"}, "Czechia", 50.0755, 14.4378},
+	{[]string{"Warsaw"}, "Poland", 52.2297, 21.0122},
+	{[]string{"Tel Aviv"}, "Israel", 32.0853, 34.7818},
+	{[]string{"Dubai"}, "UAE", 25.2048, 55.2708},
+	{[]string{"Bangalore", "Bengaluru"}, "India", 12.9716, 77.5946},
+	{[]string{"Singapore"}, "Singapore", 1.3521, 103.8198},
+	{[]string{"Hong Kong"}, "Hong Kong", 22.3193, 114.1694},
+	{[]string{"Seoul"}, "South Korea", 37.5665, 126.9780},
+	{[]string{"Tokyo"}, "Japan", 35.6762, 139.6503},
+	{[]string{"Sydney"}, "Australia", -33.8688, 151.2093},
+	{[]string{"Melbourne"}, "Australia", -37.8136, 144.9631},
+	{[]string{"Sao Paulo", "São Paulo"}, "Brazil", -23.5505, -46.6333},
+	{[]string{"Mexico City"}, "Mexico", 19.4326, -99.1332},
+	{[]string{"Buenos Aires"}, "Argentina", -34.6037, -58.3816},
+	{[]string{"Nairobi"}, "Kenya", -1.2921, 36.8219},
+	{[]string{"Lagos"}, "Nigeria", 6.5244, 3.3792},
+	{[]string{"Cape Town"}, "South Africa", -33.9249, 18.4241},
+}
+
+// geoPlaceNames maps the lowercased names and aliases of places to their place
+var geoPlaceNames = map[string]geoPlace{}
+
+// geoPattern matches any place name, longest names first so "New York City"
+// is not matched as a shorter name
+var geoPattern = func() *regexp.Regexp {
+	var names []string
+	for _, p := range geoPlaces {
+		for _, n := range p.Names {
+			geoPlaceNames[strings.ToLower(n)] = p
+			names = append(names, regexp.QuoteMeta(n))
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(names, "|") + `)\b`)
+}()
+
+// geocodeLocation will return the first known place named in a job location
+func geocodeLocation(location string) (geoPlace, bool) {
+	m := geoPattern.FindString(location)
+	if m == "" {
+		return geoPlace{}, false
+	}
+	p, ok := geoPlaceNames[strings.ToLower(m)]
+	return p, ok
+}
+
+// geoRegion is a country or a wider area location filters fall back to,
+// known by its names and aliases, holding the places of its countries
+type geoRegion struct {
+	Names     []string
+	Countries []string
+}
+
+// geoRegions are the known regions. Countries of places missing from it are
+// added as regions of their own, named after the country.
+var geoRegions = func() []geoRegion {
+	regions := []geoRegion{
+		{[]string{"USA", "United States", "US"}, []string{"USA"}},
+		{[]string{"UK", "United Kingdom", "England", "Scotland"}, []string{"UK"}},
+		{[]string{"Germany", "Deutschland"}, []string{"Germany"}},
+		{[]string{"Netherlands", "Holland"}, []string{"Netherlands"}},
+		{[]string{"Czechia", "Czech Republic"}, []string{"Czechia"}},
+		{[]string{"UAE", "United Arab Emirates"}, []string{"UAE"}},
+		{[]string{"North America"}, []string{"USA", "Canada", "Mexico"}},
+		{[]string{"Latin America", "LATAM", "South America"}, []string{"Mexico", "Brazil", "Argentina"}},
+		{[]string{"Europe", "EU"}, []string{"UK", "Ireland", "Germany", "Netherlands", "France", "Sweden", "Denmark",
+			"Norway", "Finland", "Switzerland", "Spain", "Portugal", "Italy", "Austria", "Czechia", "Poland"}},
+		{[]string{"Middle East"}, []string{"Israel", "UAE"}},
+		{[]string{"Asia"}, []string{"India", "Singapore", "Hong Kong", "South Korea", "Japan"}},
+		{[]string{"APAC", "Asia Pacific"}, []string{"India", "Singapore", "Hong Kong", "South Korea", "Japan", "Australia"}},
+		{[]string{"Australia"}, []string{"Australia"}},
+		{[]string{"Africa"}, []string{"Kenya", "Nigeria", "South Africa"}},
+	}
+	known := map[string]bool{}
+	for _, r := range regions {
+		known[strings.ToLower(r.Names[0])] = true
+	}
+	for _, p := range geoPlaces {
+		if !known[strings.ToLower(p.Country)] {
+			known[strings.ToLower(p.Country)] = true
+			regions = append(regions, geoRegion{[]string{p.Country}, []string{p.Country}})
+		}
+	}
+	return regions
+}()
+
+// locationMatch is what a location filter matches: jobs geocoded to one of
+// the places, or whose location contains one of the terms
+type locationMatch struct {
+	Places []geoPlace
+	Terms  []string
+}
+
+// resolveLocation will resolve a location filter, like "berlin", to the place
+// it names. Countries and areas, like "germany" or "europe", fall back to the
+// places in them and to locations naming them. Other locations are matched as text.
+func resolveLocation(v string) locationMatch {
+	key := strings.ToLower(strings.TrimSpace(v))
+	if p, ok := geoPlaceNames[key]; ok {
+		return locationMatch{Places: []geoPlace{p}}
+	}
+	for _, r := range geoRegions {
+		var names []string
+		for _, n := range r.Names {
+			names = append(names, strings.ToLower(n))
+		}
+		if getIndex(names, key) == -1 {
+			continue
+		}
+		var m locationMatch
+		for _, p := range geoPlaces {
+			if getIndex(r.Countries, p.Country) != -1 {
+				m.Places = append(m.Places, p)
+			}
+		}
+		// shorter names, like "US" or "EU", are part of too many words
+		for _, n := range r.Names {
+			if len(n) >= 3 {
+				m.Terms = append(m.Terms, n)
+			}
+		}
+		return m
+	}
+	return locationMatch{Terms: []string{strings.TrimSpace(v)}}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveLocation(t *testing.T) {
+	m := resolveLocation(" Berlin ")
+	if len(m.Places) != 1 || m.Places[0].Name() != "Berlin" || len(m.Terms) != 0 {
+		t.Errorf("resolveLocation(berlin) = %+v, want the place of Berlin", m)
+	}
+
+	m = resolveLocation("germany")
+	var names []string
+	for _, p := range m.Places {
+		names = append(names, p.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"Berlin", "Munich", "Hamburg"}) || !reflect.DeepEqual(m.Terms, []string{"Germany", "Deutschland"}) {
+		t.Errorf("resolveLocation(germany) = %v and terms %q, want the german places and names", names, m.Terms)
+	}
+
+	// the short names of regions are left out of the terms
+	if m := resolveLocation("EU"); len(m.Places) == 0 || getIndex(m.Terms, "EU") != -1 {
+		t.Errorf("resolveLocation(EU) = %+v, want the european places without the EU term", m)
+	}
+
+	if m := resolveLocation("Lisbon area"); len(m.Places) != 0 || !reflect.DeepEqual(m.Terms, []string{"Lisbon area"}) {
+		t.Errorf("resolveLocation(Lisbon area) = %+v, want a text match", m)
+	}
+}
+
+func TestFilterLocation(t *testing.T) {
+	useTestDB(t)
+	if _, err := CreateHiringStory(1, storyKindHiring, "Ask HN: Who is hiring?", 1700000000); err != nil {
+		t.Fatal(err)
+	}
+	berlin, munich, london := geoPlaceNames["berlin"], geoPlaceNames["munich"], geoPlaceNames["london"]
+	jobs := []jobFields{
+		{"location": "Berlin, Germany", "latitude": berlin.Latitude, "longitude": berlin.Longitude},
+		{"location": "München", "latitude": munich.Latitude, "longitude": munich.Longitude},
+		{"location": "London, UK", "latitude": london.Latitude, "longitude": london.Longitude},
+		// not geocoded, found by its text
+		{"location": "Anywhere in Germany"},
+		{"location": "Tallinn area"},
+	}
+	for i, fields := range jobs {
+		hj := HiringJob{HnId: uint64(i + 1), Text: "Acme | Engineer", Time: 1700000000}
+		if _, err := CreateHiringJob(1, jobStatusOk, hj); err != nil {
+			t.Fatal(err)
+		}
+		if err := SaveJobAttributes(hj.HnId, fields); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		location string
+		want     int
+	}{
+		{"berlin", 1},
+		{"germany", 3},
+		{"europe", 3},
+		{"tallinn", 1},
+		{"tokyo", 0},
+	}
+	for _, c := range cases {
+		n, err := CountJobList(jobScope{StoryId: 1}, FilterState{Location: c.location})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != c.want {
+			t.Errorf("location %q matched %d jobs, want %d", c.location, n, c.want)
+		}
+	}
+}
@@ -3,6 +3,17 @@ module github.com/ddominguez/who-is-hiring
 go 1.20
 
 require (
+	github.com/blevesearch/bleve v1.0.14
 	github.com/jmoiron/sqlx v1.3.5
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.16
 )
+
+require (
+	github.com/blevesearch/go-porterstemmer v1.0.3 // indirect
+	github.com/blevesearch/segment v0.9.0 // indirect
+	github.com/blevesearch/snowballstem v0.9.0 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/steveyen/gtreap v0.1.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+)
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Most posts start with a "Company | Role | Location | Compensation | Remote"
+// headline, in no fixed order past the company. The parser below labels each
+// segment with keyword heuristics first and position second.
+
+// jobHeadline is a post headline split into labeled segments. Segments that
+// could not be labeled are kept in Other, in order.
+type jobHeadline struct {
+	Company      string
+	Role         string
+	Location     string
+	Compensation string
+	Remote       string
+	Other        []string
+	// Guessed are the labels given by position rather than by keywords
+	Guessed []string
+}
+
+var (
+	headlineSeparatorPattern = regexp.MustCompile(`\s+[|•·]\s+|\s*\|\s*`)
+	dashSeparatorPattern     = regexp.MustCompile(`\s+[-–—]\s+`)
+	amountPattern            = regexp.MustCompile(`(?i)[$€£¥]\s?\d[\d,.]*k?(\s?[-–]\s?[$€£¥]?\d[\d,.]*k?)?`)
+	compPattern              = regexp.MustCompile(`(?i)[$€£¥]\s?\d|\b\d+(\.\d+)?\s?k\b|\b(usd|eur|gbp|cad|aud|chf)\b|\b(salary|equity|stock options|doe|competitive)\b`)
+	remotePattern            = regexp.MustCompile(`(?i)\b(remote|onsite|on-site|on site|hybrid|in[- ]office|in[- ]person|wfh|work from home|distributed)\b`)
+	rolePattern              = regexp.MustCompile(`(?i)\b(engineers?|developers?|designers?|managers?|scientists?|analysts?|architects?|researchers?|administrators?|specialists?|consultants?|recruiters?|programmers?|sre|devops|swe|intern(ship)?s?|head of|director|cto|vp|founding|full[- ]?stack|back[- ]?end|front[- ]?end|roles|positions|multiple)\b`)
+	locationPattern          = regexp.MustCompile(`(?i)\b(usa?|uk|eu|europe|emea|apac|latam|americas|canada|germany|france|spain|netherlands|india|japan|australia|anywhere|worldwide|global|nyc|new york|san francisco|sf|bay area|london|berlin|paris|amsterdam|toronto|seattle|boston|austin|chicago|los angeles|denver|tokyo|singapore|timezones?|pst|pt|est|et|cet|utc|gmt)\b`)
+	statePattern             = regexp.MustCompile(`,\s*[A-Z]{2}\b`)
+	urlSegmentPattern        = regexp.MustCompile(`(?i)^(https?://)?[a-z0-9\-]+(\.[a-z0-9\-]+)*\.[a-z]{2,}(/\S*)?$`)
+)
+
+// parseJobHeadline will label the segments of the headline of a job text.
+// Free form headlines, without separators, only get the segments found by
+// keywords: compensation and remote policy.
+func parseJobHeadline(text string) jobHeadline {
+	line := strings.TrimSpace(jobPlainText(jobRoleText(text)))
+	var h jobHeadline
+	if line == "" {
+		return h
+	}
+
+	segments := splitHeadline(line, headlineSeparatorPattern)
+	if len(segments) < 2 {
+		// some posts use dashes instead, but dashes are common in free text too
+		if segments = splitHeadline(line, dashSeparatorPattern); len(segments) < 3 {
+			segments = nil
+		}
+	}
+	if segments == nil {
+		if m := amountPattern.FindString(line); m != "" {
+			h.Compensation = m
+		}
+		if m := remotePattern.FindString(line); m != "" {
+			h.Remote = m
+		}
+		return h
+	}
+
+	// the company is almost always first, so keep it out of the keyword matching
+	h.Company = segments[0]
+	var unlabeled []string
+	for _, s := range segments[1:] {
+		switch {
+		case urlSegmentPattern.MatchString(s), len(matchEmploymentTypes(employmentPatterns, s)) > 0:
+			h.Other = append(h.Other, s)
+		case h.Compensation == "" && compPattern.MatchString(s):
+			h.Compensation = s
+		case h.Role == "" && rolePattern.MatchString(s):
+			h.Role = s
+		case h.Remote == "" && remotePattern.MatchString(s) && !isLocation(remotePattern.ReplaceAllString(s, "")):
+			h.Remote = s
+		case h.Location == "" && isLocation(s):
+			h.Location = s
+			if h.Remote == "" {
+				h.Remote = remotePattern.FindString(s)
+			}
+		default:
+			unlabeled = append(unlabeled, s)
+		}
+	}
+
+	// segments without keywords follow the usual order: role, then location.
+	// Places are capitalized, unlike the tech stacks often listed in headlines.
+	for _, s := range unlabeled {
+		switch {
+		case h.Role == "":
+			h.Role = s
+			h.Guessed = append(h.Guessed, "role")
+		case h.Location == "" && unicode.IsUpper([]rune(s)[0]) && jobTechTags(s) == "":
+			h.Location = s
+			h.Guessed = append(h.Guessed, "location")
+		default:
+			h.Other = append(h.Other, s)
+		}
+	}
+	return h
+}
+
+// isLocation will return true when s names a place or timezone
+func isLocation(s string) bool {
+	return locationPattern.MatchString(s) || statePattern.MatchString(s)
+}
+
+// splitHeadline will split a headline on its separators, dropping empty segments
+func splitHeadline(line string, sep *regexp.Regexp) []string {
+	var segments []string
+	for _, s := range sep.Split(line, -1) {
+		if s = strings.TrimSpace(s); s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
@@ -0,0 +1,129 @@
+package main
+
+import "testing"
+
+// headlineCorpus are headlines of real posts, in the shapes seen across the
+// threads, with the segments the parser should label
+var headlineCorpus = []struct {
+	line     string
+	company  string
+	role     string
+	location string
+	remote   string
+}{
+	{
+		line:     "Stripe | Senior Backend Engineer | San Francisco, CA or REMOTE (US) | $180k - $240k + equity | Full-time",
+		company:  "Stripe",
+		role:     "Senior Backend Engineer",
+		location: "San Francisco, CA or REMOTE (US)",
+		remote:   "REMOTE",
+	},
+	{
+		line:     "Tailscale | Software Engineer, Go | Remote (Canada, US) | https://tailscale.com/careers",
+		company:  "Tailscale",
+		role:     "Software Engineer, Go",
+		location: "Remote (Canada, US)",
+		remote:   "Remote",
+	},
+	{
+		line:    "Fly.io | Infrastructure Engineer | REMOTE | Full-time",
+		company: "Fly.io",
+		role:    "Infrastructure Engineer",
+		remote:  "REMOTE",
+	},
+	{
+		line:     "Anthropic | Research Engineer | San Francisco, CA | ONSITE | $300k-$500k",
+		company:  "Anthropic",
+		role:     "Research Engineer",
+		location: "San Francisco, CA",
+		remote:   "ONSITE",
+	},
+	{
+		line:     "Health Co | Principal Engineer II | London, UK | £120k | Hybrid",
+		company:  "Health Co",
+		role:     "Principal Engineer II",
+		location: "London, UK",
+		remote:   "Hybrid",
+	},
+	{
+		line:     "Ramp | New York, NY | Onsite | Multiple roles",
+		company:  "Ramp",
+		role:     "Multiple roles",
+		location: "New York, NY",
+		remote:   "Onsite",
+	},
+	{
+		line:     "Duolingo | Pittsburgh, PA | Senior Android Engineer | Hybrid",
+		company:  "Duolingo",
+		role:     "Senior Android Engineer",
+		location: "Pittsburgh, PA",
+		remote:   "Hybrid",
+	},
+	{
+		line:     "Oxide Computer Company | Emeryville, CA | Hardware Engineer | $201,227",
+		company:  "Oxide Computer Company",
+		role:     "Hardware Engineer",
+		location: "Emeryville, CA",
+	},
+	{
+		line:     "Chain Labs | Blockchain Engineer II | Remote (EU) | Contract",
+		company:  "Chain Labs",
+		role:     "Blockchain Engineer II",
+		location: "Remote (EU)",
+		remote:   "Remote",
+	},
+	{
+		line:    "Mozilla | Staff Engineer | Remote | Rust, C++",
+		company: "Mozilla",
+		role:    "Staff Engineer",
+		remote:  "Remote",
+	},
+	{
+		line:     "Acme - Backend Engineer - Berlin, Germany",
+		company:  "Acme",
+		role:     "Backend Engineer",
+		location: "Berlin, Germany",
+	},
+	{
+		// free form headlines only get the segments found by keywords
+		line:   "We're hiring remote engineers, $150k+",
+		remote: "remote",
+	},
+}
+
+func TestParseJobHeadline(t *testing.T) {
+	for _, c := range headlineCorpus {
+		h := parseJobHeadline(c.line + "<p>Some body text.")
+		if h.Company != c.company || h.Role != c.role || h.Location != c.location || h.Remote != c.remote {
+			t.Errorf("parseJobHeadline(%q) = company %q, role %q, location %q, remote %q, want %q, %q, %q, %q",
+				c.line, h.Company, h.Role, h.Location, h.Remote, c.company, c.role, c.location, c.remote)
+		}
+	}
+}
+
+func TestParseJobHeadlineCompensation(t *testing.T) {
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"Stripe | Senior Backend Engineer | SF | $180k - $240k + equity", "$180k - $240k + equity"},
+		{"Health Co | Principal Engineer | London, UK | £120k | Hybrid", "£120k"},
+		{"We're hiring remote engineers, $150k+", "$150k"},
+		{"Acme | Engineer | Remote", ""},
+	}
+	for _, c := range cases {
+		if got := parseJobHeadline(c.line).Compensation; got != c.want {
+			t.Errorf("parseJobHeadline(%q).Compensation = %q, want %q", c.line, got, c.want)
+		}
+	}
+}
+
+func TestParseJobHeadlineGuessed(t *testing.T) {
+	h := parseJobHeadline("Acme | Platform | Lisbon")
+	if h.Role != "Platform" || h.Location != "Lisbon" {
+		t.Fatalf("got role %q and location %q, want Platform and Lisbon", h.Role, h.Location)
+	}
+	if len(h.Guessed) != 2 || h.Guessed[0] != "role" || h.Guessed[1] != "location" {
+		t.Errorf("Guessed = %q, want [role location]", h.Guessed)
+	}
+}
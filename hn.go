@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	hnApiBaseUri = "https://hacker-news.firebaseio.com/v0"
+	// hnTypeComment is the type of the items job posts are
+	hnTypeComment = "comment"
+	// hnUserUrl is the hacker news profile page of a user
+	hnUserUrl = "https://news.ycombinator.com/user?id="
+	// hnItemUrl is the hacker news page of an item
+	hnItemUrl = "https://news.ycombinator.com/item?id="
+)
+
+// hnItem is a hacker news item as returned by the item api
+type hnItem struct {
+	Id    uint64   `json:"id"`
+	Type  string   `json:"type"`
+	By    string   `json:"by"`
+	Title string   `json:"title"`
+	Text  string   `json:"text"`
+	Time  uint64   `json:"time"`
+	Kids  []uint64 `json:"kids"`
+	// Parent is the item replied to, the story for top level comments
+	Parent uint64 `json:"parent"`
+	// Score and Descendants are only set on stories
+	Score       int  `json:"score"`
+	Descendants int  `json:"descendants"`
+	Dead        bool `json:"dead"`
+	Deleted     bool `json:"deleted"`
+}
+
+// errHnItemNotFound is returned for items the api answers with null, like
+// items too new to be served yet
+var errHnItemNotFound = errors.New("item not found")
+
+// skipReason will tell why an item listed as a kid of story hsId is not a job
+// post, or return an empty string for job posts. Flagged comments are dead
+// comments and are stored as such, like deleted ones.
+func (item hnItem) skipReason(hsId uint64) string {
+	switch {
+	case item.Deleted:
+		// deleted items may come without a type
+		if item.Type != "" && item.Type != hnTypeComment {
+			return fmt.Sprintf("a %s item, not a comment", item.Type)
+		}
+	case item.Type == "":
+		return "an item without a type"
+	case item.Type != hnTypeComment:
+		return fmt.Sprintf("a %s item, not a comment", item.Type)
+	case item.Parent != 0 && item.Parent != hsId:
+		return fmt.Sprintf("a reply to item %d, not a top level comment", item.Parent)
+	case item.Text == "" && !item.Dead:
+		return "an empty comment"
+	}
+	return ""
+}
+
+// hnItemResult is the outcome of fetching one item
+type hnItemResult struct {
+	Id   uint64
+	Item *hnItem
+	Err  error
+}
+
+// hnClient is shared by all api requests so connections are kept alive
+var hnClient = &http.Client{Timeout: 30 * time.Second}
+
+// hnReaderPool holds the read buffers used to stream decode api responses
+var hnReaderPool = sync.Pool{
+	New: func() any {
+		return bufio.NewReaderSize(nil, 16<<10)
+	},
+}
+
+// getHnJSON will stream decode the api response of path into v
+func getHnJSON(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hnApiBaseUri+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := hnClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with %s", path, resp.Status)
+	}
+
+	br := hnReaderPool.Get().(*bufio.Reader)
+	br.Reset(resp.Body)
+	defer func() {
+		br.Reset(nil)
+		hnReaderPool.Put(br)
+	}()
+
+	if err := json.NewDecoder(br).Decode(v); err != nil {
+		return err
+	}
+	// Drain the body so the connection is reused
+	_, err = io.Copy(io.Discard, br)
+	return err
+}
+
+// getHnItem will fetch a hacker news item
+func getHnItem(ctx context.Context, id uint64) (*hnItem, error) {
+	var item hnItem
+	if err := getHnJSON(ctx, fmt.Sprintf("/item/%d.json", id), &item); err != nil {
+		return nil, err
+	}
+	if item.Id == 0 {
+		return nil, fmt.Errorf("%w: %d", errHnItemNotFound, id)
+	}
+	return &item, nil
+}
+
+// getHnRawItem will fetch a hacker news item along with its raw json
+func getHnRawItem(ctx context.Context, id uint64) (*hnItem, json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := getHnJSON(ctx, fmt.Sprintf("/item/%d.json", id), &raw); err != nil {
+		return nil, nil, err
+	}
+	var item hnItem
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return nil, nil, err
+	}
+	return &item, raw, nil
+}
+
+// fetchHnItems will fetch items with a pool of workers and send each result
+// as soon as it is available. The channel is closed once all items are
+// fetched or ctx is done.
+func fetchHnItems(ctx context.Context, ids []uint64, workers int) <-chan hnItemResult {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan uint64)
+	results := make(chan hnItemResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				item, err := getHnItem(ctx, id)
+				select {
+				case results <- hnItemResult{Id: id, Item: item, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, id := range ids {
+			select {
+			case jobs <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHnItemSkipReason(t *testing.T) {
+	const hsId = 100
+	cases := []struct {
+		name string
+		item hnItem
+		want string
+	}{
+		{"comment", hnItem{Type: "comment", Parent: hsId, Text: "Acme | Engineer"}, ""},
+		{"comment without parent", hnItem{Type: "comment", Text: "Acme | Engineer"}, ""},
+		{"dead comment", hnItem{Type: "comment", Parent: hsId, Text: "Acme | Engineer", Dead: true}, ""},
+		{"dead comment without text", hnItem{Type: "comment", Parent: hsId, Dead: true}, ""},
+		{"deleted comment", hnItem{Type: "comment", Parent: hsId, Deleted: true}, ""},
+		{"deleted item without type", hnItem{Deleted: true}, ""},
+		{"deleted story", hnItem{Type: "story", Deleted: true}, "a story item, not a comment"},
+		{"story", hnItem{Type: "story", Title: "Ask HN"}, "a story item, not a comment"},
+		{"job", hnItem{Type: "job", Title: "Acme is hiring"}, "a job item, not a comment"},
+		{"poll", hnItem{Type: "poll"}, "a poll item, not a comment"},
+		{"pollopt", hnItem{Type: "pollopt", Parent: hsId}, "a pollopt item, not a comment"},
+		{"reply", hnItem{Type: "comment", Parent: 101, Text: "Is it remote?"}, "a reply to item 101, not a top level comment"},
+		{"item without type", hnItem{Text: "Acme | Engineer"}, "an item without a type"},
+		{"empty comment", hnItem{Type: "comment", Parent: hsId}, "an empty comment"},
+	}
+	for _, c := range cases {
+		if got := c.item.skipReason(hsId); got != c.want {
+			t.Errorf("%s: skipReason() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+// serveHnItems will answer the item api with items, and null for the others,
+// until the end of the test
+func serveHnItems(t testing.TB, items map[uint64]any) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id uint64
+		if _, err := fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/v0/item/"), "%d.json", &id); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		item, ok := items[id]
+		if !ok {
+			item = nil
+		}
+		json.NewEncoder(w).Encode(item)
+	}))
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	prev := hnClient.Transport
+	hnClient.Transport = hnTestTransport{target}
+	t.Cleanup(func() { hnClient.Transport = prev })
+}
+
+// hnTestTransport sends the api requests to a test server
+type hnTestTransport struct {
+	target *url.URL
+}
+
+func (tr hnTestTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = tr.target.Scheme, tr.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestProcessJobPostsSkips(t *testing.T) {
+	useTestDB(t)
+	const hsId = 100
+	if _, err := CreateHiringStory(hsId, storyKindHiring, "Ask HN: Who is hiring?", 1700000000); err != nil {
+		t.Fatal(err)
+	}
+	serveHnItems(t, map[uint64]any{
+		hsId: hnItem{Id: hsId, Type: "story", Kids: []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		1:    hnItem{Id: 1, Type: "comment", Parent: hsId, By: "acme", Text: "Acme | Engineer | Remote", Time: 1700000001},
+		2:    hnItem{Id: 2, Type: "comment", Parent: hsId, Dead: true, Text: "Spam | Spam", Time: 1700000002},
+		3:    hnItem{Id: 3, Deleted: true, Time: 1700000003},
+		4:    hnItem{Id: 4, Type: "poll", Title: "Which stack?", Time: 1700000004},
+		5:    hnItem{Id: 5, Type: "comment", Parent: 1, Text: "Is it remote?", Time: 1700000005},
+		6:    hnItem{Id: 6, Text: "Acme | Engineer", Time: 1700000006},
+		7:    hnItem{Id: 7, Type: "comment", Parent: hsId, Time: 1700000007},
+		8:    hnItem{Id: 8, Type: "job", Title: "Acme is hiring", Time: 1700000008},
+		// 9 is not served yet, it is fetched on the next sync
+	})
+
+	if err := processJobPosts(context.Background(), hsId); err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs []struct {
+		HnId   uint64 `db:"hn_id"`
+		Status uint8
+	}
+	if err := db.Select(&jobs, `SELECT hn_id, status FROM hiring_job ORDER BY hn_id`); err != nil {
+		t.Fatal(err)
+	}
+	wantJobs := map[uint64]uint8{1: jobStatusOk, 2: jobStatusDead, 3: jobStatusDeleted}
+	if len(jobs) != len(wantJobs) {
+		t.Errorf("stored %d jobs, want %d", len(jobs), len(wantJobs))
+	}
+	for _, j := range jobs {
+		if want, ok := wantJobs[j.HnId]; !ok || j.Status != want {
+			t.Errorf("job %d stored with status %d, want %d", j.HnId, j.Status, want)
+		}
+	}
+
+	var skipped []SkippedItem
+	if err := db.Select(&skipped, `SELECT hn_id, hiring_story_id, type, reason FROM skipped_item ORDER BY hn_id`); err != nil {
+		t.Fatal(err)
+	}
+	wantSkipped := []SkippedItem{
+		{HnId: 4, HiringStoryId: hsId, Type: "poll", Reason: "a poll item, not a comment"},
+		{HnId: 5, HiringStoryId: hsId, Type: "comment", Reason: "a reply to item 1, not a top level comment"},
+		{HnId: 6, HiringStoryId: hsId, Type: "", Reason: "an item without a type"},
+		{HnId: 7, HiringStoryId: hsId, Type: "comment", Reason: "an empty comment"},
+		{HnId: 8, HiringStoryId: hsId, Type: "job", Reason: "a job item, not a comment"},
+	}
+	if len(skipped) != len(wantSkipped) {
+		t.Fatalf("recorded %d skipped items, want %d: %+v", len(skipped), len(wantSkipped), skipped)
+	}
+	for i, s := range skipped {
+		if s != wantSkipped[i] {
+			t.Errorf("skipped item %+v, want %+v", s, wantSkipped[i])
+		}
+	}
+
+	// a second sync fetches neither the skipped items nor the stored jobs again
+	ids, err := SelectSkippedItemIds(hsId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != len(wantSkipped) {
+		t.Errorf("SelectSkippedItemIds = %v, want %d ids", ids, len(wantSkipped))
+	}
+}
+
+// serveHnFixture will answer every api request with the recorded item of
+// testdata/hn_item.json, until the end of the benchmark
+func serveHnFixture(b *testing.B) {
+	b.Helper()
+	fixture, err := os.ReadFile("testdata/hn_item.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(fixture)
+	}))
+	b.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	prev := hnClient.Transport
+	hnClient.Transport = hnTestTransport{target}
+	b.Cleanup(func() { hnClient.Transport = prev })
+}
+
+func BenchmarkGetHnJSON(b *testing.B) {
+	serveHnFixture(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var item hnItem
+		if err := getHnJSON(ctx, "/item/41709301.json", &item); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFetchHnItems fetches the items of a sync of a busy month
+func BenchmarkFetchHnItems(b *testing.B) {
+	serveHnFixture(b)
+	ids := make([]uint64, 500)
+	for i := range ids {
+		ids[i] = uint64(41709302 + i)
+	}
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for res := range fetchHnItems(ctx, ids, cfg.SyncWorkers) {
+			if res.Err != nil {
+				b.Fatal(res.Err)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// hotReplyWeight is the number of views a reply to a job counts as, since
+// replying takes more interest than opening a job
+const hotReplyWeight = 5
+
+// jobEngagement is the interest a job got since it was posted
+type jobEngagement struct {
+	HnId    uint64 `db:"hn_id"`
+	Time    uint64
+	Replies int
+	Views   int
+}
+
+// hotScore will return the engagement of a job decayed by its age, halving
+// every cfg.HotHalfLife, so new jobs rank over old ones with as much interest
+func (e jobEngagement) hotScore(now time.Time) float64 {
+	age := now.Sub(time.Unix(int64(e.Time), 0))
+	if age < 0 {
+		age = 0
+	}
+	interest := float64(1 + hotReplyWeight*e.Replies + e.Views)
+	return interest * math.Pow(0.5, float64(age)/float64(cfg.HotHalfLife))
+}
+
+// rankHotJobs will return the ids of the jobs from the hottest one, newest
+// first on ties
+func rankHotJobs(jobs []jobEngagement, now time.Time) []uint64 {
+	scores := make(map[uint64]float64, len(jobs))
+	for _, e := range jobs {
+		scores[e.HnId] = e.hotScore(now)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		si, sj := scores[jobs[i].HnId], scores[jobs[j].HnId]
+		if si != sj {
+			return si > sj
+		}
+		return jobs[i].HnId > jobs[j].HnId
+	})
+	ids := make([]uint64, len(jobs))
+	for i, e := range jobs {
+		ids[i] = e.HnId
+	}
+	return ids
+}
+
+// UpdateJobReplies will save the number of direct replies to a job
+func UpdateJobReplies(hnId uint64, replies int) error {
+	sql := `INSERT INTO job_engagement (hn_id, replies) VALUES (?, ?)
+            ON CONFLICT (hn_id) DO UPDATE SET replies = excluded.replies`
+	_, err := db.Exec(sql, hnId, replies)
+	return err
+}
+
+// RecordJobView will count a view of a job in the reader
+func RecordJobView(hnId uint64) error {
+	sql := `INSERT INTO job_engagement (hn_id, views) VALUES (?, 1)
+            ON CONFLICT (hn_id) DO UPDATE SET views = views + 1`
+	_, err := db.Exec(sql, hnId)
+	return err
+}
+
+// recordJobView will count a view of a job, logging failures as they must
+// not fail the page
+func recordJobView(hnId uint64) {
+	if err := RecordJobView(hnId); err != nil {
+		log.Println("failed to record job view.", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	interviewNoLeetcode = "noleetcode"
+	interviewTakeHome   = "takehome"
+	interviewPairing    = "pairing"
+	interviewPaid       = "paid"
+	// interviewFewRounds is set for processes of up to interviewFewRoundsMax rounds
+	interviewFewRounds = "fewrounds"
+
+	// interviewFewRoundsMax is the most rounds of a short interview process
+	interviewFewRoundsMax = 3
+)
+
+var interviewFlags = []string{interviewNoLeetcode, interviewTakeHome, interviewPairing, interviewPaid, interviewFewRounds}
+
+// interviewNames are the display names of the interview process flags
+var interviewNames = map[string]string{
+	interviewNoLeetcode: "No leetcode",
+	interviewTakeHome:   "Take-home",
+	interviewPairing:    "Pair programming",
+	interviewPaid:       "Paid exercise",
+	interviewFewRounds:  fmt.Sprintf("Up to %d rounds", interviewFewRoundsMax),
+}
+
+// interviewPatterns match the interview process details of the flags, but for
+// interviewFewRounds, which is set from the number of rounds
+var interviewPatterns = map[string]*regexp.Regexp{
+	interviewNoLeetcode: regexp.MustCompile(`(?i)\b(no|not|without|zero|never)\s+(leet\s?code|whiteboard(ing)?|algorithm(ic)? (puzzles|questions|trivia)|brain\s?teasers|trick questions)\b|\bleet\s?code[- ]free\b`),
+	interviewTakeHome:   regexp.MustCompile(`(?i)\btake[- ]?home\b`),
+	interviewPairing:    regexp.MustCompile(`(?i)\b(pair(ed)?[- ]programming|pairing) (interviews?|sessions?|exercises?)\b`),
+	interviewPaid:       regexp.MustCompile(`(?i)\bpaid (take[- ]?home|trial|work trial|trial (day|week|project)|project|exercise|assignment)s?\b`),
+}
+
+// interviewRoundsPattern matches the number of rounds of a process, like "4
+// rounds", "three interviews" or "a 2-step interview process"
+var interviewRoundsPattern = regexp.MustCompile(`(?i)\b([1-9]|one|two|three|four|five|six|seven|eight|nine)[- ](rounds?|interviews?|stages?|steps?)\b`)
+
+// interviewNumbers are the numbers of rounds spelled out
+var interviewNumbers = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+}
+
+// isInterviewFlag will return true when v is a known interview process flag
+func isInterviewFlag(v string) bool {
+	return getIndex(interviewFlags, v) != -1
+}
+
+// jobInterviewRounds will return the number of interview rounds a job text
+// mentions, 0 when it doesn't say
+func jobInterviewRounds(text string) int {
+	m := interviewRoundsPattern.FindStringSubmatch(jobPlainText(text))
+	if m == nil {
+		return 0
+	}
+	if n, ok := interviewNumbers[strings.ToLower(m[1])]; ok {
+		return n
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// jobInterview will return the comma separated interview process flags found
+// in a job text along with its number of rounds, 0 when it doesn't say
+func jobInterview(text string) (string, int) {
+	plain := jobPlainText(text)
+	var found []string
+	for _, f := range interviewFlags {
+		if p, ok := interviewPatterns[f]; ok && p.MatchString(plain) {
+			found = append(found, f)
+		}
+	}
+	rounds := jobInterviewRounds(text)
+	if rounds > 0 && rounds <= interviewFewRoundsMax {
+		found = append(found, interviewFewRounds)
+	}
+	return strings.Join(found, ","), rounds
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// languageMinHits is the number of stopwords needed to detect a language
+const languageMinHits = 3
+
+// languageStopwords are frequent words that identify a language. Words
+// shared by several languages are left out.
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "we", "are", "with", "you", "our", "for", "is", "to", "of", "in", "be", "have", "will", "looking", "your", "who"},
+	"de": {"und", "wir", "sind", "mit", "der", "die", "das", "ist", "für", "ein", "eine", "einen", "suchen", "bei", "auf", "nicht", "auch", "ihr"},
+	"fr": {"et", "nous", "sommes", "avec", "le", "la", "les", "est", "pour", "un", "une", "des", "recherchons", "vous", "dans", "sur", "notre"},
+	"es": {"y", "somos", "con", "el", "los", "las", "es", "para", "una", "buscamos", "en", "del", "nuestro", "estamos", "trabajo"},
+	"pt": {"e", "somos", "com", "os", "as", "para", "uma", "não", "estamos", "procurando", "nosso", "vaga", "você", "em"},
+	"nl": {"en", "wij", "zijn", "met", "het", "een", "voor", "zoeken", "van", "niet", "ons", "jij", "bij"},
+	"it": {"e", "siamo", "con", "il", "gli", "per", "una", "cerchiamo", "non", "della", "nostro", "lavoro"},
+}
+
+// languageNames are the display names of the detected languages
+var languageNames = map[string]string{
+	"en": "English", "de": "German", "fr": "French", "es": "Spanish",
+	"pt": "Portuguese", "nl": "Dutch", "it": "Italian",
+}
+
+// jobLanguage will detect the language of a job text by counting
+// stopwords, returning an ISO 639-1 code or an empty string when unsure.
+func jobLanguage(text string) string {
+	lang, _, _ := detectLanguage(text)
+	return lang
+}
+
+// detectLanguage will detect the language of a job text like jobLanguage,
+// also returning the stopword hits of that language and of all languages.
+func detectLanguage(text string) (string, int, int) {
+	words := strings.FieldsFunc(strings.ToLower(jobPlainText(text)), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	hits := map[string]int{}
+	var total int
+	for _, w := range words {
+		for lang, stopwords := range languageStopwords {
+			if getIndex(stopwords, w) != -1 {
+				hits[lang]++
+				total++
+			}
+		}
+	}
+
+	var best string
+	for lang, n := range hits {
+		if n > hits[best] || (n == hits[best] && lang < best) {
+			best = lang
+		}
+	}
+	if hits[best] < languageMinHits {
+		return "", 0, total
+	}
+	return best, hits[best], total
+}
+
+// isLanguage will return true when v is a language we detect
+func isLanguage(v string) bool {
+	_, ok := languageNames[v]
+	return ok
+}
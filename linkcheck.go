@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// linkCheckWorkers is the number of links checked at once
+	linkCheckWorkers = 8
+	// linkCheckTimeout bounds the time a link has to answer in
+	linkCheckTimeout = 10 * time.Second
+)
+
+// linkChecking is set while the links of a sync are checked, so a slow
+// check is not started again by the next sync
+var linkChecking atomic.Bool
+
+func init() {
+	// links are checked in the background, as hundreds of slow sites would
+	// hold the sync for minutes
+	bus.Subscribe(eventSyncCompleted, func(e event) {
+		if cfg.LinkCheckAge <= 0 || !linkChecking.CompareAndSwap(false, true) {
+			return
+		}
+		go func() {
+			defer linkChecking.Store(false)
+			if err := checkStoryLinks(context.Background(), e.StoryId); err != nil {
+				log.Println("failed to check apply links.", err)
+			}
+		}()
+	})
+}
+
+// linkClient checks the apply links of jobs. Redirects are followed, so links
+// to moved pages are not broken.
+var linkClient = &http.Client{Timeout: linkCheckTimeout}
+
+// LinkCheck is the outcome of requesting the apply link of a job
+type LinkCheck struct {
+	HnId      uint64 `db:"hn_id"`
+	Url       string
+	Status    int
+	Error     string
+	CheckedAt uint64 `db:"checked_at"`
+}
+
+// Broken will return true for links failing to answer, gone or failing on
+// the side of their site. Other client errors, like the 403 of sites
+// refusing bots, tell nothing about the link.
+func (lc LinkCheck) Broken() bool {
+	return lc.Error != "" || lc.Status == http.StatusNotFound || lc.Status == http.StatusGone || lc.Status >= 500
+}
+
+// Problem will describe why a broken link is broken
+func (lc LinkCheck) Problem() string {
+	if lc.Error != "" {
+		return lc.Error
+	}
+	return http.StatusText(lc.Status)
+}
+
+// linkStatus will request a link with method and return its status
+func linkStatus(ctx context.Context, method, link string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "who-is-hiring link checker (+"+cfg.PublicBaseUrl+")")
+	resp, err := linkClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// checkLink will request the apply link of a job, with a GET when the site
+// does not answer HEAD requests
+func checkLink(ctx context.Context, hnId uint64, link string) LinkCheck {
+	lc := LinkCheck{HnId: hnId, Url: link, CheckedAt: uint64(time.Now().Unix())}
+	if u, err := url.Parse(link); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		lc.Error = "not an http link"
+		return lc
+	}
+	status, err := linkStatus(ctx, http.MethodHead, link)
+	if err != nil || status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented {
+		status, err = linkStatus(ctx, http.MethodGet, link)
+	}
+	if err != nil {
+		// the error of the request repeats the url
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		lc.Error = err.Error()
+	}
+	lc.Status = status
+	return lc
+}
+
+// checkJobLinks will check the apply links of jobs with a pool of workers and
+// save the outcomes, returning them in the order of the jobs. Jobs without an
+// apply link are left out.
+func checkJobLinks(ctx context.Context, jobs []HiringJob) ([]LinkCheck, error) {
+	var linked []HiringJob
+	for _, hj := range jobs {
+		if hj.ApplyUrl != "" {
+			linked = append(linked, hj)
+		}
+	}
+	checks := make([]LinkCheck, len(linked))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < linkCheckWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				checks[i] = checkLink(ctx, linked[i].HnId, linked[i].ApplyUrl)
+			}
+		}()
+	}
+	for i := range linked {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, lc := range checks {
+		if err := SaveLinkCheck(lc); err != nil {
+			return checks, err
+		}
+	}
+	return checks, nil
+}
+
+// SaveLinkCheck will record the last check of the apply link of a job
+func SaveLinkCheck(lc LinkCheck) error {
+	sql := `INSERT OR REPLACE INTO link_check (hn_id, url, status, error, checked_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.Exec(sql, lc.HnId, lc.Url, lc.Status, lc.Error, lc.CheckedAt)
+	return err
+}
+
+// checkStoryLinks will check the apply links of the live jobs of a story not
+// checked for cfg.LinkCheckAge, or changed since their last check
+func checkStoryLinks(ctx context.Context, hsId uint64) error {
+	before := time.Now().Add(-cfg.LinkCheckAge).Unix()
+	jobs, err := SelectUncheckedLinkJobs(hsId, before)
+	if err != nil {
+		return err
+	}
+	checks, err := checkJobLinks(ctx, jobs)
+	if err != nil {
+		return err
+	}
+	var broken int
+	for _, lc := range checks {
+		if lc.Broken() {
+			broken++
+		}
+	}
+	log.Printf("checked %d apply links of story %d, %d broken", len(checks), hsId, broken)
+	return nil
+}
+
+// SelectUncheckedLinkJobs will return the live jobs of a story with an apply
+// link never checked, checked before a unix time or changed since
+func SelectUncheckedLinkJobs(hsId uint64, before int64) ([]HiringJob, error) {
+	var jobs []HiringJob
+	sql := `SELECT hj.hn_id, hj.apply_url FROM hiring_job_view hj
+            LEFT JOIN link_check lc ON lc.hn_id = hj.hn_id
+            WHERE hj.hiring_story_id=? and hj.status=? and hj.apply_url != ''
+            and (lc.hn_id IS NULL or lc.checked_at < ? or lc.url != hj.apply_url)
+            ORDER BY hj.hn_id`
+	if err := db.Select(&jobs, sql, hsId, jobStatusOk, before); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
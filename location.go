@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+)
+
+const (
+	remoteFull   = "remote"
+	remoteHybrid = "hybrid"
+	remoteOnsite = "onsite"
+)
+
+var remotePolicies = []string{remoteFull, remoteHybrid, remoteOnsite}
+
+// remotePolicyNames are the display names of the remote policies
+var remotePolicyNames = map[string]string{
+	remoteFull:   "Remote",
+	remoteHybrid: "Hybrid",
+	remoteOnsite: "Onsite",
+}
+
+// jobLocation will return the location segment of the job headline
+func jobLocation(text string) string {
+	return strings.Trim(parseJobHeadline(text).Location, " ,.-–")
+}
+
+// jobRemote will return the comma separated remote policies of a job, from
+// the headline segments labeled as remote or location.
+func jobRemote(text string) string {
+	h := parseJobHeadline(text)
+	var found []string
+	for _, m := range remotePattern.FindAllString(h.Remote+" "+h.Location, -1) {
+		p := remoteFull
+		switch strings.ToLower(m) {
+		case "hybrid":
+			p = remoteHybrid
+		case "onsite", "on-site", "on site", "in office", "in-office", "in person", "in-person":
+			p = remoteOnsite
+		}
+		if getIndex(found, p) == -1 {
+			found = append(found, p)
+		}
+	}
+
+	var policies []string
+	for _, p := range remotePolicies {
+		if getIndex(found, p) != -1 {
+			policies = append(policies, p)
+		}
+	}
+	return strings.Join(policies, ",")
+}
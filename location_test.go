@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestJobLocation(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"Stripe | Senior Backend Engineer | San Francisco, CA or REMOTE (US) | $180k", "San Francisco, CA or REMOTE (US)"},
+		{"Health Co | Principal Engineer | London, UK. | Hybrid", "London, UK"},
+		{"Acme - Backend Engineer - Berlin, Germany<p>We build things.", "Berlin, Germany"},
+		{"Fly.io | Infrastructure Engineer | REMOTE | Full-time", ""},
+		{"We're hiring engineers", ""},
+	}
+	for _, c := range cases {
+		if got := jobLocation(c.text); got != c.want {
+			t.Errorf("jobLocation(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestJobRemote(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"Fly.io | Infrastructure Engineer | REMOTE | Full-time", "remote"},
+		{"Tailscale | Software Engineer | Remote (Canada, US)", "remote"},
+		{"Anthropic | Research Engineer | San Francisco, CA | ONSITE", "onsite"},
+		{"Health Co | Principal Engineer | London, UK | Hybrid", "hybrid"},
+		{"Acme | Engineer | NYC | In-office or Remote", "remote,onsite"},
+		{"Globex | Engineer | Austin, TX | Hybrid, on-site or remote", "remote,hybrid,onsite"},
+		{"Initech | Engineer | Berlin<p>Our team works remote.", ""},
+	}
+	for _, c := range cases {
+		if got := jobRemote(c.text); got != c.want {
+			t.Errorf("jobRemote(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
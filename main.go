@@ -1,18 +1,24 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
 	"strconv"
 	"strings"
-	"text/template"
+	"time"
 )
 
-const (
-	hnApiBaseUri = "https://hacker-news.firebaseio.com/v0"
-)
+// archiveMaxAge is the max age in seconds of archived story pages
+const archiveMaxAge = 3600
 
 // getIndex will return the position of v in s
 func getIndex[K comparable](s []K, v K) int {
@@ -24,110 +30,146 @@ func getIndex[K comparable](s []K, v K) int {
 	return -1
 }
 
-// newHiringStory will attempt to insert a new hiring story to our db.
+// newHiringStory will attempt to insert a new story of a kind to our db.
 // Return the hacker news id.
-func newHiringStory(s []int) (uint64, error) {
-	type hiringStory struct {
-		Id    uint64 `json:"id"`
-		Title string `json:"title"`
-		Time  uint64 `json:"time"`
-	}
-
+func newHiringStory(ctx context.Context, s []int, kind string) (uint64, error) {
 	for _, sv := range s {
-		resp, err := http.Get(hnApiBaseUri + fmt.Sprintf("/item/%d.json", sv))
+		hs, err := getHnItem(ctx, uint64(sv))
 		if err != nil {
 			return 0, err
 		}
-		defer resp.Body.Close()
-
-		var hs hiringStory
-		if err := json.NewDecoder(resp.Body).Decode(&hs); err != nil {
-			return 0, err
-		}
 
-		if strings.HasPrefix(hs.Title, "Ask HN: Who is hiring?") {
-			hsId, err := CreateHiringStory(hs.Id, hs.Title, hs.Time)
+		if strings.HasPrefix(hs.Title, storyPrefixes[kind]) {
+			hsId, err := CreateHiringStory(hs.Id, kind, hs.Title, hs.Time)
 			if err != nil {
 				return 0, err
 			}
+			if err := RecordAudit("system", "story.create", strconv.FormatUint(hsId, 10), hs.Title); err != nil {
+				log.Println("failed to record audit entry.", err)
+			}
 			return hsId, nil
 		}
 	}
 
-	return 0, fmt.Errorf("could not add new hiring story from Ids %v", s)
+	return 0, fmt.Errorf("could not add new %s story from Ids %v", kind, s)
 }
 
-// newHiringJob will attempt to fetch a job item from hacker news
-// and saves it to our database.
-func newHiringJob(hsid, hjid uint64) (uint64, error) {
-	resp, err := http.Get(hnApiBaseUri + fmt.Sprintf("/item/%d.json", hjid))
-	if err != nil {
-		return 0, err
+// saveHiringJob will save a job item fetched from hacker news to our database.
+// Fields derived from the text of live jobs are set by the enrichment chain
+// and saved as the job attributes.
+func saveHiringJob(hsid uint64, hj *hnItem) error {
+	hjStatus := HiringJobStatus(hj.Dead, hj.Deleted)
+	job := HiringJob{
+		HnId:          hj.Id,
+		HiringStoryId: hsid,
+		Text:          hj.Text,
+		Time:          hj.Time,
+		Poster:        hj.By,
 	}
-	defer resp.Body.Close()
-
-	var hj struct {
-		Id      uint64 `json:"id"`
-		Text    string `json:"text"`
-		Time    uint64 `json:"time"`
-		Dead    bool   `json:"dead"`
-		Deleted bool   `json:"deleted"`
+	var fields jobFields
+	if hjStatus == jobStatusOk {
+		var err error
+		if fields, err = enrichment.enrich(&job); err != nil {
+			return err
+		}
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&hj); err != nil {
-		return 0, err
+	if _, err := CreateHiringJob(hsid, hjStatus, job); err != nil {
+		return err
 	}
-
-	hjStatus := HiringJobStatus(hj.Dead, hj.Deleted)
-	_, err = CreateHiringJob(hj.Id, hsid, hj.Text, hj.Time, hjStatus)
-	if err != nil {
-		return 0, nil
+	if err := UpdateJobReplies(job.HnId, len(hj.Kids)); err != nil {
+		return err
 	}
-
-	return hjid, nil
+	if fields == nil {
+		return nil
+	}
+	return SaveJobAttributes(job.HnId, fields)
 }
 
 // processJobPosts will attempt to fetch and process job items for a given hiring story
-func processJobPosts(hsid uint64) error {
+func processJobPosts(ctx context.Context, hsid uint64) error {
 	log.Printf("process jobs for hiring story id %d", hsid)
-	itemPath := fmt.Sprintf("/item/%d.json", hsid)
-	resp, err := http.Get(hnApiBaseUri + itemPath)
+	hs, raw, err := getHnRawItem(ctx, hsid)
 	if err != nil {
-		log.Printf("failed to request %s\n", itemPath)
+		log.Printf("failed to get hiring story item %d\n", hsid)
 		return err
 	}
-	defer resp.Body.Close()
-
-	var hs struct {
-		Kids []uint64 `json:"kids"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&hs); err != nil {
-		log.Printf("failed to decode response for %s\n", itemPath)
+	if err := UpdateHiringStoryMetadata(hsid, hs.Score, hs.Descendants, len(hs.Kids), raw); err != nil {
 		return err
 	}
 
 	var savedIds = make(map[uint64]bool)
+	var refreshIds []uint64
 	rows, err := SelectHiringJobIds(int(hsid))
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var hnid uint64
 		if err := rows.Scan(&hnid); err != nil {
 			return err
 		}
 		savedIds[hnid] = true
+		refreshIds = append(refreshIds, hnid)
 	}
 
-	// Save new job posts
+	// items skipped by a previous sync are not fetched again
+	skippedIds, err := SelectSkippedItemIds(hsid)
+	if err != nil {
+		return err
+	}
+	for _, id := range skippedIds {
+		savedIds[id] = true
+	}
+
+	var newIds []uint64
 	for _, v := range hs.Kids {
-		if _, ok := savedIds[v]; ok {
+		if _, ok := savedIds[v]; !ok {
+			newIds = append(newIds, v)
+		}
+	}
+
+	// Job posts are fetched by a pool of workers and saved one at a
+	// time, since sqlite only allows a single writer.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for res := range fetchHnItems(ctx, newIds, cfg.SyncWorkers) {
+		if errors.Is(res.Err, errHnItemNotFound) {
+			// the api may not serve new items yet, they are fetched on the next sync
+			log.Printf("hiring job item %d not found, will retry on the next sync", res.Id)
 			continue
 		}
-		_, err := newHiringJob(uint64(hsid), v)
-		if err != nil {
+		if res.Err != nil {
+			log.Printf("failed to get hiring job item %d\n", res.Id)
+			return res.Err
+		}
+		if reason := res.Item.skipReason(hsid); reason != "" {
+			skipped := SkippedItem{HnId: res.Id, HiringStoryId: hsid, Type: res.Item.Type, Reason: reason}
+			if err := SaveSkippedItem(skipped); err != nil {
+				return err
+			}
+			log.Printf("skipped item %d of hiring story %d, %s", res.Id, hsid, reason)
+			continue
+		}
+		if err := saveHiringJob(hsid, res.Item); err != nil {
+			return err
+		}
+		log.Printf("added new hiring job %d", res.Id)
+	}
+
+	// Saved jobs are fetched again to keep up with the edits made to them
+	for res := range fetchHnItems(ctx, refreshIds, cfg.SyncWorkers) {
+		if errors.Is(res.Err, errHnItemNotFound) {
+			log.Printf("saved hiring job item %d not found, keeping it as stored", res.Id)
+			continue
+		}
+		if res.Err != nil {
+			log.Printf("failed to get hiring job item %d\n", res.Id)
+			return res.Err
+		}
+		if err := refreshHiringJob(res.Item); err != nil {
 			return err
 		}
-		log.Printf("added new hiring job %d", v)
 	}
 
 	return nil
@@ -135,23 +177,16 @@ func processJobPosts(hsid uint64) error {
 
 // syncData will fetch the latest who is hiring story
 // insert new jobs from that story into our database.
-func syncData() error {
+func syncData(ctx context.Context) error {
 	log.Println("starting data sync...")
 
 	type hnUserResp struct {
 		StoryIds []int `json:"submitted"`
 	}
 
-	resp, err := http.Get(hnApiBaseUri + "/user/whoishiring.json")
-	if err != nil {
-		log.Println("whoishiring.json request failed")
-		return err
-	}
-	defer resp.Body.Close()
-
 	var userResp hnUserResp
-	if err := json.NewDecoder(resp.Body).Decode(&userResp); err != nil {
-		log.Println("failed to decode whoishiring.json response")
+	if err := getHnJSON(ctx, "/user/whoishiring.json", &userResp); err != nil {
+		log.Println("whoishiring.json request failed")
 		return err
 	}
 
@@ -172,7 +207,16 @@ func syncData() error {
 	var hsid uint64
 	if idx == -1 {
 		log.Printf("expected story id %d not found in %v. will update...", hs.HnId, userStoryIds)
-		hsid, err = newHiringStory(userStoryIds)
+		// the previous story is synced one last time, for the posts made since
+		// the last sync. Stories missing it are reported by the check command.
+		if hs.HnId != 0 {
+			if err := processJobPosts(ctx, hs.HnId); err != nil {
+				log.Printf("failed the final sweep of hiring story %d. %v", hs.HnId, err)
+			} else {
+				bus.Publish(event{Topic: eventSyncCompleted, StoryId: hs.HnId})
+			}
+		}
+		hsid, err = newHiringStory(ctx, userStoryIds, storyKindHiring)
 		if err != nil {
 			log.Println("failed to create new hiring story")
 			return err
@@ -181,76 +225,761 @@ func syncData() error {
 		hsid = uint64(userStoryIds[idx])
 	}
 
-	return processJobPosts(hsid)
+	if err := fetchExchangeRates(ctx); err != nil {
+		log.Println("failed to fetch exchange rates, keeping the current ones.", err)
+	}
+	if err := processJobPosts(ctx, hsid); err != nil {
+		return err
+	}
+	if err := enrichStaleJobs(ctx, hsid); err != nil {
+		return err
+	}
+	if err := syncFreelancerStory(ctx, userStoryIds); err != nil {
+		log.Println("failed to sync freelancer story.", err)
+	}
+
+	bus.Publish(event{Topic: eventSyncCompleted, StoryId: hsid})
+	return nil
 }
 
-// paramValue will return a parsed string as uint64 or a default value
-func paramValue(v string, d uint64) uint64 {
-	if v == "" {
-		return d
+// renderJobBody will render the body of hj for r. Long bodies are truncated
+// unless the full param of r asks for this job to be expanded.
+func renderJobBody(r *http.Request, hj HiringJob, archived bool) jobBody {
+	limit := cfg.TruncateAt
+	if paramValue(r.URL.Query().Get("full"), 0) == hj.HnId {
+		limit = 0
+	}
+	body := jobBodyHTML(hj, archived, limit)
+	if body.Truncated {
+		q := r.URL.Query()
+		q.Set("full", strconv.FormatUint(hj.HnId, 10))
+		body.MoreUrl = fmt.Sprintf("%s?%s#job-%d", r.URL.Path, q.Encode(), hj.HnId)
+	}
+	return body
+}
+
+func indexHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
 	}
 
-	converted, err := strconv.ParseUint(v, 10, 64)
+	hs, err := GetCurrentHiringStory()
 	if err != nil {
-		return d
+		log.Println("failed to get current story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
 	}
+	log.Printf("found hiring story -- %s [%d]", hs.Title, hs.HnId)
 
-	return converted
+	if r.URL.RawQuery == "" && requestPrefs(w, r).Layout == layoutList {
+		http.Redirect(w, r, "/combined", http.StatusFound)
+		return
+	}
+	renderReader(w, r, hs, nil, "/", false)
 }
 
-func indexHandler(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/" {
+// storyHandler will serve the reader for any stored hiring story
+func storyHandler(w http.ResponseWriter, r *http.Request) {
+	hsId := paramValue(strings.TrimPrefix(r.URL.Path, "/story/"), 0)
+	hs, err := GetHiringStory(hsId)
+	if errors.Is(err, sql.ErrNoRows) {
 		http.NotFound(w, r)
 		return
 	}
+	if err != nil {
+		log.Println("failed to get story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
 
-	hs, err := GetLatestHiringStory()
+	latest, err := GetLatestHiringStory()
 	if err != nil {
 		log.Println("failed to get latest story.", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	log.Printf("found hiring story -- %s [%d]", hs.Title, hs.HnId)
 
-	after := paramValue(r.URL.Query().Get("after"), 0)
-	before := paramValue(r.URL.Query().Get("before"), 0)
-	var hj *HiringJob
-	if before > 0 {
-		hj, err = SelectPreviousHiringJob(hs.HnId, before)
-	} else {
-		hj, err = SelectNextHiringJob(hs.HnId, after)
+	renderReader(w, r, hs, nil, r.URL.Path, hs.HnId != latest.HnId)
+}
+
+// storiesHandler will list all stored hiring stories
+func storiesHandler(w http.ResponseWriter, r *http.Request) {
+	stories, err := SelectHiringStories(storyKindHiring)
+	if err != nil {
+		log.Println("failed to select stories.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Stories   []HiringStorySummary
+		Canonical string
+	}{
+		Stories:   stories,
+		Canonical: canonicalUrl("/stories"),
+	}
+	renderTemplate(w, "stories.html", data)
+}
+
+// jobHandler will serve the reader showing a single job, at its permalink
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/job/")
+	if id, ok := strings.CutSuffix(id, "/qr.png"); ok {
+		jobQrHandler(w, r, id)
+		return
+	}
+	hj, err := GetHiringJob(paramValue(id, 0))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Println("failed to get hiring job.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	hs, err := GetHiringStory(hj.HiringStoryId)
+	if err != nil {
+		log.Println("failed to get story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	latest, err := GetLatestHiringStory()
+	if err != nil {
+		log.Println("failed to get latest story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
 	}
+	current, err := GetCurrentHiringStory()
 	if err != nil {
-		log.Println("failed to select hiring job.", err)
+		log.Println("failed to get current story.", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	log.Printf("found hiring job [%d]", hj.HnId)
 
-	hj.Text = hj.transformedText()
+	basePath := "/"
+	if hs.HnId != current.HnId {
+		basePath = fmt.Sprintf("/story/%d", hs.HnId)
+	}
+	renderReader(w, r, hs, hj, basePath, hs.HnId != latest.HnId)
+}
+
+// renderReader will render the one job at a time reader of a story, showing
+// job or, when nil, the job selected by the cursor params.
+// Archived stories don't change anymore, so their job bodies are cached.
+func renderReader(w http.ResponseWriter, r *http.Request, hs *HiringStory, job *HiringJob, basePath string, archived bool) {
+	// invalid params are left out of the filter, so links still show jobs
+	filter, _ := parseFilterState(r.URL.Query())
+	filter = withSeenJobs(w, r, withSavedExclusions(w, r, withPreferences(w, r, filter)))
+	var notice string
+	hj := &HiringJob{}
+	var cursor HiringJob
+	var err error
+	if job == nil {
+		cursor, err = readerCursor(filter, hs.HnId)
+	}
+	if job != nil {
+		hj = job
+	} else if errors.Is(err, errInvalidCursor) {
+		notice = "This link points to a job that is not part of this story. It may be mistyped or from another story."
+		err = nil
+	} else if err != nil {
+		log.Println("failed to get cursor hiring job.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	} else if filter.Before > 0 {
+		hj, err = SelectPreviousHiringJob(hs.HnId, cursor, filter)
+	} else {
+		hj, err = SelectNextHiringJob(hs.HnId, cursor, filter)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		filterError(w, "failed to select hiring job.", err)
+		return
+	}
+	if hj.HnId > 0 {
+		log.Printf("found hiring job [%d]", hj.HnId)
+		recordJobView(hj.HnId)
+		// cached pages of archived stories are shared by every reader
+		if !archived || filter.Unseen {
+			recordSeenJobs(w, r, hj.HnId)
+		}
+	}
+
+	// the panels around the job are left out when slow, rather than failing the page
+	var duplicateIds []uint64
+	var evidence JobEvidence
+	var changes []JobChange
+	var languages []string
+	var company *Company
+	var ingestion StoryIngestion
+	meta := pageMeta{Title: hs.Title}
+	loaders := []panelLoader{
+		panel("languages", &languages, func() ([]string, error) { return SelectHiringJobLanguages(hs.HnId) }),
+		panel("stats", &meta, func() (pageMeta, error) { return storyMeta(hs) }),
+		panel("ingestion", &ingestion, func() (StoryIngestion, error) { return GetStoryIngestion(hs.HnId) }),
+	}
+	if hj.HnId > 0 {
+		hnId := hj.HnId
+		loaders = append(loaders,
+			panel("reposts", &duplicateIds, func() ([]uint64, error) { return SelectHiringJobDuplicateIds(hnId) }),
+			panel("evidence", &evidence, func() (JobEvidence, error) { return SelectJobEvidence(hnId) }),
+			panel("revisions", &changes, func() ([]JobChange, error) {
+				revs, err := SelectJobRevisions(hnId)
+				if err != nil {
+					return nil, err
+				}
+				return jobChanges(*hj, revs), nil
+			}),
+		)
+	}
+	if hj.CompanyId > 0 {
+		companyId := hj.CompanyId
+		loaders = append(loaders, panel("company", &company, func() (*Company, error) { return GetCompany(companyId) }))
+	}
+	loadPanels(r.Context(), loaders...)
+
+	dupesFilter := filter
+	dupesFilter.Duplicates = !filter.Duplicates
+	remoteFilter := filter
+	remoteFilter.Remote = !filter.Remote
+	salaryUnknownFilter := filter
+	salaryUnknownFilter.SalaryUnknown = !filter.SalaryUnknown
+	fuzzyFilter := filter
+	fuzzyFilter.Fuzzy = !filter.Fuzzy
+	unseenFilter := filter
+	unseenFilter.Unseen = !filter.Unseen
+	openFilter := filter
+	openFilter.Open = !filter.Open
+	ycFilter := filter
+	ycFilter.YC = !filter.YC
+	tagLinks := make([]filterLink, len(filter.Tags))
+	for i, t := range filter.Tags {
+		tagLinks[i] = filterLink{Label: t, Url: filter.withoutTag(t).cursorUrl(basePath, "", 0)}
+	}
+	data := struct {
+		Story     HiringStory
+		Layout    string
+		Ingestion StoryIngestion
+		Job       HiringJob
+		Body      jobBody
+		Archived  bool
+		Company   *Company
+		Evidence  JobEvidence
+		Reposts   []uint64
+		Changes   []JobChange
+		Filter    FilterState
+		BasePath  string
+		Canonical string
+		Meta      pageMeta
+		Notice    string
+		PrevUrl   string
+		NextUrl   string
+		ResetUrl  string
+		DupesUrl  string
+		RemoteUrl string
+		SalaryUrl string
+		FuzzyUrl  string
+		UnseenUrl string
+		OpenUrl   string
+		YCUrl     string
+		// FeedUrl is the atom feed of the jobs matching the filter
+		FeedUrl string
+		// SaveParams are the filter params a reader saves the search with
+		SaveParams string
+		TagLinks   []filterLink
+		Sorts      []sortLink
+		Levels     []string
+		Types      []string
+		Visas      []string
+		Fundings   []string
+		Sizes      []string
+		Benefits   []string
+		Interviews []string
+		Salaries   []salaryOption
+		Posted     []postedOption
+		Timezones  []timezoneOption
+		Langs      []string
+	}{
+		Story:      *hs,
+		Layout:     experimentVariant(w, r, "reader_layout"),
+		Ingestion:  ingestion,
+		Job:        *hj,
+		Body:       renderJobBody(r, *hj, archived),
+		Archived:   archived,
+		Company:    company,
+		Evidence:   evidence,
+		Reposts:    duplicateIds,
+		Changes:    changes,
+		Filter:     filter,
+		BasePath:   basePath,
+		Canonical:  canonicalUrl(basePath),
+		Meta:       meta,
+		Notice:     notice,
+		PrevUrl:    filter.cursorUrl(basePath, "before", hj.HnId),
+		NextUrl:    filter.cursorUrl(basePath, "after", hj.HnId),
+		ResetUrl:   filter.cursorUrl(basePath, "", 0),
+		DupesUrl:   dupesFilter.cursorUrl(basePath, "", 0),
+		RemoteUrl:  remoteFilter.cursorUrl(basePath, "", 0),
+		SalaryUrl:  salaryUnknownFilter.cursorUrl(basePath, "", 0),
+		FuzzyUrl:   fuzzyFilter.cursorUrl(basePath, "", 0),
+		UnseenUrl:  unseenFilter.cursorUrl(basePath, "", 0),
+		OpenUrl:    openFilter.cursorUrl(basePath, "", 0),
+		YCUrl:      ycFilter.cursorUrl(basePath, "", 0),
+		FeedUrl:    filter.cursorUrl("/feed.atom", "", 0),
+		SaveParams: filter.cursorParams(),
+		TagLinks:   tagLinks,
+		Sorts:      filter.sortLinks(basePath),
+		Levels:     jobLevels,
+		Types:      employmentTypes,
+		Visas:      visaOptions,
+		Fundings:   fundingStages,
+		Sizes:      companySizes,
+		Benefits:   benefits,
+		Interviews: interviewFlags,
+		Salaries:   salaryFilters,
+		Posted:     postedFilters,
+		Timezones:  timezoneOptions,
+		Langs:      languages,
+	}
+	if hj.HnId > 0 {
+		data.Canonical = canonicalUrl(fmt.Sprintf("/job/%d", hj.HnId))
+	}
+	if notice != "" {
+		renderTemplateStatus(w, http.StatusNotFound, "base.html", data)
+		return
+	}
+	if hj.HnId > 0 {
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="prev"`, data.PrevUrl))
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, data.NextUrl))
+	}
+	if archived && hj.HnId > 0 && !filter.Unseen {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", archiveMaxAge))
+	}
+	renderTemplate(w, "base.html", data)
+}
+
+// errInvalidCursor is returned for reader cursors that are not jobs of the story
+var errInvalidCursor = errors.New("cursor is not a job of the story")
+
+// readerCursor will return the job the after or before cursor of a reader
+// points to, or an empty job when neither is set. Cursors are job ids, so
+// links to a job stay valid as new jobs are added.
+func readerCursor(f FilterState, hsId uint64) (HiringJob, error) {
+	id := f.After
+	if f.Before > 0 {
+		id = f.Before
+	}
+	if id == 0 {
+		return HiringJob{}, nil
+	}
+
+	hj, err := GetHiringJob(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && hj.HiringStoryId != hsId) {
+		return HiringJob{}, errInvalidCursor
+	}
+	if err != nil {
+		return HiringJob{}, err
+	}
+	return *hj, nil
+}
+
+// jobListEntry is a listed job along with its rendered body
+type jobListEntry struct {
+	HiringJobListItem
+	Content jobBody
+	// Snippet is the text around the matched terms of a search, shown by
+	// search results instead of the content
+	Snippet template.HTML
+	// Source is the thread of the post, shown by listings merging threads
+	Source string
+}
+
+func domainHandler(w http.ResponseWriter, r *http.Request) {
+	domain := normalizeDomain(strings.TrimPrefix(r.URL.Path, "/domain/"))
+	if domain == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	filter, _ := parseFilterState(r.URL.Query())
+	filter = withSavedExclusions(w, r, withPreferences(w, r, filter))
+	page, err := selectJobPage(jobScope{Domain: domain}, filter)
+	if err != nil {
+		filterError(w, "failed to select hiring jobs by domain.", err)
+		return
+	}
+	entries := make([]jobListEntry, len(page.Jobs))
+	for i, hj := range page.Jobs {
+		entries[i] = jobListEntry{HiringJobListItem: hj, Content: renderJobBody(r, hj.HiringJob, false)}
+	}
+
 	data := struct {
-		Story HiringStory
-		Job   HiringJob
+		Title     string
+		Jobs      []jobListEntry
+		Canonical string
+		Profile   string
+		Summary   string
+		Path      string
+		Filter    FilterState
+		Sorts     []sortLink
+		AllUrl    string
+		RemoteUrl string
+		DupesUrl  string
+		PrevUrl   string
+		NextUrl   string
 	}{
-		Story: *hs,
-		Job:   *hj,
+		Title:     domain,
+		Jobs:      entries,
+		Canonical: canonicalUrl("/domain/" + domain),
+		Path:      "/domain/" + domain,
+		Filter:    filter,
+	}
+	data.Sorts = filter.sortLinks(data.Path)
+	data.AllUrl, data.RemoteUrl, data.DupesUrl = listToggleUrls(data.Path, filter)
+	data.PrevUrl, data.NextUrl = page.pageUrls(data.Path, filter, nil)
+	setLinkHeaders(w, data.PrevUrl, data.NextUrl)
+	renderTemplate(w, "list.html", data)
+}
+
+// ycHandler will list the jobs of the companies of a YC batch across the
+// months, at /yc/<batch> like /yc/s24, and of every YC backed company at /yc
+func ycHandler(w http.ResponseWriter, r *http.Request) {
+	slug := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/yc"), "/")
+	batch := ycBatchFromSlug(slug)
+	if slug != "" && batch == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	filter, _ := parseFilterState(r.URL.Query())
+	filter = withSavedExclusions(w, r, withPreferences(w, r, filter))
+	title, path := "YC companies", "/yc"
+	if batch == "" {
+		filter.YC = true
+	} else {
+		title, path = batch+" companies", "/yc/"+ycBatchSlug(batch)
+	}
+	page, err := selectJobPage(jobScope{YCBatch: batch}, filter)
+	if err != nil {
+		filterError(w, "failed to select hiring jobs by yc batch.", err)
+		return
+	}
+	entries := make([]jobListEntry, len(page.Jobs))
+	for i, hj := range page.Jobs {
+		entries[i] = jobListEntry{HiringJobListItem: hj, Content: renderJobBody(r, hj.HiringJob, false)}
+	}
+
+	data := struct {
+		Title     string
+		Jobs      []jobListEntry
+		Canonical string
+		Profile   string
+		Summary   string
+		Path      string
+		Filter    FilterState
+		Sorts     []sortLink
+		AllUrl    string
+		RemoteUrl string
+		DupesUrl  string
+		PrevUrl   string
+		NextUrl   string
+	}{
+		Title:     title,
+		Jobs:      entries,
+		Canonical: canonicalUrl(path),
+		Summary:   countNoun(page.Total, "job", "jobs"),
+		Path:      path,
+		Filter:    filter,
+	}
+	// the listing of every batch keeps its path rather than a yc param
+	filter.YC = false
+	data.Sorts = filter.sortLinks(data.Path)
+	data.AllUrl, data.RemoteUrl, data.DupesUrl = listToggleUrls(data.Path, filter)
+	data.PrevUrl, data.NextUrl = page.pageUrls(data.Path, filter, nil)
+	setLinkHeaders(w, data.PrevUrl, data.NextUrl)
+	renderTemplate(w, "list.html", data)
+}
+
+// listToggleUrls will return the urls of a listing at path with the filter
+// showing all jobs, only the remote ones, and with reposts toggled
+func listToggleUrls(path string, f FilterState) (string, string, string) {
+	dupes := f
+	dupes.Duplicates = !f.Duplicates
+	f.Remote = false
+	all := f.cursorUrl(path, "", 0)
+	f.Remote = true
+	return all, f.cursorUrl(path, "", 0), dupes.cursorUrl(path, "", 0)
+}
+
+// countNoun will format a count along with the singular or plural noun
+func countNoun(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// posterPattern matches hacker news usernames
+var posterPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// companyHandler will list every post of a company across the months, under
+// any of its names, so its hiring history is seen at a glance. Companies
+// reached through an alias redirect to their slug.
+func companyHandler(w http.ResponseWriter, r *http.Request) {
+	slug := companyKey(strings.TrimPrefix(r.URL.Path, "/company/"))
+	if slug == "" {
+		http.NotFound(w, r)
+		return
+	}
+	company, err := GetCompanyBySlug(slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
 	}
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	tmpl := template.Must(template.ParseFiles("templates/base.html"))
-	if err := tmpl.Execute(w, data); err != nil {
-		log.Println("failed to execute to templates", err)
+	if err != nil {
+		log.Println("failed to get company.", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	path := "/company/" + company.Slug
+	if r.URL.Path != path {
+		http.Redirect(w, r, (&url.URL{Path: path, RawQuery: r.URL.RawQuery}).String(), http.StatusMovedPermanently)
+		return
+	}
+
+	filter, _ := parseFilterState(r.URL.Query())
+	filter = withSavedExclusions(w, r, withPreferences(w, r, filter))
+	page, err := selectJobPage(jobScope{CompanyId: company.Id}, filter)
+	if err != nil {
+		filterError(w, "failed to select hiring jobs by company.", err)
+		return
+	}
+	entries := make([]jobListEntry, len(page.Jobs))
+	for i, hj := range page.Jobs {
+		entries[i] = jobListEntry{HiringJobListItem: hj, Content: renderJobBody(r, hj.HiringJob, false)}
+	}
+	// the months and first post are of every job, past the page
+	var jobs []HiringJob
+	if err := selectJobFields(&jobs, jobScope{CompanyId: company.Id}, filter, "hj.hiring_story_id, hj.time"); err != nil {
+		filterError(w, "failed to select hiring job months by company.", err)
+		return
+	}
+	months := map[uint64]bool{}
+	var first uint64
+	for _, hj := range jobs {
+		months[hj.HiringStoryId] = true
+		if first == 0 || hj.Time < first {
+			first = hj.Time
+		}
+	}
+	summary := fmt.Sprintf("%s in %s", countNoun(page.Total, "post", "posts"), countNoun(len(months), "month", "months"))
+	if first > 0 {
+		summary += ", first in " + time.Unix(int64(first), 0).UTC().Format("January 2006")
+	}
+
+	data := struct {
+		Title     string
+		Jobs      []jobListEntry
+		Canonical string
+		Profile   string
+		Summary   string
+		Path      string
+		Filter    FilterState
+		Sorts     []sortLink
+		AllUrl    string
+		RemoteUrl string
+		DupesUrl  string
+		PrevUrl   string
+		NextUrl   string
+	}{
+		Title:     company.Name,
+		Jobs:      entries,
+		Canonical: canonicalUrl(path),
+		Summary:   summary,
+		Path:      path,
+		Filter:    filter,
+	}
+	data.Sorts = filter.sortLinks(data.Path)
+	data.AllUrl, data.RemoteUrl, data.DupesUrl = listToggleUrls(data.Path, filter)
+	data.PrevUrl, data.NextUrl = page.pageUrls(data.Path, filter, nil)
+	setLinkHeaders(w, data.PrevUrl, data.NextUrl)
+	renderTemplate(w, "list.html", data)
+}
+
+// posterHandler will list every post of a hacker news account, along with the
+// number of companies it posted for, which tells recruiters from founders
+func posterHandler(w http.ResponseWriter, r *http.Request) {
+	poster := strings.TrimPrefix(r.URL.Path, "/poster/")
+	if !posterPattern.MatchString(poster) {
+		http.NotFound(w, r)
+		return
+	}
+
+	filter, _ := parseFilterState(r.URL.Query())
+	filter = withSavedExclusions(w, r, withPreferences(w, r, filter))
+	page, err := selectJobPage(jobScope{Poster: poster}, filter)
+	if err != nil {
+		filterError(w, "failed to select hiring jobs by poster.", err)
+		return
+	}
+	entries := make([]jobListEntry, len(page.Jobs))
+	for i, hj := range page.Jobs {
+		entries[i] = jobListEntry{HiringJobListItem: hj, Content: renderJobBody(r, hj.HiringJob, false)}
+	}
+	// the companies are of every job, past the page. The text is only needed
+	// for the headlines of jobs without a detected company.
+	var jobs []HiringJob
+	columns := "hj.company_id, CASE WHEN hj.company_id = 0 THEN hj.text ELSE '' END AS text"
+	if err := selectJobFields(&jobs, jobScope{Poster: poster}, filter, columns); err != nil {
+		filterError(w, "failed to select hiring job companies by poster.", err)
+		return
+	}
+	companies := map[string]bool{}
+	for _, hj := range jobs {
+		if hj.CompanyId > 0 {
+			companies[strconv.FormatUint(hj.CompanyId, 10)] = true
+		} else if key := companyKey(parseJobHeadline(hj.Text).Company); key != "" {
+			companies[key] = true
+		}
+	}
+
+	data := struct {
+		Title     string
+		Jobs      []jobListEntry
+		Canonical string
+		Profile   string
+		Summary   string
+		Path      string
+		Filter    FilterState
+		Sorts     []sortLink
+		AllUrl    string
+		RemoteUrl string
+		DupesUrl  string
+		PrevUrl   string
+		NextUrl   string
+	}{
+		Title:     poster,
+		Jobs:      entries,
+		Canonical: canonicalUrl("/poster/" + poster),
+		Profile:   hnUserUrl + url.QueryEscape(poster),
+		Summary:   fmt.Sprintf("%s for %s", countNoun(page.Total, "post", "posts"), countNoun(len(companies), "company", "companies")),
+		Path:      "/poster/" + poster,
+		Filter:    filter,
+	}
+	data.Sorts = filter.sortLinks(data.Path)
+	data.AllUrl, data.RemoteUrl, data.DupesUrl = listToggleUrls(data.Path, filter)
+	data.PrevUrl, data.NextUrl = page.pageUrls(data.Path, filter, nil)
+	setLinkHeaders(w, data.PrevUrl, data.NextUrl)
+	renderTemplate(w, "list.html", data)
 }
 
 func main() {
-	if err := syncData(); err != nil {
+	if len(os.Args) > 1 && os.Args[1] == "reprocess" {
+		if err := reprocessCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		if err := reportCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		if err := checkCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		if err := reindexCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tui" {
+		if err := tuiCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := ensureSearchIndex(); err != nil {
+		log.Fatal(err)
+	}
+	if err := syncData(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if err := resumeTagRules(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshStoryAggregates(false); err != nil {
+		log.Fatal(err)
+	}
+	if err := startScheduler(context.Background()); err != nil {
 		log.Fatal(err)
 	}
 
-	http.HandleFunc("/", indexHandler)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", indexHandler)
+	mux.HandleFunc("/stories", storiesHandler)
+	mux.HandleFunc("/trends", trendsHandler)
+	mux.HandleFunc("/story/", pageCache.wrap(storyHandler))
+	mux.HandleFunc("/job/", pageCache.wrap(jobHandler))
+	mux.HandleFunc("/domain/", domainHandler)
+	mux.HandleFunc("/poster/", posterHandler)
+	mux.HandleFunc("/company/", companyHandler)
+	mux.HandleFunc("/yc", ycHandler)
+	mux.HandleFunc("/yc/", ycHandler)
+	mux.HandleFunc("/search", searchHandler)
+	mux.HandleFunc("/combined", combinedHandler)
+	mux.HandleFunc("/plain", plainHandler)
+	mux.HandleFunc("/plain/job/", plainJobHandler)
+	mux.HandleFunc("/whatsnew", whatsNewHandler)
+	mux.HandleFunc("/exclude", excludeHandler)
+	mux.HandleFunc("/saved", savedSearchesHandler)
+	mux.HandleFunc("/settings", settingsHandler)
+	mux.HandleFunc("/feed.rss", rssFeedHandler)
+	mux.HandleFunc("/feed.atom", atomFeedHandler)
+	mux.HandleFunc(embedPathPrefix, embedJobsHandler)
+	mux.HandleFunc("/sitemap.xml", sitemapIndexHandler)
+	mux.HandleFunc("/sitemaps/", storySitemapHandler)
+	mux.HandleFunc("/og/", pageCache.wrap(ogCardHandler))
+	mux.HandleFunc(mapPath, mapHandler)
+	mux.HandleFunc(apiStoriesPath, pageCache.wrap(storyJobsGeoJSONHandler))
+	mux.HandleFunc("/api/companies", companiesApiHandler)
+	mux.HandleFunc("/api/tags", tagsApiHandler)
+	mux.HandleFunc("/api/suggest", suggestApiHandler)
+	mux.HandleFunc("/api/search", searchApiHandler)
+	mux.HandleFunc(apiJobsPath, jobProvenanceApiHandler)
+	mux.HandleFunc(apiV1Path, pageCache.wrap(apiV1Handler))
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	mux.HandleFunc("/admin/audit", requireAdminOrSigned(auditLogHandler))
+	mux.HandleFunc("/admin/sign", requireAdmin(signHandler))
+	mux.HandleFunc("/admin/export/", requireAdminOrSigned(exportJobsHandler))
+	mux.HandleFunc("/export/sqlite", requireAdminOrSigned(sqliteSnapshotHandler))
+	mux.HandleFunc("/admin/job/", requireAdmin(jobDebugHandler))
+	mux.HandleFunc("/admin/companies", requireAdmin(companyMergeHandler))
+	mux.HandleFunc("/admin/searches", requireAdmin(searchMissesHandler))
+	mux.HandleFunc("/admin/tags", requireAdmin(tagRulesHandler))
+	mux.HandleFunc("/admin/review", requireAdmin(reviewQueueHandler))
+	mux.HandleFunc("/admin/consistency", requireAdmin(consistencyHandler))
+	mux.HandleFunc("/admin/stories", requireAdmin(storyPinHandler))
+	mux.HandleFunc("/admin/sql", requireAdmin(sqlPlaygroundHandler))
+	mux.HandleFunc("/admin/metrics", requireAdmin(expvar.Handler().ServeHTTP))
+
+	if cfg.GeminiAddr != "" {
+		go func() {
+			// the site keeps being served over http when gemini fails
+			if err := serveGemini(cfg.GeminiAddr); err != nil {
+				log.Println("gemini server stopped.", err)
+			}
+		}()
+	}
 
 	fmt.Println("Listening on http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Fatal(http.ListenAndServe(":8080", securityHeaders(canonicalUrls(experimentsMiddleware(excludeMiddleware(prefsMiddleware(mux)))))))
 }
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	// mapPath is the route of the jobs map, which loads its jobs from storyJobsGeoJSONUrl
+	mapPath = "/map"
+	// apiStoriesPath is the route prefix of the story api
+	apiStoriesPath = "/api/stories/"
+	// mapStyleSrc is the CSP source of the map library stylesheet
+	mapStyleSrc = "https://unpkg.com"
+)
+
+// geoJSONCollection is a GeoJSON FeatureCollection
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// geoJSONFeature is a GeoJSON Point Feature for a job
+type geoJSONFeature struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties geoJSONJob `json:"properties"`
+}
+
+// geoJSONJob are the properties of a job feature
+type geoJSONJob struct {
+	Id       uint64 `json:"id"`
+	Company  string `json:"company"`
+	Role     string `json:"role"`
+	Location string `json:"location"`
+	Remote   string `json:"remote"`
+	Link     string `json:"link"`
+}
+
+// storyJobsGeoJSONUrl will return the api url of the geocoded jobs of a story
+func storyJobsGeoJSONUrl(hsId uint64) string {
+	return fmt.Sprintf("%s%d/jobs.geojson", apiStoriesPath, hsId)
+}
+
+// tilesOrigin will return the CSP source of a tile url template, with
+// subdomain placeholders like {s} allowed as any subdomain
+func tilesOrigin(tilesUrl string) string {
+	scheme, rest, ok := strings.Cut(tilesUrl, "://")
+	if !ok {
+		return "'none'"
+	}
+	host, _, _ := strings.Cut(rest, "/")
+	if strings.HasPrefix(host, "{") {
+		if _, domain, ok := strings.Cut(host, "."); ok {
+			host = "*." + domain
+		}
+	}
+	return scheme + "://" + host
+}
+
+// mapStory will return the story picked by the story param, defaulting to the
+// current one served on /, and whether it is archived, read outside of /
+func mapStory(r *http.Request) (*HiringStory, bool, error) {
+	current, err := GetCurrentHiringStory()
+	if err != nil {
+		return nil, false, err
+	}
+	if !r.URL.Query().Has("story") {
+		return current, false, nil
+	}
+	hs, err := GetHiringStory(paramValue(r.URL.Query().Get("story"), 0))
+	if err != nil {
+		return nil, false, err
+	}
+	return hs, hs.HnId != current.HnId, nil
+}
+
+// mapHandler will serve the map of the on-site jobs of a story
+func mapHandler(w http.ResponseWriter, r *http.Request) {
+	hs, archived, err := mapStory(r)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Println("failed to get story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	filter, _ := parseFilterState(r.URL.Query())
+	dataUrl := filter.cursorUrl(storyJobsGeoJSONUrl(hs.HnId), "", 0)
+	readerUrl := "/"
+	if archived {
+		readerUrl = fmt.Sprintf("/story/%d", hs.HnId)
+	}
+
+	data := struct {
+		Story       HiringStory
+		Canonical   string
+		ReaderUrl   string
+		DataUrl     string
+		TilesUrl    string
+		Attribution string
+		Nonce       string
+	}{
+		Story:       *hs,
+		Canonical:   canonicalUrl(r.URL.RequestURI()),
+		ReaderUrl:   readerUrl,
+		DataUrl:     dataUrl,
+		TilesUrl:    cfg.MapTilesUrl,
+		Attribution: cfg.MapAttribution,
+		Nonce:       cspNonce(r),
+	}
+	renderTemplate(w, "map.html", data)
+}
+
+// storyJobsGeoJSONHandler will serve the geocoded live jobs of a story as
+// GeoJSON, narrowed down by the reader filter params
+func storyJobsGeoJSONHandler(w http.ResponseWriter, r *http.Request) {
+	id, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, apiStoriesPath), "/")
+	if name != "jobs.geojson" {
+		http.NotFound(w, r)
+		return
+	}
+	filter, err := parseFilterState(r.URL.Query())
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid query params", err)
+		return
+	}
+	filter = withSavedExclusions(w, r, filter)
+	hs, err := GetHiringStory(paramValue(id, 0))
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(w, http.StatusNotFound, "story not found", nil)
+		return
+	}
+	if err != nil {
+		log.Println("failed to get story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	latest, err := GetLatestHiringStory()
+	if err != nil {
+		log.Println("failed to get latest story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	jobs, err := SelectGeocodedHiringJobs(hs.HnId, filter)
+	if err != nil {
+		filterError(w, "failed to select geocoded hiring jobs.", err)
+		return
+	}
+
+	fc := geoJSONCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, len(jobs))}
+	for i, hj := range jobs {
+		h := parseJobHeadline(hj.Text)
+		f := &fc.Features[i]
+		f.Type = "Feature"
+		f.Geometry.Type = "Point"
+		f.Geometry.Coordinates = [2]float64{hj.Longitude, hj.Latitude}
+		f.Properties = geoJSONJob{
+			Id:       hj.HnId,
+			Company:  h.Company,
+			Role:     h.Role,
+			Location: hj.Location,
+			Remote:   hj.Remote,
+			Link:     canonicalUrl(fmt.Sprintf("/job/%d", hj.HnId)),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if hs.HnId != latest.HnId {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", archiveMaxAge))
+	}
+	if err := json.NewEncoder(w).Encode(fc); err != nil {
+		log.Println("failed to encode story jobs.", err)
+	}
+}
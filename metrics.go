@@ -0,0 +1,16 @@
+package main
+
+import "expvar"
+
+// Metrics are published with expvar and served on /admin/metrics
+var (
+	templateRenderFailures = expvar.NewMap("template_render_failures")
+	// panelFailures counts the page panels left out, by panel name
+	panelFailures = expvar.NewMap("panel_failures")
+)
+
+func init() {
+	// the consistency checker runs in another process, so its issues are
+	// read from the database when the metrics are served
+	expvar.Publish("consistency_issues", expvar.Func(consistencyMetrics))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type ctxKey int
+
+const (
+	cspNonceKey ctxKey = iota
+)
+
+// embedPathPrefix is the route prefix for pages meant to be framed by other sites
+const embedPathPrefix = "/embed/"
+
+// cspNonce will return the script nonce generated for the request
+func cspNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(cspNonceKey).(string)
+	return nonce
+}
+
+// newNonce will return a random base64 encoded value
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// contentSecurityPolicy will build the CSP header value for a request path
+func contentSecurityPolicy(path, nonce string) string {
+	frameAncestors := cfg.FrameAncestors
+	if strings.HasPrefix(path, embedPathPrefix) {
+		frameAncestors = cfg.EmbedFrameAncestors
+	}
+
+	styleSrc, imgSrc := cfg.CSPStyleSrc, cfg.CSPImgSrc
+	if path == mapPath {
+		styleSrc += " " + mapStyleSrc
+		imgSrc += " " + tilesOrigin(cfg.MapTilesUrl)
+	}
+
+	directives := []string{
+		"default-src 'self'",
+		fmt.Sprintf("script-src 'self' 'nonce-%s' %s", nonce, cfg.CSPScriptSrc),
+		fmt.Sprintf("style-src 'self' %s", styleSrc),
+		fmt.Sprintf("img-src 'self' %s", imgSrc),
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors " + frameAncestors,
+	}
+	return strings.Join(directives, "; ")
+}
+
+// securityHeaders will set the CSP and other security related headers
+// and make a script nonce available to handlers through cspNonce.
+func securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce, err := newNonce()
+		if err != nil {
+			log.Println("failed to generate csp nonce.", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Security-Policy", contentSecurityPolicy(r.URL.Path, nonce))
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", cfg.ReferrerPolicy)
+		if !strings.HasPrefix(r.URL.Path, embedPathPrefix) {
+			h.Set("X-Frame-Options", "DENY")
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), cspNonceKey, nonce)))
+	})
+}
+
+// multiValueParams are the query params that may be repeated with different values
+var multiValueParams = map[string]bool{"benefit": true, "interview": true, "tag": true}
+
+// canonicalQuery will normalize a query: params are sorted, empty and
+// repeated values are dropped, and only multi value params keep more than
+// one value.
+func canonicalQuery(q url.Values) string {
+	nq := url.Values{}
+	for k, vs := range q {
+		for _, v := range vs {
+			if v == "" || getIndex(nq[k], v) != -1 || (len(nq[k]) > 0 && !multiValueParams[k]) {
+				continue
+			}
+			nq.Add(k, v)
+		}
+	}
+	return nq.Encode()
+}
+
+// canonicalUrls will permanently redirect public page requests to their
+// canonical url, without a trailing slash and with a normalized query, so
+// pages are not indexed under several equivalent urls.
+func canonicalUrls(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || strings.HasPrefix(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		path := r.URL.Path
+		if len(path) > 1 {
+			path = strings.TrimRight(path, "/")
+			if path == "" {
+				path = "/"
+			}
+		}
+		query := canonicalQuery(r.URL.Query())
+		if path == r.URL.Path && query == r.URL.RawQuery {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		u := url.URL{Path: path, RawQuery: query}
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+	})
+}
+
+// canonicalUrl will return the absolute url of a page path
+func canonicalUrl(path string) string {
+	return cfg.PublicBaseUrl + path
+}
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE hiring_job ADD COLUMN apply_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN apply_url TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE hiring_job DROP COLUMN apply_url;
+ALTER TABLE hiring_job DROP COLUMN apply_email;
+-- +goose StatementEnd
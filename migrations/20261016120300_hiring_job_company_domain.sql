@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE hiring_job ADD COLUMN company_domain TEXT NOT NULL DEFAULT '';
+CREATE INDEX hiring_job_company_domain_idx ON hiring_job (company_domain);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX hiring_job_company_domain_idx;
+ALTER TABLE hiring_job DROP COLUMN company_domain;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE companies (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    domain TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX companies_domain_idx ON companies (domain);
+ALTER TABLE hiring_job ADD COLUMN company_id INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX hiring_job_company_id_idx ON hiring_job (company_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX hiring_job_company_id_idx;
+ALTER TABLE hiring_job DROP COLUMN company_id;
+DROP TABLE companies;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE hiring_job ADD COLUMN simhash INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_job ADD COLUMN duplicate_of INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX hiring_job_duplicate_of_idx ON hiring_job (duplicate_of);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX hiring_job_duplicate_of_idx;
+ALTER TABLE hiring_job DROP COLUMN duplicate_of;
+ALTER TABLE hiring_job DROP COLUMN simhash;
+-- +goose StatementEnd
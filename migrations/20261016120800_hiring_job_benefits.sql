@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE hiring_job ADD COLUMN equity TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN benefits TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE hiring_job DROP COLUMN benefits;
+ALTER TABLE hiring_job DROP COLUMN equity;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE hiring_job ADD COLUMN salary_min INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_job ADD COLUMN salary_max INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_job ADD COLUMN salary_currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN location TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN remote TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN enrichers TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE hiring_job DROP COLUMN enrichers;
+ALTER TABLE hiring_job DROP COLUMN remote;
+ALTER TABLE hiring_job DROP COLUMN location;
+ALTER TABLE hiring_job DROP COLUMN tags;
+ALTER TABLE hiring_job DROP COLUMN salary_currency;
+ALTER TABLE hiring_job DROP COLUMN salary_max;
+ALTER TABLE hiring_job DROP COLUMN salary_min;
+-- +goose StatementEnd
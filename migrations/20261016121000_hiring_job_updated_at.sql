@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE hiring_job ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+UPDATE hiring_job SET updated_at = time;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE hiring_job DROP COLUMN updated_at;
+-- +goose StatementEnd
@@ -0,0 +1,126 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE job_attribute (
+    hn_id INTEGER NOT NULL PRIMARY KEY,
+    level TEXT NOT NULL DEFAULT '',
+    apply_email TEXT NOT NULL DEFAULT '',
+    apply_url TEXT NOT NULL DEFAULT '',
+    company_domain TEXT NOT NULL DEFAULT '',
+    company_id INTEGER NOT NULL DEFAULT 0,
+    simhash INTEGER NOT NULL DEFAULT 0,
+    duplicate_of INTEGER NOT NULL DEFAULT 0,
+    language TEXT NOT NULL DEFAULT '',
+    employment_type TEXT NOT NULL DEFAULT '',
+    equity TEXT NOT NULL DEFAULT '',
+    benefits TEXT NOT NULL DEFAULT '',
+    salary_min INTEGER NOT NULL DEFAULT 0,
+    salary_max INTEGER NOT NULL DEFAULT 0,
+    salary_currency TEXT NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '',
+    location TEXT NOT NULL DEFAULT '',
+    remote TEXT NOT NULL DEFAULT '',
+    enrichers TEXT NOT NULL DEFAULT '',
+    updated_at INTEGER NOT NULL DEFAULT 0,
+    parser_version INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO job_attribute (hn_id, level, apply_email, apply_url, company_domain, company_id, simhash, duplicate_of, language, employment_type, equity, benefits, salary_min, salary_max, salary_currency, tags, location, remote, enrichers, updated_at)
+SELECT hn_id, level, apply_email, apply_url, company_domain, company_id, simhash, duplicate_of, language, employment_type, equity, benefits, salary_min, salary_max, salary_currency, tags, location, remote, enrichers, updated_at FROM hiring_job;
+DROP INDEX hiring_job_company_domain_idx;
+DROP INDEX hiring_job_company_id_idx;
+DROP INDEX hiring_job_duplicate_of_idx;
+ALTER TABLE hiring_job DROP COLUMN level;
+ALTER TABLE hiring_job DROP COLUMN apply_email;
+ALTER TABLE hiring_job DROP COLUMN apply_url;
+ALTER TABLE hiring_job DROP COLUMN company_domain;
+ALTER TABLE hiring_job DROP COLUMN company_id;
+ALTER TABLE hiring_job DROP COLUMN simhash;
+ALTER TABLE hiring_job DROP COLUMN duplicate_of;
+ALTER TABLE hiring_job DROP COLUMN language;
+ALTER TABLE hiring_job DROP COLUMN employment_type;
+ALTER TABLE hiring_job DROP COLUMN equity;
+ALTER TABLE hiring_job DROP COLUMN benefits;
+ALTER TABLE hiring_job DROP COLUMN salary_min;
+ALTER TABLE hiring_job DROP COLUMN salary_max;
+ALTER TABLE hiring_job DROP COLUMN salary_currency;
+ALTER TABLE hiring_job DROP COLUMN tags;
+ALTER TABLE hiring_job DROP COLUMN location;
+ALTER TABLE hiring_job DROP COLUMN remote;
+ALTER TABLE hiring_job DROP COLUMN enrichers;
+ALTER TABLE hiring_job DROP COLUMN updated_at;
+CREATE INDEX job_attribute_company_domain_idx ON job_attribute (company_domain);
+CREATE INDEX job_attribute_company_id_idx ON job_attribute (company_id);
+CREATE INDEX job_attribute_duplicate_of_idx ON job_attribute (duplicate_of);
+CREATE VIEW hiring_job_view AS
+SELECT hj.hn_id, hj.hiring_story_id, hj.text, hj.time, hj.status,
+    COALESCE(ja.level, '') AS level,
+    COALESCE(ja.apply_email, '') AS apply_email,
+    COALESCE(ja.apply_url, '') AS apply_url,
+    COALESCE(ja.company_domain, '') AS company_domain,
+    COALESCE(ja.company_id, 0) AS company_id,
+    COALESCE(ja.simhash, 0) AS simhash,
+    COALESCE(ja.duplicate_of, 0) AS duplicate_of,
+    COALESCE(ja.language, '') AS language,
+    COALESCE(ja.employment_type, '') AS employment_type,
+    COALESCE(ja.equity, '') AS equity,
+    COALESCE(ja.benefits, '') AS benefits,
+    COALESCE(ja.salary_min, 0) AS salary_min,
+    COALESCE(ja.salary_max, 0) AS salary_max,
+    COALESCE(ja.salary_currency, '') AS salary_currency,
+    COALESCE(ja.tags, '') AS tags,
+    COALESCE(ja.location, '') AS location,
+    COALESCE(ja.remote, '') AS remote,
+    COALESCE(ja.enrichers, '') AS enrichers,
+    COALESCE(ja.updated_at, 0) AS updated_at,
+    COALESCE(ja.parser_version, 0) AS parser_version
+FROM hiring_job hj
+LEFT JOIN job_attribute ja ON ja.hn_id = hj.hn_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW hiring_job_view;
+ALTER TABLE hiring_job ADD COLUMN level TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN apply_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN apply_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN company_domain TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN company_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_job ADD COLUMN simhash INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_job ADD COLUMN duplicate_of INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_job ADD COLUMN language TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN employment_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN equity TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN benefits TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN salary_min INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_job ADD COLUMN salary_max INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_job ADD COLUMN salary_currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN location TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN remote TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN enrichers TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_job ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+UPDATE hiring_job SET
+    level = (SELECT ja.level FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    apply_email = (SELECT ja.apply_email FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    apply_url = (SELECT ja.apply_url FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    company_domain = (SELECT ja.company_domain FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    company_id = (SELECT ja.company_id FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    simhash = (SELECT ja.simhash FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    duplicate_of = (SELECT ja.duplicate_of FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    language = (SELECT ja.language FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    employment_type = (SELECT ja.employment_type FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    equity = (SELECT ja.equity FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    benefits = (SELECT ja.benefits FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    salary_min = (SELECT ja.salary_min FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    salary_max = (SELECT ja.salary_max FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    salary_currency = (SELECT ja.salary_currency FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    tags = (SELECT ja.tags FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    location = (SELECT ja.location FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    remote = (SELECT ja.remote FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    enrichers = (SELECT ja.enrichers FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id),
+    updated_at = (SELECT ja.updated_at FROM job_attribute ja WHERE ja.hn_id = hiring_job.hn_id)
+WHERE hn_id IN (SELECT hn_id FROM job_attribute);
+CREATE INDEX hiring_job_company_domain_idx ON hiring_job (company_domain);
+CREATE INDEX hiring_job_company_id_idx ON hiring_job (company_id);
+CREATE INDEX hiring_job_duplicate_of_idx ON hiring_job (duplicate_of);
+DROP TABLE job_attribute;
+-- +goose StatementEnd
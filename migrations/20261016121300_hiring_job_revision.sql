@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE hiring_job_revision (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    hn_id INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    replaced_at INTEGER NOT NULL
+);
+CREATE INDEX hiring_job_revision_hn_id_idx ON hiring_job_revision (hn_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE hiring_job_revision;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE company_alias (
+    alias_key TEXT NOT NULL PRIMARY KEY,
+    company_id INTEGER NOT NULL,
+    name TEXT NOT NULL
+);
+CREATE INDEX company_alias_company_id_idx ON company_alias (company_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE company_alias;
+-- +goose StatementEnd
@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE job_attribute ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE job_attribute ADD COLUMN tz_min INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE job_attribute ADD COLUMN tz_max INTEGER NOT NULL DEFAULT 0;
+DROP VIEW hiring_job_view;
+CREATE VIEW hiring_job_view AS
+SELECT hj.hn_id, hj.hiring_story_id, hj.text, hj.time, hj.status,
+    COALESCE(ja.level, '') AS level,
+    COALESCE(ja.apply_email, '') AS apply_email,
+    COALESCE(ja.apply_url, '') AS apply_url,
+    COALESCE(ja.company_domain, '') AS company_domain,
+    COALESCE(ja.company_id, 0) AS company_id,
+    COALESCE(ja.simhash, 0) AS simhash,
+    COALESCE(ja.duplicate_of, 0) AS duplicate_of,
+    COALESCE(ja.language, '') AS language,
+    COALESCE(ja.employment_type, '') AS employment_type,
+    COALESCE(ja.equity, '') AS equity,
+    COALESCE(ja.benefits, '') AS benefits,
+    COALESCE(ja.salary_min, 0) AS salary_min,
+    COALESCE(ja.salary_max, 0) AS salary_max,
+    COALESCE(ja.salary_currency, '') AS salary_currency,
+    COALESCE(ja.salary_min_usd, 0) AS salary_min_usd,
+    COALESCE(ja.salary_max_usd, 0) AS salary_max_usd,
+    COALESCE(ja.tags, '') AS tags,
+    COALESCE(ja.location, '') AS location,
+    COALESCE(ja.remote, '') AS remote,
+    COALESCE(ja.timezone, '') AS timezone,
+    COALESCE(ja.tz_min, 0) AS tz_min,
+    COALESCE(ja.tz_max, 0) AS tz_max,
+    COALESCE(ja.enrichers, '') AS enrichers,
+    COALESCE(ja.updated_at, 0) AS updated_at,
+    COALESCE(ja.parser_version, 0) AS parser_version,
+    COALESCE(ja.latitude, 0) AS latitude,
+    COALESCE(ja.longitude, 0) AS longitude
+FROM hiring_job hj
+LEFT JOIN job_attribute ja ON ja.hn_id = hj.hn_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW hiring_job_view;
+ALTER TABLE job_attribute DROP COLUMN timezone;
+ALTER TABLE job_attribute DROP COLUMN tz_min;
+ALTER TABLE job_attribute DROP COLUMN tz_max;
+CREATE VIEW hiring_job_view AS
+SELECT hj.hn_id, hj.hiring_story_id, hj.text, hj.time, hj.status,
+    COALESCE(ja.level, '') AS level,
+    COALESCE(ja.apply_email, '') AS apply_email,
+    COALESCE(ja.apply_url, '') AS apply_url,
+    COALESCE(ja.company_domain, '') AS company_domain,
+    COALESCE(ja.company_id, 0) AS company_id,
+    COALESCE(ja.simhash, 0) AS simhash,
+    COALESCE(ja.duplicate_of, 0) AS duplicate_of,
+    COALESCE(ja.language, '') AS language,
+    COALESCE(ja.employment_type, '') AS employment_type,
+    COALESCE(ja.equity, '') AS equity,
+    COALESCE(ja.benefits, '') AS benefits,
+    COALESCE(ja.salary_min, 0) AS salary_min,
+    COALESCE(ja.salary_max, 0) AS salary_max,
+    COALESCE(ja.salary_currency, '') AS salary_currency,
+    COALESCE(ja.salary_min_usd, 0) AS salary_min_usd,
+    COALESCE(ja.salary_max_usd, 0) AS salary_max_usd,
+    COALESCE(ja.tags, '') AS tags,
+    COALESCE(ja.location, '') AS location,
+    COALESCE(ja.remote, '') AS remote,
+    COALESCE(ja.enrichers, '') AS enrichers,
+    COALESCE(ja.updated_at, 0) AS updated_at,
+    COALESCE(ja.parser_version, 0) AS parser_version,
+    COALESCE(ja.latitude, 0) AS latitude,
+    COALESCE(ja.longitude, 0) AS longitude
+FROM hiring_job hj
+LEFT JOIN job_attribute ja ON ja.hn_id = hj.hn_id;
+-- +goose StatementEnd
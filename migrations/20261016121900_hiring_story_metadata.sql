@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE hiring_story ADD COLUMN score INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_story ADD COLUMN descendants INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_story ADD COLUMN comments INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE hiring_story ADD COLUMN raw TEXT NOT NULL DEFAULT '';
+ALTER TABLE hiring_story ADD COLUMN synced_at INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE hiring_story DROP COLUMN synced_at;
+ALTER TABLE hiring_story DROP COLUMN raw;
+ALTER TABLE hiring_story DROP COLUMN comments;
+ALTER TABLE hiring_story DROP COLUMN descendants;
+ALTER TABLE hiring_story DROP COLUMN score;
+-- +goose StatementEnd
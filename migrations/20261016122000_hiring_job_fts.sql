@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE VIRTUAL TABLE hiring_job_fts USING fts5(text, tokenize='porter unicode61');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE hiring_job_fts;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE job_tag (
+    hn_id INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (hn_id, tag)
+) WITHOUT ROWID;
+CREATE INDEX job_tag_tag_idx ON job_tag (tag, hn_id);
+INSERT INTO job_tag (hn_id, tag)
+WITH RECURSIVE split(hn_id, tag, rest) AS (
+    SELECT hn_id, '', tags || ',' FROM job_attribute WHERE tags != ''
+    UNION ALL
+    SELECT hn_id, substr(rest, 1, instr(rest, ',') - 1), substr(rest, instr(rest, ',') + 1) FROM split WHERE rest != ''
+)
+SELECT DISTINCT hn_id, tag FROM split WHERE tag != '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE job_tag;
+-- +goose StatementEnd
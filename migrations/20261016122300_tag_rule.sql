@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tag_rule (
+    tag TEXT NOT NULL PRIMARY KEY,
+    action TEXT NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE tag_rule;
+-- +goose StatementEnd
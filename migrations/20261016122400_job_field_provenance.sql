@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE job_field_provenance (
+    hn_id INTEGER NOT NULL,
+    field TEXT NOT NULL,
+    source TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 0,
+    derived_at INTEGER NOT NULL,
+    PRIMARY KEY (hn_id, field)
+) WITHOUT ROWID;
+CREATE INDEX job_field_provenance_source_idx ON job_field_provenance (source, version);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE job_field_provenance;
+-- +goose StatementEnd
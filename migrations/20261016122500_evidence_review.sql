@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE job_attribute_evidence ADD COLUMN confirmed_value TEXT;
+CREATE INDEX job_attribute_evidence_confidence_idx ON job_attribute_evidence (confidence, field);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX job_attribute_evidence_confidence_idx;
+ALTER TABLE job_attribute_evidence DROP COLUMN confirmed_value;
+-- +goose StatementEnd
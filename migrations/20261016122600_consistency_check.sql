@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE consistency_check (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    checked_at INTEGER NOT NULL,
+    issues INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE consistency_issue (
+    check_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    target TEXT NOT NULL,
+    detail TEXT NOT NULL,
+    FOREIGN KEY(check_id) REFERENCES consistency_check(id) ON DELETE CASCADE
+);
+CREATE INDEX consistency_issue_check_id_idx ON consistency_issue (check_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX consistency_issue_check_id_idx;
+DROP TABLE consistency_issue;
+DROP TABLE consistency_check;
+-- +goose StatementEnd
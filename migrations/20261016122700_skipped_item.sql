@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE skipped_item (
+    hn_id INTEGER NOT NULL PRIMARY KEY,
+    hiring_story_id INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    skipped_at INTEGER NOT NULL
+);
+CREATE INDEX skipped_item_hiring_story_id_idx ON skipped_item (hiring_story_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX skipped_item_hiring_story_id_idx;
+DROP TABLE skipped_item;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE hiring_story ADD COLUMN kind TEXT NOT NULL DEFAULT 'hiring';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE hiring_story DROP COLUMN kind;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE job_mark (
+    hn_id INTEGER PRIMARY KEY,
+    read_at INTEGER NOT NULL DEFAULT 0,
+    favorite_at INTEGER NOT NULL DEFAULT 0
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE job_mark;
+-- +goose StatementEnd
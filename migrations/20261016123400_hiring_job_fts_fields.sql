@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+DROP TABLE hiring_job_fts;
+CREATE VIRTUAL TABLE hiring_job_fts USING fts5(text, company, role, headline, tokenize='porter unicode61');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE hiring_job_fts;
+CREATE VIRTUAL TABLE hiring_job_fts USING fts5(text, tokenize='porter unicode61');
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE story_aggregate (
+    story_id INTEGER PRIMARY KEY,
+    jobs INTEGER NOT NULL DEFAULT 0,
+    remote INTEGER NOT NULL DEFAULT 0,
+    salaries INTEGER NOT NULL DEFAULT 0,
+    salary_p25 INTEGER NOT NULL DEFAULT 0,
+    salary_p50 INTEGER NOT NULL DEFAULT 0,
+    salary_p75 INTEGER NOT NULL DEFAULT 0,
+    salary_p90 INTEGER NOT NULL DEFAULT 0,
+    refreshed_at INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE story_tag_aggregate (
+    story_id INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    jobs INTEGER NOT NULL,
+    PRIMARY KEY (story_id, tag)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE story_tag_aggregate;
+DROP TABLE story_aggregate;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE story_term_aggregate (
+    story_id INTEGER NOT NULL,
+    term TEXT NOT NULL,
+    jobs INTEGER NOT NULL,
+    PRIMARY KEY (story_id, term)
+);
+-- the stories are aggregated again at startup, along with their terms
+DELETE FROM story_aggregate;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE story_term_aggregate;
+-- +goose StatementEnd
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// notifier sends messages to the operators
+type notifier interface {
+	Notify(subject, body string) error
+}
+
+// smtpNotifier emails messages to the operators through an smtp server
+type smtpNotifier struct {
+	Addr     string
+	User     string
+	Password string
+	From     string
+	To       []string
+}
+
+func (n smtpNotifier) Notify(subject, body string) error {
+	var auth smtp.Auth
+	if n.User != "" {
+		host, _, _ := strings.Cut(n.Addr, ":")
+		auth = smtp.PlainAuth("", n.User, n.Password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		n.From, strings.Join(n.To, ", "), subject, strings.ReplaceAll(body, "\n", "\r\n"))
+	return smtp.SendMail(n.Addr, auth, n.From, n.To, []byte(msg))
+}
+
+// stdoutNotifier prints messages, for deployments without an smtp server
+type stdoutNotifier struct{}
+
+func (stdoutNotifier) Notify(subject, body string) error {
+	_, err := fmt.Printf("%s\n\n%s", subject, body)
+	return err
+}
+
+// newNotifier will return the smtp notifier when an smtp server and
+// recipients are configured, and the stdout notifier otherwise
+func newNotifier() notifier {
+	if cfg.SMTPAddr == "" || len(cfg.NotifyTo) == 0 {
+		return stdoutNotifier{}
+	}
+	from := cfg.NotifyFrom
+	if from == "" {
+		host, _ := os.Hostname()
+		from = "who-is-hiring@" + host
+	}
+	return smtpNotifier{
+		Addr:     cfg.SMTPAddr,
+		User:     cfg.SMTPUser,
+		Password: cfg.SMTPPassword,
+		From:     from,
+		To:       cfg.NotifyTo,
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// ogCardWidth and ogCardHeight are the size of social preview cards
+	ogCardWidth  = 1200
+	ogCardHeight = 630
+	ogCardMargin = 60
+
+	// storyTopTags is the number of tags shown in story summaries
+	storyTopTags = 5
+)
+
+var (
+	ogCardBackground = color.RGBA{0x47, 0x55, 0x69, 0xff}
+	ogCardFooter     = color.RGBA{0x0f, 0x17, 0x2a, 0xff}
+	ogCardText       = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	ogCardMuted      = color.RGBA{0xcb, 0xd5, 0xe1, 0xff}
+)
+
+// storyMonthPattern matches the month of story titles like "Ask HN: Who is hiring? (June 2024)"
+var storyMonthPattern = regexp.MustCompile(`\(([^)]+)\)\s*$`)
+
+// pageMeta is the description and social preview of a page
+type pageMeta struct {
+	Title       string
+	Description string
+	Image       string
+}
+
+// RemotePercent will return the share of remote jobs, rounded
+func (s StoryStats) RemotePercent() int {
+	if s.Jobs == 0 {
+		return 0
+	}
+	return (s.Remote*100 + s.Jobs/2) / s.Jobs
+}
+
+// storyMeta will summarize a story for search results and social previews,
+// like "June 2024 thread, parsed: 320 jobs from 290 companies, 41% remote."
+func storyMeta(hs *HiringStory) (pageMeta, error) {
+	stats, err := SelectStoryStats(hs.HnId)
+	if err != nil {
+		return pageMeta{}, err
+	}
+
+	month, _ := storyTitleParts(hs.Title)
+	desc := fmt.Sprintf("%s thread, parsed: %d jobs from %d companies, %d%% remote.",
+		month, stats.Jobs, stats.Companies, stats.RemotePercent())
+	if len(stats.TopTags) > 0 {
+		desc += " Top tags: " + strings.Join(stats.TopTags, ", ") + "."
+	}
+	return pageMeta{
+		Title:       hs.Title,
+		Description: desc,
+		Image:       canonicalUrl(fmt.Sprintf("/og/story-%d.png", hs.HnId)),
+	}, nil
+}
+
+// storyTitleParts will split a story title into its month and the rest of
+// the title, like "June 2024" and "Who is hiring?". Titles without a month
+// are returned whole as the month.
+func storyTitleParts(title string) (string, string) {
+	title = strings.TrimPrefix(title, "Ask HN: ")
+	m := storyMonthPattern.FindStringSubmatchIndex(title)
+	if m == nil {
+		return title, ""
+	}
+	return title[m[2]:m[3]], strings.TrimSpace(title[:m[0]])
+}
+
+// ogCardHandler will serve the social preview card of a story
+func ogCardHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/og/")
+	if !strings.HasPrefix(name, "story-") || !strings.HasSuffix(name, ".png") {
+		http.NotFound(w, r)
+		return
+	}
+	hs, err := GetHiringStory(paramValue(strings.TrimSuffix(strings.TrimPrefix(name, "story-"), ".png"), 0))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	stats, err := SelectStoryStats(hs.HnId)
+	if err != nil {
+		log.Println("failed to select story stats.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	card, err := renderStoryCard(hs, stats)
+	if err != nil {
+		log.Println("failed to render story card.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", archiveMaxAge))
+	w.Write(card)
+}
+
+// ogLine is a line of text drawn on a card at a scale of the bitmap font
+type ogLine struct {
+	text  string
+	scale int
+	color color.Color
+}
+
+// renderStoryCard will draw the social preview card of a story as a png
+func renderStoryCard(hs *HiringStory, stats StoryStats) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, ogCardWidth, ogCardHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(ogCardBackground), image.Point{}, draw.Src)
+
+	month, title := storyTitleParts(hs.Title)
+	var lines []ogLine
+	if title != "" {
+		lines = append(lines, ogLine{title, 5, ogCardMuted})
+	}
+	for _, l := range wrapOgText(month, 12) {
+		lines = append(lines, ogLine{l, 12, ogCardText})
+	}
+	lines = append(lines, ogLine{"", 3, ogCardText})
+	summary := fmt.Sprintf("%d jobs - %d companies - %d%% remote", stats.Jobs, stats.Companies, stats.RemotePercent())
+	for _, l := range wrapOgText(summary, 5) {
+		lines = append(lines, ogLine{l, 5, ogCardText})
+	}
+	if len(stats.TopTags) > 0 {
+		for _, l := range wrapOgText("Top: "+strings.Join(stats.TopTags, ", "), 4) {
+			lines = append(lines, ogLine{l, 4, ogCardMuted})
+		}
+	}
+
+	y := ogCardMargin
+	for _, l := range lines {
+		drawOgText(img, ogCardMargin, y, l.text, l.scale, l.color)
+		y += 10 * l.scale
+	}
+
+	footerTop := ogCardHeight - 100
+	draw.Draw(img, image.Rect(0, footerTop, ogCardWidth, ogCardHeight), image.NewUniform(ogCardFooter), image.Point{}, draw.Src)
+	host := cfg.PublicBaseUrl
+	if u, err := url.Parse(cfg.PublicBaseUrl); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	drawOgText(img, ogCardMargin, footerTop+(100-7*4)/2, host+" - thread, parsed", 4, ogCardMuted)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// wrapOgText will split text into lines fitting the card width at a scale,
+// breaking at spaces
+func wrapOgText(text string, scale int) []string {
+	width := (ogCardWidth - 2*ogCardMargin) / (6 * scale)
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// drawOgText will draw text with its top left corner at x, y, each font pixel
+// drawn as a square of scale pixels
+func drawOgText(img *image.RGBA, x, y int, text string, scale int, c color.Color) {
+	src := image.NewUniform(c)
+	for _, r := range text {
+		if glyph, ok := ogFont[unicode.ToUpper(r)]; ok {
+			for row, bits := range glyph {
+				for col, bit := range bits {
+					if bit != '#' {
+						continue
+					}
+					px, py := x+col*scale, y+row*scale
+					draw.Draw(img, image.Rect(px, py, px+scale, py+scale), src, image.Point{}, draw.Src)
+				}
+			}
+		}
+		x += 6 * scale
+	}
+}
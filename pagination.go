@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// listPageSize is the number of jobs a page of a listing shows by default
+	listPageSize = 50
+	// listPageMax is the max number of jobs a limit param shows in a page
+	listPageMax = 200
+)
+
+// listPage is a page of the jobs of a listing along with the cursors of the
+// pages around it
+type listPage struct {
+	Jobs []HiringJobListItem
+	// Offset is the position of the first job of the page in the listing,
+	// and Total the number of jobs of the listing
+	Offset int
+	Total  int
+	// After is the cursor of the next page and Before the cursor of the
+	// previous one, 0 when there is no such page
+	After  uint64
+	Before uint64
+}
+
+// pageSize will return the number of jobs a page of the filter shows
+func (f FilterState) pageSize() int {
+	if f.Limit > 0 {
+		return f.Limit
+	}
+	return listPageSize
+}
+
+// selectJobPage will select the page of the jobs of a scope matching the
+// filter after its after cursor, or before its before cursor, in the order
+// they are listed in. Cursors are job ids, so a page keeps its jobs as new
+// jobs are added. Only the jobs of the page are selected, the others are
+// counted.
+func selectJobPage(scope jobScope, f FilterState) (listPage, error) {
+	var p listPage
+	size := f.pageSize()
+	// one more job than a page tells whether there is a page past it
+	cursor := jobPage{Cursor: f.After, Limit: size + 1}
+	if f.Before > 0 {
+		cursor = jobPage{Cursor: f.Before, Backward: true, Limit: size + 1}
+	}
+	jobs, err := selectJobs(scope, f, cursor)
+	if err != nil {
+		return p, err
+	}
+	more := len(jobs) > size
+	if more && cursor.Backward {
+		jobs = jobs[1:]
+	} else if more {
+		jobs = jobs[:size]
+	}
+	p.Jobs = jobs
+	if p.Total, err = countJobs(scope, f, jobPage{}); err != nil {
+		return p, err
+	}
+	if len(jobs) == 0 {
+		return p, nil
+	}
+	if cursor.Cursor > 0 {
+		// the jobs before the page are the jobs before its first job
+		if p.Offset, err = countJobs(scope, f, jobPage{Cursor: jobs[0].HnId, Backward: true}); err != nil {
+			return p, err
+		}
+	}
+	if p.Offset > 0 {
+		p.Before = jobs[0].HnId
+	}
+	if p.Offset+len(jobs) < p.Total {
+		p.After = jobs[len(jobs)-1].HnId
+	}
+	return p, nil
+}
+
+// pageUrls will return the urls of the previous and next pages of a listing
+// at path for the filter, empty when there is no such page. Extra params,
+// like the story of a search, are kept.
+func (p listPage) pageUrls(path string, f FilterState, extra url.Values) (string, string) {
+	pageUrl := func(param string, v uint64) string {
+		if v == 0 {
+			return ""
+		}
+		q := f.query()
+		q.Del("after")
+		q.Del("before")
+		q.Set(param, strconv.FormatUint(v, 10))
+		for k, vs := range extra {
+			q[k] = vs
+		}
+		return path + "?" + q.Encode()
+	}
+	return pageUrl("before", p.Before), pageUrl("after", p.After)
+}
+
+// setLinkHeaders will send the urls of the pages around a page in Link
+// headers, like the reader does for the jobs around a job
+func setLinkHeaders(w http.ResponseWriter, prevUrl, nextUrl string) {
+	if prevUrl != "" {
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="prev"`, prevUrl))
+	}
+	if nextUrl != "" {
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, nextUrl))
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSelectJobPage(t *testing.T) {
+	useTestDB(t)
+	if _, err := CreateHiringStory(1, storyKindHiring, "Ask HN: Who is hiring?", 1700000000); err != nil {
+		t.Fatal(err)
+	}
+	for id := uint64(1); id <= 7; id++ {
+		hj := HiringJob{HnId: id, Text: fmt.Sprintf("Company %d | Engineer", id), Time: 1700000000 + id}
+		if _, err := CreateHiringJob(1, jobStatusOk, hj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := func(p listPage) []uint64 {
+		var ids []uint64
+		for _, hj := range p.Jobs {
+			ids = append(ids, hj.HnId)
+		}
+		return ids
+	}
+
+	for _, sort := range []string{sortNewest, sortOldest, sortHot} {
+		f := FilterState{Sort: sort, Limit: 3}
+		var listed []uint64
+		var pages []listPage
+		for {
+			p, err := selectJobPage(jobScope{StoryId: 1}, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Total != 7 || p.Offset != len(listed) {
+				t.Errorf("%s: page %v has offset %d of %d, want %d of 7", sort, ids(p), p.Offset, p.Total, len(listed))
+			}
+			if (p.Before != 0) != (len(listed) > 0) {
+				t.Errorf("%s: page %v has before cursor %d", sort, ids(p), p.Before)
+			}
+			listed = append(listed, ids(p)...)
+			pages = append(pages, p)
+			if p.After == 0 {
+				break
+			}
+			f.After, f.Before = p.After, 0
+		}
+		if len(listed) != 7 || len(pages) != 3 {
+			t.Fatalf("%s: listed %v in %d pages, want 7 jobs in 3 pages", sort, listed, len(pages))
+		}
+
+		// the before cursor of the last page leads back to the page before it
+		f.After, f.Before = 0, pages[2].Before
+		p, err := selectJobPage(jobScope{StoryId: 1}, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(ids(p)) != fmt.Sprint(ids(pages[1])) || p.Offset != 3 || p.After != pages[1].After {
+			t.Errorf("%s: page before %v is %v at %d, want %v at 3", sort, ids(pages[2]), ids(p), p.Offset, ids(pages[1]))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log"
+)
+
+// panelLoader loads the data of an auxiliary panel of a page, like the
+// story stats or the reposts of a job, which the page renders without
+type panelLoader struct {
+	name string
+	// run will load the panel data and return a func setting it
+	run func() (func(), error)
+}
+
+// panel will return a loader setting dst to the value returned by load.
+// dst is only set by loadPanels, from the goroutine rendering the page.
+func panel[T any](name string, dst *T, load func() (T, error)) panelLoader {
+	return panelLoader{name: name, run: func() (func(), error) {
+		v, err := load()
+		return func() { *dst = v }, err
+	}}
+}
+
+// panelResult is the outcome of the loader at index i
+type panelResult struct {
+	i   int
+	set func()
+	err error
+}
+
+// loadPanels will run the loaders concurrently and set the data of the panels
+// loaded within cfg.PanelTimeout. Panels failing or taking longer are logged
+// and left empty, so the page renders without them. Queries of timed out
+// panels are not interrupted and finish in the background.
+func loadPanels(ctx context.Context, loaders ...panelLoader) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.PanelTimeout)
+	defer cancel()
+
+	// buffered so loaders finishing after the timeout don't block
+	results := make(chan panelResult, len(loaders))
+	for i, l := range loaders {
+		go func(i int, l panelLoader) {
+			set, err := l.run()
+			results <- panelResult{i: i, set: set, err: err}
+		}(i, l)
+	}
+
+	done := make([]bool, len(loaders))
+	for range loaders {
+		select {
+		case res := <-results:
+			done[res.i] = true
+			if res.err != nil {
+				log.Printf("failed to load %s panel. %v", loaders[res.i].name, res.err)
+				panelFailures.Add(loaders[res.i].name, 1)
+				continue
+			}
+			res.set()
+		case <-ctx.Done():
+			for i, l := range loaders {
+				if !done[i] {
+					log.Printf("skipped %s panel, not loaded within %s", l.name, cfg.PanelTimeout)
+					panelFailures.Add(l.name, 1)
+				}
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// invalidParamError is returned for a query param whose value is not valid,
+// along with the values the param expects
+type invalidParamError struct {
+	Param    string `json:"param"`
+	Value    string `json:"value"`
+	Expected string `json:"expected"`
+	// Reason tells what is wrong with values that are not simply unknown
+	Reason string `json:"reason,omitempty"`
+}
+
+func (e invalidParamError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("invalid %s param %q, %s", e.Param, e.Value, e.Reason)
+	}
+	return fmt.Sprintf("invalid %s param %q, expected %s", e.Param, e.Value, e.Expected)
+}
+
+// oneOf will describe the values of an enum param
+func oneOf(values []string) string {
+	return "one of " + strings.Join(values, ", ")
+}
+
+// languageCodes will return the codes of the detected languages, sorted
+func languageCodes() []string {
+	codes := make([]string, 0, len(languageNames))
+	for code := range languageNames {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// uintParam will parse the name param of q as an id, returning 0 when it is not set
+func uintParam(q url.Values, name string) (uint64, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, invalidParamError{Param: name, Value: v, Expected: "a positive integer id"}
+	}
+	return id, nil
+}
+
+// paramValue will return a parsed string as uint64 or a default value.
+// Html routes use it to fall back to a default, api routes validate params
+// with uintParam instead.
+func paramValue(v string, d uint64) uint64 {
+	if v == "" {
+		return d
+	}
+
+	converted, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return d
+	}
+
+	return converted
+}
+
+// paramErrors will return every invalid param reported by err, which may join several errors
+func paramErrors(err error) []invalidParamError {
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else {
+		errs = []error{err}
+	}
+
+	var params []invalidParamError
+	for _, e := range errs {
+		var pe invalidParamError
+		if errors.As(e, &pe) {
+			params = append(params, pe)
+		}
+	}
+	return params
+}
+
+// filterError will answer a request whose jobs failed to be selected. Filters
+// the jobs can't be selected by, like a regex search that does not compile or
+// runs too long, are a 400 telling what to fix, other errors are a 500.
+func filterError(w http.ResponseWriter, msg string, err error) {
+	if len(paramErrors(err)) > 0 {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Println(msg, err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// apiError will respond to an api request with a json error. Invalid params
+// reported by err are listed, so clients can tell which value to fix.
+func apiError(w http.ResponseWriter, status int, message string, err error) {
+	body := struct {
+		Error  string              `json:"error"`
+		Params []invalidParamError `json:"params,omitempty"`
+	}{
+		Error: message,
+	}
+	if err != nil {
+		body.Params = paramErrors(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Println("failed to encode api error.", err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// PinHiringStory will pin the story served on /, replacing the pinned story.
+// A hnId of 0 unpins it, so / serves the latest story again.
+func PinHiringStory(hnId uint64) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE hiring_story SET pinned=0 WHERE pinned=1`); err != nil {
+		return err
+	}
+	if hnId > 0 {
+		res, err := tx.Exec(`UPDATE hiring_story SET pinned=1 WHERE hn_id=? and kind=?`, hnId, storyKindHiring)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	return tx.Commit()
+}
+
+// storyPinHandler will pin the story given by hn_id on POST, or unpin it
+// when hn_id is 0, and list the stories to pick from on GET
+func storyPinHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if !requireSameOrigin(w, r) {
+			return
+		}
+
+		hnId := paramValue(r.PostFormValue("hn_id"), 0)
+		err := PinHiringStory(hnId)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "unknown story", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Println("failed to pin story.", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		action, detail := "story.pin", fmt.Sprintf("pinned story %d on /", hnId)
+		if hnId == 0 {
+			action, detail = "story.unpin", "unpinned the story of /, the latest story is served"
+		}
+		if err := RecordAudit(cfg.AdminUser, action, fmt.Sprintf("%d", hnId), detail); err != nil {
+			log.Println("failed to record audit entry.", err)
+		}
+		bus.Publish(event{Topic: eventStoryPinned, StoryId: hnId})
+		http.Redirect(w, r, "/admin/stories", http.StatusSeeOther)
+		return
+	}
+
+	stories, err := SelectHiringStories(storyKindHiring)
+	if err != nil {
+		log.Println("failed to select stories.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var pinned bool
+	for _, s := range stories {
+		pinned = pinned || s.Pinned
+	}
+
+	data := struct {
+		Stories []HiringStorySummary
+		Pinned  bool
+	}{
+		Stories: stories,
+		Pinned:  pinned,
+	}
+	renderTemplate(w, "admin_stories.html", data)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// plainJobUrl will return the url of the plain text page of a job
+func plainJobUrl(hnId uint64) string {
+	return canonicalUrl(fmt.Sprintf("/plain/job/%d", hnId))
+}
+
+// plainJobTitle will return the role and company of a job, like "Backend
+// Engineer at Stripe", or its headline when they are not known
+func plainJobTitle(hj HiringJob) string {
+	h := parseJobHeadline(hj.Text)
+	switch {
+	case h.Role != "" && h.Company != "":
+		return h.Role + " at " + h.Company
+	case h.Company != "":
+		return h.Company
+	}
+	return hj.Headline()
+}
+
+// plainJobDetails will return the location, remote policies and salary of a
+// job, the ones known
+func plainJobDetails(hj HiringJob) string {
+	var details []string
+	if hj.Location != "" {
+		details = append(details, hj.Location)
+	}
+	if policies := hj.RemotePolicies(); len(policies) > 0 {
+		details = append(details, strings.Join(policies, ", "))
+	}
+	if salary := hj.Salary(); salary != "" {
+		details = append(details, salary)
+	}
+	return strings.Join(details, " | ")
+}
+
+// writePlainJob will write a job as plain text: its title, the fields
+// derived from it, and the text of the post without markup
+func writePlainJob(w io.Writer, hj HiringJob) {
+	fmt.Fprintln(w, plainJobTitle(hj))
+	if details := plainJobDetails(hj); details != "" {
+		fmt.Fprintln(w, details)
+	}
+	fmt.Fprintln(w, "Posted:", time.Unix(int64(hj.Time), 0).UTC().Format(time.DateOnly))
+	switch {
+	case hj.ApplyUrl != "":
+		fmt.Fprintln(w, "Apply:", hj.ApplyUrl)
+	case hj.ApplyEmail != "":
+		fmt.Fprintln(w, "Apply:", hj.ApplyEmail)
+	}
+	fmt.Fprintln(w, "Post:", hnItemUrl+fmt.Sprint(hj.HnId))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, jobPlainText(hj.Text))
+}
+
+// plainHandler will list the jobs of the current story as plain text, for
+// screen readers and terminals. It takes the reader filter params and is
+// paged like the other listings, with the pages around sent as Link headers.
+func plainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/plain" {
+		http.NotFound(w, r)
+		return
+	}
+	hs, err := GetCurrentHiringStory()
+	if err != nil {
+		log.Println("failed to get current story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	filter, _ := parseFilterState(r.URL.Query())
+	filter = withSavedExclusions(w, r, withPreferences(w, r, filter))
+	page, err := selectJobPage(jobScope{StoryId: hs.HnId}, filter)
+	if err != nil {
+		filterError(w, "failed to select hiring jobs.", err)
+		return
+	}
+	prevUrl, nextUrl := page.pageUrls("/plain", filter, nil)
+	setLinkHeaders(w, prevUrl, nextUrl)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, hs.Title)
+	fmt.Fprintf(w, "%s, %s.", countNoun(page.Total, "job", "jobs"), filter.SortName())
+	if len(page.Jobs) > 0 {
+		fmt.Fprintf(w, " Showing %d to %d.", page.Offset+1, page.Offset+len(page.Jobs))
+	}
+	fmt.Fprintln(w)
+	for i, hj := range page.Jobs {
+		fmt.Fprintf(w, "\n---\n\nJob %d of %d. %s\n", page.Offset+i+1, page.Total, plainJobUrl(hj.HnId))
+		writePlainJob(w, hj.HiringJob)
+	}
+	if prevUrl != "" || nextUrl != "" {
+		fmt.Fprintln(w, "\n---")
+	}
+	if prevUrl != "" {
+		fmt.Fprintln(w, "\nPrevious page:", canonicalUrl(prevUrl))
+	}
+	if nextUrl != "" {
+		fmt.Fprintln(w, "\nNext page:", canonicalUrl(nextUrl))
+	}
+}
+
+// plainJobHandler will serve a job as plain text
+func plainJobHandler(w http.ResponseWriter, r *http.Request) {
+	hj, err := GetHiringJob(paramValue(strings.TrimPrefix(r.URL.Path, "/plain/job/"), 0))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Println("failed to get hiring job.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	hs, err := GetHiringStory(hj.HiringStoryId)
+	if err != nil {
+		log.Println("failed to get story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, hs.Title)
+	fmt.Fprintln(w)
+	writePlainJob(w, *hj)
+}
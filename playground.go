@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// playgroundRowLimit is the max number of rows a playground query returns
+	playgroundRowLimit = 1000
+	// playgroundTimeout is how long a playground query may run
+	playgroundTimeout = 5 * time.Second
+	// playgroundConns is the number of playground queries run at once
+	playgroundConns = 2
+)
+
+// playgroundDb is a read only pool of connections to the database, so
+// playground queries can't change it whatever they run. Connections are
+// opened on the first query.
+var playgroundDb = func() *sqlx.DB {
+	ro := sqlx.MustOpen(db.DriverName(), "file:"+dbPath+"?mode=ro&_query_only=true")
+	ro.SetMaxOpenConns(playgroundConns)
+	return ro
+}()
+
+// playgroundResult are the rows of a playground query, as text
+type playgroundResult struct {
+	Columns []string
+	Rows    [][]string
+	// Truncated is set when the query returned more than playgroundRowLimit rows
+	Truncated bool
+	Elapsed   time.Duration
+}
+
+// playgroundValue will format a column value of a playground row
+func playgroundValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+// runPlaygroundQuery will run a query over a read only connection to the
+// database, stopping it after playgroundTimeout
+func runPlaygroundQuery(ctx context.Context, query string) (playgroundResult, error) {
+	var res playgroundResult
+	ctx, cancel := context.WithTimeout(ctx, playgroundTimeout)
+	defer cancel()
+	start := time.Now()
+	rows, err := playgroundDb.QueryxContext(ctx, query)
+	if err != nil {
+		return res, playgroundError(ctx, err)
+	}
+	defer rows.Close()
+	if res.Columns, err = rows.Columns(); err != nil {
+		return res, err
+	}
+	for rows.Next() {
+		if len(res.Rows) == playgroundRowLimit {
+			res.Truncated = true
+			break
+		}
+		values, err := rows.SliceScan()
+		if err != nil {
+			return res, playgroundError(ctx, err)
+		}
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = playgroundValue(v)
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	res.Elapsed = time.Since(start)
+	return res, playgroundError(ctx, rows.Err())
+}
+
+// playgroundError will tell queries stopped for running too long from failing ones
+func playgroundError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("the query was stopped after %s", playgroundTimeout)
+	}
+	return err
+}
+
+// sqlPlaygroundHandler will run the read only sql query of the q form value
+// on POST and show its rows, or send them as csv with format=csv, for the
+// questions the pages don't answer yet
+func sqlPlaygroundHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.FormValue("q"))
+	var res playgroundResult
+	var message string
+	if r.Method == http.MethodPost && query != "" {
+		if !requireSameOrigin(w, r) {
+			return
+		}
+
+		var err error
+		res, err = runPlaygroundQuery(r.Context(), query)
+		if err := RecordAudit(cfg.AdminUser, "sql.query", "", query); err != nil {
+			log.Println("failed to record audit entry.", err)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			message = "Failed to run the query: " + err.Error()
+		} else if r.PostFormValue("format") == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="query.csv"`)
+			cw := csv.NewWriter(w)
+			cw.Write(res.Columns)
+			cw.WriteAll(res.Rows)
+			return
+		}
+	}
+
+	data := struct {
+		Query    string
+		Message  string
+		Result   playgroundResult
+		RowLimit int
+		Timeout  time.Duration
+	}{
+		Query:    query,
+		Message:  message,
+		Result:   res,
+		RowLimit: playgroundRowLimit,
+		Timeout:  playgroundTimeout,
+	}
+	renderTemplate(w, "admin_sql.html", data)
+}
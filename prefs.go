@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// prefsCookie holds the default view of a reader, applied on every visit
+	prefsCookie = "wih_prefs"
+	// prefsHeader carries the saved default view of a request. Pages applying
+	// it vary on it, so cached pages are kept per default view.
+	prefsHeader = "X-Wih-Prefs"
+
+	// layoutReader shows the jobs of / one at a time, layoutList lists the
+	// jobs of the month on a page
+	layoutReader = "reader"
+	layoutList   = "list"
+)
+
+// viewLayouts are the layouts / is shown in, the first one being the default
+var viewLayouts = []string{layoutReader, layoutList}
+
+// viewPrefs is the default view of a reader: the filters of the pages loaded
+// without params, and the sort and page size of the pages not setting them
+type viewPrefs struct {
+	Filter FilterState
+	Layout string
+}
+
+// parseViewPrefs will parse encoded prefs like "layout=list&remote=1&sort=hot".
+// Invalid params are left out, like they are from urls.
+func parseViewPrefs(v string) viewPrefs {
+	q, _ := url.ParseQuery(v)
+	p := viewPrefs{Layout: q.Get("layout")}
+	if getIndex(viewLayouts, p.Layout) == -1 {
+		p.Layout = layoutReader
+	}
+	q.Del("layout")
+	p.Filter, _ = parseFilterState(q)
+	p.Filter.After, p.Filter.Before = 0, 0
+	return p
+}
+
+// encode will return the prefs as params, leaving out the default layout
+func (p viewPrefs) encode() string {
+	q := p.Filter.query()
+	q.Del("after")
+	q.Del("before")
+	if p.Layout != layoutReader {
+		q.Set("layout", p.Layout)
+	}
+	return q.Encode()
+}
+
+// FilterParams will return the encoded default filters, without the sort or page size
+func (p viewPrefs) FilterParams() string {
+	f := p.Filter
+	f.Sort = ""
+	return f.cursorParams()
+}
+
+// requestPrefs will return the default view saved by the reader. The
+// response varies on it.
+func requestPrefs(w http.ResponseWriter, r *http.Request) viewPrefs {
+	w.Header().Add("Vary", prefsHeader)
+	return parseViewPrefs(r.Header.Get(prefsHeader))
+}
+
+// withPreferences will apply the default view saved by the reader to a
+// filter: its filters when the page is loaded without params, and its sort
+// and page size when the filter sets none. The saved sort is not added to
+// urls, like the default sort of a listing.
+func withPreferences(w http.ResponseWriter, r *http.Request, f FilterState) FilterState {
+	p := requestPrefs(w, r)
+	if r.URL.RawQuery == "" {
+		f = p.Filter
+		f.Sort = ""
+	}
+	if f.Sort == "" {
+		f.defaultSort = p.Filter.Sort
+	}
+	if f.Limit == 0 {
+		f.Limit = p.Filter.Limit
+	}
+	return f
+}
+
+// prefsMiddleware will pass the saved default view of a request to the
+// handlers in the prefs header
+func prefsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the header is only set by us
+		r.Header.Del(prefsHeader)
+		if c, err := r.Cookie(prefsCookie); err == nil && c.Value != "" {
+			r.Header.Set(prefsHeader, parseViewPrefs(c.Value).encode())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseViewPrefsForm will validate the default view posted from the settings
+// page. Default filters posted from the reader keep the other prefs of p.
+func parseViewPrefsForm(r *http.Request, p viewPrefs) (viewPrefs, error) {
+	q, err := url.ParseQuery(r.PostFormValue("params"))
+	if err != nil {
+		return p, errors.New("invalid filter params")
+	}
+	f, err := parseFilterState(q)
+	if err != nil {
+		return p, err
+	}
+	f.After, f.Before = 0, 0
+	if r.PostFormValue("filters_only") != "" {
+		if f.Sort == "" {
+			f.Sort = p.Filter.Sort
+		}
+		f.Limit = p.Filter.Limit
+		p.Filter = f
+		return p, nil
+	}
+
+	if p.Layout = r.PostFormValue("layout"); getIndex(viewLayouts, p.Layout) == -1 {
+		return p, fmt.Errorf("pick a layout, %s", oneOf(viewLayouts))
+	}
+	if f.Sort = r.PostFormValue("sort"); f.Sort != "" && !isJobSort(f.Sort) {
+		return p, fmt.Errorf("pick a sort, %s", oneOf(jobSorts))
+	}
+	f.Limit = 0
+	if v := strings.TrimSpace(r.PostFormValue("limit")); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 1 || f.Limit > listPageMax {
+			return p, fmt.Errorf("show from 1 to %d jobs a page", listPageMax)
+		}
+	}
+	p.Filter = f
+	return p, nil
+}
+
+// settingsHandler will save the default view posted by a reader in a cookie,
+// forgetting it when it is the default one, and show the settings on GET
+func settingsHandler(w http.ResponseWriter, r *http.Request) {
+	p := requestPrefs(w, r)
+	var message string
+	if r.Method == http.MethodPost {
+		saved, err := parseViewPrefsForm(r, p)
+		if err == nil {
+			cookie := &http.Cookie{
+				Name:     prefsCookie,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			}
+			if cookie.Value = saved.encode(); cookie.Value != "" {
+				cookie.MaxAge = int((365 * 24 * time.Hour).Seconds())
+			} else {
+				cookie.MaxAge = -1
+			}
+			http.SetCookie(w, cookie)
+			http.Redirect(w, r, "/settings", http.StatusSeeOther)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		message = "Failed to save the settings: " + err.Error()
+	}
+
+	var sorts []sortLink
+	for _, s := range jobSorts {
+		if s != sortRelevance {
+			sorts = append(sorts, sortLink{Sort: s, Label: jobSortNames[s], Selected: s == p.Filter.Sort})
+		}
+	}
+	data := struct {
+		Prefs    viewPrefs
+		Message  string
+		Sorts    []sortLink
+		Layouts  []string
+		PageSize int
+		PageMax  int
+	}{
+		Prefs:    p,
+		Message:  message,
+		Sorts:    sorts,
+		Layouts:  viewLayouts,
+		PageSize: listPageSize,
+		PageMax:  listPageMax,
+	}
+	renderTemplate(w, "settings.html", data)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// sourceHN is the provenance of the fields fetched from hacker news
+	sourceHN = "hn"
+	// sourceCompanyMerge is the provenance of companies set by an admin merging companies
+	sourceCompanyMerge = "company.merge"
+	// sourceTagRule is the provenance of tags changed by the admin tag rules
+	sourceTagRule = "tag.rule"
+	// apiJobsPath is the route prefix of the job api
+	apiJobsPath = "/api/jobs/"
+)
+
+// hnFields are the job fields fetched from hacker news
+var hnFields = []string{"text", "time", "poster", "status"}
+
+// derived wraps a field value with the enricher that derived it
+type derived struct {
+	Value    any
+	Enricher string
+}
+
+// FieldProvenance tells where a stored job field comes from: the enricher
+// that derived it, with the parser version it ran with, or hacker news
+type FieldProvenance struct {
+	Field  string `json:"field"`
+	Source string `json:"source"`
+	// Version is the parser version of derived fields, 0 for fields fetched from hacker news
+	Version   int   `json:"version"`
+	DerivedAt int64 `json:"-" db:"derived_at"`
+}
+
+// recordProvenance will record the source of fields of a job as of now
+func recordProvenance(x sqlx.Execer, hnId uint64, source string, version int, fields ...string) error {
+	now := time.Now().Unix()
+	for _, f := range fields {
+		sql := `INSERT OR REPLACE INTO job_field_provenance (hn_id, field, source, version, derived_at) VALUES (?, ?, ?, ?, ?)`
+		if _, err := x.Exec(sql, hnId, f, source, version, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SelectJobProvenance will return the provenance of the stored fields of a job, by field name
+func SelectJobProvenance(hnId uint64) ([]FieldProvenance, error) {
+	var rows []FieldProvenance
+	sql := `SELECT field, source, version, derived_at FROM job_field_provenance WHERE hn_id=? ORDER BY field`
+	if err := db.Select(&rows, sql, hnId); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// provenanceApiField is the provenance of a job field as returned by the api
+type provenanceApiField struct {
+	FieldProvenance
+	DerivedAt string `json:"derived_at"`
+	// Stale is set for fields derived by an older parser version, which a
+	// reprocess derives again
+	Stale      bool     `json:"stale"`
+	Confidence *float64 `json:"confidence,omitempty"`
+	Evidence   string   `json:"evidence,omitempty"`
+}
+
+// jobProvenanceApiHandler will return where each stored field of a job comes
+// from, at /api/jobs/<hn id>/provenance, along with the confidence and
+// evidence of the derived fields that have one
+func jobProvenanceApiHandler(w http.ResponseWriter, r *http.Request) {
+	id, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, apiJobsPath), "/")
+	hnId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || name != "provenance" {
+		apiError(w, http.StatusNotFound, "unknown job api path", nil)
+		return
+	}
+	if _, err := GetHiringJob(hnId); errors.Is(err, sql.ErrNoRows) {
+		apiError(w, http.StatusNotFound, fmt.Sprintf("job %d not found", hnId), nil)
+		return
+	} else if err != nil {
+		log.Println("failed to get hiring job.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+	rows, err := SelectJobProvenance(hnId)
+	if err != nil {
+		log.Println("failed to select job provenance.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+	evidence, err := SelectJobEvidence(hnId)
+	if err != nil {
+		log.Println("failed to select job evidence.", err)
+		apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), nil)
+		return
+	}
+
+	body := struct {
+		Id            uint64               `json:"id"`
+		ParserVersion int                  `json:"parser_version"`
+		Fields        []provenanceApiField `json:"fields"`
+	}{
+		Id:            hnId,
+		ParserVersion: parserVersion,
+		Fields:        make([]provenanceApiField, len(rows)),
+	}
+	for i, p := range rows {
+		f := provenanceApiField{
+			FieldProvenance: p,
+			DerivedAt:       time.Unix(p.DerivedAt, 0).UTC().Format(time.RFC3339),
+			Stale:           p.Source != sourceHN && p.Version < parserVersion,
+		}
+		if e, ok := evidence[p.Field]; ok {
+			f.Confidence = &e.Confidence
+			f.Evidence = e.Source
+		}
+		body.Fields[i] = f
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Println("failed to encode job provenance.", err)
+	}
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"net/http"
+)
+
+const (
+	// qrModuleSize is the size in pixels of a module of the rendered qr codes
+	qrModuleSize = 8
+	// qrQuietZone is the light border around the qr codes, in modules
+	qrQuietZone = 4
+)
+
+// errQrTooLong is returned for text past the capacity of the qr versions
+var errQrTooLong = errors.New("text too long for a qr code")
+
+// qrVersion is the block structure of a qr code version at error correction
+// level M, which restores up to 15% of the codewords
+type qrVersion struct {
+	// codewords is the number of data and error correction codewords
+	codewords int
+	// blocks is the number of blocks the codewords are split into, each with
+	// eccPerBlock error correction codewords
+	blocks      int
+	eccPerBlock int
+	// alignment are the centers of the alignment patterns on each axis
+	alignment []int
+}
+
+// qrVersions are the versions 1 to 10 at level M, which hold up to 213 bytes,
+// plenty for job permalinks
+var qrVersions = []qrVersion{
+	{26, 1, 10, nil},
+	{44, 1, 16, []int{6, 18}},
+	{70, 1, 26, []int{6, 22}},
+	{100, 2, 18, []int{6, 26}},
+	{134, 2, 24, []int{6, 30}},
+	{172, 4, 16, []int{6, 34}},
+	{196, 4, 18, []int{6, 22, 38}},
+	{242, 4, 22, []int{6, 24, 42}},
+	{292, 5, 22, []int{6, 26, 46}},
+	{346, 5, 26, []int{6, 28, 50}},
+}
+
+// dataCodewords will return the number of data codewords of the version
+func (v qrVersion) dataCodewords() int {
+	return v.codewords - v.blocks*v.eccPerBlock
+}
+
+// qrCode is the matrix of modules of a qr code, true for dark modules
+type qrCode struct {
+	size    int
+	modules [][]bool
+	// reserved marks the function patterns, which data and masks leave alone
+	reserved [][]bool
+}
+
+// encodeQr will encode text in byte mode as a qr code at level M, in the
+// smallest version it fits
+func encodeQr(text string) (*qrCode, error) {
+	data := []byte(text)
+	ver := 0
+	for ; ver < len(qrVersions); ver++ {
+		countBits := 8
+		if ver+1 >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*qrVersions[ver].dataCodewords() {
+			break
+		}
+	}
+	if ver == len(qrVersions) {
+		return nil, errQrTooLong
+	}
+	v := qrVersions[ver]
+
+	var bits qrBits
+	bits.append(0b0100, 4)
+	if ver+1 >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := 8 * v.dataCodewords()
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits.append(0, 1)
+	}
+	for len(bits)%8 != 0 {
+		bits.append(0, 1)
+	}
+	for pad := 0xec; len(bits) < capacity; pad ^= 0xec ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	q := newQrCode(ver+1, v)
+	q.placeCodewords(interleaveQrBlocks(bits.bytes(), v))
+	q.applyBestMask()
+	return q, nil
+}
+
+// qrBits is a buffer of bits, one per element
+type qrBits []bool
+
+// append will add the n low bits of v, most significant first
+func (b *qrBits) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>uint(i)&1 == 1)
+	}
+}
+
+// bytes will pack the bits, whose length is a multiple of 8
+func (b qrBits) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return out
+}
+
+// interleaveQrBlocks will split the data codewords in blocks, add the error
+// correction codewords of each and interleave them. The first blocks are one
+// codeword shorter when the codewords don't split evenly.
+func interleaveQrBlocks(data []byte, v qrVersion) []byte {
+	shortBlocks := v.blocks - v.codewords%v.blocks
+	shortLen := v.codewords/v.blocks - v.eccPerBlock
+	gen := rsGenerator(v.eccPerBlock)
+	blocks := make([][]byte, v.blocks)
+	eccs := make([][]byte, v.blocks)
+	for i, k := 0, 0; i < v.blocks; i++ {
+		n := shortLen
+		if i >= shortBlocks {
+			n++
+		}
+		blocks[i] = data[k : k+n]
+		eccs[i] = rsRemainder(blocks[i], gen)
+		k += n
+	}
+
+	out := make([]byte, 0, v.codewords)
+	for i := 0; i <= shortLen; i++ {
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := 0; i < v.eccPerBlock; i++ {
+		for _, e := range eccs {
+			out = append(out, e[i])
+		}
+	}
+	return out
+}
+
+// gfMul will multiply in GF(256) modulo the qr polynomial x^8+x^4+x^3+x^2+1
+func gfMul(a, b byte) byte {
+	var p byte
+	for ; b > 0; b >>= 1 {
+		if b&1 == 1 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1d
+		}
+	}
+	return p
+}
+
+// rsGenerator will return the coefficients of the reed-solomon generator
+// polynomial of degree n, highest first and leaving out the leading 1
+func rsGenerator(n int) []byte {
+	gen := make([]byte, n)
+	gen[n-1] = 1
+	var root byte = 1
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			gen[j] = gfMul(gen[j], root)
+			if j+1 < n {
+				gen[j] ^= gen[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return gen
+}
+
+// rsRemainder will return the error correction codewords of data
+func rsRemainder(data, gen []byte) []byte {
+	rem := make([]byte, len(gen))
+	for _, b := range data {
+		factor := b ^ rem[0]
+		copy(rem, rem[1:])
+		rem[len(rem)-1] = 0
+		for i, g := range gen {
+			rem[i] ^= gfMul(g, factor)
+		}
+	}
+	return rem
+}
+
+// newQrCode will return the matrix of a version with its function patterns drawn
+func newQrCode(version int, v qrVersion) *qrCode {
+	size := 17 + 4*version
+	q := &qrCode{size: size, modules: make([][]bool, size), reserved: make([][]bool, size)}
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.reserved[i] = make([]bool, size)
+	}
+
+	for i := 0; i < size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+	q.drawFinder(3, 3)
+	q.drawFinder(size-4, 3)
+	q.drawFinder(3, size-4)
+	last := len(v.alignment) - 1
+	for i, x := range v.alignment {
+		for j, y := range v.alignment {
+			// the corners with finder patterns are left out
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			q.drawAlignment(x, y)
+		}
+	}
+	// the format is drawn again once the mask is picked
+	q.drawFormat(0)
+	if version >= 7 {
+		q.drawVersion(version)
+	}
+	return q
+}
+
+// setFunction will set the module at column x and row y as part of a function pattern
+func (q *qrCode) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.reserved[y][x] = true
+}
+
+// drawFinder will draw a finder pattern and its separator centered on x, y
+func (q *qrCode) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= q.size || yy >= q.size {
+				continue
+			}
+			d := chebyshev(dx, dy)
+			q.setFunction(xx, yy, d != 2 && d != 4)
+		}
+	}
+}
+
+// drawAlignment will draw an alignment pattern centered on x, y
+func (q *qrCode) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			q.setFunction(x+dx, y+dy, chebyshev(dx, dy) != 1)
+		}
+	}
+}
+
+// chebyshev will return the distance of dx, dy from the center of a pattern
+func chebyshev(dx, dy int) int {
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
+
+// drawFormat will draw both copies of the error correction level and mask,
+// protected by a BCH code
+func (q *qrCode) drawFormat(mask int) {
+	// level M is 00
+	data := mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>uint(i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(i))
+	}
+	q.setFunction(8, q.size-8, true)
+}
+
+// drawVersion will draw both copies of the version, for versions 7 and up
+func (q *qrCode) drawVersion(version int) {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1f25
+	}
+	bits := version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := bits>>uint(i)&1 == 1
+		a, b := q.size-11+i%3, i/3
+		q.setFunction(a, b, dark)
+		q.setFunction(b, a, dark)
+	}
+}
+
+// placeCodewords will draw the codewords in the zigzag of two module wide
+// columns, from the bottom right corner, around the function patterns
+func (q *qrCode) placeCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		// the vertical timing pattern takes a whole column
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < q.size; vert++ {
+			y := vert
+			if upward {
+				y = q.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if q.reserved[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				q.modules[y][x] = codewords[i/8]>>uint(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// qrMasks are the patterns xored over the data modules, which break up
+// areas that would confuse readers
+var qrMasks = []func(x, y int) bool{
+	func(x, y int) bool { return (x+y)%2 == 0 },
+	func(x, y int) bool { return y%2 == 0 },
+	func(x, y int) bool { return x%3 == 0 },
+	func(x, y int) bool { return (x+y)%3 == 0 },
+	func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+	func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+	func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+	func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+}
+
+// applyMask will xor a mask over the data modules, so applying it twice undoes it
+func (q *qrCode) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if !q.reserved[y][x] && qrMasks[mask](x, y) {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask will apply the mask with the lowest penalty, as readers do best with
+func (q *qrCode) applyBestMask() {
+	best, lowest := 0, -1
+	for mask := range qrMasks {
+		q.applyMask(mask)
+		q.drawFormat(mask)
+		if p := q.penalty(); lowest < 0 || p < lowest {
+			best, lowest = mask, p
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormat(best)
+}
+
+// penalty will score the patterns of the matrix that are hard to read: runs
+// of a color, 2x2 blocks, finder lookalikes and unbalanced dark modules
+func (q *qrCode) penalty() int {
+	p := 0
+	at := func(x, y int, column bool) bool {
+		if column {
+			return q.modules[x][y]
+		}
+		return q.modules[y][x]
+	}
+	finderLike := []bool{true, false, true, true, true, false, true}
+	for _, column := range []bool{false, true} {
+		for y := 0; y < q.size; y++ {
+			run := 1
+			for x := 1; x <= q.size; x++ {
+				if x < q.size && at(x, y, column) == at(x-1, y, column) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					p += 3 + run - 5
+				}
+				run = 1
+			}
+			for x := 0; x+7 <= q.size; x++ {
+				match := true
+				for i, dark := range finderLike {
+					if at(x+i, y, column) != dark {
+						match = false
+						break
+					}
+				}
+				if match && (q.lightRun(x-4, x, y, column) || q.lightRun(x+7, x+11, y, column)) {
+					p += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				c := q.modules[y][x]
+				if q.modules[y-1][x] == c && q.modules[y][x-1] == c && q.modules[y-1][x-1] == c {
+					p += 3
+				}
+			}
+		}
+	}
+	deviation := dark*20 - q.size*q.size*10
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	p += deviation / (q.size * q.size) * 10
+	return p
+}
+
+// lightRun will return true when the modules from a to b of a row, or a
+// column, are light, counting the quiet zone outside the matrix as light
+func (q *qrCode) lightRun(a, b, y int, column bool) bool {
+	for x := a; x < b; x++ {
+		if x < 0 || x >= q.size {
+			continue
+		}
+		if (column && q.modules[x][y]) || (!column && q.modules[y][x]) {
+			return false
+		}
+	}
+	return true
+}
+
+// renderQrPng will draw a qr code with its quiet zone as a png
+func renderQrPng(q *qrCode) ([]byte, error) {
+	side := (q.size + 2*qrQuietZone) * qrModuleSize
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if !q.modules[y][x] {
+				continue
+			}
+			px, py := (x+qrQuietZone)*qrModuleSize, (y+qrQuietZone)*qrModuleSize
+			for dy := 0; dy < qrModuleSize; dy++ {
+				for dx := 0; dx < qrModuleSize; dx++ {
+					img.SetColorIndex(px+dx, py+dy, 1)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// jobQrHandler will serve a qr code of the permalink of a job, at
+// /job/<hn id>/qr.png, for print views and to move a job from a desktop
+// reader to a phone
+func jobQrHandler(w http.ResponseWriter, r *http.Request, id string) {
+	hj, err := GetHiringJob(paramValue(id, 0))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Println("failed to get hiring job.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	q, err := encodeQr(canonicalUrl(fmt.Sprintf("/job/%d", hj.HnId)))
+	if err != nil {
+		log.Println("failed to encode qr code.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	img, err := renderQrPng(q)
+	if err != nil {
+		log.Println("failed to render qr code.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	// permalinks never change
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", archiveMaxAge))
+	w.Write(img)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRsRemainder(t *testing.T) {
+	// the version 1-M examples of the qr code specification and tutorials,
+	// encoding 01234567 in numeric mode and HELLO WORLD in alphanumeric mode
+	cases := []struct {
+		data []byte
+		want []byte
+	}{
+		{
+			[]byte{0x10, 0x20, 0x0c, 0x56, 0x61, 0x80, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11},
+			[]byte{0xa5, 0x24, 0xd4, 0xc1, 0xed, 0x36, 0xc7, 0x87, 0x2c, 0x55},
+		},
+		{
+			[]byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17},
+			[]byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23},
+		},
+	}
+	for _, c := range cases {
+		if got := rsRemainder(c.data, rsGenerator(len(c.want))); !bytes.Equal(got, c.want) {
+			t.Errorf("rsRemainder(% x) = % x, want % x", c.data, got, c.want)
+		}
+	}
+}
+
+// qrFormatBits will read the first copy of the format bits of a qr code,
+// around the top left finder
+func qrFormatBits(q *qrCode) string {
+	var b strings.Builder
+	for i := 14; i >= 0; i-- {
+		var x, y int
+		switch {
+		case i <= 5:
+			x, y = 8, i
+		case i == 6:
+			x, y = 8, 7
+		case i == 7:
+			x, y = 8, 8
+		case i == 8:
+			x, y = 7, 8
+		default:
+			x, y = 14-i, 8
+		}
+		if q.modules[y][x] {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+func TestQrFormat(t *testing.T) {
+	// the format strings of level M from the specification, by mask
+	want := []string{
+		"101010000010010", "101000100100101", "101111001111100", "101101101001011",
+		"100010111111001", "100000011001110", "100111110010111", "100101010100000",
+	}
+	for mask, w := range want {
+		q := newQrCode(1, qrVersions[0])
+		q.drawFormat(mask)
+		if got := qrFormatBits(q); got != w {
+			t.Errorf("format of mask %d = %s, want %s", mask, got, w)
+		}
+	}
+}
+
+func TestQrVersionBits(t *testing.T) {
+	// the version information of the specification, by version
+	want := map[int]int{7: 0x07c94, 8: 0x085bc, 9: 0x09a99, 10: 0x0a4d3}
+	for version, w := range want {
+		q := newQrCode(version, qrVersions[version-1])
+		var bottom, right int
+		for i := 0; i < 18; i++ {
+			a, b := q.size-11+i%3, i/3
+			if q.modules[b][a] {
+				right |= 1 << i
+			}
+			if q.modules[a][b] {
+				bottom |= 1 << i
+			}
+		}
+		if right != w || bottom != w {
+			t.Errorf("version %d bits = %#x and %#x, want %#x", version, right, bottom, w)
+		}
+	}
+}
+
+// qrGolden are texts with the matrices they encode to in testdata, decoded
+// back to their text with an independent reader when recorded
+var qrGolden = []struct {
+	version int
+	text    string
+}{
+	{1, "HELLO WORLD"},
+	// two blocks
+	{4, "https://whoishiring.example.com/job/41234567"},
+	// version information and four blocks
+	{7, "https://whoishiring.example.com/search?q=" + strings.Repeat("golang+rust+", 6)},
+	// blocks of two lengths
+	{9, "https://whoishiring.example.com/search?q=" + strings.Repeat("golang+rust+", 10)},
+	// a 16 bit length
+	{10, strings.Repeat("0123456789abcdef", 13) + "01234"},
+}
+
+func TestEncodeQrGolden(t *testing.T) {
+	for _, g := range qrGolden {
+		q, err := encodeQr(g.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 17 + 4*g.version; q.size != want {
+			t.Errorf("%d bytes encoded in a %d module code, want version %d of %d modules", len(g.text), q.size, g.version, want)
+			continue
+		}
+		golden, err := os.ReadFile(fmt.Sprintf("testdata/qr_v%d.txt", g.version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows := strings.Split(strings.TrimSpace(string(golden)), "\n")
+		if len(rows) != q.size {
+			t.Errorf("version %d golden has %d rows, want %d", g.version, len(rows), q.size)
+			continue
+		}
+		for y, row := range q.modules {
+			var b strings.Builder
+			for _, dark := range row {
+				if dark {
+					b.WriteByte('#')
+				} else {
+					b.WriteByte('.')
+				}
+			}
+			if b.String() != rows[y] {
+				t.Errorf("version %d row %d = %s, want %s", g.version, y, b.String(), rows[y])
+				break
+			}
+		}
+	}
+}
+
+func TestEncodeQrVersions(t *testing.T) {
+	cases := []struct {
+		length  int
+		version int
+	}{
+		{0, 1},
+		{14, 1},
+		{15, 2},
+		{106, 6},
+		{107, 7},
+		{213, 10},
+	}
+	for _, c := range cases {
+		q, err := encodeQr(strings.Repeat("a", c.length))
+		if err != nil {
+			t.Fatalf("encodeQr of %d bytes failed. %v", c.length, err)
+		}
+		if want := 17 + 4*c.version; q.size != want {
+			t.Errorf("encodeQr of %d bytes has %d modules, want version %d of %d", c.length, q.size, c.version, want)
+		}
+	}
+	if _, err := encodeQr(strings.Repeat("a", 214)); err != errQrTooLong {
+		t.Errorf("encodeQr of 214 bytes = %v, want errQrTooLong", err)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	// regexSearchPrefix starts the regex searches, like re:/senior (go|rust)/i
+	regexSearchPrefix = "re:"
+	// regexPatternMax is the max length of the pattern of a regex search.
+	// Patterns are matched in linear time, so their length bounds the work
+	// of matching each post.
+	regexPatternMax = 200
+	// regexCacheSize is the number of compiled patterns kept
+	regexCacheSize = 64
+	// regexSearchTimeout bounds the time a regex search runs for, as the
+	// posts of a busy month can still take long to match
+	regexSearchTimeout = 2 * time.Second
+)
+
+// errRegexAllStories is returned for regex searches of every story, which
+// are only run over the posts of a month
+var errRegexAllStories = errors.New("regex searches run over the posts of a single month")
+
+// regexFlags maps the flags of a regex search to the flags of go regexps
+var regexFlags = map[rune]string{'i': "i", 'm': "m", 's': "s"}
+
+// isRegexSearch will return true when q is a regex search and they are enabled
+func isRegexSearch(q string) bool {
+	return cfg.RegexSearch && strings.HasPrefix(q, regexSearchPrefix)
+}
+
+// hasTerms will return true when the filter searches terms, which the search
+// engine ranks, rather than a regex
+func (f FilterState) hasTerms() bool {
+	return f.Query != "" && !isRegexSearch(f.Query)
+}
+
+// regexPattern will return the go regexp pattern of a regex search like
+// re:/senior (go|rust)/i, its flags turned into a (?i) prefix
+func regexPattern(q string) (string, error) {
+	v := strings.TrimPrefix(q, regexSearchPrefix)
+	end := strings.LastIndex(v, "/")
+	if !strings.HasPrefix(v, "/") || end < 1 {
+		return "", errors.New("regex searches look like re:/pattern/ with optional i, m or s flags")
+	}
+	pattern, flags := v[1:end], ""
+	for _, c := range v[end+1:] {
+		flag, ok := regexFlags[c]
+		if !ok {
+			return "", errors.New("unknown regex flag " + string(c) + ", expected i, m or s")
+		}
+		if !strings.Contains(flags, flag) {
+			flags += flag
+		}
+	}
+	if pattern == "" {
+		return "", errors.New("the regex is empty")
+	}
+	if len(pattern) > regexPatternMax {
+		return "", errors.New("regexes are limited to 200 characters")
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	if _, err := compileRegex(pattern); err != nil {
+		return "", err
+	}
+	return pattern, nil
+}
+
+// invalidRegexError will report the error of a regex search as an invalid q param
+func invalidRegexError(q string, err error) error {
+	return invalidParamError{Param: "q", Value: q, Expected: "a regex search like re:/senior (go|rust)/i", Reason: err.Error()}
+}
+
+// errRegexTimeout is the error of regex searches running past regexSearchTimeout
+var errRegexTimeout = fmt.Errorf("the regex search ran longer than %s, try a narrower pattern", regexSearchTimeout)
+
+// selectFiltered will select the rows of a query built with the conditions
+// of f into dest, like db.Select
+func selectFiltered(dest any, f FilterState, query string, args ...any) error {
+	return runFiltered(f, func(ctx context.Context) error {
+		return db.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+// getFiltered will get the row of a query built with the conditions of f into
+// dest, like db.Get
+func getFiltered(dest any, f FilterState, query string, args ...any) error {
+	return runFiltered(f, func(ctx context.Context) error {
+		return db.GetContext(ctx, dest, query, args...)
+	})
+}
+
+// runFiltered will run a query built with the conditions of f. Regex searches
+// are cancelled past regexSearchTimeout, which is reported as an invalid q
+// param.
+func runFiltered(f FilterState, run func(ctx context.Context) error) error {
+	if !isRegexSearch(f.Query) {
+		return run(context.Background())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), regexSearchTimeout)
+	defer cancel()
+	err := run(ctx)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return invalidRegexError(f.Query, errRegexTimeout)
+	}
+	return err
+}
+
+// regexSyntaxError will return the error of a regex search, nil for valid
+// ones and other searches
+func regexSyntaxError(q string) error {
+	if !isRegexSearch(q) {
+		return nil
+	}
+	_, err := regexPattern(q)
+	return err
+}
+
+var regexCache = newLruCache[string, *regexp.Regexp](regexCacheSize)
+
+// compileRegex will compile a pattern, reusing the patterns compiled for
+// recent searches as every post of a search is matched with its pattern
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Get(pattern); ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.New("invalid regex: " + strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+	}
+	regexCache.Add(pattern, re)
+	return re, nil
+}
+
+// jobRegexp is the job_regexp sql function, matching the text of a post
+// without markup against a pattern. Invalid patterns match no post.
+func jobRegexp(pattern, text string) bool {
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(searchIndexText(text))
+}
+
+// sqliteDriverName is the sqlite driver registered with the sql functions of the app
+const sqliteDriverName = "sqlite3_wih"
+
+// sqliteDriver will register the sqlite driver with the sql functions of the
+// app and return its name
+func sqliteDriver() string {
+	sql.Register(sqliteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("job_regexp", jobRegexp, true)
+		},
+	})
+	return sqliteDriverName
+}
+
+// regexSnippets will return the text around the first match of a regex
+// search in each job, by job id, with the matched words marked
+func regexSnippets(q string, jobs []HiringJobListItem) map[uint64]string {
+	pattern, err := regexPattern(q)
+	if err != nil {
+		return nil
+	}
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil
+	}
+	snippets := make(map[uint64]string, len(jobs))
+	for _, hj := range jobs {
+		text := searchIndexText(hj.Text)
+		words := textWords(text)
+		matched := make([]bool, len(words))
+		first := -1
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			for i, w := range words {
+				if w.End > loc[0] && w.Start < loc[1] {
+					matched[i] = true
+					if first < 0 {
+						first = i
+					}
+				}
+			}
+		}
+		if s := snippetAround(text, words, matched, first); s != "" {
+			snippets[hj.HnId] = s
+		}
+	}
+	return snippets
+}
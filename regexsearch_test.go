@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFilterWhereRegex(t *testing.T) {
+	prev := cfg.RegexSearch
+	cfg.RegexSearch = true
+	t.Cleanup(func() { cfg.RegexSearch = prev })
+
+	where, args, err := FilterState{Query: "re:/senior (go|rust)/i"}.where()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(where, " AND job_regexp(?, text)") || len(args) != 1 || args[0] != "(?i)senior (go|rust)" {
+		t.Errorf("where() = %q, %v", where, args)
+	}
+
+	for _, q := range []string{"re:/senior (go/", "re:/go/x", "re://", "re:go"} {
+		_, _, err := FilterState{Query: q}.where()
+		var pe invalidParamError
+		if !errors.As(err, &pe) || pe.Param != "q" {
+			t.Errorf("where() of %q = %v, want an invalid q param", q, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+)
+
+var (
+	templatesMu sync.Mutex
+	templates   = map[string]*template.Template{}
+)
+
+// templateFuncs are the functions every template can call
+var templateFuncs = template.FuncMap{
+	"latestRelease": latestRelease,
+}
+
+// renderBufferPool holds the buffers templates are executed into
+var renderBufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// loadTemplate will parse a template file once and return the cached template
+func loadTemplate(name string) (*template.Template, error) {
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	if tmpl, ok := templates[name]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).ParseFiles("templates/" + name)
+	if err != nil {
+		return nil, err
+	}
+	templates[name] = tmpl
+	return tmpl, nil
+}
+
+// errorPage is served when a template fails to render. It doesn't depend on
+// any template so it can't fail itself.
+const errorPage = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><title>who is hiring?</title></head>
+<body><p>Something went wrong while rendering this page. Please try again later.</p><p><a href="/">Back to jobs</a></p></body>
+</html>
+`
+
+// renderTemplate will execute a template into a pooled buffer and write it
+// to w. When the execution fails, none of the partial render is written:
+// the failure is counted and a minimal error page is served instead.
+func renderTemplate(w http.ResponseWriter, name string, data any) {
+	renderTemplateStatus(w, http.StatusOK, name, data)
+}
+
+// renderTemplateStatus will render a template like renderTemplate with the given status
+func renderTemplateStatus(w http.ResponseWriter, status int, name string, data any) {
+	buf := renderBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer renderBufferPool.Put(buf)
+
+	tmpl, err := loadTemplate(name)
+	if err == nil {
+		err = tmpl.Execute(buf, data)
+	}
+	if err != nil {
+		log.Printf("failed to render template %s. %v", name, err)
+		templateRenderFailures.Add(name, 1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Del("Cache-Control")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, errorPage)
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("failed to write template %s. %v", name, err)
+	}
+}
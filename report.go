@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// reportExamplesMax is the max number of job ids listed as examples of a parser failure
+const reportExamplesMax = 10
+
+// operatorReport summarizes a month of ingestion, pointing operators at the
+// stories missing posts, the headlines the parser fails on, the broken apply
+// links and the searches finding no jobs.
+type operatorReport struct {
+	Month   time.Time
+	Stories []HiringStorySummary
+	// Jobs are the live jobs posted during the month
+	Jobs int
+	// NoCompany are the ids of the jobs no company was detected for, and
+	// NoRole of the jobs whose headline gave no role
+	NoCompany []uint64
+	NoRole    []uint64
+	// Links are the apply links of the jobs checked, and BrokenLinks the
+	// checks of the links that are broken
+	Links       int
+	BrokenLinks []LinkCheck
+	// Misses are the most common searches without results made during the month
+	Misses []SearchMiss
+}
+
+// monthRange will return the unix times the month of t starts and ends at
+func monthRange(t time.Time) (int64, int64) {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start.Unix(), start.AddDate(0, 1, 0).Unix()
+}
+
+// previousMonth will return the first day of the month before the month of
+// t. Going back a month from t itself would skip the shorter months, as March
+// 31 less a month is March 3.
+func previousMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+}
+
+// buildOperatorReport will build the report of the month of t
+func buildOperatorReport(month time.Time) (operatorReport, error) {
+	from, to := monthRange(month)
+	report := operatorReport{Month: time.Unix(from, 0).UTC()}
+
+	stories, err := SelectHiringStories(storyKindHiring)
+	if err != nil {
+		return report, err
+	}
+	for _, hs := range stories {
+		if int64(hs.Time) >= from && int64(hs.Time) < to {
+			report.Stories = append(report.Stories, hs)
+		}
+	}
+
+	if report.Misses, err = SelectSearchMissesBetween(from, to, reportExamplesMax); err != nil {
+		return report, err
+	}
+
+	jobs, err := SelectHiringJobTextsBetween(from, to)
+	if err != nil {
+		return report, err
+	}
+	report.Jobs = len(jobs)
+	for _, hj := range jobs {
+		if hj.CompanyId == 0 {
+			report.NoCompany = append(report.NoCompany, hj.HnId)
+		}
+		if parseJobHeadline(hj.Text).Role == "" {
+			report.NoRole = append(report.NoRole, hj.HnId)
+		}
+	}
+
+	checks, err := checkJobLinks(context.Background(), jobs)
+	if err != nil {
+		return report, err
+	}
+	report.Links = len(checks)
+	for _, lc := range checks {
+		if lc.Broken() {
+			report.BrokenLinks = append(report.BrokenLinks, lc)
+		}
+	}
+	return report, nil
+}
+
+// failureLine will describe the jobs of a parser failure, with the links of a few of them
+func (r operatorReport) failureLine(what string, ids []uint64) string {
+	rate := 0.0
+	if r.Jobs > 0 {
+		rate = 100 * float64(len(ids)) / float64(r.Jobs)
+	}
+	line := fmt.Sprintf("%s: %d of %d jobs (%.1f%%)\n", what, len(ids), r.Jobs, rate)
+	for i, id := range ids {
+		if i == reportExamplesMax {
+			line += fmt.Sprintf("  and %d more\n", len(ids)-i)
+			break
+		}
+		line += fmt.Sprintf("  %s\n", canonicalUrl(fmt.Sprintf("/admin/job/%d", id)))
+	}
+	return line
+}
+
+// Subject will return the subject of the report message
+func (r operatorReport) Subject() string {
+	return "who is hiring? report for " + r.Month.Format("January 2006")
+}
+
+// Body will return the report as plain text
+func (r operatorReport) Body() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Jobs posted in %s: %d\n\n", r.Month.Format("January 2006"), r.Jobs)
+
+	b.WriteString("Stories\n")
+	if len(r.Stories) == 0 {
+		b.WriteString("  no story was posted\n")
+	}
+	for _, hs := range r.Stories {
+		fmt.Fprintf(&b, "  %s: %d jobs, %d of %d posts ingested", hs.Title, hs.Jobs, hs.Ingested, hs.Comments)
+		if hs.Missing() > 0 {
+			fmt.Fprintf(&b, ", %d missing", hs.Missing())
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\nParser failures\n")
+	b.WriteString(r.failureLine("No company", r.NoCompany))
+	b.WriteString(r.failureLine("No role", r.NoRole))
+
+	fmt.Fprintf(&b, "\nBroken apply links: %d of %d\n", len(r.BrokenLinks), r.Links)
+	for i, lc := range r.BrokenLinks {
+		if i == reportExamplesMax {
+			fmt.Fprintf(&b, "  and %d more\n", len(r.BrokenLinks)-i)
+			break
+		}
+		fmt.Fprintf(&b, "  %s, %s\n    %s\n", lc.Url, lc.Problem(), canonicalUrl(fmt.Sprintf("/admin/job/%d", lc.HnId)))
+	}
+
+	b.WriteString("\nSearches without results\n")
+	if len(r.Misses) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, m := range r.Misses {
+		fmt.Fprintf(&b, "  %s (%d)\n", m.Query, m.Count)
+	}
+	fmt.Fprintf(&b, "  suggestions: %s\n", canonicalUrl("/admin/searches"))
+	return b.String()
+}
+
+// reportCommand will send the operator report of a month, the previous month
+// by default. It is meant to run monthly from cron.
+func reportCommand(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	month := fs.String("month", previousMonth(time.Now()).Format("2006-01"), "month to report on, like 2026-10")
+	fs.Parse(args)
+
+	t, err := time.Parse("2006-01", *month)
+	if err != nil {
+		return fmt.Errorf("invalid month %q, expected a month like 2026-10", *month)
+	}
+	return sendOperatorReport(t)
+}
+
+// sendOperatorReport will send the operators the report of the month of t
+func sendOperatorReport(t time.Time) error {
+	report, err := buildOperatorReport(t)
+	if err != nil {
+		return err
+	}
+	if err := newNotifier().Notify(report.Subject(), report.Body()); err != nil {
+		return err
+	}
+	if err := RecordAudit("system", "report.send", t.Format("2006-01"), fmt.Sprintf("%d jobs", report.Jobs)); err != nil {
+		log.Println("failed to record audit entry.", err)
+	}
+	return nil
+}
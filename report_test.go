@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPreviousMonth(t *testing.T) {
+	cases := []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC), "2026-02"},
+		{time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), "2025-12"},
+		{time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), "2026-09"},
+		{time.Date(2026, 5, 31, 23, 0, 0, 0, time.FixedZone("", -3*3600)), "2026-05"},
+	}
+	for _, c := range cases {
+		if got := previousMonth(c.now).Format("2006-01"); got != c.want {
+			t.Errorf("previousMonth(%s) = %s, want %s", c.now, got, c.want)
+		}
+	}
+}
+
+func TestCheckJobLinks(t *testing.T) {
+	useTestDB(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			http.NotFound(w, r)
+		case "/get-only":
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/bots":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	jobs := []HiringJob{
+		{HnId: 1, ApplyUrl: srv.URL + "/careers"},
+		{HnId: 2, ApplyUrl: srv.URL + "/gone"},
+		{HnId: 3, ApplyUrl: srv.URL + "/get-only"},
+		{HnId: 4, ApplyUrl: srv.URL + "/bots"},
+		{HnId: 5, ApplyUrl: "mailto:jobs@example.com"},
+		{HnId: 6},
+	}
+	checks, err := checkJobLinks(context.Background(), jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint64]bool{1: false, 2: true, 3: false, 4: false, 5: true}
+	if len(checks) != len(want) {
+		t.Fatalf("checked %d links, want %d", len(checks), len(want))
+	}
+	for _, lc := range checks {
+		if lc.Broken() != want[lc.HnId] {
+			t.Errorf("link of job %d broken = %t, want %t (%d %q)", lc.HnId, lc.Broken(), want[lc.HnId], lc.Status, lc.Error)
+		}
+	}
+	var saved int
+	if err := db.Get(&saved, `SELECT COUNT(*) FROM link_check`); err != nil {
+		t.Fatal(err)
+	}
+	if saved != len(want) {
+		t.Errorf("saved %d link checks, want %d", saved, len(want))
+	}
+}
+
+func TestSelectUncheckedLinkJobs(t *testing.T) {
+	useTestDB(t)
+	if _, err := CreateHiringStory(1, storyKindHiring, "Ask HN: Who is hiring?", 1700000000); err != nil {
+		t.Fatal(err)
+	}
+	links := []string{"https://acme.com/jobs", "https://initech.com/careers", "https://globex.com/apply", "https://umbrella.com/jobs", ""}
+	for i, link := range links {
+		hj := HiringJob{HnId: uint64(i + 1), Text: "Acme | Engineer | Remote", Time: 1700000000}
+		if _, err := CreateHiringJob(1, jobStatusOk, hj); err != nil {
+			t.Fatal(err)
+		}
+		if err := SaveJobAttributes(hj.HnId, jobFields{"apply_url": link}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// checked recently, checked long ago, and checked before its link changed
+	checks := []LinkCheck{
+		{HnId: 1, Url: links[0], Status: 200, CheckedAt: 2000},
+		{HnId: 2, Url: links[1], Status: 200, CheckedAt: 500},
+		{HnId: 3, Url: "https://globex.com/old", Status: 404, CheckedAt: 2000},
+	}
+	for _, lc := range checks {
+		if err := SaveLinkCheck(lc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := SelectUncheckedLinkJobs(1, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint64
+	for _, hj := range jobs {
+		got = append(got, hj.HnId)
+	}
+	if fmt.Sprint(got) != "[2 3 4]" {
+		t.Errorf("SelectUncheckedLinkJobs = %v, want [2 3 4]", got)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// reprocessBatchSize is the number of jobs read at a time by reprocess.
+// Jobs are read in batches rather than through one open cursor, since
+// sqlite can't commit the updates while a read is in progress.
+const reprocessBatchSize = 500
+
+// reprocessJobs will run an enrichment chain over the stored jobs, optionally
+// limited to one story, and update their derived fields. HN is not queried.
+// Returns the number of updated jobs.
+func reprocessJobs(ctx context.Context, chain enrichmentChain, hsId uint64) (int, error) {
+	var total int
+	var afterId uint64
+	for {
+		jobs, err := SelectHiringJobBatch(hsId, afterId, reprocessBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(jobs) == 0 {
+			return total, nil
+		}
+		for _, hj := range jobs {
+			if err := ctx.Err(); err != nil {
+				return total, err
+			}
+			fields, err := chain.enrich(&hj)
+			if err != nil {
+				return total, fmt.Errorf("job %d: %w", hj.HnId, err)
+			}
+			// a partial chain doesn't make the job up to date with the configured one
+			if chain.signature() != enrichment.signature() {
+				delete(fields, "enrichers")
+				delete(fields, "parser_version")
+			}
+			if err := SaveJobAttributes(hj.HnId, fields); err != nil {
+				return total, err
+			}
+			total++
+		}
+		afterId = jobs[len(jobs)-1].HnId
+		log.Printf("reprocessed %d jobs, up to %d", total, afterId)
+	}
+}
+
+// reprocessCommand will run the reprocess command with its command line args
+func reprocessCommand(args []string) error {
+	fs := flag.NewFlagSet("reprocess", flag.ExitOnError)
+	story := fs.Uint64("story", 0, "only reprocess the jobs of this hacker news story id")
+	names := fs.String("enrichers", strings.Join(cfg.Enrichers, ","), "comma separated enrichers to run, all when empty")
+	fs.Parse(args)
+
+	var chain enrichmentChain
+	if *names == "" {
+		chain = newEnrichmentChain(nil)
+	} else {
+		chain = newEnrichmentChain(strings.Split(*names, ","))
+	}
+	if len(chain) == 0 {
+		return fmt.Errorf("no known enrichers in %q", *names)
+	}
+
+	if err := fetchExchangeRates(context.Background()); err != nil {
+		log.Println("failed to fetch exchange rates, keeping the configured ones.", err)
+	}
+	total, err := reprocessJobs(context.Background(), chain, *story)
+	if err != nil {
+		return err
+	}
+	detail := fmt.Sprintf("%d jobs with %s", total, chain.signature())
+	target := "all"
+	if *story > 0 {
+		target = fmt.Sprint(*story)
+	}
+	if err := RecordAudit("system", "jobs.reprocess", target, detail); err != nil {
+		log.Println("failed to record audit entry.", err)
+	}
+	log.Printf("reprocessed %s", detail)
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// responseCacheSize is the max number of cached responses
+const responseCacheSize = 1000
+
+// uncachedHeaders are left out of cached responses: the policy holds the
+// nonce of its request and is set for every request by securityHeaders, and
+// cookies belong to the client the response was first sent to
+var uncachedHeaders = []string{"Content-Security-Policy", "Set-Cookie"}
+
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// responseCache caches responses marked as "Cache-Control: public" by their
+// handler, keyed by request uri and the request headers listed in the
+// response Vary header.
+type responseCache struct {
+	entries *lruCache[string, cachedResponse]
+	// vary holds the Vary header names of the last response for a request uri
+	vary *lruCache[string, []string]
+}
+
+func newResponseCache(size int) *responseCache {
+	return &responseCache{
+		entries: newLruCache[string, cachedResponse](size),
+		vary:    newLruCache[string, []string](size),
+	}
+}
+
+// pageCache holds the rendered pages of archived stories
+var pageCache = newResponseCache(responseCacheSize)
+
+func init() {
+	bus.Subscribe(eventSyncCompleted, func(event) { pageCache.Purge() })
+	bus.Subscribe(eventCompaniesMerged, func(event) { pageCache.Purge() })
+	bus.Subscribe(eventTagsChanged, func(event) { pageCache.Purge() })
+	bus.Subscribe(eventStoryPinned, func(event) { pageCache.Purge() })
+}
+
+// responseRecorder writes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	rr.status = status
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
+// key will return the cache key of a request for the given vary header names
+func (rc *responseCache) key(r *http.Request, vary []string) string {
+	var b strings.Builder
+	b.WriteString(r.Method + " " + r.URL.RequestURI())
+	for _, h := range vary {
+		b.WriteString("\n" + h + ": " + strings.Join(r.Header.Values(h), ","))
+	}
+	return b.String()
+}
+
+// varyHeaders will return the header names of a response Vary header and
+// false when the response varies on everything.
+func varyHeaders(h http.Header) ([]string, bool) {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, true
+}
+
+// isPublic will return true when a response may be stored by shared caches
+func isPublic(h http.Header) bool {
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	return strings.Contains(cc, "public") && !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
+
+// wrap will serve cached responses for next when available and cache the
+// public responses of next otherwise.
+func (rc *responseCache) wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		uri := r.URL.RequestURI()
+		if vary, ok := rc.vary.Get(uri); ok {
+			if resp, ok := rc.entries.Get(rc.key(r, vary)); ok {
+				for k, v := range resp.header {
+					w.Header()[k] = v
+				}
+				w.Header().Set("X-Cache", "HIT")
+				w.WriteHeader(resp.status)
+				w.Write(resp.body)
+				return
+			}
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		if rec.status != http.StatusOK || !isPublic(rec.Header()) {
+			return
+		}
+		vary, ok := varyHeaders(rec.Header())
+		if !ok {
+			return
+		}
+		// pages with inline scripts carry the nonce of their request, which
+		// would not match the policy of the requests they are served to
+		if nonce := cspNonce(r); nonce != "" && bytes.Contains(rec.body.Bytes(), []byte(nonce)) {
+			return
+		}
+		header := rec.Header().Clone()
+		for _, h := range uncachedHeaders {
+			header.Del(h)
+		}
+		rc.vary.Add(uri, vary)
+		rc.entries.Add(rc.key(r, vary), cachedResponse{
+			status: rec.status,
+			header: header,
+			body:   rec.body.Bytes(),
+		})
+	}
+}
+
+// Purge will drop all cached responses
+func (rc *responseCache) Purge() {
+	rc.entries.Purge()
+	rc.vary.Purge()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseCacheSecurityHeaders(t *testing.T) {
+	rc := newResponseCache(10)
+	// nonce is the script nonce of the last page rendered
+	var nonce string
+	h := securityHeaders(rc.wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		if r.URL.Path == "/script" {
+			nonce = cspNonce(r)
+			io.WriteString(w, `<script nonce="`+nonce+`"></script>`)
+			return
+		}
+		io.WriteString(w, "<p>archived</p>")
+	}))
+	get := func(path string) *http.Response {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Result()
+	}
+
+	first, second := get("/page"), get("/page")
+	if second.Header.Get("X-Cache") != "HIT" {
+		t.Fatal("page without a nonce was not cached")
+	}
+	if first.Header.Get("Content-Security-Policy") == second.Header.Get("Content-Security-Policy") {
+		t.Error("cached page was served with the policy of the first request")
+	}
+
+	get("/script")
+	res := get("/script")
+	if res.Header.Get("X-Cache") == "HIT" {
+		t.Error("page using the nonce of its request was cached")
+	}
+	if !strings.Contains(res.Header.Get("Content-Security-Policy"), "'nonce-"+nonce+"'") {
+		t.Error("script nonce does not match the policy")
+	}
+}
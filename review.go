@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// reviewQueueSize is the number of uncertain fields listed on the review page
+const reviewQueueSize = 100
+
+// ReviewItem is a field derived with a low confidence, waiting for an admin to confirm it
+type ReviewItem struct {
+	HnId       uint64 `db:"hn_id"`
+	Field      string
+	Confidence float64
+	Source     string
+	Text       string
+	Time       uint64
+	// Value is the derived value, as text
+	Value string
+}
+
+// Headline will return the first line of the job text
+func (item ReviewItem) Headline() string {
+	return HiringJob{Text: item.Text}.Headline()
+}
+
+// SelectReviewQueue will select the uncertain fields of live jobs, the newest jobs first
+func SelectReviewQueue(limit int) ([]ReviewItem, error) {
+	var items []ReviewItem
+	sql := `SELECT e.hn_id, e.field, e.confidence, e.source, hj.text, hj.time
+            FROM job_attribute_evidence e
+            JOIN hiring_job_view hj ON hj.hn_id = e.hn_id
+            WHERE e.confidence < ? and hj.status=? and hj.duplicate_of=0
+            ORDER BY hj.time DESC, e.hn_id DESC, e.field
+            LIMIT ?`
+	if err := db.Select(&items, sql, uncertainConfidence, jobStatusOk, limit); err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		v, err := jobFieldText(item.HnId, item.Field)
+		if err != nil {
+			return nil, err
+		}
+		items[i].Value = v
+	}
+	return items, nil
+}
+
+// jobFieldText will return the value of a job attribute as text
+func jobFieldText(hnId uint64, field string) (string, error) {
+	if !isJobColumn(field) {
+		return "", fmt.Errorf("unknown job field %s", field)
+	}
+	var v string
+	err := db.Get(&v, `SELECT CAST(`+field+` AS TEXT) FROM hiring_job_view WHERE hn_id=?`, hnId)
+	return v, err
+}
+
+// isJobColumn will return true when col is one of the hiring_job_view columns
+func isJobColumn(col string) bool {
+	for _, c := range strings.Split(hiringJobColumns, ",") {
+		if strings.TrimSpace(c) == col {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfirmJobEvidence will mark the current value of an uncertain field as
+// confirmed by an admin, which gives it full confidence until it is derived
+// with another value
+func ConfirmJobEvidence(hnId uint64, field, admin string) error {
+	value, err := jobFieldText(hnId, field)
+	if err != nil {
+		return err
+	}
+	sql := `UPDATE job_attribute_evidence SET confidence=1, source=source || ?, confirmed_value=?
+            WHERE hn_id=? and field=?`
+	_, err = db.Exec(sql, fmt.Sprintf(", confirmed by %s on %s", admin, time.Now().UTC().Format(time.DateOnly)), value, hnId, field)
+	return err
+}
+
+// reviewQueueHandler will confirm an uncertain field on POST and list the
+// uncertain fields on GET
+func reviewQueueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if !requireSameOrigin(w, r) {
+			return
+		}
+
+		hnId := paramValue(r.PostFormValue("hn_id"), 0)
+		field := r.PostFormValue("field")
+		if err := ConfirmJobEvidence(hnId, field, cfg.AdminUser); err != nil {
+			log.Println("failed to confirm job evidence.", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		target := fmt.Sprintf("%d", hnId)
+		if err := RecordAudit(cfg.AdminUser, "evidence.confirm", target, "confirmed "+field); err != nil {
+			log.Println("failed to record audit entry.", err)
+		}
+		http.Redirect(w, r, "/admin/review", http.StatusSeeOther)
+		return
+	}
+
+	items, err := SelectReviewQueue(reviewQueueSize)
+	if err != nil {
+		log.Println("failed to select review queue.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Items     []ReviewItem
+		Limit     int
+		Threshold float64
+	}{
+		Items:     items,
+		Limit:     reviewQueueSize,
+		Threshold: uncertainConfidence,
+	}
+	renderTemplate(w, "admin_review.html", data)
+}
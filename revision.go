@@ -0,0 +1,217 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// diffContext is the number of unchanged words kept around the changes of a diff
+const diffContext = 8
+
+// JobRevision is a previous text of an edited job, replaced at a unix time
+type JobRevision struct {
+	Id         uint64
+	HnId       uint64 `db:"hn_id"`
+	Text       string
+	ReplacedAt uint64 `db:"replaced_at"`
+}
+
+// JobChange is an edit of a job, as the diff between two of its texts
+type JobChange struct {
+	ReplacedAt uint64
+	Diff       []diffOp
+}
+
+// Date will format the time of the edit
+func (jc JobChange) Date() string {
+	return time.Unix(int64(jc.ReplacedAt), 0).UTC().Format("Jan 2, 15:04 UTC")
+}
+
+const (
+	diffEqual  = ""
+	diffInsert = "insert"
+	diffDelete = "delete"
+	// diffSkip stands for unchanged words left out of a diff
+	diffSkip = "skip"
+)
+
+// diffOp is a run of words kept, inserted or deleted by an edit
+type diffOp struct {
+	Kind string
+	Text string
+}
+
+// refreshHiringJob will save the new text of an edited live job, keeping the
+// previous text as a revision, and enrich it again
+func refreshHiringJob(item *hnItem) error {
+	if HiringJobStatus(item.Dead, item.Deleted) != jobStatusOk {
+		return nil
+	}
+	hj, err := GetHiringJob(item.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if reason := item.skipReason(hj.HiringStoryId); reason != "" {
+		log.Printf("saved hiring job %d is now %s, keeping it as stored", item.Id, reason)
+		return nil
+	}
+	if err := UpdateJobReplies(hj.HnId, len(item.Kids)); err != nil {
+		return err
+	}
+	if hj.Poster == "" && item.By != "" {
+		// jobs saved before posters were stored get theirs on the next sync
+		if err := UpdateHiringJobPoster(hj.HnId, item.By); err != nil {
+			return err
+		}
+	}
+	if hj.Text == item.Text {
+		return nil
+	}
+
+	if err := UpdateHiringJobText(hj.HnId, hj.Text, item.Text); err != nil {
+		return err
+	}
+	hj.Text = item.Text
+	fields, err := enrichment.enrich(hj)
+	if err != nil {
+		return err
+	}
+	if err := SaveJobAttributes(hj.HnId, fields); err != nil {
+		return err
+	}
+	log.Printf("updated edited hiring job %d", hj.HnId)
+	return nil
+}
+
+// UpdateHiringJobText will replace the text of a job, storing its previous text as a revision
+func UpdateHiringJobText(hnId uint64, prev, text string) error {
+	now := time.Now().Unix()
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO hiring_job_revision (hn_id, text, replaced_at) VALUES (?, ?, ?)`, hnId, prev, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE hiring_job SET text=? WHERE hn_id=?`, text, hnId); err != nil {
+		return err
+	}
+	if err := recordProvenance(tx, hnId, sourceHN, 0, "text"); err != nil {
+		return err
+	}
+	if err := jobSearch.Index(tx, hnId, text); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE job_attribute SET updated_at=? WHERE hn_id=?`, now, hnId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SelectJobRevisions will return the previous texts of a job, oldest first
+func SelectJobRevisions(hnId uint64) ([]JobRevision, error) {
+	var revs []JobRevision
+	sql := `SELECT id, hn_id, text, replaced_at FROM hiring_job_revision WHERE hn_id=? ORDER BY id ASC`
+	if err := db.Select(&revs, sql, hnId); err != nil {
+		return nil, err
+	}
+
+	return revs, nil
+}
+
+// jobChanges will diff each revision of a job with the text that replaced it,
+// newest edit first
+func jobChanges(hj HiringJob, revs []JobRevision) []JobChange {
+	changes := make([]JobChange, len(revs))
+	for i, rev := range revs {
+		next := hj.Text
+		if i+1 < len(revs) {
+			next = revs[i+1].Text
+		}
+		changes[len(revs)-1-i] = JobChange{
+			ReplacedAt: rev.ReplacedAt,
+			Diff:       diffWords(jobPlainText(rev.Text), jobPlainText(next)),
+		}
+	}
+	return changes
+}
+
+// diffWords will diff two texts word by word, leaving out the unchanged words
+// farther than diffContext words from a change
+func diffWords(a, b string) []diffOp {
+	aw, bw := strings.Fields(a), strings.Fields(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of aw[i:] and bw[j:]
+	lcs := make([][]int, len(aw)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bw)+1)
+	}
+	for i := len(aw) - 1; i >= 0; i-- {
+		for j := len(bw) - 1; j >= 0; j-- {
+			if aw[i] == bw[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type word struct {
+		kind string
+		text string
+	}
+	var words []word
+	i, j := 0, 0
+	for i < len(aw) || j < len(bw) {
+		switch {
+		case i < len(aw) && j < len(bw) && aw[i] == bw[j]:
+			words = append(words, word{diffEqual, aw[i]})
+			i++
+			j++
+		case i < len(aw) && (j == len(bw) || lcs[i+1][j] >= lcs[i][j+1]):
+			words = append(words, word{diffDelete, aw[i]})
+			i++
+		default:
+			words = append(words, word{diffInsert, bw[j]})
+			j++
+		}
+	}
+
+	// unchanged words are kept only near a change
+	near := make([]bool, len(words))
+	for k, w := range words {
+		if w.kind == diffEqual {
+			continue
+		}
+		for d := k - diffContext; d <= k+diffContext; d++ {
+			if d >= 0 && d < len(words) {
+				near[d] = true
+			}
+		}
+	}
+
+	var ops []diffOp
+	for k, w := range words {
+		kind, text := w.kind, w.text
+		if !near[k] {
+			kind, text = diffSkip, "…"
+		}
+		if n := len(ops); n > 0 && ops[n-1].Kind == kind {
+			if kind != diffSkip {
+				ops[n-1].Text += " " + text
+			}
+			continue
+		}
+		ops = append(ops, diffOp{Kind: kind, Text: text})
+	}
+	return ops
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// salaryMin and salaryMax bound the yearly amounts accepted as salaries
+	salaryMin = 10000
+	salaryMax = 2000000
+)
+
+// salaryCurrencies maps currency symbols and codes to ISO codes
+var salaryCurrencies = map[string]string{
+	"$": "USD", "€": "EUR", "£": "GBP",
+	"usd": "USD", "eur": "EUR", "gbp": "GBP", "cad": "CAD", "aud": "AUD", "chf": "CHF",
+}
+
+// salaryPattern matches amounts and ranges with a currency symbol or code,
+// like "$180k-$220k", "€80-100k", "120,000 - 150,000 USD" or "USD 120k".
+var salaryPattern = regexp.MustCompile(`(?i)(?:\b(usd|eur|gbp|cad|aud|chf)\s?)?([$€£])?\s?(\d{1,3}(?:[,.]\d{3})+|\d+(?:\.\d+)?)\s?(k)?` +
+	`(?:\s?(?:-|–|to)\s?[$€£]?\s?(\d{1,3}(?:[,.]\d{3})+|\d+(?:\.\d+)?)\s?(k)?)?(?:\s?\b(usd|eur|gbp|cad|aud|chf)\b)?`)
+
+// hourlyPattern matches the rate suffixes of amounts that are not yearly salaries
+var hourlyPattern = regexp.MustCompile(`(?i)^\s?(/|per|an?)\s?(h|hr|hour|day|month|mo)\b`)
+
+// jobSalary will return the yearly salary range and currency of a job,
+// looking at the headline first. Single amounts return the same min and max.
+func jobSalary(text string) (uint64, uint64, string) {
+	lo, hi, currency, _, _ := matchSalary(text)
+	return lo, hi, currency
+}
+
+// matchSalary will find the salary of a job like jobSalary, also returning
+// the matched text and whether it was found in the headline.
+func matchSalary(text string) (uint64, uint64, string, string, bool) {
+	for i, t := range []string{jobPlainText(jobRoleText(text)), jobPlainText(text)} {
+		for _, m := range salaryPattern.FindAllStringSubmatchIndex(t, -1) {
+			g := func(i int) string {
+				if m[2*i] < 0 {
+					return ""
+				}
+				return t[m[2*i]:m[2*i+1]]
+			}
+			currency := salaryCurrencies[strings.ToLower(g(2))]
+			if currency == "" {
+				currency = salaryCurrencies[strings.ToLower(g(1)+g(7))]
+			}
+			if currency == "" || hourlyPattern.MatchString(t[m[1]:]) {
+				continue
+			}
+
+			// "80-100k" applies the k of the max to the min as well
+			kMin, kMax := g(4) != "", g(6) != ""
+			if g(5) == "" {
+				kMax = kMin
+			} else if !kMin && kMax {
+				kMin = true
+			}
+			lo, okLo := salaryAmount(g(3), kMin)
+			hi, okHi := salaryAmount(g(5), kMax)
+			if g(5) == "" {
+				hi, okHi = lo, okLo
+			}
+			if !okLo || !okHi || lo > hi {
+				continue
+			}
+			return lo, hi, currency, strings.TrimSpace(t[m[0]:m[1]]), i == 0
+		}
+	}
+	return 0, 0, "", "", false
+}
+
+// salaryAmount will parse an amount like "120", "120.5" or "120,000",
+// returning false when it is not a plausible yearly salary.
+func salaryAmount(v string, thousands bool) (uint64, bool) {
+	if strings.Count(v, ",")+strings.Count(v, ".") > 0 && len(v) > 4 && !strings.Contains(v[len(v)-3:], ".") {
+		v = strings.NewReplacer(",", "", ".", "").Replace(v)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, false
+	}
+	if thousands {
+		f *= 1000
+	}
+	if f < salaryMin || f > salaryMax {
+		return 0, false
+	}
+	return uint64(f), true
+}
+
+// formatSalary will format a salary range like "USD 120k-150k"
+func formatSalary(lo, hi uint64, currency string) string {
+	if currency == "" {
+		return ""
+	}
+	if lo == hi {
+		return fmt.Sprintf("%s %dk", currency, lo/1000)
+	}
+	return fmt.Sprintf("%s %dk-%dk", currency, lo/1000, hi/1000)
+}
@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestJobSalary(t *testing.T) {
+	cases := []struct {
+		text     string
+		lo, hi   uint64
+		currency string
+	}{
+		{"Acme | Engineer | Remote | $180k - $220k", 180000, 220000, "USD"},
+		{"Acme | Engineer | Berlin | €80-100k", 80000, 100000, "EUR"},
+		{"Acme | Engineer | London | £120k", 120000, 120000, "GBP"},
+		{"Acme | Engineer | Remote | 120,000 - 150,000 USD", 120000, 150000, "USD"},
+		{"Acme | Engineer | Toronto | CAD 130k", 130000, 130000, "CAD"},
+		{"Acme | Engineer | Zurich | 140k to 160k CHF", 140000, 160000, "CHF"},
+		// the headline wins over the body
+		{"Acme | Engineer | Remote | $150k<p>Seniors earn up to $250k.", 150000, 150000, "USD"},
+		{"Acme | Engineer | Remote<p>The salary is $130k-$160k plus equity.", 130000, 160000, "USD"},
+		// hourly rates, amounts without a currency and implausible amounts are no salaries
+		{"Acme | Contractor | Remote | $90/hr", 0, 0, ""},
+		{"Acme | Contractor | Remote | $600 per day", 0, 0, ""},
+		{"Acme | Engineer | Remote | 150k", 0, 0, ""},
+		{"Acme | Engineer | Remote | raised $20M", 0, 0, ""},
+		{"Acme | Engineer | Remote | $5k signing bonus", 0, 0, ""},
+	}
+	for _, c := range cases {
+		lo, hi, currency := jobSalary(c.text)
+		if lo != c.lo || hi != c.hi || currency != c.currency {
+			t.Errorf("jobSalary(%q) = %d, %d, %q, want %d, %d, %q", c.text, lo, hi, currency, c.lo, c.hi, c.currency)
+		}
+	}
+}
+
+func TestSalaryAmount(t *testing.T) {
+	cases := []struct {
+		v         string
+		thousands bool
+		want      uint64
+		ok        bool
+	}{
+		{"120", true, 120000, true},
+		{"120.5", true, 120500, true},
+		{"120,000", false, 120000, true},
+		{"120.000", false, 120000, true},
+		{"1,200,000", false, 1200000, true},
+		{"120", false, 0, false},
+		{"5000", true, 0, false},
+	}
+	for _, c := range cases {
+		got, ok := salaryAmount(c.v, c.thousands)
+		if got != c.want || ok != c.ok {
+			t.Errorf("salaryAmount(%q, %t) = %d, %t, want %d, %t", c.v, c.thousands, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestFormatSalary(t *testing.T) {
+	cases := []struct {
+		lo, hi   uint64
+		currency string
+		want     string
+	}{
+		{120000, 150000, "USD", "USD 120k-150k"},
+		{90000, 90000, "EUR", "EUR 90k"},
+		{0, 0, "", ""},
+	}
+	for _, c := range cases {
+		if got := formatSalary(c.lo, c.hi, c.currency); got != c.want {
+			t.Errorf("formatSalary(%d, %d, %q) = %q, want %q", c.lo, c.hi, c.currency, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// savedSearchesMax is the max number of searches a session saves
+	savedSearchesMax = 50
+	// savedSearchNameMax is the max length of the name of a saved search
+	savedSearchNameMax = 60
+)
+
+// SavedSearch is a named filter set saved by a reader
+type SavedSearch struct {
+	Id   uint64
+	Name string
+	// Params are the encoded filter params, without a cursor
+	Params    string
+	CreatedAt int64 `db:"created_at"`
+}
+
+// Filter will return the filter state of the search. Params are validated
+// when saved, so filters that became invalid since, like a removed tag, are
+// left out.
+func (s SavedSearch) Filter() FilterState {
+	q, _ := url.ParseQuery(s.Params)
+	f, _ := parseFilterState(q)
+	return f
+}
+
+// Url will return the reader url of the current story filtered by the search
+func (s SavedSearch) Url() string {
+	return s.Filter().cursorUrl("/", "", 0)
+}
+
+// Description will list the filter params of the search, like "q=go, remote=1"
+func (s SavedSearch) Description() string {
+	q := s.Filter().query()
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sessionOwner will return the owner of the searches saved by a session. The
+// session id is not stored, so the database does not hold reusable cookies.
+func sessionOwner(session string) string {
+	sum := sha256.Sum256([]byte(session))
+	return hex.EncodeToString(sum[:])
+}
+
+// SelectSavedSearches will select the searches of an owner, by name
+func SelectSavedSearches(owner string) ([]SavedSearch, error) {
+	var searches []SavedSearch
+	sql := `SELECT id, name, params, created_at FROM saved_search WHERE owner=? ORDER BY name COLLATE NOCASE`
+	if err := db.Select(&searches, sql, owner); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+// SaveSearch will save the filter params of f under a name, replacing the
+// search of the same name
+func SaveSearch(owner, name string, f FilterState) error {
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM saved_search WHERE owner=? and name != ?`, owner, name); err != nil {
+		return err
+	}
+	if count >= savedSearchesMax {
+		return fmt.Errorf("up to %d searches can be saved, delete one first", savedSearchesMax)
+	}
+	sql := `INSERT INTO saved_search (owner, name, params, created_at) VALUES (?, ?, ?, ?)
+            ON CONFLICT (owner, name) DO UPDATE SET params=excluded.params, created_at=excluded.created_at`
+	_, err := db.Exec(sql, owner, name, f.cursorParams(), time.Now().Unix())
+	return err
+}
+
+// DeleteSavedSearch will delete a search of an owner
+func DeleteSavedSearch(owner string, id uint64) error {
+	_, err := db.Exec(`DELETE FROM saved_search WHERE owner=? and id=?`, owner, id)
+	return err
+}
+
+// cursorParams will return the encoded filter params of the state without a
+// cursor or page size
+func (f FilterState) cursorParams() string {
+	f.After, f.Before, f.Limit = 0, 0, 0
+	return f.encode()
+}
+
+// parseSavedSearch will validate the name and the filter params of a search to save
+func parseSavedSearch(name, params string) (string, FilterState, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || len(name) > savedSearchNameMax {
+		return "", FilterState{}, fmt.Errorf("name the search with up to %d characters", savedSearchNameMax)
+	}
+	q, err := url.ParseQuery(params)
+	if err != nil {
+		return "", FilterState{}, errors.New("invalid filter params")
+	}
+	f, err := parseFilterState(q)
+	if err != nil {
+		return "", FilterState{}, err
+	}
+	if f.cursorParams() == "" {
+		return "", FilterState{}, errors.New("pick some filters to save first")
+	}
+	return name, f, nil
+}
+
+// savedSearchEntry is a saved search listed with its jobs in the current story
+type savedSearchEntry struct {
+	SavedSearch
+	Jobs int
+}
+
+// savedSearchesHandler will save or delete a search of the session on POST,
+// and list the saved searches with their jobs in the current story on GET
+func savedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	session, _ := requestSession(w, r)
+	owner := sessionOwner(session)
+
+	var message string
+	if r.Method == http.MethodPost {
+		if id := paramValue(r.PostFormValue("delete"), 0); id > 0 {
+			if err := DeleteSavedSearch(owner, id); err != nil {
+				log.Println("failed to delete saved search.", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, "/saved", http.StatusSeeOther)
+			return
+		}
+
+		name, f, err := parseSavedSearch(r.PostFormValue("name"), r.PostFormValue("params"))
+		if err == nil {
+			err = SaveSearch(owner, name, f)
+		}
+		if err == nil {
+			http.Redirect(w, r, "/saved", http.StatusSeeOther)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		message = "Failed to save the search: " + err.Error()
+	}
+
+	searches, err := SelectSavedSearches(owner)
+	if err != nil {
+		log.Println("failed to select saved searches.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	hs, err := GetCurrentHiringStory()
+	if err != nil {
+		log.Println("failed to get current story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	saved := withSavedExclusions(w, r, FilterState{})
+	entries := make([]savedSearchEntry, len(searches))
+	for i, s := range searches {
+		f := s.Filter()
+		if len(f.Exclude) == 0 {
+			f.Exclude, f.excludeSaved = saved.Exclude, saved.excludeSaved
+		}
+		// searches saved with a regex that no longer runs count no job
+		jobs, err := SelectJobList(jobScope{StoryId: hs.HnId}, f, 0)
+		if err != nil && len(paramErrors(err)) == 0 {
+			log.Println("failed to select saved search jobs.", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		entries[i] = savedSearchEntry{SavedSearch: s, Jobs: len(jobs)}
+	}
+
+	data := struct {
+		Story    HiringStory
+		Message  string
+		Searches []savedSearchEntry
+	}{
+		Story:    *hs,
+		Message:  message,
+		Searches: entries,
+	}
+	renderTemplate(w, "saved.html", data)
+}
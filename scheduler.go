@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// scheduleOff disables a scheduled job
+const scheduleOff = "off"
+
+// Scheduled runs are counted by job name, along with the failed runs, the
+// runs skipped as the previous one was still running and the time of the
+// last run, served on /admin/metrics
+var (
+	scheduledRuns     = expvar.NewMap("scheduled_runs")
+	scheduledFailures = expvar.NewMap("scheduled_failures")
+	scheduledSkips    = expvar.NewMap("scheduled_skips")
+	scheduledLastRun  = expvar.NewMap("scheduled_last_run")
+)
+
+// schedule computes the runs of a scheduled job
+type schedule interface {
+	// next will return the first run after t
+	next(t time.Time) time.Time
+}
+
+// intervalSchedule runs a job every interval
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// cronField holds the values a field of a cron spec matches, as bits
+type cronField uint64
+
+func (f cronField) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+// cronSchedule runs a job on the minutes matching a crontab spec, in UTC
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+	// anyDom and anyDow are set for * days, as days match either field when both are restricted
+	anyDom, anyDow bool
+}
+
+// cronAliases are the shorthands of common cron specs
+var cronAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// parseSchedule will parse a crontab spec of minute, hour, day of month, month
+// and day of week fields, like "30 3 * * 1-5", an alias like @daily or an
+// interval like "@every 1h"
+func parseSchedule(spec string) (schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if v, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("invalid interval %q, expected a duration of at least 1s like 1h", v)
+		}
+		return intervalSchedule(d), nil
+	}
+	if v, ok := cronAliases[spec]; ok {
+		spec = v
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q, expected 5 fields like \"0 3 * * *\"", spec)
+	}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var parsed [5]cronField
+	for i, field := range fields {
+		f, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		parsed[i] = f
+	}
+	s := &cronSchedule{minute: parsed[0], hour: parsed[1], dom: parsed[2], month: parsed[3], dow: parsed[4],
+		anyDom: fields[2] == "*", anyDow: fields[4] == "*"}
+	// 7 is sunday too
+	if s.dow.has(7) {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// parseCronField will parse a comma separated list of values, ranges like 1-5
+// and steps like */15 or 0-30/10, from lo to hi
+func parseCronField(field string, lo, hi int) (cronField, error) {
+	var f cronField
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if r, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", s)
+			}
+			part, step = r, n
+		}
+		first, last := lo, hi
+		if part != "*" {
+			a, b, isRange := strings.Cut(part, "-")
+			var err error
+			if first, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", a)
+			}
+			last = first
+			if isRange {
+				if last, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid value %q", b)
+				}
+			} else if step > 1 {
+				last = hi
+			}
+		}
+		if first < lo || last > hi || first > last {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, lo, hi)
+		}
+		for v := first; v <= last; v += step {
+			f |= 1 << uint(v)
+		}
+	}
+	return f, nil
+}
+
+// matchesDay will return true when the day of t matches the day fields
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	dom, dow := s.dom.has(t.Day()), s.dow.has(int(t.Weekday()))
+	if s.anyDom || s.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	// specs like "0 0 30 2 *" never match, so the search gives up after 5 years
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		switch {
+		case !s.month.has(int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !s.hour.has(t.Hour()):
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !s.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return end
+}
+
+// scheduledJob is a job the scheduler runs on a schedule
+type scheduledJob struct {
+	name     string
+	spec     string
+	schedule schedule
+	run      func(context.Context) error
+	// running is set while the job runs, so runs never overlap
+	running atomic.Bool
+}
+
+// scheduler runs the periodic jobs of the app, like syncs and backups, in
+// the server process. Runs are delayed by a random jitter, skipped while the
+// previous run of the job is still going, and recover from panics, so a
+// failing job never takes the server or the other jobs down.
+type scheduler struct {
+	jitter time.Duration
+	jobs   []*scheduledJob
+}
+
+func newScheduler(jitter time.Duration) *scheduler {
+	return &scheduler{jitter: jitter}
+}
+
+// Register will add a job run on spec, a crontab spec or "@every <duration>".
+// Jobs scheduled "off" are left out.
+func (s *scheduler) Register(name, spec string, run func(context.Context) error) error {
+	if spec == scheduleOff {
+		return nil
+	}
+	sched, err := parseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	s.jobs = append(s.jobs, &scheduledJob{name: name, spec: spec, schedule: sched, run: run})
+	return nil
+}
+
+// Start will run every job on its schedule until ctx is done
+func (s *scheduler) Start(ctx context.Context) {
+	for _, j := range s.jobs {
+		log.Printf("scheduled %s on %q", j.name, j.spec)
+		go s.loop(ctx, j)
+	}
+}
+
+// loop will wait for each run of a job and start it
+func (s *scheduler) loop(ctx context.Context, j *scheduledJob) {
+	for {
+		wait := time.Until(j.schedule.next(time.Now()))
+		if s.jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(s.jitter)))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !j.running.CompareAndSwap(false, true) {
+			log.Printf("skipped %s, the previous run is still running", j.name)
+			scheduledSkips.Add(j.name, 1)
+			continue
+		}
+		go func() {
+			defer j.running.Store(false)
+			s.runJob(ctx, j)
+		}()
+	}
+}
+
+// runJob will run a job once, logging and counting its failures and panics
+func (s *scheduler) runJob(ctx context.Context, j *scheduledJob) {
+	start := time.Now()
+	scheduledRuns.Add(j.name, 1)
+	last := new(expvar.Int)
+	last.Set(start.Unix())
+	scheduledLastRun.Set(j.name, last)
+	defer func() {
+		if v := recover(); v != nil {
+			log.Printf("scheduled %s panicked. %v", j.name, v)
+			scheduledFailures.Add(j.name, 1)
+		}
+	}()
+	if err := j.run(ctx); err != nil {
+		log.Printf("scheduled %s failed after %s. %v", j.name, time.Since(start).Round(time.Millisecond), err)
+		scheduledFailures.Add(j.name, 1)
+		return
+	}
+	log.Printf("scheduled %s finished in %s", j.name, time.Since(start).Round(time.Millisecond))
+}
+
+// startScheduler will run the periodic jobs of the server on their configured
+// schedules until ctx is done
+func startScheduler(ctx context.Context) error {
+	s := newScheduler(cfg.ScheduleJitter)
+	jobs := []struct {
+		name string
+		spec string
+		run  func(context.Context) error
+	}{
+		{"sync", cfg.SyncSchedule, syncData},
+		{"check", cfg.CheckSchedule, func(ctx context.Context) error {
+			_, err := checkConsistency(ctx, true)
+			return err
+		}},
+		// the report of the previous month, as cron runs it on the first day of a month
+		{"report", cfg.ReportSchedule, func(context.Context) error {
+			return sendOperatorReport(previousMonth(time.Now()))
+		}},
+		{"backup", cfg.BackupSchedule, backupDatabase},
+		{"prune", cfg.PruneSchedule, pruneData},
+	}
+	for _, j := range jobs {
+		if err := s.Register(j.name, j.spec, j.run); err != nil {
+			return err
+		}
+	}
+	s.Start(ctx)
+	return nil
+}
+
+// pruneData will delete the data kept for a limited time, like the jobs seen
+// by sessions whose cookie expired
+func pruneData(context.Context) error {
+	n, err := PruneSeenJobs(time.Now().Add(-sessionMaxAge))
+	if err != nil {
+		return err
+	}
+	log.Printf("pruned %d seen jobs", n)
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"@yearly",
+		"@every 1",
+		"@every 500ms",
+	} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("parseSchedule(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// a wednesday
+	from := time.Date(2026, 10, 14, 10, 17, 42, 0, time.UTC)
+	cases := []struct {
+		spec string
+		want []string
+	}{
+		{"* * * * *", []string{"2026-10-14T10:18", "2026-10-14T10:19"}},
+		{"@hourly", []string{"2026-10-14T11:00", "2026-10-14T12:00"}},
+		{"@daily", []string{"2026-10-15T00:00", "2026-10-16T00:00"}},
+		{"@weekly", []string{"2026-10-18T00:00", "2026-10-25T00:00"}},
+		{"@monthly", []string{"2026-11-01T00:00", "2026-12-01T00:00"}},
+		{"30 3 * * *", []string{"2026-10-15T03:30", "2026-10-16T03:30"}},
+		// steps, ranges and lists
+		{"*/15 * * * *", []string{"2026-10-14T10:30", "2026-10-14T10:45", "2026-10-14T11:00"}},
+		{"0-30/10 9-10 * * *", []string{"2026-10-14T10:20", "2026-10-14T10:30", "2026-10-15T09:00"}},
+		{"5/20 * * * *", []string{"2026-10-14T10:25", "2026-10-14T10:45", "2026-10-14T11:05"}},
+		{"0 8,20 * * *", []string{"2026-10-14T20:00", "2026-10-15T08:00"}},
+		// weekdays, with 0 and 7 both sunday
+		{"0 9 * * 1-5", []string{"2026-10-15T09:00", "2026-10-16T09:00", "2026-10-19T09:00"}},
+		{"0 0 * * 7", []string{"2026-10-18T00:00", "2026-10-25T00:00"}},
+		{"0 0 * * 0", []string{"2026-10-18T00:00", "2026-10-25T00:00"}},
+		// restricted days of month and week match either one
+		{"0 0 1 * 5", []string{"2026-10-16T00:00", "2026-10-23T00:00", "2026-10-30T00:00", "2026-11-01T00:00"}},
+		// a restricted day of month alone must match
+		{"0 0 31 * *", []string{"2026-10-31T00:00", "2026-12-31T00:00", "2027-01-31T00:00"}},
+		{"0 0 29 2 *", []string{"2028-02-29T00:00"}},
+		{"0 12 1 1,7 *", []string{"2027-01-01T12:00", "2027-07-01T12:00"}},
+		{"@every 90m", []string{"2026-10-14T11:47", "2026-10-14T13:17"}},
+	}
+	for _, c := range cases {
+		s, err := parseSchedule(c.spec)
+		if err != nil {
+			t.Errorf("parseSchedule(%q) failed. %v", c.spec, err)
+			continue
+		}
+		at := from
+		for _, w := range c.want {
+			at = s.next(at)
+			if got := at.UTC().Format("2006-01-02T15:04"); got != w {
+				t.Errorf("%q: next run %s, want %s", c.spec, got, w)
+				break
+			}
+		}
+	}
+}
+
+func TestScheduleNextNever(t *testing.T) {
+	s, err := parseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	if next := s.next(from); next.Before(from.AddDate(5, 0, 0)) {
+		t.Errorf("next run of february 30 = %s, want no run in 5 years", next)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// searchAllStories is the story param searching the jobs of every story
+	searchAllStories = "all"
+	// searchSyntaxHint describes the search syntax
+	searchSyntaxHint = `words, "quoted phrases", parentheses and AND, OR, NOT between terms`
+)
+
+// errSearchUnavailable is returned when sqlite was built without FTS5
+var errSearchUnavailable = errors.New("search needs sqlite with FTS5, build with -tags sqlite_fts5 or set WIH_SEARCH_ENGINE=scan")
+
+// searchExpr will parse a search with every term also matching its synonyms
+func searchExpr(q string) *searchNode {
+	return searchTerms(q).withSynonyms(searchSynonyms)
+}
+
+// searchTerms will parse a search as typed. Searches with a syntax error match
+// the jobs with every word instead, each word searched as plain text.
+func searchTerms(q string) *searchNode {
+	if n, err := parseSearch(q); err == nil {
+		return n
+	}
+	var n *searchNode
+	for _, t := range strings.Fields(q) {
+		n = joinSearch(tokenAnd, n, &searchNode{Op: tokenTerm, Text: t})
+	}
+	return n
+}
+
+// searchSyntaxError will return the error of the q param when its search syntax is invalid
+func searchSyntaxError(q string) error {
+	if err := regexSyntaxError(q); err != nil {
+		return invalidRegexError(q, err)
+	}
+	if isRegexSearch(q) {
+		return nil
+	}
+	if _, err := parseSearch(q); err != nil {
+		return invalidParamError{Param: "q", Value: q, Expected: searchSyntaxHint, Reason: err.Error()}
+	}
+	return nil
+}
+
+// searchIndexText will return the text a job is indexed by
+func searchIndexText(text string) string {
+	return jobPlainText(text)
+}
+
+// searchError will wrap the errors of search queries, telling a missing FTS5 module apart
+func searchError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "no such module: fts5") {
+		return fmt.Errorf("%w: %v", errSearchUnavailable, err)
+	}
+	return err
+}
+
+// searchResult are the jobs found by a search
+type searchResult struct {
+	Jobs []HiringJobListItem
+	// Query is the search the jobs were found with, which matches close
+	// spellings of the terms when Spellings are set
+	Query     string
+	Spellings []string
+}
+
+// searchQuery will return the search the jobs of a scope matching f are found
+// with, leaving its jobs out. Typo tolerant searches finding fewer than
+// typoFallbackBelow jobs are run with every term also matching its close
+// spellings among the words of the live jobs.
+func searchQuery(scope jobScope, f FilterState) (searchResult, error) {
+	if !f.Typos || !f.hasTerms() {
+		return searchResult{Query: f.Query}, nil
+	}
+	found, err := CountJobList(scope, f)
+	if err != nil || found >= typoFallbackBelow {
+		return searchResult{Query: f.Query}, err
+	}
+	words, err := typoVocabulary.get()
+	if err != nil {
+		return searchResult{}, err
+	}
+	// synonyms are added when the expanded search is run
+	expanded, spellings := searchTerms(f.Query).withSpellings(words)
+	if len(spellings) == 0 {
+		return searchResult{Query: f.Query}, nil
+	}
+	// the fts5 query of a parsed search is a search of the same syntax
+	return searchResult{Query: expanded.fts(), Spellings: spellings}, nil
+}
+
+// searchJobs will search the jobs of a scope matching f, with the search of
+// searchQuery
+func searchJobs(scope jobScope, f FilterState, limit int) (searchResult, error) {
+	res, err := searchQuery(scope, f)
+	if err != nil {
+		return res, err
+	}
+	f.Query = res.Query
+	res.Jobs, err = SelectJobList(scope, f, limit)
+	return res, err
+}
+
+// searchSnippets will return the text around the matched terms of a search
+// in each job, by job id
+func searchSnippets(q string, jobs []HiringJobListItem) (map[uint64]string, error) {
+	if q == "" {
+		return nil, nil
+	}
+	if isRegexSearch(q) {
+		return regexSnippets(q, jobs), nil
+	}
+	ids := make([]uint64, len(jobs))
+	for i, hj := range jobs {
+		ids[i] = hj.HnId
+	}
+	return jobSearch.Snippets(searchExpr(q), ids)
+}
+
+// ensureSearchIndex will index the jobs missing from the search index, like
+// the jobs saved before it existed
+func ensureSearchIndex() error {
+	n, err := jobSearch.IndexMissing()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("indexed %d hiring jobs for %s search", n, jobSearch.Name())
+	}
+	return nil
+}
+
+// reindexCommand will rebuild the search index of the configured engine from
+// the stored jobs, after the index got corrupted or its tokenizer changed
+func reindexCommand(args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	fs.Parse(args)
+
+	start := time.Now()
+	n, err := jobSearch.Rebuild()
+	if err != nil {
+		return err
+	}
+	typoVocabulary.purge()
+	fmt.Printf("indexed %d hiring jobs for %s search in %s\n", n, jobSearch.Name(), time.Since(start).Round(time.Millisecond))
+	if err := RecordAudit("system", "search.reindex", jobSearch.Name(), fmt.Sprintf("%d jobs", n)); err != nil {
+		log.Println("failed to record audit entry.", err)
+	}
+	return nil
+}
+
+// searchStory will return the story searched, defaulting to the current one
+// served on / when hsId is 0
+func searchStory(hsId uint64) (*HiringStory, error) {
+	if hsId > 0 {
+		return GetHiringStory(hsId)
+	}
+	return GetCurrentHiringStory()
+}
+
+// monthFacet counts the jobs a search found in the story of a month
+type monthFacet struct {
+	StoryId uint64
+	Month   string
+	Count   int
+	// Url is the search narrowed down to the story
+	Url string
+}
+
+// searchMonthFacets will list the jobs found in each story, newest stories
+// first, linking to the search of f narrowed down to each story
+func searchMonthFacets(counts []storyJobCount, f FilterState) []monthFacet {
+	facets := make([]monthFacet, len(counts))
+	for i, c := range counts {
+		q := f.query()
+		q.Set("story", strconv.FormatUint(c.StoryId, 10))
+		month, _ := storyTitleParts(c.StoryTitle)
+		facets[i] = monthFacet{StoryId: c.StoryId, Month: month, Count: c.Jobs, Url: "/search?" + q.Encode()}
+	}
+	return facets
+}
+
+// searchHandler will serve the jobs of a story, or of every story with
+// story=all, matching the q param and the filter params, best matches first
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	storyParam := r.URL.Query().Get("story")
+	all := storyParam == searchAllStories
+	hs, err := searchStory(paramValue(storyParam, 0))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Println("failed to get story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	filter, _ := parseFilterState(r.URL.Query())
+	filter = withSeenJobs(w, r, withSavedExclusions(w, r, withPreferences(w, r, filter)))
+	scope := jobScope{StoryId: hs.HnId}
+	storyParam = strconv.FormatUint(hs.HnId, 10)
+	if all {
+		scope, storyParam = jobScope{}, searchAllStories
+	}
+	var entries []jobListEntry
+	var facets []monthFacet
+	var page listPage
+	var found int
+	var didYouMean string
+	var spellings []string
+	// searches may only filter, like by company or employment type, and
+	// regex searches only run over the posts of a month. Invalid regexes
+	// match no job.
+	regexAll := all && isRegexSearch(filter.Query)
+	searched := !regexAll && regexSyntaxError(filter.Query) == nil && (filter.Query != "" || filter.cursorParams() != "")
+	if searched {
+		res, err := searchQuery(scope, filter)
+		if err != nil {
+			filterError(w, "failed to search hiring jobs.", err)
+			return
+		}
+		matched := filter
+		matched.Query = res.Query
+		if page, err = selectJobPage(scope, matched); err != nil {
+			filterError(w, "failed to search hiring jobs.", err)
+			return
+		}
+		if all {
+			counts, err := CountJobsByStory(scope, matched)
+			if err != nil {
+				filterError(w, "failed to count hiring jobs by story.", err)
+				return
+			}
+			facets = searchMonthFacets(counts, filter)
+		}
+		jobs := page.Jobs
+		found = page.Total
+		spellings = res.Spellings
+		recordSearchMiss(filter, found)
+		var snippets map[uint64]string
+		loadPanels(r.Context(),
+			panel("suggestion", &didYouMean, func() (string, error) { return searchSuggestion(filter.Query, found) }),
+			panel("snippets", &snippets, func() (map[uint64]string, error) { return searchSnippets(res.Query, jobs) }),
+		)
+		entries = make([]jobListEntry, len(jobs))
+		ids := make([]uint64, len(jobs))
+		for i, hj := range jobs {
+			entries[i] = jobListEntry{HiringJobListItem: hj, Content: renderJobBody(r, hj.HiringJob, false)}
+			if s, ok := snippets[hj.HnId]; ok {
+				entries[i].Snippet = highlightSnippet(s)
+			}
+			ids[i] = hj.HnId
+		}
+		recordSeenJobs(w, r, ids...)
+	}
+
+	// searches with a syntax error still show the jobs with every word,
+	// while invalid regexes match no job
+	var syntaxError string
+	if regexAll {
+		syntaxError = errRegexAllStories.Error()
+	} else if isRegexSearch(filter.Query) {
+		if err := regexSyntaxError(filter.Query); err != nil {
+			syntaxError = err.Error()
+		}
+	} else if _, err := parseSearch(filter.Query); filter.Query != "" && err != nil {
+		syntaxError = err.Error()
+	}
+
+	data := struct {
+		Story       HiringStory
+		All         bool
+		Searched    bool
+		Query       string
+		SyntaxError string
+		Regex       bool
+		DidYouMean  string
+		SuggestUrl  string
+		Typos       bool
+		Unseen      bool
+		Sort        string
+		SortName    string
+		Sorts       []sortLink
+		Type        string
+		Types       []string
+		Spellings   []string
+		Found       int
+		Months      []monthFacet
+		Jobs        []jobListEntry
+		From        int
+		To          int
+		PrevUrl     string
+		NextUrl     string
+		Canonical   string
+	}{
+		Story:       *hs,
+		All:         all,
+		Searched:    searched,
+		Found:       found,
+		Months:      facets,
+		Query:       filter.Query,
+		Typos:       filter.Typos,
+		Unseen:      filter.Unseen,
+		Sort:        filter.Sort,
+		SortName:    filter.SortName(),
+		Sorts:       filter.sortLinks("/search"),
+		Type:        filter.EmploymentType,
+		Types:       employmentTypes,
+		Spellings:   spellings,
+		SyntaxError: syntaxError,
+		Regex:       isRegexSearch(filter.Query),
+		DidYouMean:  didYouMean,
+		Jobs:        entries,
+		From:        page.Offset + 1,
+		To:          page.Offset + len(entries),
+		Canonical:   canonicalUrl(r.URL.RequestURI()),
+	}
+	data.PrevUrl, data.NextUrl = page.pageUrls("/search", filter, url.Values{"story": {storyParam}})
+	setLinkHeaders(w, data.PrevUrl, data.NextUrl)
+	if didYouMean != "" {
+		suggested := filter
+		suggested.Query = didYouMean
+		q := suggested.query()
+		q.Set("story", storyParam)
+		data.SuggestUrl = "/search?" + q.Encode()
+	}
+	renderTemplate(w, "search.html", data)
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/blevesearch/bleve/analysis"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/analysis/lang/en"
+	"github.com/blevesearch/bleve/document"
+	"github.com/blevesearch/bleve/index"
+	"github.com/blevesearch/bleve/index/store/gtreap"
+	"github.com/blevesearch/bleve/index/upsidedown"
+	"github.com/blevesearch/bleve/mapping"
+	"github.com/blevesearch/bleve/search"
+	"github.com/blevesearch/bleve/search/collector"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/jmoiron/sqlx"
+)
+
+// bleveEngine searches a Bleve index of the jobs, analyzed by its english
+// analyzer and scored by Bleve's tf-idf with the searchFieldBoosts of each
+// field. The index is kept in memory and built at startup like the scan
+// engine's, as the on-disk stores of Bleve lock their file for as long as the
+// app runs, which would block the reprocess and reindex commands.
+type bleveEngine struct {
+	once  sync.Once
+	idx   index.Index
+	err   error
+	texts *analysis.Analyzer
+	story *analysis.Analyzer
+	m     mapping.IndexMapping
+}
+
+// bleveFields are the names of the indexed fields of a job, in the order of jobSearchFields
+var bleveFields = [searchFieldCount]string{"text", "company", "role", "headline"}
+
+// bleveStoryField holds the story id of a job, to rank the jobs of a story
+const bleveStoryField = "story"
+
+func newBleveEngine() *bleveEngine {
+	return &bleveEngine{}
+}
+
+func (*bleveEngine) Name() string {
+	return "bleve"
+}
+
+// open will return the index, creating it and its analyzers on the first call
+func (e *bleveEngine) open() (index.Index, error) {
+	e.once.Do(func() {
+		m := mapping.NewIndexMapping()
+		m.DefaultAnalyzer = en.AnalyzerName
+		e.texts, e.story = m.AnalyzerNamed(en.AnalyzerName), m.AnalyzerNamed(keyword.Name)
+		if e.texts == nil || e.story == nil {
+			e.err = fmt.Errorf("the bleve search engine is missing the %s or %s analyzer", en.AnalyzerName, keyword.Name)
+			return
+		}
+		// an empty path keeps the gtreap store in memory
+		store := map[string]interface{}{"path": ""}
+		idx, err := upsidedown.NewUpsideDownCouch(gtreap.Name, store, index.NewAnalysisQueue(1))
+		if err != nil {
+			e.err = err
+			return
+		}
+		if err := idx.Open(); err != nil {
+			e.err = err
+			return
+		}
+		e.m, e.idx = m, idx
+	})
+	return e.idx, e.err
+}
+
+// document will return the indexed document of a job of story hsId
+func (e *bleveEngine) document(hnId, hsId uint64, text string) *document.Document {
+	doc := document.NewDocument(strconv.FormatUint(hnId, 10))
+	for i, f := range jobSearchFields(text) {
+		doc.AddField(document.NewTextFieldCustom(bleveFields[i], nil, []byte(f), document.IndexField|document.IncludeTermVectors, e.texts))
+	}
+	doc.AddField(document.NewTextFieldCustom(bleveStoryField, nil, []byte(strconv.FormatUint(hsId, 10)), document.IndexField, e.story))
+	return doc
+}
+
+func (e *bleveEngine) Index(_ sqlx.Execer, hnId uint64, text string) error {
+	idx, err := e.open()
+	if err != nil {
+		return err
+	}
+	var hsId uint64
+	if err := db.Get(&hsId, `SELECT hiring_story_id FROM hiring_job WHERE hn_id=?`, hnId); err != nil {
+		return err
+	}
+	return idx.Update(e.document(hnId, hsId, text))
+}
+
+// indexed will return the ids of the indexed jobs
+func (e *bleveEngine) indexed(idx index.Index) (map[uint64]bool, error) {
+	r, err := idx.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	all, err := r.DocIDReaderAll()
+	if err != nil {
+		return nil, err
+	}
+	defer all.Close()
+	ids := map[uint64]bool{}
+	for {
+		internal, err := all.Next()
+		if err != nil {
+			return nil, err
+		}
+		if internal == nil {
+			return ids, nil
+		}
+		id, err := r.ExternalID(internal)
+		if err != nil {
+			return nil, err
+		}
+		hnId, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids[hnId] = true
+	}
+}
+
+// indexJobs will index the jobs in a single batch, replacing them in the
+// index, and remove the stale ids from it
+func (e *bleveEngine) indexJobs(idx index.Index, jobs []HiringJob, stale []uint64) (int, error) {
+	b := index.NewBatch()
+	for _, id := range stale {
+		b.Delete(strconv.FormatUint(id, 10))
+	}
+	for _, hj := range jobs {
+		b.Update(e.document(hj.HnId, hj.HiringStoryId, hj.Text))
+	}
+	if err := idx.Batch(b); err != nil {
+		return 0, err
+	}
+	return len(jobs), nil
+}
+
+func (e *bleveEngine) IndexMissing() (int, error) {
+	idx, err := e.open()
+	if err != nil {
+		return 0, err
+	}
+	indexed, err := e.indexed(idx)
+	if err != nil {
+		return 0, err
+	}
+	var jobs []HiringJob
+	if err := db.Select(&jobs, `SELECT hn_id, hiring_story_id, text FROM hiring_job`); err != nil {
+		return 0, err
+	}
+	var missing []HiringJob
+	for _, hj := range jobs {
+		if !indexed[hj.HnId] {
+			missing = append(missing, hj)
+		}
+	}
+	return e.indexJobs(idx, missing, nil)
+}
+
+func (e *bleveEngine) Rebuild() (int, error) {
+	idx, err := e.open()
+	if err != nil {
+		return 0, err
+	}
+	indexed, err := e.indexed(idx)
+	if err != nil {
+		return 0, err
+	}
+	var jobs []HiringJob
+	if err := db.Select(&jobs, `SELECT hn_id, hiring_story_id, text FROM hiring_job ORDER BY hn_id`); err != nil {
+		return 0, err
+	}
+	for _, hj := range jobs {
+		delete(indexed, hj.HnId)
+	}
+	stale := make([]uint64, 0, len(indexed))
+	for id := range indexed {
+		stale = append(stale, id)
+	}
+	return e.indexJobs(idx, jobs, stale)
+}
+
+// bleveQuery will return the node as a Bleve query. Terms and phrases match
+// any of the fields, weighed by their searchFieldBoosts, and are analyzed
+// like the indexed text, so they need no escaping.
+func (n *searchNode) bleveQuery() query.Query {
+	switch n.Op {
+	case tokenTerm, tokenPhrase:
+		fields := make([]query.Query, len(bleveFields))
+		for i, name := range bleveFields {
+			if n.Op == tokenPhrase {
+				q := query.NewMatchPhraseQuery(n.Text)
+				q.SetField(name)
+				q.SetBoost(searchFieldBoosts[i])
+				fields[i] = q
+				continue
+			}
+			q := query.NewMatchQuery(n.Text)
+			q.SetField(name)
+			q.SetBoost(searchFieldBoosts[i])
+			q.SetOperator(query.MatchQueryOperatorAnd)
+			fields[i] = q
+		}
+		return query.NewDisjunctionQuery(fields)
+	case tokenAnd:
+		return query.NewConjunctionQuery([]query.Query{n.Left.bleveQuery(), n.Right.bleveQuery()})
+	case tokenOr:
+		return query.NewDisjunctionQuery([]query.Query{n.Left.bleveQuery(), n.Right.bleveQuery()})
+	case tokenNot:
+		return query.NewBooleanQuery([]query.Query{n.Left.bleveQuery()}, nil, []query.Query{n.Right.bleveQuery()})
+	}
+	return query.NewMatchNoneQuery()
+}
+
+// Match will rank the matches in Bleve and hand their ids to sqlite
+func (e *bleveEngine) Match(q *searchNode) (string, []any, error) {
+	ids, err := e.Rank(0, q)
+	if err != nil {
+		return "", nil, err
+	}
+	return "SELECT value FROM json_each(?)", []any{jsonIds(ids)}, nil
+}
+
+func (e *bleveEngine) Rank(hsId uint64, q *searchNode) ([]uint64, error) {
+	idx, err := e.open()
+	if err != nil {
+		return nil, err
+	}
+	bq := q.bleveQuery()
+	if hsId > 0 {
+		story := query.NewTermQuery(strconv.FormatUint(hsId, 10))
+		story.SetField(bleveStoryField)
+		bq = query.NewBooleanQuery([]query.Query{bq, story}, nil, nil)
+	}
+
+	r, err := idx.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	count, err := r.DocCount()
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	searcher, err := bq.Searcher(r, e.m, search.SearcherOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer searcher.Close()
+	c := collector.NewTopNCollector(int(count), 0, search.SortOrder{&search.SortScore{Desc: true}})
+	if err := c.Collect(context.Background(), searcher, r); err != nil {
+		return nil, err
+	}
+
+	var jobs []scoredJob
+	for _, m := range c.Results() {
+		hnId, err := strconv.ParseUint(m.ID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, scoredJob{HnId: hnId, Score: m.Score})
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Score != jobs[j].Score {
+			return jobs[i].Score > jobs[j].Score
+		}
+		return jobs[i].HnId > jobs[j].HnId
+	})
+	ids := make([]uint64, len(jobs))
+	for i, j := range jobs {
+		ids[i] = j.HnId
+	}
+	return ids, nil
+}
+
+// Snippets are cut from the stored text of the jobs, like the scan engine
+// does, as the index keeps no copy of it
+func (e *bleveEngine) Snippets(q *searchNode, ids []uint64) (map[uint64]string, error) {
+	var jobs []HiringJob
+	sql := `SELECT hn_id, text FROM hiring_job WHERE hn_id IN (SELECT value FROM json_each(?))`
+	if err := db.Select(&jobs, sql, jsonIds(ids)); err != nil {
+		return nil, err
+	}
+	snippets := make(map[uint64]string, len(jobs))
+	for _, hj := range jobs {
+		if s := textSnippet(searchIndexText(hj.Text), q); s != "" {
+			snippets[hj.HnId] = s
+		}
+	}
+	return snippets, nil
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// searchEngine indexes the text of jobs and matches searches against it.
+// Engines are selected by cfg.SearchEngine. Jobs are stored in sqlite, so
+// engines hand matches to the sql queries as subqueries of job ids; engines
+// of other backends, like postgres tsvector, implement the same methods and
+// are added to searchEngines.
+type searchEngine interface {
+	Name() string
+	// Index will add or replace the text of a job, within the transaction x when it is one
+	Index(x sqlx.Execer, hnId uint64, text string) error
+	// IndexMissing will index the jobs missing from the index and return how many were added
+	IndexMissing() (int, error)
+	// Rebuild will drop the index and index every stored job again, returning how many were indexed
+	Rebuild() (int, error)
+	// Match will return a subquery selecting the ids of the jobs matching a search, with its args.
+	// Engines that cannot search fail rather than matching no job.
+	Match(q *searchNode) (string, []any, error)
+	// Rank will return the ids of the jobs of a story, or of every story when
+	// hsId is 0, matching a search, best matches first
+	Rank(hsId uint64, q *searchNode) ([]uint64, error)
+	// Snippets will return the text around the first match of a search in
+	// each of the jobs ids, by job id, with the matched terms between
+	// snippetOpen and snippetClose
+	Snippets(q *searchNode, ids []uint64) (map[uint64]string, error)
+}
+
+// searchEngines are the available engines, the first one being the default
+var searchEngines = []searchEngine{
+	fts5Engine{},
+	newScanEngine(),
+	newPostgresEngine(),
+	newBleveEngine(),
+}
+
+// searchEngineNames will return the names of the available engines
+func searchEngineNames() []string {
+	names := make([]string, len(searchEngines))
+	for i, e := range searchEngines {
+		names[i] = e.Name()
+	}
+	return names
+}
+
+// newSearchEngine will return the engine named name, failing for unknown names
+// so a misspelled engine stops the app instead of searching with another one
+func newSearchEngine(name string) (searchEngine, error) {
+	for _, e := range searchEngines {
+		if e.Name() == name {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown search engine %q, expected %s", name, oneOf(searchEngineNames()))
+}
+
+// jobSearch is the engine of cfg.SearchEngine, stopping the app at startup when it is unknown
+var jobSearch = func() searchEngine {
+	e, err := newSearchEngine(cfg.SearchEngine)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return e
+}()
+
+// searchFieldBoosts are the bm25 weights of the text, company, role and
+// headline fields of the index, the columns of hiring_job_fts. Matches in the
+// parsed headline of a post count more than matches in its body.
+var searchFieldBoosts = [searchFieldCount]float64{1, 5, 4, 2}
+
+// searchFieldCount is the number of indexed fields of a job
+const searchFieldCount = 4
+
+// jobSearchFields will return the text of a job indexed for searches, then
+// the company, role and headline parsed from it
+func jobSearchFields(text string) [searchFieldCount]string {
+	h := parseJobHeadline(text)
+	headline := strings.TrimSpace(jobPlainText(jobRoleText(text)))
+	return [searchFieldCount]string{searchIndexText(text), h.Company, h.Role, headline}
+}
+
+// bm25 parameters, the defaults of FTS5
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// fts5Engine searches the hiring_job_fts table of the sqlite FTS5 module,
+// ranking matches by bm25 with the searchFieldBoosts of its columns
+type fts5Engine struct{}
+
+func (fts5Engine) Name() string {
+	return "fts5"
+}
+
+func (fts5Engine) Index(x sqlx.Execer, hnId uint64, text string) error {
+	f := jobSearchFields(text)
+	sql := `INSERT OR REPLACE INTO hiring_job_fts (rowid, text, company, role, headline) VALUES (?, ?, ?, ?, ?)`
+	_, err := x.Exec(sql, hnId, f[0], f[1], f[2], f[3])
+	return searchError(err)
+}
+
+func (e fts5Engine) IndexMissing() (int, error) {
+	var jobs []HiringJob
+	sql := `SELECT hn_id, text FROM hiring_job WHERE hn_id NOT IN (SELECT rowid FROM hiring_job_fts)`
+	if err := db.Select(&jobs, sql); err != nil {
+		return 0, searchError(err)
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, hj := range jobs {
+		if err := e.Index(tx, hj.HnId, hj.Text); err != nil {
+			return 0, err
+		}
+	}
+	return len(jobs), tx.Commit()
+}
+
+// fts5Schema creates the hiring_job_fts table, as its latest migration does
+const fts5Schema = `CREATE VIRTUAL TABLE hiring_job_fts USING fts5(text, company, role, headline, tokenize='porter unicode61')`
+
+func (e fts5Engine) Rebuild() (int, error) {
+	var jobs []HiringJob
+	if err := db.Select(&jobs, `SELECT hn_id, text FROM hiring_job ORDER BY hn_id`); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DROP TABLE IF EXISTS hiring_job_fts`); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(fts5Schema); err != nil {
+		return 0, searchError(err)
+	}
+	for _, hj := range jobs {
+		if err := e.Index(tx, hj.HnId, hj.Text); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO hiring_job_fts (hiring_job_fts) VALUES ('optimize')`); err != nil {
+		return 0, err
+	}
+	return len(jobs), tx.Commit()
+}
+
+func (fts5Engine) Match(q *searchNode) (string, []any, error) {
+	return "SELECT rowid FROM hiring_job_fts WHERE hiring_job_fts MATCH ?", []any{q.fts()}, nil
+}
+
+// fts5Bm25 is the bm25 call ranking the matches of hiring_job_fts, lower first
+var fts5Bm25 = func() string {
+	weights := make([]string, len(searchFieldBoosts))
+	for i, w := range searchFieldBoosts {
+		weights[i] = fmt.Sprint(w)
+	}
+	return "bm25(hiring_job_fts, " + strings.Join(weights, ", ") + ")"
+}()
+
+func (fts5Engine) Rank(hsId uint64, q *searchNode) ([]uint64, error) {
+	var ids []uint64
+	sql := `SELECT hiring_job_fts.rowid FROM hiring_job_fts
+            JOIN hiring_job hj ON hj.hn_id = hiring_job_fts.rowid
+            WHERE hiring_job_fts MATCH ? AND (? = 0 OR hj.hiring_story_id=?)
+            ORDER BY ` + fts5Bm25 + `, hiring_job_fts.rowid DESC`
+	if err := db.Select(&ids, sql, q.fts(), hsId, hsId); err != nil {
+		return nil, searchError(err)
+	}
+	return ids, nil
+}
+
+func (fts5Engine) Snippets(q *searchNode, ids []uint64) (map[uint64]string, error) {
+	var rows []struct {
+		HnId    uint64 `db:"hn_id"`
+		Snippet string
+	}
+	sql := `SELECT rowid AS hn_id, snippet(hiring_job_fts, 0, ?, ?, ?, ?) AS snippet FROM hiring_job_fts
+            WHERE hiring_job_fts MATCH ? AND rowid IN (SELECT value FROM json_each(?))`
+	if err := db.Select(&rows, sql, snippetOpen, snippetClose, snippetEllipsis, snippetWords, q.fts(), jsonIds(ids)); err != nil {
+		return nil, searchError(err)
+	}
+	snippets := make(map[uint64]string, len(rows))
+	for _, r := range rows {
+		snippets[r.HnId] = strings.Join(strings.Fields(r.Snippet), " ")
+	}
+	return snippets, nil
+}
+
+// scanEngine matches searches by scanning the stemmed words of every job, kept
+// in memory, and ranks matches by bm25 like the fts5 engine. It needs no
+// sqlite module, for builds without FTS5 and small deployments. The index is
+// built at startup, so jobs changed by another process, like the reprocess
+// command, are searched by their new text after a restart.
+type scanEngine struct {
+	mu sync.RWMutex
+	// docs are the indexed fields of each job, by job id
+	docs map[uint64]scanDoc
+}
+
+// scanDoc is a job indexed by the scan engine
+type scanDoc struct {
+	story uint64
+	// fields are the stems of the lowercased words of each indexed field
+	fields [searchFieldCount][]string
+}
+
+// newScanDoc will index the text of a job of story hsId
+func newScanDoc(hsId uint64, text string) scanDoc {
+	d := scanDoc{story: hsId}
+	for i, f := range jobSearchFields(text) {
+		d.fields[i] = stemWords(searchWords(f))
+	}
+	return d
+}
+
+// length will return the number of words of the doc, weighted like its matches
+func (d scanDoc) length() float64 {
+	var n float64
+	for i, words := range d.fields {
+		n += searchFieldBoosts[i] * float64(len(words))
+	}
+	return n
+}
+
+func newScanEngine() *scanEngine {
+	return &scanEngine{docs: map[uint64]scanDoc{}}
+}
+
+func (*scanEngine) Name() string {
+	return "scan"
+}
+
+// searchWords will split a text into lowercased words of letters and digits,
+// like the unicode61 tokenizer of FTS5
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// stemWord will strip the plural and verb endings of a word, a light take on
+// the porter tokenizer of FTS5, so "engineers" and "engineering" both match
+// "engineer". Words are stemmed alike when indexed and searched, so stems
+// like "servic" need not be words.
+func stemWord(w string) string {
+	stem := w
+	switch {
+	case strings.HasSuffix(stem, "ies"):
+		stem = strings.TrimSuffix(stem, "ies") + "y"
+	case strings.HasSuffix(stem, "ing"):
+		stem = strings.TrimSuffix(stem, "ing")
+	case strings.HasSuffix(stem, "ed"):
+		stem = strings.TrimSuffix(stem, "ed")
+	case strings.HasSuffix(stem, "s") && !strings.HasSuffix(stem, "ss") && !strings.HasSuffix(stem, "us") && !strings.HasSuffix(stem, "is"):
+		stem = strings.TrimSuffix(stem, "s")
+	}
+	stem = strings.TrimSuffix(stem, "e")
+	// short words, like "bus" or "sing", are kept as they are
+	if len(stem) < 3 {
+		return w
+	}
+	return stem
+}
+
+// stemWords will return the stems of words
+func stemWords(words []string) []string {
+	stems := make([]string, len(words))
+	for i, w := range words {
+		stems[i] = stemWord(w)
+	}
+	return stems
+}
+
+func (e *scanEngine) Index(_ sqlx.Execer, hnId uint64, text string) error {
+	var hsId uint64
+	if err := db.Get(&hsId, `SELECT hiring_story_id FROM hiring_job WHERE hn_id=?`, hnId); err != nil {
+		return err
+	}
+	doc := newScanDoc(hsId, text)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.docs[hnId] = doc
+	return nil
+}
+
+func (e *scanEngine) IndexMissing() (int, error) {
+	var jobs []HiringJob
+	if err := db.Select(&jobs, `SELECT hn_id, hiring_story_id, text FROM hiring_job`); err != nil {
+		return 0, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for _, hj := range jobs {
+		if _, ok := e.docs[hj.HnId]; ok {
+			continue
+		}
+		e.docs[hj.HnId] = newScanDoc(hj.HiringStoryId, hj.Text)
+		n++
+	}
+	return n, nil
+}
+
+func (e *scanEngine) Rebuild() (int, error) {
+	e.mu.Lock()
+	e.docs = map[uint64]scanDoc{}
+	e.mu.Unlock()
+	return e.IndexMissing()
+}
+
+// occurrences will return how many times the words of phrase appear in a row in words
+func occurrences(words, phrase []string) int {
+	if len(phrase) == 0 {
+		return 0
+	}
+	n := 0
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		found := true
+		for j, w := range phrase {
+			if words[i+j] != w {
+				found = false
+				break
+			}
+		}
+		if found {
+			n++
+		}
+	}
+	return n
+}
+
+// score will return how many times the terms of a search appear in the
+// stemmed words, or 0 when words do not match the search
+func (n *searchNode) score(words []string) int {
+	switch n.Op {
+	case tokenTerm, tokenPhrase:
+		return occurrences(words, stemWords(searchWords(n.Text)))
+	case tokenAnd:
+		left := n.Left.score(words)
+		if left == 0 {
+			return 0
+		}
+		if right := n.Right.score(words); right > 0 {
+			return left + right
+		}
+		return 0
+	case tokenOr:
+		return n.Left.score(words) + n.Right.score(words)
+	case tokenNot:
+		if n.Right.score(words) > 0 {
+			return 0
+		}
+		return n.Left.score(words)
+	}
+	return 0
+}
+
+// terms will return the stemmed words of the terms and phrases a search
+// matches, leaving out the excluded ones
+func (n *searchNode) terms() [][]string {
+	switch n.Op {
+	case tokenTerm, tokenPhrase:
+		return [][]string{stemWords(searchWords(n.Text))}
+	case tokenAnd, tokenOr:
+		return append(n.Left.terms(), n.Right.terms()...)
+	case tokenNot:
+		return n.Left.terms()
+	}
+	return nil
+}
+
+// scoredJob is a job matching a search with the score of the match
+type scoredJob struct {
+	HnId  uint64
+	Score float64
+}
+
+// bm25 will score a doc for the terms of a search, the frequency of each
+// term being the weighted sum of its frequencies in the fields of the doc.
+// df are the number of docs with each term, out of n docs of avgLength words.
+func (d scanDoc) bm25(terms [][]string, df []int, n int, avgLength float64) float64 {
+	var score float64
+	norm := bm25K1 * (1 - bm25B + bm25B*d.length()/avgLength)
+	for i, t := range terms {
+		var tf float64
+		for f, words := range d.fields {
+			tf += searchFieldBoosts[f] * float64(occurrences(words, t))
+		}
+		if tf == 0 {
+			continue
+		}
+		idf := math.Log(1 + (float64(n-df[i])+0.5)/(float64(df[i])+0.5))
+		score += idf * tf * (bm25K1 + 1) / (tf + norm)
+	}
+	return score
+}
+
+// matches will return the jobs matching a search, best matches first. Only
+// the jobs of story hsId are matched unless it is 0. Terms are weighed by
+// their frequency across every job, like the fts5 engine does.
+func (e *scanEngine) matches(hsId uint64, q *searchNode) []scoredJob {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	terms := q.terms()
+	df := make([]int, len(terms))
+	var totalLength float64
+	for _, d := range e.docs {
+		totalLength += d.length()
+		for i, t := range terms {
+			if occurrences(d.fields[0], t) > 0 {
+				df[i]++
+			}
+		}
+	}
+	avgLength := totalLength / math.Max(float64(len(e.docs)), 1)
+
+	var jobs []scoredJob
+	for id, d := range e.docs {
+		if hsId > 0 && d.story != hsId {
+			continue
+		}
+		if q.score(d.fields[0]) > 0 {
+			jobs = append(jobs, scoredJob{HnId: id, Score: d.bm25(terms, df, len(e.docs), avgLength)})
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Score != jobs[j].Score {
+			return jobs[i].Score > jobs[j].Score
+		}
+		return jobs[i].HnId > jobs[j].HnId
+	})
+	return jobs
+}
+
+func (e *scanEngine) Match(q *searchNode) (string, []any, error) {
+	ids, err := e.Rank(0, q)
+	if err != nil {
+		return "", nil, err
+	}
+	return "SELECT value FROM json_each(?)", []any{jsonIds(ids)}, nil
+}
+
+func (e *scanEngine) Rank(hsId uint64, q *searchNode) ([]uint64, error) {
+	jobs := e.matches(hsId, q)
+	ids := make([]uint64, len(jobs))
+	for i, j := range jobs {
+		ids[i] = j.HnId
+	}
+	return ids, nil
+}
+
+func (e *scanEngine) Snippets(q *searchNode, ids []uint64) (map[uint64]string, error) {
+	var jobs []HiringJob
+	sql := `SELECT hn_id, text FROM hiring_job WHERE hn_id IN (SELECT value FROM json_each(?))`
+	if err := db.Select(&jobs, sql, jsonIds(ids)); err != nil {
+		return nil, err
+	}
+	snippets := make(map[uint64]string, len(jobs))
+	for _, hj := range jobs {
+		if s := textSnippet(searchIndexText(hj.Text), q); s != "" {
+			snippets[hj.HnId] = s
+		}
+	}
+	return snippets, nil
+}
+
+// jsonIds will encode job ids as a json array, for json_each
+func jsonIds(ids []uint64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(id, 10)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewSearchEngine(t *testing.T) {
+	for _, name := range []string{"fts5", "scan", "postgres", "bleve"} {
+		t.Setenv("WIH_SEARCH_ENGINE", name)
+		e, err := newSearchEngine(loadConfig().SearchEngine)
+		if err != nil {
+			t.Fatalf("newSearchEngine(%q): %v", name, err)
+		}
+		if e.Name() != name {
+			t.Errorf("WIH_SEARCH_ENGINE=%s selected the %s engine", name, e.Name())
+		}
+	}
+	if e, err := newSearchEngine("elastic"); err == nil {
+		t.Errorf("newSearchEngine(elastic) = %s, want an error", e.Name())
+	}
+}
+
+// TestJobSearchEngine checks jobSearch for TestSearchEngineStartup, which
+// runs it in a process started with WIH_SEARCH_ENGINE
+func TestJobSearchEngine(t *testing.T) {
+	want := os.Getenv("WIH_TEST_SEARCH_ENGINE")
+	if want == "" {
+		t.Skip("run by TestSearchEngineStartup")
+	}
+	if jobSearch.Name() != want {
+		t.Errorf("jobSearch = %s, want %s", jobSearch.Name(), want)
+	}
+}
+
+func TestSearchEngineStartup(t *testing.T) {
+	run := func(engine string) (string, error) {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestJobSearchEngine$")
+		cmd.Env = append(os.Environ(), "WIH_SEARCH_ENGINE="+engine, "WIH_TEST_SEARCH_ENGINE="+engine)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	for _, engine := range []string{"scan", "bleve"} {
+		if out, err := run(engine); err != nil {
+			t.Errorf("starting with WIH_SEARCH_ENGINE=%s: %v\n%s", engine, err, out)
+		}
+	}
+	out, err := run("elastic")
+	if err == nil {
+		t.Fatalf("starting with WIH_SEARCH_ENGINE=elastic did not fail\n%s", out)
+	}
+	if !strings.Contains(out, `unknown search engine "elastic"`) {
+		t.Errorf("starting with WIH_SEARCH_ENGINE=elastic failed with\n%s", out)
+	}
+}
+
+func TestBleveEngine(t *testing.T) {
+	useTestDB(t)
+	for _, hsId := range []uint64{1, 2} {
+		if _, err := CreateHiringStory(hsId, storyKindHiring, "Ask HN: Who is hiring?", 1700000000+hsId); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs := []struct {
+		hsId uint64
+		hj   HiringJob
+	}{
+		{1, HiringJob{HnId: 101, Text: "Acme | Golang Engineer | Remote<p>We ship payments with Go and Postgres.", Time: 1700000001}},
+		{1, HiringJob{HnId: 102, Text: "Initech | Rust Engineer | Berlin<p>Our platform team also writes some golang tools.", Time: 1700000002}},
+		{1, HiringJob{HnId: 103, Text: "Globex | Staff Engineer | Onsite<p>Crypto trading engines in golang.", Time: 1700000003}},
+		{1, HiringJob{HnId: 100, Text: "Rust Labs | Platform Engineer | Berlin<p>Our platform team also writes some golang tools.", Time: 1700000005}},
+		{2, HiringJob{HnId: 201, Text: "Hooli | Golang Engineers | Remote<p>Search infrastructure.", Time: 1700000004}},
+	}
+	for _, j := range jobs {
+		if _, err := CreateHiringJob(j.hsId, jobStatusOk, j.hj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prev := jobSearch
+	jobSearch = newBleveEngine()
+	t.Cleanup(func() { jobSearch = prev })
+	if n, err := jobSearch.IndexMissing(); err != nil || n != len(jobs) {
+		t.Fatalf("IndexMissing() = %d, %v, want %d", n, err, len(jobs))
+	}
+	if n, err := jobSearch.IndexMissing(); err != nil || n != 0 {
+		t.Fatalf("IndexMissing() again = %d, %v, want 0", n, err)
+	}
+
+	cases := []struct {
+		hsId uint64
+		q    string
+		want []uint64
+	}{
+		// the jobs with golang in their role come first
+		{1, "golang", []uint64{101, 103, 102, 100}},
+		{0, "hooli", []uint64{201}},
+		{2, "engineer", []uint64{201}},
+		{1, `"staff engineer"`, []uint64{103}},
+		{1, "golang NOT crypto", []uint64{101, 102, 100}},
+		// a company match outweighs a role match
+		{1, "rust", []uint64{100, 102}},
+		{1, "rust OR postgres", []uint64{100, 102, 101}},
+		{2, "rust", nil},
+	}
+	for _, c := range cases {
+		got, err := jobSearch.Rank(c.hsId, searchExpr(c.q))
+		if err != nil {
+			t.Fatalf("Rank(%d, %q): %v", c.hsId, c.q, err)
+		}
+		if len(got) != len(c.want) || (len(got) > 0 && !reflect.DeepEqual(got, c.want)) {
+			t.Errorf("Rank(%d, %q) = %v, want %v", c.hsId, c.q, got, c.want)
+		}
+	}
+
+	count, err := CountJobList(jobScope{StoryId: 1}, FilterState{Query: "golang", Exclude: []string{"crypto"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("CountJobList of golang without crypto = %d, want 3", count)
+	}
+
+	// reindexed jobs are searched by their new text
+	if err := jobSearch.Index(db, 103, "Globex | Staff Engineer | Onsite<p>Crypto trading engines in rust."); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := jobSearch.Rank(1, searchExpr("golang")); err != nil || !reflect.DeepEqual(got, []uint64{101, 102, 100}) {
+		t.Errorf("Rank of golang after reindexing = %v, %v, want [101 102 100]", got, err)
+	}
+	if n, err := jobSearch.Rebuild(); err != nil || n != len(jobs) {
+		t.Errorf("Rebuild() = %d, %v, want %d", n, err, len(jobs))
+	}
+}
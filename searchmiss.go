@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// searchMissMaxLength is the max length of a recorded search
+	searchMissMaxLength = 100
+	// searchMissesShown is the number of searches listed on the admin page
+	searchMissesShown = 100
+)
+
+// digitsPattern matches long numbers, like phone numbers
+var digitsPattern = regexp.MustCompile(`\d{5,}`)
+
+// anonymizeSearch will normalize a search before it is recorded. Emails and
+// long numbers, like phone numbers, are redacted, and nothing about who
+// searched is kept.
+func anonymizeSearch(q string) string {
+	q = strings.ToLower(strings.Join(strings.Fields(q), " "))
+	q = emailPattern.ReplaceAllString(q, "[email]")
+	q = digitsPattern.ReplaceAllString(q, "[number]")
+	if r := []rune(q); len(r) > searchMissMaxLength {
+		q = string(r[:searchMissMaxLength])
+	}
+	return q
+}
+
+// SearchMiss is a search that found no jobs, counted across searches
+type SearchMiss struct {
+	Query     string
+	Count     int
+	FirstSeen int64 `db:"first_seen"`
+	LastSeen  int64 `db:"last_seen"`
+}
+
+// LastSeenTime will return when the search was last made
+func (m SearchMiss) LastSeenTime() time.Time {
+	return time.Unix(m.LastSeen, 0).UTC()
+}
+
+// Suggestions will suggest how to make the terms of the search find jobs
+func (m SearchMiss) Suggestions() []string {
+	tokens, err := lexSearch(m.Query)
+	if err != nil {
+		return nil
+	}
+	var suggestions []string
+	for _, t := range tokens {
+		// redacted terms can't be improved on
+		if t.Kind != tokenTerm && t.Kind != tokenPhrase || t.Text == "[email]" || t.Text == "[number]" {
+			continue
+		}
+		suggestions = append(suggestions, termSuggestion(t.Text))
+	}
+	return suggestions
+}
+
+// termSuggestion will suggest how to make a search term find jobs: a synonym
+// of the tag it is a spelling or a misspelling of, or a new tag
+func termSuggestion(term string) string {
+	if isJobTag(term) {
+		return fmt.Sprintf("%q is a tag, add the spellings of its pattern as search synonyms", term)
+	}
+	for _, tag := range jobTags {
+		if tagPatterns[tag].MatchString(term) {
+			return fmt.Sprintf("add %q as a synonym of the %q tag", term, tag)
+		}
+	}
+	for _, tag := range jobTags {
+		if len(term) >= 4 && editDistance(term, tag) <= 2 {
+			return fmt.Sprintf("%q may be a misspelling of the %q tag, add it as a synonym", term, tag)
+		}
+	}
+	return fmt.Sprintf("no tag matches %q, consider a new tag or a synonym of an existing term", term)
+}
+
+// editDistance will return the levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// RecordSearchMiss will count a search that found no jobs
+func RecordSearchMiss(q string) error {
+	q = anonymizeSearch(q)
+	if q == "" {
+		return nil
+	}
+	now := time.Now().Unix()
+	sql := `INSERT INTO search_miss (query, count, first_seen, last_seen) VALUES (?, 1, ?, ?)
+            ON CONFLICT (query) DO UPDATE SET count = count + 1, last_seen = excluded.last_seen`
+	_, err := db.Exec(sql, q, now, now)
+	return err
+}
+
+// recordSearchMiss will record a search of f when it found no jobs, logging
+// failures as they must not fail the search. Searches narrowed by other
+// filters are left out, as their terms may find jobs on their own. Typo
+// tolerance narrows nothing, so those searches are recorded.
+func recordSearchMiss(f FilterState, found int) {
+	q := f.query()
+	q.Del("typos")
+	if found > 0 || !f.hasTerms() || len(q) > 1 {
+		return
+	}
+	if err := RecordSearchMiss(f.Query); err != nil {
+		log.Println("failed to record search miss.", err)
+	}
+}
+
+// SelectSearchMisses will select the most common searches that found no jobs
+func SelectSearchMisses(limit int) ([]SearchMiss, error) {
+	var misses []SearchMiss
+	sql := `SELECT query, count, first_seen, last_seen FROM search_miss ORDER BY count DESC, last_seen DESC LIMIT ?`
+	if err := db.Select(&misses, sql, limit); err != nil {
+		return nil, err
+	}
+	return misses, nil
+}
+
+// SelectSearchMissesBetween will select the most common searches without
+// results last made from one unix time up to another
+func SelectSearchMissesBetween(from, to int64, limit int) ([]SearchMiss, error) {
+	var misses []SearchMiss
+	sql := `SELECT query, count, first_seen, last_seen FROM search_miss
+            WHERE last_seen >= ? and last_seen < ?
+            ORDER BY count DESC, last_seen DESC LIMIT ?`
+	if err := db.Select(&misses, sql, from, to, limit); err != nil {
+		return nil, err
+	}
+	return misses, nil
+}
+
+// searchMissesHandler will list the searches that found no jobs with
+// suggestions to make them find some
+func searchMissesHandler(w http.ResponseWriter, r *http.Request) {
+	misses, err := SelectSearchMisses(searchMissesShown)
+	if err != nil {
+		log.Println("failed to select search misses.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Misses []SearchMiss
+		Limit  int
+	}{
+		Misses: misses,
+		Limit:  searchMissesShown,
+	}
+	renderTemplate(w, "admin_search_misses.html", data)
+}
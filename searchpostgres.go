@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// postgresEngine searches a tsvector index kept in the postgres database of
+// cfg.SearchPostgresUrl, ranking matches with ts_rank_cd. Jobs stay in sqlite,
+// so the index holds a copy of their text and engines hand the ids of the
+// matches back to the sqlite queries. The weights A to D of the tsvector are
+// the company, role, headline and text fields, scaled from searchFieldBoosts.
+type postgresEngine struct {
+	once sync.Once
+	pg   *sqlx.DB
+	err  error
+}
+
+// postgresSearchSchema creates the index table, on the first use of the engine
+const postgresSearchSchema = `CREATE TABLE IF NOT EXISTS hiring_job_search (
+    hn_id BIGINT PRIMARY KEY,
+    story_id BIGINT NOT NULL,
+    text TEXT NOT NULL,
+    document TSVECTOR NOT NULL
+);
+CREATE INDEX IF NOT EXISTS hiring_job_search_document_idx ON hiring_job_search USING GIN (document);
+CREATE INDEX IF NOT EXISTS hiring_job_search_story_idx ON hiring_job_search (story_id)`
+
+// postgresDocument builds the weighted tsvector of a job from its text,
+// company, role and headline fields
+const postgresDocument = `setweight(to_tsvector('english', ?), 'D') || setweight(to_tsvector('english', ?), 'A') ||
+    setweight(to_tsvector('english', ?), 'B') || setweight(to_tsvector('english', ?), 'C')`
+
+// postgresRankWeights are the ts_rank_cd weights of the D, C, B and A
+// lexemes: the text, headline, role and company boosts over the largest one
+var postgresRankWeights = func() string {
+	var most float64
+	for _, w := range searchFieldBoosts {
+		if w > most {
+			most = w
+		}
+	}
+	order := []int{0, 3, 2, 1}
+	weights := make([]string, len(order))
+	for i, f := range order {
+		weights[i] = fmt.Sprint(searchFieldBoosts[f] / most)
+	}
+	return "'{" + strings.Join(weights, ", ") + "}'"
+}()
+
+func newPostgresEngine() *postgresEngine {
+	return &postgresEngine{}
+}
+
+func (*postgresEngine) Name() string {
+	return "postgres"
+}
+
+// conn will return the postgres pool, connecting and creating the index table
+// on the first call
+func (e *postgresEngine) conn() (*sqlx.DB, error) {
+	e.once.Do(func() {
+		if cfg.SearchPostgresUrl == "" {
+			e.err = fmt.Errorf("the postgres search engine needs WIH_SEARCH_POSTGRES_URL")
+			return
+		}
+		pg, err := sqlx.Connect("postgres", cfg.SearchPostgresUrl)
+		if err != nil {
+			e.err = err
+			return
+		}
+		if _, err := pg.Exec(postgresSearchSchema); err != nil {
+			e.err = err
+			return
+		}
+		e.pg = pg
+	})
+	return e.pg, e.err
+}
+
+// tsquery will return the node as a postgres tsquery expression, with its
+// args. Terms and phrases are parsed by postgres, so their text needs no
+// escaping.
+func (n *searchNode) tsquery(args *[]any) string {
+	switch n.Op {
+	case tokenTerm:
+		*args = append(*args, n.Text)
+		return "plainto_tsquery('english', ?)"
+	case tokenPhrase:
+		*args = append(*args, n.Text)
+		return "phraseto_tsquery('english', ?)"
+	case tokenAnd:
+		return "(" + n.Left.tsquery(args) + " && " + n.Right.tsquery(args) + ")"
+	case tokenOr:
+		return "(" + n.Left.tsquery(args) + " || " + n.Right.tsquery(args) + ")"
+	case tokenNot:
+		return "(" + n.Left.tsquery(args) + " && !!" + n.Right.tsquery(args) + ")"
+	}
+	return ""
+}
+
+// index will add or replace a job of story hsId in the index through x
+func (e *postgresEngine) index(x sqlx.Execer, pg *sqlx.DB, hnId, hsId uint64, text string) error {
+	f := jobSearchFields(text)
+	sql := pg.Rebind(`INSERT INTO hiring_job_search (hn_id, story_id, text, document)
+            VALUES (?, ?, ?, ` + postgresDocument + `)
+            ON CONFLICT (hn_id) DO UPDATE SET story_id = EXCLUDED.story_id, text = EXCLUDED.text, document = EXCLUDED.document`)
+	_, err := x.Exec(sql, hnId, hsId, f[0], f[0], f[1], f[2], f[3])
+	return err
+}
+
+// Index will write the job to postgres right away, as x is a sqlite transaction
+func (e *postgresEngine) Index(_ sqlx.Execer, hnId uint64, text string) error {
+	pg, err := e.conn()
+	if err != nil {
+		return err
+	}
+	var hsId uint64
+	if err := db.Get(&hsId, `SELECT hiring_story_id FROM hiring_job WHERE hn_id=?`, hnId); err != nil {
+		return err
+	}
+	return e.index(pg, pg, hnId, hsId, text)
+}
+
+// indexJobs will index jobs in a single postgres transaction
+func (e *postgresEngine) indexJobs(pg *sqlx.DB, jobs []HiringJob, truncate bool) (int, error) {
+	tx, err := pg.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if truncate {
+		if _, err := tx.Exec(`TRUNCATE hiring_job_search`); err != nil {
+			return 0, err
+		}
+	}
+	for _, hj := range jobs {
+		if err := e.index(tx, pg, hj.HnId, hj.HiringStoryId, hj.Text); err != nil {
+			return 0, err
+		}
+	}
+	return len(jobs), tx.Commit()
+}
+
+func (e *postgresEngine) IndexMissing() (int, error) {
+	pg, err := e.conn()
+	if err != nil {
+		return 0, err
+	}
+	var indexed []uint64
+	if err := pg.Select(&indexed, `SELECT hn_id FROM hiring_job_search`); err != nil {
+		return 0, err
+	}
+	var jobs []HiringJob
+	sql := `SELECT hn_id, hiring_story_id, text FROM hiring_job WHERE hn_id NOT IN (SELECT value FROM json_each(?))`
+	if err := db.Select(&jobs, sql, jsonIds(indexed)); err != nil {
+		return 0, err
+	}
+	return e.indexJobs(pg, jobs, false)
+}
+
+func (e *postgresEngine) Rebuild() (int, error) {
+	pg, err := e.conn()
+	if err != nil {
+		return 0, err
+	}
+	var jobs []HiringJob
+	if err := db.Select(&jobs, `SELECT hn_id, hiring_story_id, text FROM hiring_job ORDER BY hn_id`); err != nil {
+		return 0, err
+	}
+	return e.indexJobs(pg, jobs, true)
+}
+
+// Match will select the matches in postgres and hand their ids to sqlite.
+// Failed searches fail the sqlite query too, rather than matching no job.
+func (e *postgresEngine) Match(q *searchNode) (string, []any, error) {
+	ids, err := e.Rank(0, q)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to match postgres search: %w", err)
+	}
+	return "SELECT value FROM json_each(?)", []any{jsonIds(ids)}, nil
+}
+
+func (e *postgresEngine) Rank(hsId uint64, q *searchNode) ([]uint64, error) {
+	pg, err := e.conn()
+	if err != nil {
+		return nil, err
+	}
+	// the query is written twice, for the match and for the rank
+	var args, rankArgs []any
+	match := q.tsquery(&args)
+	rank := q.tsquery(&rankArgs)
+	args = append(append(args, hsId, hsId), rankArgs...)
+	var ids []uint64
+	sql := pg.Rebind(`SELECT hn_id FROM hiring_job_search
+            WHERE document @@ ` + match + ` AND (? = 0 OR story_id = ?)
+            ORDER BY ts_rank_cd(` + postgresRankWeights + `, document, ` + rank + `) DESC, hn_id DESC`)
+	if err := pg.Select(&ids, sql, args...); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (e *postgresEngine) Snippets(q *searchNode, ids []uint64) (map[uint64]string, error) {
+	pg, err := e.conn()
+	if err != nil {
+		return nil, err
+	}
+	var args []any
+	query := q.tsquery(&args)
+	options := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=%d, MinWords=%d, MaxFragments=1, FragmentDelimiter="%s"`,
+		snippetOpen, snippetClose, snippetWords, snippetWords/2, snippetEllipsis)
+	var rows []struct {
+		HnId    uint64 `db:"hn_id"`
+		Snippet string
+	}
+	sql := pg.Rebind(`SELECT hn_id, ts_headline('english', text, ` + query + `, ?) AS snippet
+            FROM hiring_job_search WHERE hn_id IN (SELECT jsonb_array_elements_text(?::jsonb)::bigint)`)
+	args = append(args, options, jsonIds(ids))
+	if err := pg.Select(&rows, sql, args...); err != nil {
+		return nil, err
+	}
+	snippets := make(map[uint64]string, len(rows))
+	for _, r := range rows {
+		snippets[r.HnId] = strings.Join(strings.Fields(r.Snippet), " ")
+	}
+	return snippets, nil
+}
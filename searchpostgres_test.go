@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSearchNodeTsquery(t *testing.T) {
+	cases := []struct {
+		q     string
+		query string
+		args  []any
+	}{
+		{"golang", "plainto_tsquery('english', ?)", []any{"golang"}},
+		{`"staff engineer"`, "phraseto_tsquery('english', ?)", []any{"staff engineer"}},
+		{"go rust", "(plainto_tsquery('english', ?) && plainto_tsquery('english', ?))", []any{"go", "rust"}},
+		{"go OR rust", "(plainto_tsquery('english', ?) || plainto_tsquery('english', ?))", []any{"go", "rust"}},
+		{"go NOT crypto", "(plainto_tsquery('english', ?) && !!plainto_tsquery('english', ?))", []any{"go", "crypto"}},
+	}
+	for _, c := range cases {
+		n, err := parseSearch(c.q)
+		if err != nil {
+			t.Fatalf("parseSearch(%q): %v", c.q, err)
+		}
+		var args []any
+		if got := n.tsquery(&args); got != c.query || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("tsquery of %q = %s %v, want %s %v", c.q, got, args, c.query, c.args)
+		}
+	}
+}
+
+func TestPostgresRankWeights(t *testing.T) {
+	// D, C, B and A are the text, headline, role and company
+	if want := "'{0.2, 0.4, 0.8, 1}'"; postgresRankWeights != want {
+		t.Errorf("postgresRankWeights = %s, want %s", postgresRankWeights, want)
+	}
+}
+
+func TestPostgresMatchError(t *testing.T) {
+	prev, prevUrl := jobSearch, cfg.SearchPostgresUrl
+	jobSearch, cfg.SearchPostgresUrl = newPostgresEngine(), ""
+	t.Cleanup(func() { jobSearch, cfg.SearchPostgresUrl = prev, prevUrl })
+
+	_, _, err := FilterState{Query: "golang"}.where()
+	if err == nil {
+		t.Fatal("where() of a search postgres failed to match = nil, want an error")
+	}
+	w := httptest.NewRecorder()
+	filterError(w, "failed to list jobs.", err)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("a failed postgres match answered %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// searchToken is a term, a quoted phrase, an operator or a parenthesis of a search
+type searchToken struct {
+	Kind string
+	Text string
+}
+
+const (
+	tokenTerm   = "term"
+	tokenPhrase = "phrase"
+	tokenAnd    = "AND"
+	tokenOr     = "OR"
+	tokenNot    = "NOT"
+	tokenOpen   = "("
+	tokenClose  = ")"
+)
+
+// lexSearch will split a search into tokens. Operators are only recognized in
+// uppercase, so "and" or "not" are searched as words.
+func lexSearch(q string) ([]searchToken, error) {
+	var tokens []searchToken
+	for i := 0; i < len(q); {
+		switch c := q[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, searchToken{Kind: string(c)})
+			i++
+		case c == '"':
+			end := strings.IndexByte(q[i+1:], '"')
+			if end == -1 {
+				return nil, errors.New(`a quoted phrase is missing its closing "`)
+			}
+			if phrase := strings.TrimSpace(q[i+1 : i+1+end]); phrase != "" {
+				tokens = append(tokens, searchToken{Kind: tokenPhrase, Text: phrase})
+			}
+			i += end + 2
+		default:
+			end := strings.IndexAny(q[i:], " \t\n\r()\"")
+			if end == -1 {
+				end = len(q) - i
+			}
+			word := q[i : i+end]
+			switch word {
+			case tokenAnd, tokenOr, tokenNot:
+				tokens = append(tokens, searchToken{Kind: word})
+			default:
+				tokens = append(tokens, searchToken{Kind: tokenTerm, Text: word})
+			}
+			i += end
+		}
+	}
+	return tokens, nil
+}
+
+// searchNode is a parsed search: a term, a phrase, or an operator joining two searches
+type searchNode struct {
+	Op   string
+	Text string
+	// Left and Right are the operands of AND, OR and NOT
+	Left  *searchNode
+	Right *searchNode
+}
+
+// fts will return the node as a fully parenthesized fts5 query. Terms and
+// phrases are quoted, so characters with a meaning in the fts5 syntax are
+// searched as plain text.
+func (n *searchNode) fts() string {
+	switch n.Op {
+	case tokenTerm, tokenPhrase:
+		return `"` + strings.ReplaceAll(n.Text, `"`, `""`) + `"`
+	}
+	return "(" + n.Left.fts() + " " + n.Op + " " + n.Right.fts() + ")"
+}
+
+// join will join two searches with an operator, returning the other one when either is nil
+func joinSearch(op string, left, right *searchNode) *searchNode {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	return &searchNode{Op: op, Left: left, Right: right}
+}
+
+// searchParser parses search tokens. NOT binds tighter than AND, which binds
+// tighter than OR, like in fts5. Terms next to each other are joined with AND.
+type searchParser struct {
+	tokens []searchToken
+	pos    int
+}
+
+func (p *searchParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].Kind
+	}
+	return ""
+}
+
+func (p *searchParser) parseOr() (*searchNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == tokenOr {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = joinSearch(tokenOr, left, right)
+	}
+	return left, nil
+}
+
+func (p *searchParser) parseAnd() (*searchNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case tokenAnd:
+			p.pos++
+		case tokenTerm, tokenPhrase, tokenOpen:
+		default:
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = joinSearch(tokenAnd, left, right)
+	}
+}
+
+func (p *searchParser) parseNot() (*searchNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == tokenNot {
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = joinSearch(tokenNot, left, right)
+	}
+	return left, nil
+}
+
+func (p *searchParser) parsePrimary() (*searchNode, error) {
+	switch p.peek() {
+	case tokenTerm, tokenPhrase:
+		t := p.tokens[p.pos]
+		p.pos++
+		return &searchNode{Op: t.Kind, Text: t.Text}, nil
+	case tokenOpen:
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != tokenClose {
+			return nil, errors.New("a parenthesis is missing its closing )")
+		}
+		p.pos++
+		return n, nil
+	case tokenNot:
+		return nil, errors.New(`NOT needs a term before it, like "go NOT crypto"`)
+	case tokenAnd, tokenOr:
+		return nil, errors.New(p.peek() + " needs a term on each side")
+	case tokenClose:
+		return nil, errors.New("a ) has no opening parenthesis")
+	}
+	return nil, errors.New("the search ends with an operator")
+}
+
+// parseSearch will parse a search like `"staff engineer" AND (go OR rust) NOT crypto`
+func parseSearch(q string) (*searchNode, error) {
+	tokens, err := lexSearch(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("the search has no terms")
+	}
+	p := &searchParser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, errors.New("a ) has no opening parenthesis")
+	}
+	return n, nil
+}
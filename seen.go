@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// seenVisitLength is how long the jobs served to a reader keep showing in
+// unseen listings, so the reader can go back and forth within a visit
+const seenVisitLength = time.Hour
+
+// withSeenJobs will set the owner of the jobs seen by the reader on an unseen
+// filter, starting a session when there is none
+func withSeenJobs(w http.ResponseWriter, r *http.Request, f FilterState) FilterState {
+	if f.Unseen {
+		session, _ := requestSession(w, r)
+		f.seenBy = sessionOwner(session)
+	}
+	return f
+}
+
+// RecordSeenJobs will record the jobs served to an owner. Jobs keep the time
+// they were first seen.
+func RecordSeenJobs(owner string, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?), ", len(ids)), ", ")
+	args := make([]any, 0, 3*len(ids))
+	now := time.Now().Unix()
+	for _, id := range ids {
+		args = append(args, owner, id, now)
+	}
+	_, err := db.Exec(`INSERT INTO seen_job (owner, hn_id, seen_at) VALUES `+values+` ON CONFLICT (owner, hn_id) DO NOTHING`, args...)
+	return err
+}
+
+// recordSeenJobs will record the jobs served to the session of a request,
+// starting one when there is none. Failures are logged as they must not fail
+// the page.
+func recordSeenJobs(w http.ResponseWriter, r *http.Request, ids ...uint64) {
+	if len(ids) == 0 {
+		return
+	}
+	session, _ := requestSession(w, r)
+	if err := RecordSeenJobs(sessionOwner(session), ids); err != nil {
+		log.Println("failed to record seen jobs.", err)
+	}
+}
+
+// PruneSeenJobs will delete the jobs seen before t, returning how many were deleted
+func PruneSeenJobs(t time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM seen_job WHERE seen_at < ?`, t.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
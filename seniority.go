@@ -0,0 +1,55 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	levelIntern = "intern"
+	levelJunior = "junior"
+	levelMid    = "mid"
+	levelSenior = "senior"
+	levelStaff  = "staff+"
+)
+
+// jobLevels lists the seniority levels from least to most senior
+var jobLevels = []string{levelIntern, levelJunior, levelMid, levelSenior, levelStaff}
+
+var levelPatterns = map[string]*regexp.Regexp{
+	levelIntern: regexp.MustCompile(`(?i)\b(interns?|internships?|co-?op)\b`),
+	levelJunior: regexp.MustCompile(`(?i)\b(junior|jr\.?|entry[- ]level|new grads?|graduate)\b`),
+	levelMid:    regexp.MustCompile(`(?i)\b(mid[- ]?level|mid[- ]senior|intermediate|engineer ii)\b`),
+	levelSenior: regexp.MustCompile(`(?i)\b(senior|sr\.?|lead|engineer iii)\b`),
+	levelStaff:  regexp.MustCompile(`(?i)\b(staff|principal|distinguished|fellow|architect|head of|director|vp)\b`),
+}
+
+// jobRoleText will return the part of a job post that usually describes
+// the role, which is the first line or paragraph.
+func jobRoleText(text string) string {
+	if i := strings.Index(text, "<p>"); i >= 0 {
+		text = text[:i]
+	}
+	if i := strings.Index(text, "\n"); i >= 0 {
+		text = text[:i]
+	}
+	return text
+}
+
+// jobLevel will classify a job post into seniority levels based on its role text.
+// Posts hiring for several levels return a comma separated list.
+func jobLevel(text string) string {
+	roleText := jobRoleText(text)
+	var found []string
+	for _, l := range jobLevels {
+		if levelPatterns[l].MatchString(roleText) {
+			found = append(found, l)
+		}
+	}
+	return strings.Join(found, ",")
+}
+
+// isJobLevel will return true when v is a known seniority level
+func isJobLevel(v string) bool {
+	return getIndex(jobLevels, v) != -1
+}
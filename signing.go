@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// signature will return the url safe HMAC of a path and its query params.
+// The query params are encoded sorted by key so the signature is stable.
+func signature(path string, q url.Values) string {
+	mac := hmac.New(sha256.New, []byte(cfg.SigningKey))
+	mac.Write([]byte(path + "?" + q.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signUrl will return a url to path and query params q that stays valid for ttl
+func signUrl(path string, q url.Values, ttl time.Duration) string {
+	sq := url.Values{}
+	for k, v := range q {
+		sq[k] = v
+	}
+	sq.Del("sig")
+	sq.Set("expires", strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))
+	sq.Set("sig", signature(path, sq))
+	return path + "?" + sq.Encode()
+}
+
+// validSignature will return true when the request url carries a valid,
+// unexpired signature
+func validSignature(r *http.Request) bool {
+	if cfg.SigningKey == "" {
+		return false
+	}
+
+	q := r.URL.Query()
+	sig := q.Get("sig")
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if sig == "" || err != nil || time.Now().Unix() > expires {
+		return false
+	}
+
+	q.Del("sig")
+	return hmac.Equal([]byte(sig), []byte(signature(r.URL.Path, q)))
+}
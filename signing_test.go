@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidSignature(t *testing.T) {
+	key := cfg.SigningKey
+	t.Cleanup(func() { cfg.SigningKey = key })
+	cfg.SigningKey = "test-signing-key"
+
+	signed := signUrl("/export/jobs.csv", url.Values{"story": {"41234567"}}, time.Hour)
+	expired := signUrl("/export/jobs.csv", url.Values{"story": {"41234567"}}, -time.Minute)
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+
+	// with will return the signed params with k set to v, or removed when v is empty
+	with := func(k, v string) string {
+		c := url.Values{}
+		for k, v := range q {
+			c[k] = v
+		}
+		if v == "" {
+			c.Del(k)
+		} else {
+			c.Set(k, v)
+		}
+		return "/export/jobs.csv?" + c.Encode()
+	}
+
+	cases := []struct {
+		name string
+		url  string
+		want bool
+	}{
+		{"signed", signed, true},
+		{"params reordered", "/export/jobs.csv?sig=" + url.QueryEscape(q.Get("sig")) + "&story=41234567&expires=" + q.Get("expires"), true},
+		{"expired", expired, false},
+		{"other path", strings.Replace(signed, "/export/jobs.csv", "/export/sqlite", 1), false},
+		{"param changed", with("story", "41234568"), false},
+		{"expiry extended", with("expires", strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10)), false},
+		{"unsigned", with("sig", ""), false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", c.url, nil)
+		if got := validSignature(r); got != c.want {
+			t.Errorf("%s: validSignature(%q) = %t, want %t", c.name, c.url, got, c.want)
+		}
+	}
+
+	// urls signed with another key, or without a key set, are not valid
+	cfg.SigningKey = "another-key"
+	if validSignature(httptest.NewRequest("GET", signed, nil)) {
+		t.Errorf("validSignature(%q) = true with another key", signed)
+	}
+	cfg.SigningKey = ""
+	if validSignature(httptest.NewRequest("GET", signed, nil)) {
+		t.Errorf("validSignature(%q) = true without a signing key", signed)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The sitemap is split into one file per story, listed by a sitemap index,
+// so search engines only fetch the files of stories whose jobs changed.
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapUrlSet struct {
+	XMLName xml.Name       `xml:"urlset"`
+	Xmlns   string         `xml:"xmlns,attr"`
+	Urls    []sitemapEntry `xml:"url"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapTime will format a unix time as a sitemap lastmod date
+func sitemapTime(t uint64) string {
+	if t == 0 {
+		return ""
+	}
+	return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+}
+
+// writeSitemap will write v as an xml document
+func writeSitemap(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	if err := enc.Encode(v); err != nil {
+		log.Println("failed to write sitemap.", err)
+	}
+}
+
+// sitemapIndexHandler will list the sitemap of every story with the last
+// time one of its jobs changed
+func sitemapIndexHandler(w http.ResponseWriter, r *http.Request) {
+	stories, err := SelectStorySitemaps()
+	if err != nil {
+		log.Println("failed to select story sitemaps.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	index := sitemapIndex{Xmlns: sitemapNamespace}
+	for _, s := range stories {
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{
+			Loc:     fmt.Sprintf("%s/sitemaps/story-%d.xml", cfg.PublicBaseUrl, s.HnId),
+			LastMod: sitemapTime(s.Time),
+		})
+	}
+	writeSitemap(w, index)
+}
+
+// storySitemapHandler will list the pages of a story and of each of its jobs
+func storySitemapHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/sitemaps/")
+	if !strings.HasPrefix(name, "story-") || !strings.HasSuffix(name, ".xml") {
+		http.NotFound(w, r)
+		return
+	}
+	hsId := paramValue(strings.TrimSuffix(strings.TrimPrefix(name, "story-"), ".xml"), 0)
+	if _, err := GetHiringStory(hsId); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	jobs, err := SelectHiringJobUpdates(hsId)
+	if err != nil {
+		log.Println("failed to select hiring job updates.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var storyMod uint64
+	set := sitemapUrlSet{Xmlns: sitemapNamespace, Urls: []sitemapEntry{{}}}
+	for _, hj := range jobs {
+		mod := hj.UpdatedAt
+		if hj.Time > mod {
+			mod = hj.Time
+		}
+		if mod > storyMod {
+			storyMod = mod
+		}
+		set.Urls = append(set.Urls, sitemapEntry{
+			Loc:     fmt.Sprintf("%s/job/%d", cfg.PublicBaseUrl, hj.HnId),
+			LastMod: sitemapTime(mod),
+		})
+	}
+	set.Urls[0] = sitemapEntry{
+		Loc:     fmt.Sprintf("%s/story/%d", cfg.PublicBaseUrl, hsId),
+		LastMod: sitemapTime(storyMod),
+	}
+	writeSitemap(w, set)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// snapshotPrivateTables are the tables emptied in snapshots, as they hold the
+// sessions, marks and searches of readers and the actions of admins rather
+// than jobs
+var snapshotPrivateTables = []string{"saved_search", "seen_job", "job_mark", "search_miss", "audit_log"}
+
+// sqliteConn will call fn with the sqlite connection of conn
+func sqliteConn(conn *sql.Conn, fn func(*sqlite3.SQLiteConn) error) error {
+	return conn.Raw(func(dc any) error {
+		c, ok := dc.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", dc)
+		}
+		return fn(c)
+	})
+}
+
+// createSnapshot will copy the database to a new file at path with the
+// sqlite backup api, which copies a consistent state of the database while
+// the app keeps writing to it. The private tables are emptied in the copy
+// unless keepPrivate is set, as for backups.
+func createSnapshot(ctx context.Context, path string, keepPrivate bool) error {
+	dst, err := sql.Open(sqliteDriverName, path)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	err = sqliteConn(dstConn, func(d *sqlite3.SQLiteConn) error {
+		return sqliteConn(srcConn, func(s *sqlite3.SQLiteConn) error {
+			backup, err := d.Backup("main", s, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if keepPrivate {
+		return nil
+	}
+
+	for _, table := range snapshotPrivateTables {
+		if _, err := dstConn.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			return err
+		}
+	}
+	_, err = dstConn.ExecContext(ctx, "VACUUM")
+	return err
+}
+
+// sqliteSnapshotHandler will send a snapshot of the database file, so power
+// users can run their own sql over the jobs. The snapshot is written to a
+// temporary file first, as the backup must finish before its size is known.
+func sqliteSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	dir, err := os.MkdirTemp("", "wih-snapshot")
+	if err != nil {
+		log.Println("failed to create snapshot dir.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, filepath.Base(dbPath))
+	if err := createSnapshot(r.Context(), path, false); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Println("failed to create database snapshot.", err)
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Println("failed to open database snapshot.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		log.Println("failed to stat database snapshot.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	name := fmt.Sprintf("whoishiring-%s.db", time.Now().UTC().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	if _, err := io.Copy(w, f); err != nil {
+		log.Println("failed to send database snapshot.", err)
+	}
+}
+
+// backupDatabase will write a full copy of the database to the backup dir,
+// named by the time of the backup, and delete the oldest backups past the
+// last cfg.BackupKeep
+func backupDatabase(ctx context.Context) error {
+	if err := os.MkdirAll(cfg.BackupDir, 0o700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("whoishiring-%s.db", time.Now().UTC().Format("2006-01-02T150405"))
+	path := filepath.Join(cfg.BackupDir, name)
+	if err := createSnapshot(ctx, path, true); err != nil {
+		os.Remove(path)
+		return err
+	}
+	log.Printf("backed up the database to %s", path)
+
+	// names sort by time
+	backups, err := filepath.Glob(filepath.Join(cfg.BackupDir, "whoishiring-*.db"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(backups)
+	for len(backups) > cfg.BackupKeep && cfg.BackupKeep > 0 {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestCreateSnapshotEmptiesPrivateTables(t *testing.T) {
+	useTestDB(t)
+	if _, err := CreateHiringStory(1, storyKindHiring, "Ask HN: Who is hiring?", 1700000000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateHiringJob(1, jobStatusOk, HiringJob{HnId: 10, Text: "Acme | Engineer | Remote", Time: 1700000000}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO job_mark (hn_id, read_at, favorite_at) VALUES (10, 1700000100, 1700000100)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordSearchMiss("haskell"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keepPrivate := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "snapshot.db")
+		if err := createSnapshot(context.Background(), path, keepPrivate); err != nil {
+			t.Fatal(err)
+		}
+		snap, err := sqlx.Open(sqliteDriverName, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer snap.Close()
+		var jobs int
+		if err := snap.Get(&jobs, `SELECT COUNT(*) FROM hiring_job`); err != nil {
+			t.Fatal(err)
+		}
+		if jobs != 1 {
+			t.Errorf("keepPrivate %t: snapshot has %d jobs, want 1", keepPrivate, jobs)
+		}
+		for _, table := range snapshotPrivateTables {
+			var rows int
+			if err := snap.Get(&rows, `SELECT COUNT(*) FROM `+table); err != nil {
+				t.Fatal(err)
+			}
+			if !keepPrivate && rows != 0 {
+				t.Errorf("snapshot kept %d rows of private table %s", rows, table)
+			}
+			if keepPrivate && (table == "job_mark" || table == "search_miss") && rows != 1 {
+				t.Errorf("backup has %d rows of table %s, want 1", rows, table)
+			}
+		}
+	}
+}
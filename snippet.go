@@ -0,0 +1,136 @@
+package main
+
+import (
+	"html/template"
+	"strings"
+	"unicode"
+)
+
+const (
+	// snippetOpen and snippetClose surround the matched terms of a snippet.
+	// They are control chars, which job texts never contain, so snippets are
+	// escaped before the marks are turned into html.
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+	// snippetEllipsis marks the text cut before or after a snippet
+	snippetEllipsis = "…"
+	// snippetWords is the number of words a snippet shows around the first match
+	snippetWords = 24
+)
+
+// highlightSnippet will escape a snippet and mark its matched terms
+func highlightSnippet(s string) template.HTML {
+	escaped := template.HTMLEscapeString(s)
+	escaped = strings.ReplaceAll(escaped, snippetOpen, "<mark>")
+	escaped = strings.ReplaceAll(escaped, snippetClose, "</mark>")
+	return template.HTML(escaped)
+}
+
+// phrases will return the stemmed words of the terms a job matching the
+// search contains, leaving out the excluded terms
+func (n *searchNode) phrases() [][]string {
+	switch n.Op {
+	case tokenTerm, tokenPhrase:
+		return [][]string{stemWords(searchWords(n.Text))}
+	case tokenAnd, tokenOr:
+		return append(n.Left.phrases(), n.Right.phrases()...)
+	case tokenNot:
+		return n.Left.phrases()
+	}
+	return nil
+}
+
+// textWord is a word of a text with its byte offsets
+type textWord struct {
+	Text       string
+	Start, End int
+}
+
+// textWords will split a text into lowercased words like searchWords, keeping
+// where each word is in the text
+func textWords(text string) []textWord {
+	var words []textWord
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			words = append(words, textWord{Text: strings.ToLower(text[start:i]), Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, textWord{Text: strings.ToLower(text[start:]), Start: start, End: len(text)})
+	}
+	return words
+}
+
+// textSnippet will return snippetWords words of text around the first match
+// of a search, with the matched terms marked like the FTS5 snippet function
+// does. Texts without a match return an empty snippet.
+func textSnippet(text string, q *searchNode) string {
+	words := textWords(text)
+	stems := make([]string, len(words))
+	for i, w := range words {
+		stems[i] = stemWord(w.Text)
+	}
+	matched := make([]bool, len(words))
+	first := -1
+	for _, phrase := range q.phrases() {
+		if len(phrase) == 0 {
+			continue
+		}
+		for i := 0; i+len(phrase) <= len(words); i++ {
+			if occurrences(stems[i:i+len(phrase)], phrase) == 0 {
+				continue
+			}
+			for j := i; j < i+len(phrase); j++ {
+				matched[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	return snippetAround(text, words, matched, first)
+}
+
+// snippetAround will return snippetWords words of text around the first
+// matched word, with the matched words marked. Texts without a match,
+// when first is negative, return an empty snippet.
+func snippetAround(text string, words []textWord, matched []bool, first int) string {
+	if first < 0 {
+		return ""
+	}
+
+	start := first - snippetWords/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWords
+	if end > len(words) {
+		end = len(words)
+	}
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(snippetEllipsis)
+	}
+	pos := words[start].Start
+	for i := start; i < end; i++ {
+		w := words[i]
+		b.WriteString(text[pos:w.Start])
+		if matched[i] {
+			b.WriteString(snippetOpen + text[w.Start:w.End] + snippetClose)
+		} else {
+			b.WriteString(text[w.Start:w.End])
+		}
+		pos = w.End
+	}
+	if end < len(words) {
+		b.WriteString(snippetEllipsis)
+	} else {
+		b.WriteString(text[pos:])
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import "strings"
+
+const (
+	sortNewest    = "newest"
+	sortOldest    = "oldest"
+	sortSalary    = "salary"
+	sortRelevance = "relevance"
+	sortHot       = "hot"
+)
+
+// jobSorts are the orders jobs of a listing can be sorted in
+var jobSorts = []string{sortNewest, sortOldest, sortHot, sortSalary, sortRelevance}
+
+// jobSortNames are the display names of the sorts
+var jobSortNames = map[string]string{
+	sortNewest:    "newest first",
+	sortOldest:    "oldest first",
+	sortSalary:    "highest salary first",
+	sortRelevance: "best matches first",
+	sortHot:       "most engaging first",
+}
+
+// isJobSort will return true when v is a known sort
+func isJobSort(v string) bool {
+	return getIndex(jobSorts, v) != -1
+}
+
+// sortOrder will return the sort jobs are listed in: the sort param when set,
+// the best matches first for searches, and the default sort of the listing or
+// the newest first otherwise.
+// Only searches of terms have a relevance, so other listings, and regex
+// searches, sorted by it show the newest jobs first.
+func (f FilterState) sortOrder() string {
+	switch {
+	case f.Sort == sortRelevance && !f.hasTerms():
+		return sortNewest
+	case f.Sort != "":
+		return f.Sort
+	case f.hasTerms():
+		return sortRelevance
+	case f.defaultSort != "":
+		return f.defaultSort
+	}
+	return sortNewest
+}
+
+// SortName will return the display name of the sort jobs are listed in
+func (f FilterState) SortName() string {
+	return jobSortNames[f.sortOrder()]
+}
+
+// sortKey will return the columns of hiring_job_view hj a sort orders jobs by
+// and whether they are descending. Keys end with a unique column, so every
+// job has a stable position to page from. Relevance and hot order by the
+// position of jobs in the ranked json_each of the search or of their score.
+func sortKey(sort string) ([]string, bool) {
+	switch sort {
+	case sortOldest:
+		return []string{"hj.time", "hj.hn_id"}, false
+	case sortSalary:
+		// jobs without a salary have a salary_max_usd of 0, so they come last
+		return []string{"hj.salary_max_usd", "hj.hn_id"}, true
+	case sortRelevance, sortHot:
+		return []string{"ranked.key"}, false
+	}
+	return []string{"hj.time", "hj.hn_id"}, true
+}
+
+// orderBy will return the ORDER BY terms of a sort key
+func orderBy(key []string, desc bool) string {
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	terms := make([]string, len(key))
+	for i, col := range key {
+		terms[i] = col + dir
+	}
+	return strings.Join(terms, ", ")
+}
+
+// sortLink is a sort offered for a listing
+type sortLink struct {
+	Sort     string
+	Label    string
+	Url      string
+	Selected bool
+}
+
+// sortLinks will return the links listing the jobs of the filter at path in
+// each sort, from the first job. Relevance is only offered for searches of terms.
+func (f FilterState) sortLinks(path string) []sortLink {
+	var links []sortLink
+	for _, s := range jobSorts {
+		if s == sortRelevance && !f.hasTerms() {
+			continue
+		}
+		sorted := f
+		sorted.Sort = s
+		links = append(links, sortLink{Sort: s, Label: jobSortNames[s], Url: sorted.cursorUrl(path, "", 0), Selected: s == f.sortOrder()})
+	}
+	return links
+}
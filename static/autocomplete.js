@@ -0,0 +1,39 @@
+// Inputs with a data-autocomplete attribute suggest values from an api
+// endpoint as the user types: companies from /api/companies fill in the
+// company slug and tags from /api/tags show their number of jobs.
+(function () {
+    function suggestion(item) {
+        var option = document.createElement("option");
+        if (item.tag !== undefined) {
+            option.value = item.tag;
+            option.label = item.tag + " (" + item.count + ")";
+        } else {
+            option.value = item.slug;
+            option.label = item.alias ? item.name + " (" + item.alias + ")" : item.name;
+        }
+        return option;
+    }
+
+    document.querySelectorAll("input[data-autocomplete]").forEach(function (input) {
+        var list = document.createElement("datalist");
+        list.id = input.name + "-suggestions";
+        input.setAttribute("list", list.id);
+        input.after(list);
+
+        var timer;
+        input.addEventListener("input", function () {
+            clearTimeout(timer);
+            timer = setTimeout(function () {
+                if (!input.value) {
+                    list.replaceChildren();
+                    return;
+                }
+                fetch(input.dataset.autocomplete + "?prefix=" + encodeURIComponent(input.value))
+                    .then(function (resp) { return resp.json(); })
+                    .then(function (items) {
+                        list.replaceChildren.apply(list, items.map(suggestion));
+                    });
+            }, 200);
+        });
+    });
+})();
@@ -0,0 +1,27 @@
+// Links with a data-last-visit attribute point to their jobs posted since the
+// reader's previous visit. A visit lasts as long as the browser session, so
+// the jobs stay listed while the reader goes through them.
+(function () {
+    var key = "wih_last_visit";
+    var previous;
+    try {
+        previous = sessionStorage.getItem(key);
+        if (previous === null) {
+            previous = localStorage.getItem(key) || "";
+            sessionStorage.setItem(key, previous);
+            localStorage.setItem(key, new Date().toISOString().replace(/\.\d+Z$/, "Z"));
+        }
+    } catch (e) {
+        return;
+    }
+    if (!previous) {
+        return;
+    }
+
+    document.querySelectorAll("a[data-last-visit]").forEach(function (link) {
+        var url = new URL(link.href);
+        url.searchParams.set("posted_after", previous);
+        link.href = url.pathname + url.search;
+        link.hidden = false;
+    });
+})();
@@ -0,0 +1,175 @@
+// Pressing "/" opens a palette to jump to a tag, a company, a location or a
+// search. Tags, companies and locations come from the suggest api endpoint and
+// filter the reader, the typed text can always be searched. Arrows move through the
+// choices, enter picks one and escape closes the palette.
+(function () {
+    var overlay, input, list, choices = [], active = 0, timer;
+
+    // readerPath is the reader filtered by tags and companies: the story of
+    // the page when it shows one, the latest story otherwise
+    function readerPath() {
+        return /^\/story\/\d+$/.test(location.pathname) ? location.pathname : "/";
+    }
+
+    // filterUrl will add a tag to the tags of the reader, as jobs must have
+    // them all, and replace the company or the location
+    function filterUrl(param, value) {
+        var q = new URLSearchParams(readerPath() === location.pathname ? location.search : "");
+        q.delete("after");
+        q.delete("before");
+        if (param === "tag") {
+            if (q.getAll("tag").indexOf(value) === -1) {
+                q.append("tag", value);
+            }
+        } else {
+            q.set(param, value);
+        }
+        return readerPath() + "?" + q.toString();
+    }
+
+    function searchUrl(text) {
+        var q = new URLSearchParams({ q: text });
+        var story = location.pathname.match(/^\/story\/(\d+)$/);
+        if (story) {
+            q.set("story", story[1]);
+        }
+        return "/search?" + q.toString();
+    }
+
+    function fetchJson(url) {
+        return fetch(url)
+            .then(function (resp) { return resp.ok ? resp.json() : []; })
+            .catch(function () { return []; });
+    }
+
+    function render() {
+        list.replaceChildren.apply(list, choices.map(function (choice, i) {
+            var item = document.createElement("li");
+            item.id = "palette-choice-" + i;
+            item.setAttribute("role", "option");
+            item.setAttribute("aria-selected", i === active ? "true" : "false");
+            item.className = "px-2 py-1 cursor-pointer" + (i === active ? " bg-slate-700" : "");
+            item.textContent = choice.label;
+            item.addEventListener("mousedown", function (e) {
+                e.preventDefault();
+                location.href = choice.url;
+            });
+            return item;
+        }));
+        input.setAttribute("aria-activedescendant", choices.length ? "palette-choice-" + active : "");
+    }
+
+    function update() {
+        var text = input.value.trim();
+        if (!text) {
+            choices = [];
+            render();
+            return;
+        }
+        fetchJson("/api/suggest?q=" + encodeURIComponent(text))
+            .then(function (suggestions) {
+                if (input.value.trim() !== text) {
+                    return;
+                }
+                choices = [{ label: "Search: " + text, url: searchUrl(text) }];
+                (suggestions.tags || []).forEach(function (t) {
+                    choices.push({ label: "Tag: " + t.tag + " (" + t.count + ")", url: filterUrl("tag", t.tag) });
+                });
+                (suggestions.companies || []).forEach(function (c) {
+                    var name = c.alias ? c.name + " (" + c.alias + ")" : c.name;
+                    choices.push({ label: "Company: " + name, url: filterUrl("company", c.slug) });
+                });
+                (suggestions.locations || []).forEach(function (l) {
+                    choices.push({ label: "Location: " + l.location + " (" + l.count + ")", url: filterUrl("location", l.location.toLowerCase()) });
+                });
+                active = 0;
+                render();
+            });
+    }
+
+    function build() {
+        overlay = document.createElement("div");
+        overlay.className = "fixed inset-0 bg-black/50 hidden items-start justify-center pt-24 z-50";
+        overlay.setAttribute("role", "dialog");
+        overlay.setAttribute("aria-label", "Quick filter");
+
+        var panel = document.createElement("div");
+        panel.className = "bg-slate-800 text-white w-full max-w-lg mx-3 text-sm";
+
+        input = document.createElement("input");
+        input.className = "bg-slate-900 w-full px-2 py-2";
+        input.placeholder = "Jump to a tag, a company, a location or a search";
+        input.setAttribute("role", "combobox");
+        input.setAttribute("aria-controls", "palette-choices");
+        input.setAttribute("aria-expanded", "true");
+
+        list = document.createElement("ul");
+        list.id = "palette-choices";
+        list.setAttribute("role", "listbox");
+
+        panel.append(input, list);
+        overlay.append(panel);
+        document.body.append(overlay);
+
+        overlay.addEventListener("mousedown", function (e) {
+            if (e.target === overlay) {
+                close();
+            }
+        });
+        input.addEventListener("input", function () {
+            clearTimeout(timer);
+            timer = setTimeout(update, 150);
+        });
+        input.addEventListener("keydown", function (e) {
+            switch (e.key) {
+                case "ArrowDown":
+                    active = Math.min(active + 1, choices.length - 1);
+                    break;
+                case "ArrowUp":
+                    active = Math.max(active - 1, 0);
+                    break;
+                case "Enter":
+                    if (choices[active]) {
+                        location.href = choices[active].url;
+                    } else if (input.value.trim()) {
+                        location.href = searchUrl(input.value.trim());
+                    }
+                    break;
+                case "Escape":
+                    close();
+                    break;
+                default:
+                    return;
+            }
+            e.preventDefault();
+            render();
+        });
+    }
+
+    function open() {
+        if (!overlay) {
+            build();
+        }
+        overlay.classList.remove("hidden");
+        overlay.classList.add("flex");
+        input.value = "";
+        choices = [];
+        render();
+        input.focus();
+    }
+
+    function close() {
+        overlay.classList.add("hidden");
+        overlay.classList.remove("flex");
+    }
+
+    document.addEventListener("keydown", function (e) {
+        var target = e.target;
+        if (e.key !== "/" || e.ctrlKey || e.metaKey || e.altKey ||
+            target.isContentEditable || /^(INPUT|TEXTAREA|SELECT)$/.test(target.tagName)) {
+            return;
+        }
+        e.preventDefault();
+        open();
+    });
+})();
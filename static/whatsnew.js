@@ -0,0 +1,33 @@
+// Links with a data-release attribute get an unread dot when there is a
+// release the reader hasn't seen. Releases are seen by opening the release
+// notes, and first visits see every release so only returning readers get the dot.
+(function () {
+    var key = "wih_seen_release";
+    var seen;
+    try {
+        seen = localStorage.getItem(key);
+    } catch (e) {
+        return;
+    }
+
+    var notes = document.querySelector("[data-latest-release]");
+    if (notes) {
+        localStorage.setItem(key, notes.dataset.latestRelease);
+        return;
+    }
+
+    document.querySelectorAll("a[data-release]").forEach(function (link) {
+        var release = link.dataset.release;
+        if (!release) {
+            return;
+        }
+        if (seen === null) {
+            localStorage.setItem(key, release);
+        } else if (seen < release) {
+            var dot = document.createElement("span");
+            dot.className = "inline-block w-2 h-2 ml-1 rounded-full bg-amber-300 align-middle";
+            dot.title = "New since your last visit";
+            link.append(dot);
+        }
+    });
+})();
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// kindSuggestions is the max number of tags, companies and locations each
+// returned by the suggest api
+const kindSuggestions = 5
+
+// LocationCount is a known place or region with its number of jobs
+type LocationCount struct {
+	Location string `json:"location"`
+	Count    int    `json:"count"`
+}
+
+// Suggestions are the filters starting with a typed prefix, most jobs first
+type Suggestions struct {
+	Tags      []TagCount      `json:"tags"`
+	Companies []CompanyMatch  `json:"companies"`
+	Locations []LocationCount `json:"locations"`
+}
+
+// SelectStoryPlaceCounts will count the live jobs of a story geocoded to each
+// known place, by place name
+func SelectStoryPlaceCounts(hsId uint64) (map[string]int, error) {
+	var rows []struct {
+		Latitude  float64
+		Longitude float64
+		Count     int
+	}
+	sql := `SELECT latitude, longitude, COUNT(*) AS count FROM hiring_job_view
+            WHERE hiring_story_id=? and status=? and duplicate_of=0 and latitude != 0
+            GROUP BY latitude, longitude`
+	if err := db.Select(&rows, sql, hsId, jobStatusOk); err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, r := range rows {
+		for _, p := range geoPlaces {
+			if p.Latitude == r.Latitude && p.Longitude == r.Longitude {
+				counts[p.Name()] += r.Count
+				break
+			}
+		}
+	}
+	return counts, nil
+}
+
+// hasNamePrefix will return true when any of the names starts with prefix,
+// ignoring case
+func hasNamePrefix(names []string, prefix string) bool {
+	for _, n := range names {
+		if strings.HasPrefix(strings.ToLower(n), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// locationsByPrefix will return the places and regions with a name or alias
+// starting with prefix along with their counts, the most frequent first.
+// Regions count the jobs of the places of their countries.
+func locationsByPrefix(prefix string, counts map[string]int) []LocationCount {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	matches := []LocationCount{}
+	if prefix == "" {
+		return matches
+	}
+	for _, p := range geoPlaces {
+		if hasNamePrefix(p.Names, prefix) {
+			matches = append(matches, LocationCount{Location: p.Name(), Count: counts[p.Name()]})
+		}
+	}
+	for _, r := range geoRegions {
+		if !hasNamePrefix(r.Names, prefix) {
+			continue
+		}
+		m := LocationCount{Location: r.Names[0]}
+		for _, p := range geoPlaces {
+			if getIndex(r.Countries, p.Country) != -1 {
+				m.Count += counts[p.Name()]
+			}
+		}
+		matches = append(matches, m)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Count != matches[j].Count {
+			return matches[i].Count > matches[j].Count
+		}
+		return matches[i].Location < matches[j].Location
+	})
+	return matches
+}
+
+// suggestApiHandler will return the tags, companies and locations starting
+// with the q param, for type-ahead search boxes. Tags and locations count the
+// jobs of the current story and companies the jobs of every story.
+func suggestApiHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("q")
+	hs, err := GetCurrentHiringStory()
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println("failed to get current story.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	tagCounts, err := SelectStoryTagCounts(hs.HnId)
+	if err != nil {
+		log.Println("failed to count tags.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	placeCounts, err := SelectStoryPlaceCounts(hs.HnId)
+	if err != nil {
+		log.Println("failed to count places.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	companies, err := SelectCompaniesByPrefix(prefix, kindSuggestions)
+	if err != nil {
+		log.Println("failed to select companies.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	s := Suggestions{Tags: []TagCount{}, Companies: companies, Locations: locationsByPrefix(prefix, placeCounts)}
+	// every tag starts with an empty prefix
+	if tags := tagsByPrefix(prefix, tagCounts); strings.TrimSpace(prefix) != "" && len(tags) > 0 {
+		s.Tags = tags
+	}
+	if len(s.Tags) > kindSuggestions {
+		s.Tags = s.Tags[:kindSuggestions]
+	}
+	if len(s.Locations) > kindSuggestions {
+		s.Locations = s.Locations[:kindSuggestions]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		log.Println("failed to encode suggestions.", err)
+	}
+}
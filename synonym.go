@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// defaultSynonyms are the aliases searched along with each other, like
+// "k8s=kubernetes". cfg.Synonyms adds to them.
+var defaultSynonyms = []string{
+	"golang=go",
+	"k8s=kubernetes",
+	"ml=machine learning",
+	"ai=artificial intelligence",
+	"llm=large language model",
+	"js=javascript",
+	"ts=typescript",
+	"postgres=postgresql",
+	"nodejs=node",
+	"reactjs=react",
+	"gcp=google cloud",
+	"sre=site reliability",
+}
+
+// synonymTable will parse synonyms like "ml=machine learning" into the terms
+// searched along with each term. Terms are lowercased words, multiple words
+// are searched as a phrase. Synonyms apply both ways and are not chained, so
+// "ml=machine learning" does not make "machine learning" a synonym of the
+// other synonyms of "ml".
+func synonymTable(items []string) map[string][]string {
+	table := map[string][]string{}
+	for _, item := range items {
+		a, b, _ := strings.Cut(item, "=")
+		a, b = strings.Join(searchWords(a), " "), strings.Join(searchWords(b), " ")
+		if a == "" || b == "" || a == b {
+			log.Printf("invalid search synonym %q, skipping", item)
+			continue
+		}
+		if getIndex(table[a], b) == -1 {
+			table[a] = append(table[a], b)
+		}
+		if getIndex(table[b], a) == -1 {
+			table[b] = append(table[b], a)
+		}
+	}
+	return table
+}
+
+var searchSynonyms = synonymTable(append(defaultSynonyms, cfg.Synonyms...))
+
+// withSynonyms will return the search with every term and phrase also
+// matching its synonyms, so excluded terms also exclude their synonyms
+func (n *searchNode) withSynonyms(table map[string][]string) *searchNode {
+	if n == nil {
+		return nil
+	}
+	switch n.Op {
+	case tokenTerm, tokenPhrase:
+		expanded := n
+		for _, s := range table[strings.Join(searchWords(n.Text), " ")] {
+			op := tokenTerm
+			if strings.Contains(s, " ") {
+				op = tokenPhrase
+			}
+			expanded = joinSearch(tokenOr, expanded, &searchNode{Op: op, Text: s})
+		}
+		return expanded
+	case tokenAnd, tokenOr, tokenNot:
+		return &searchNode{Op: n.Op, Left: n.Left.withSynonyms(table), Right: n.Right.withSynonyms(table)}
+	}
+	return n
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tagRename    = "rename"
+	tagMerge     = "merge"
+	tagBlacklist = "blacklist"
+	// tagBatchSize is the number of jobs retagged per transaction
+	tagBatchSize = 200
+	// tagBatchesShown is the number of recent batches listed on the admin page
+	tagBatchesShown = 10
+)
+
+// tagNamePattern matches the names tags can be renamed to, like "nodejs" or "c#"
+var tagNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#.-]{0,29}$`)
+
+// TagRule renames a detected tag, merges it into another tag or blacklists it
+type TagRule struct {
+	Tag    string
+	Action string
+	// Target is the tag a renamed or merged tag becomes
+	Target    string
+	CreatedAt int64 `db:"created_at"`
+}
+
+// tagRuleCache holds the tag rules, loaded on first use and after they change
+type tagRuleCache struct {
+	mu     sync.Mutex
+	rules  map[string]TagRule
+	loaded bool
+}
+
+var tagRules = &tagRuleCache{}
+
+// get will return the rules by tag. Rules failing to load are retried on the
+// next call, tags are left as detected meanwhile.
+func (c *tagRuleCache) get() map[string]TagRule {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.loaded {
+		rules, err := SelectTagRules()
+		if err != nil {
+			log.Println("failed to load tag rules.", err)
+			return map[string]TagRule{}
+		}
+		c.rules = map[string]TagRule{}
+		for _, r := range rules {
+			c.rules[r.Tag] = r
+		}
+		c.loaded = true
+	}
+	return c.rules
+}
+
+// invalidate will reload the rules on their next use
+func (c *tagRuleCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded = false
+}
+
+// applyTagRules will rename, merge and drop tags by the rules, without
+// duplicates. Renamed tags are followed to the last rule applying to them.
+func applyTagRules(tags []string) []string {
+	rules := tagRules.get()
+	var applied []string
+	for _, t := range tags {
+		for i := 0; i <= len(rules); i++ {
+			r, ok := rules[t]
+			if !ok {
+				break
+			}
+			if r.Action == tagBlacklist {
+				t = ""
+				break
+			}
+			t = r.Target
+		}
+		if t != "" && getIndex(applied, t) == -1 {
+			applied = append(applied, t)
+		}
+	}
+	return applied
+}
+
+// currentTags will return the tags jobs can have once the rules are applied
+func currentTags() []string {
+	return applyTagRules(jobTags)
+}
+
+// SelectTagRules will select every tag rule, newest first
+func SelectTagRules() ([]TagRule, error) {
+	var rules []TagRule
+	if err := db.Select(&rules, `SELECT tag, action, target, created_at FROM tag_rule ORDER BY created_at DESC, tag`); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// SaveTagRule will validate and save a rule for a current tag. A tag renamed
+// back to a name it had before loses the rule of that name.
+func SaveTagRule(r TagRule) error {
+	if !isJobTag(r.Tag) {
+		return fmt.Errorf("unknown tag %q", r.Tag)
+	}
+	switch r.Action {
+	case tagRename:
+		if !tagNamePattern.MatchString(r.Target) {
+			return fmt.Errorf("invalid tag name %q, use up to 30 lowercase letters, digits and +#.-", r.Target)
+		}
+		if isJobTag(r.Target) {
+			return fmt.Errorf("%q is already a tag, merge into it instead", r.Target)
+		}
+	case tagMerge:
+		if !isJobTag(r.Target) || r.Target == r.Tag {
+			return fmt.Errorf("merge %q into another current tag", r.Tag)
+		}
+	case tagBlacklist:
+		r.Target = ""
+	default:
+		return fmt.Errorf("unknown tag action %q", r.Action)
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if r.Target != "" {
+		if _, err := tx.Exec(`DELETE FROM tag_rule WHERE tag=?`, r.Target); err != nil {
+			return err
+		}
+	}
+	sql := `INSERT INTO tag_rule (tag, action, target, created_at) VALUES (?, ?, ?, ?)
+            ON CONFLICT (tag) DO UPDATE SET action=excluded.action, target=excluded.target, created_at=excluded.created_at`
+	if _, err := tx.Exec(sql, r.Tag, r.Action, r.Target, time.Now().Unix()); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	tagRules.invalidate()
+	return nil
+}
+
+// SelectRuledTagJobs will select the ids of the jobs still tagged with a tag having a rule
+func SelectRuledTagJobs() ([]uint64, error) {
+	var ids []uint64
+	sql := `SELECT DISTINCT hn_id FROM job_tag WHERE tag IN (SELECT tag FROM tag_rule) ORDER BY hn_id`
+	if err := db.Select(&ids, sql); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// RetagJobs will apply the tag rules to the stored tags of jobs, in one transaction
+func RetagJobs(ids []uint64) error {
+	now := time.Now().Unix()
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, id := range ids {
+		var tags string
+		if err := tx.Get(&tags, `SELECT tags FROM job_attribute WHERE hn_id=?`, id); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		retagged := strings.Join(applyTagRules(strings.Split(tags, ",")), ",")
+		if _, err := tx.Exec(`UPDATE job_attribute SET tags=?, updated_at=? WHERE hn_id=?`, retagged, now, id); err != nil {
+			return err
+		}
+		if err := saveJobTags(tx, id, retagged); err != nil {
+			return err
+		}
+		if err := recordProvenance(tx, id, sourceTagRule, 0, "tags"); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SelectTagCounts will count the jobs of every story by tag
+func SelectTagCounts() ([]TagCount, error) {
+	var tags []TagCount
+	if err := db.Select(&tags, `SELECT tag, COUNT(*) AS count FROM job_tag GROUP BY tag ORDER BY count DESC, tag`); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// tagBatch is a background run applying the tag rules to the stored jobs
+type tagBatch struct {
+	Id          int
+	Description string
+	Total       int
+	Done        int
+	Started     time.Time
+	Finished    time.Time
+	Error       string
+}
+
+// Running will return true while the batch has jobs left to retag
+func (b tagBatch) Running() bool {
+	return b.Finished.IsZero()
+}
+
+// tagBatches tracks the recent batches. Batches run one at a time, in the
+// order they were started.
+var tagBatches = struct {
+	mu      sync.Mutex
+	run     sync.Mutex
+	batches []*tagBatch
+}{}
+
+// recentTagBatches will return copies of the recent batches, newest first
+func recentTagBatches() []tagBatch {
+	tagBatches.mu.Lock()
+	defer tagBatches.mu.Unlock()
+	var recent []tagBatch
+	for i := len(tagBatches.batches) - 1; i >= 0 && len(recent) < tagBatchesShown; i-- {
+		recent = append(recent, *tagBatches.batches[i])
+	}
+	return recent
+}
+
+// updateTagBatch will change a batch while holding the lock of the batches
+func updateTagBatch(b *tagBatch, fn func(b *tagBatch)) {
+	tagBatches.mu.Lock()
+	defer tagBatches.mu.Unlock()
+	fn(b)
+}
+
+// startTagBatch will apply the tag rules to the stored jobs in the background.
+// Every batch applies all rules, so one interrupted by a restart is completed
+// by the next one.
+func startTagBatch(description string) {
+	tagBatches.mu.Lock()
+	b := &tagBatch{Id: len(tagBatches.batches) + 1, Description: description, Started: time.Now()}
+	tagBatches.batches = append(tagBatches.batches, b)
+	tagBatches.mu.Unlock()
+
+	go func() {
+		tagBatches.run.Lock()
+		defer tagBatches.run.Unlock()
+		err := runTagBatch(b)
+		updateTagBatch(b, func(b *tagBatch) {
+			b.Finished = time.Now()
+			if err != nil {
+				b.Error = err.Error()
+			}
+		})
+		if err != nil {
+			log.Println("failed to apply tag rules.", err)
+		}
+		bus.Publish(event{Topic: eventTagsChanged})
+	}()
+}
+
+// runTagBatch will retag the jobs with ruled tags, tagBatchSize jobs at a time
+func runTagBatch(b *tagBatch) error {
+	ids, err := SelectRuledTagJobs()
+	if err != nil {
+		return err
+	}
+	updateTagBatch(b, func(b *tagBatch) { b.Total = len(ids) })
+	for start := 0; start < len(ids); start += tagBatchSize {
+		end := start + tagBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := RetagJobs(ids[start:end]); err != nil {
+			return err
+		}
+		updateTagBatch(b, func(b *tagBatch) { b.Done = end })
+	}
+	return nil
+}
+
+// resumeTagRules will start a batch when stored jobs still have ruled tags,
+// like after a restart interrupted a batch
+func resumeTagRules() error {
+	ids, err := SelectRuledTagJobs()
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		startTagBatch(fmt.Sprintf("resume retagging %d jobs", len(ids)))
+	}
+	return nil
+}
+
+// describeTagRule will describe a rule for the audit log and the batches
+func describeTagRule(r TagRule) string {
+	switch r.Action {
+	case tagRename:
+		return fmt.Sprintf("rename %s to %s", r.Tag, r.Target)
+	case tagMerge:
+		return fmt.Sprintf("merge %s into %s", r.Tag, r.Target)
+	}
+	return "blacklist " + r.Tag
+}
+
+// tagRulesHandler will save a tag rule and start applying it to the stored
+// jobs on POST, and show the rules, the tags and the batch progress on GET
+func tagRulesHandler(w http.ResponseWriter, r *http.Request) {
+	var message string
+	if r.Method == http.MethodPost {
+		if !requireSameOrigin(w, r) {
+			return
+		}
+
+		rule := TagRule{
+			Tag:    strings.ToLower(strings.TrimSpace(r.PostFormValue("tag"))),
+			Action: r.PostFormValue("action"),
+			Target: strings.ToLower(strings.TrimSpace(r.PostFormValue("target"))),
+		}
+		if err := SaveTagRule(rule); err != nil {
+			message = "Failed to save the tag rule: " + err.Error()
+		} else {
+			description := describeTagRule(rule)
+			if err := RecordAudit(cfg.AdminUser, "tag."+rule.Action, rule.Tag, description); err != nil {
+				log.Println("failed to record audit entry.", err)
+			}
+			startTagBatch(description)
+			http.Redirect(w, r, "/admin/tags?saved="+url.QueryEscape(description), http.StatusSeeOther)
+			return
+		}
+	} else if saved := r.URL.Query().Get("saved"); saved != "" {
+		message = "Saved: " + saved + ". Stored jobs are retagged in the background."
+	}
+
+	rules, err := SelectTagRules()
+	if err != nil {
+		log.Println("failed to select tag rules.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	counts, err := SelectTagCounts()
+	if err != nil {
+		log.Println("failed to count tags.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	tags := currentTags()
+	sort.Strings(tags)
+	batches := recentTagBatches()
+	running := false
+	for _, b := range batches {
+		running = running || b.Running()
+	}
+
+	data := struct {
+		Message string
+		Tags    []string
+		Counts  []TagCount
+		Rules   []TagRule
+		Batches []tagBatch
+		Running bool
+	}{
+		Message: message,
+		Tags:    tags,
+		Counts:  counts,
+		Rules:   rules,
+		Batches: batches,
+		Running: running,
+	}
+	renderTemplate(w, "admin_tags.html", data)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// jobTags are the technology tags detected in job posts
+var jobTags = []string{
+	"go", "python", "rust", "java", "kotlin", "scala", "swift", "typescript", "javascript", "ruby",
+	"rails", "elixir", "php", "c++", "c#", "react", "vue", "angular", "node", "django",
+	"postgres", "mysql", "aws", "gcp", "azure", "kubernetes", "docker", "terraform", "ml", "llm",
+}
+
+// tagPatterns match the usual spellings of each tag. Ambiguous words, like
+// "go" or "rust", only match in their capitalized or qualified forms.
+var tagPatterns = map[string]*regexp.Regexp{
+	"go":         regexp.MustCompile(`\b(Go|Golang|golang)\b`),
+	"python":     regexp.MustCompile(`(?i)\bpython\b`),
+	"rust":       regexp.MustCompile(`\b(Rust|rustlang)\b`),
+	"java":       regexp.MustCompile(`(?i)\bjava\b`),
+	"kotlin":     regexp.MustCompile(`(?i)\bkotlin\b`),
+	"scala":      regexp.MustCompile(`(?i)\bscala\b`),
+	"swift":      regexp.MustCompile(`\b(Swift|SwiftUI)\b`),
+	"typescript": regexp.MustCompile(`(?i)\btypescript\b`),
+	"javascript": regexp.MustCompile(`(?i)\bjavascript\b`),
+	"ruby":       regexp.MustCompile(`\bRuby\b`),
+	"rails":      regexp.MustCompile(`(?i)\b(rails|ror)\b`),
+	"elixir":     regexp.MustCompile(`(?i)\belixir\b`),
+	"php":        regexp.MustCompile(`(?i)\bphp\b`),
+	"c++":        regexp.MustCompile(`(?i)(^|[^a-z0-9])c\+\+`),
+	"c#":         regexp.MustCompile(`(?i)(^|[^a-z0-9])(c#|\.net)\b`),
+	"react":      regexp.MustCompile(`(?i)\breact(\.js| native)?\b`),
+	"vue":        regexp.MustCompile(`(?i)\bvue(\.js)?\b`),
+	"angular":    regexp.MustCompile(`\bAngular\b`),
+	"node":       regexp.MustCompile(`(?i)\bnode(\.js|js)?\b`),
+	"django":     regexp.MustCompile(`(?i)\bdjango\b`),
+	"postgres":   regexp.MustCompile(`(?i)\b(postgres|postgresql)\b`),
+	"mysql":      regexp.MustCompile(`(?i)\bmysql\b`),
+	"aws":        regexp.MustCompile(`\bAWS\b`),
+	"gcp":        regexp.MustCompile(`(?i)\b(gcp|google cloud)\b`),
+	"azure":      regexp.MustCompile(`\bAzure\b`),
+	"kubernetes": regexp.MustCompile(`(?i)\b(kubernetes|k8s)\b`),
+	"docker":     regexp.MustCompile(`(?i)\bdocker\b`),
+	"terraform":  regexp.MustCompile(`(?i)\bterraform\b`),
+	"ml":         regexp.MustCompile(`(?i)\b(machine learning|ml|deep learning|pytorch|tensorflow)\b`),
+	"llm":        regexp.MustCompile(`(?i)\b(llms?|large language models?|genai|generative ai)\b`),
+}
+
+// TagCount is a tag along with the number of jobs tagged with it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// isJobTag will return true when t is a known tag, once the tag rules are applied
+func isJobTag(t string) bool {
+	return getIndex(currentTags(), t) != -1
+}
+
+// tagsByPrefix will return the known tags starting with prefix along with
+// their counts, the most frequent first
+func tagsByPrefix(prefix string, counts map[string]int) []TagCount {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	var matches []TagCount
+	for _, t := range currentTags() {
+		if strings.HasPrefix(t, prefix) {
+			matches = append(matches, TagCount{Tag: t, Count: counts[t]})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Count != matches[j].Count {
+			return matches[i].Count > matches[j].Count
+		}
+		return matches[i].Tag < matches[j].Tag
+	})
+	return matches
+}
+
+// jobTechTags will return the comma separated technology tags found in a job text
+func jobTechTags(text string) string {
+	plain := jobPlainText(text)
+	var found []string
+	for _, t := range jobTags {
+		if tagPatterns[t].MatchString(plain) {
+			found = append(found, t)
+		}
+	}
+	return strings.Join(applyTagRules(found), ",")
+}
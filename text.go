@@ -0,0 +1,241 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// HN job texts are html fragments made of paragraphs separated by <p> with
+// escaped entities, links and a few formatting tags. The functions below
+// turn them into safe html for the web pages and plain text for everything else.
+
+// allowedTags are the inline tags kept when sanitizing job texts
+var allowedTags = map[string]bool{
+	"a": true, "b": true, "code": true, "em": true, "i": true, "pre": true, "strong": true,
+}
+
+var tagTokenPattern = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9]*)([^<>]*)>`)
+var hrefPattern = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+
+type textTokenKind int
+
+const (
+	textToken textTokenKind = iota
+	startTagToken
+	endTagToken
+	paragraphToken
+)
+
+type textTokenItem struct {
+	kind textTokenKind
+	// data is the decoded text for text tokens and the tag name for tags
+	data string
+	href string
+}
+
+// tokenizeJobText will split a job text into decoded text, tag and paragraph
+// tokens. Unknown tags are dropped and stray brackets are kept as text.
+func tokenizeJobText(raw string) []textTokenItem {
+	var tokens []textTokenItem
+	var text strings.Builder
+	inPre := false
+	flushText := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, textTokenItem{kind: textToken, data: html.UnescapeString(text.String())})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(raw); {
+		c := raw[i]
+		if c == '\n' && !inPre {
+			flushText()
+			tokens = append(tokens, textTokenItem{kind: paragraphToken})
+			i++
+			continue
+		}
+		if c != '<' {
+			text.WriteByte(c)
+			i++
+			continue
+		}
+
+		m := tagTokenPattern.FindStringSubmatch(raw[i:])
+		if m == nil {
+			text.WriteByte(c)
+			i++
+			continue
+		}
+		i += len(m[0])
+		name := strings.ToLower(m[2])
+		closing := m[1] == "/"
+		if name == "p" || name == "br" {
+			if !closing {
+				flushText()
+				tokens = append(tokens, textTokenItem{kind: paragraphToken})
+			}
+			continue
+		}
+		if !allowedTags[name] {
+			continue
+		}
+
+		flushText()
+		if name == "pre" {
+			inPre = !closing
+		}
+		if closing {
+			tokens = append(tokens, textTokenItem{kind: endTagToken, data: name})
+			continue
+		}
+		t := textTokenItem{kind: startTagToken, data: name}
+		if name == "a" {
+			if hm := hrefPattern.FindStringSubmatch(m[3]); hm != nil {
+				t.href = safeHref(html.UnescapeString(hm[1] + hm[2] + hm[3]))
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	flushText()
+
+	return tokens
+}
+
+// safeHref will return u when it is an absolute http(s) url
+func safeHref(u string) string {
+	pu, err := url.Parse(strings.TrimSpace(u))
+	if err != nil || (pu.Scheme != "http" && pu.Scheme != "https") || pu.Host == "" {
+		return ""
+	}
+	return pu.String()
+}
+
+// sanitizeJobHTML will decode the entities of a job text and rebuild its
+// html from the allowed tags only, wrapping each paragraph in a styled <p>.
+func sanitizeJobHTML(raw string) template.HTML {
+	body, _ := truncateJobHTML(raw, 0)
+	return body
+}
+
+// truncateJobHTML will sanitize a job text like sanitizeJobHTML, stopping at
+// the first paragraph boundary once limit characters of text were written.
+// It also returns whether any text was left out. A limit of 0 keeps everything.
+func truncateJobHTML(raw string, limit int) (template.HTML, bool) {
+	var b strings.Builder
+	var written int
+	var open []string
+	var paragraph strings.Builder
+
+	closeParagraph := func() {
+		for i := len(open) - 1; i >= 0; i-- {
+			paragraph.WriteString("</" + open[i] + ">")
+		}
+		content := paragraph.String()
+		if strings.TrimSpace(content) != "" {
+			b.WriteString(`<p class="my-2">` + content + `</p>`)
+		}
+		paragraph.Reset()
+	}
+	reopen := func() {
+		for _, t := range open {
+			paragraph.WriteString("<" + t + ">")
+		}
+	}
+
+	tokens := tokenizeJobText(raw)
+	for i, t := range tokens {
+		switch t.kind {
+		case textToken:
+			paragraph.WriteString(html.EscapeString(t.data))
+			written += utf8.RuneCountInString(t.data)
+		case paragraphToken:
+			closeParagraph()
+			if limit > 0 && written >= limit && hasJobText(tokens[i+1:]) {
+				return template.HTML(b.String()), true
+			}
+			// formatting spanning paragraphs, like long <pre> blocks, continues in the next one
+			reopen()
+		case startTagToken:
+			if t.data == "a" {
+				if t.href == "" {
+					open = append(open, "span")
+					paragraph.WriteString("<span>")
+					continue
+				}
+				open = append(open, "a")
+				paragraph.WriteString(`<a href="` + html.EscapeString(t.href) + `" rel="nofollow noopener" target="_blank" class="underline">`)
+				continue
+			}
+			open = append(open, t.data)
+			paragraph.WriteString("<" + t.data + ">")
+		case endTagToken:
+			name := t.data
+			idx := -1
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == name || (name == "a" && open[i] == "span") {
+					idx = i
+					break
+				}
+			}
+			if idx == -1 {
+				continue
+			}
+			for i := len(open) - 1; i >= idx; i-- {
+				paragraph.WriteString("</" + open[i] + ">")
+			}
+			open = open[:idx]
+		}
+	}
+	closeParagraph()
+
+	return template.HTML(b.String()), false
+}
+
+// hasJobText will return true when tokens contain any non blank text
+func hasJobText(tokens []textTokenItem) bool {
+	for _, t := range tokens {
+		if t.kind == textToken && strings.TrimSpace(t.data) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// jobPlainText will return a job text without html, with entities decoded
+// and paragraphs separated by blank lines.
+func jobPlainText(raw string) string {
+	var paragraphs []string
+	var p strings.Builder
+	inLink := false
+	for _, t := range tokenizeJobText(raw) {
+		switch t.kind {
+		case textToken:
+			if !inLink {
+				p.WriteString(t.data)
+			}
+		case paragraphToken:
+			if s := strings.TrimSpace(p.String()); s != "" {
+				paragraphs = append(paragraphs, s)
+			}
+			p.Reset()
+		case startTagToken:
+			if t.data == "a" && t.href != "" {
+				// link texts are truncated by HN, so keep the full url instead
+				p.WriteString(t.href)
+				inLink = true
+			}
+		case endTagToken:
+			if t.data == "a" {
+				inLink = false
+			}
+		}
+	}
+	if s := strings.TrimSpace(p.String()); s != "" {
+		paragraphs = append(paragraphs, s)
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
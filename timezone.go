@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tzRange is a span of UTC offsets in minutes
+type tzRange struct {
+	Min, Max int
+}
+
+var (
+	// utcOffsetPattern matches UTC offsets like "UTC", "GMT+2", "UTC-03:00" or "UTC+5:30"
+	utcOffsetPattern = regexp.MustCompile(`(?i)\b(?:utc|gmt)(?:\s?([+−-])\s?(\d{1,2})(?::?([0-5]\d))?)?`)
+	// utcSpreadPattern matches offsets spread around UTC, like "UTC±3" or "GMT +/- 2"
+	utcSpreadPattern = regexp.MustCompile(`(?i)\b(?:utc|gmt)\s?(?:±|\+/-|\+-|-/\+)\s?(\d{1,2})\b`)
+	// tzAbbrPattern matches timezone abbreviations, which are only trusted in uppercase
+	tzAbbrPattern = regexp.MustCompile(`\b(EST|EDT|CST|CDT|MST|MDT|PST|PDT|AKST|HST|WET|CET|CEST|EET|EEST|BST|SGT|JST|AEST|AEDT)\b`)
+	// tzShortPattern matches short US zones after a time, like "9am-5pm ET"
+	tzShortPattern = regexp.MustCompile(`\d\s?(?:[aApP][mM])?\s?\b(ET|CT|MT|PT)\b`)
+	// tzNamePattern matches US zone names like "Pacific time"
+	tzNamePattern = regexp.MustCompile(`(?i)\b(eastern|central|mountain|pacific)\s+(?:standard\s+)?time\b`)
+	// tzRegionPattern matches working hours of a region, like "US hours" or "overlap with EU"
+	tzRegionPattern = regexp.MustCompile(`(?i)\b(?:(us|u\.s\.|usa|north america|americas|east coast|west coast|eu|europe|european|uk|emea|apac|latam|asia)\s+(?:business\s+|working\s+|office\s+)?(?:hours|time\s?zones?|tz)\b|overlap(?:ping)?\s+with\s+(?:the\s+)?(us|u\.s\.|usa|north america|americas|east coast|west coast|eu|europe|uk|emea|apac|latam|asia)\b)`)
+)
+
+// tzAbbrOffsets are the UTC offsets in minutes of timezone abbreviations and US zone names
+var tzAbbrOffsets = map[string]int{
+	"EST": -300, "EDT": -240, "CST": -360, "CDT": -300, "MST": -420, "MDT": -360,
+	"PST": -480, "PDT": -420, "AKST": -540, "HST": -600,
+	"WET": 0, "CET": 60, "CEST": 120, "EET": 120, "EEST": 180, "BST": 60,
+	"SGT": 480, "JST": 540, "AEST": 600, "AEDT": 660,
+	"ET": -300, "CT": -360, "MT": -420, "PT": -480,
+	"eastern": -300, "central": -360, "mountain": -420, "pacific": -480,
+}
+
+// tzRegions are the ranges of UTC offsets in minutes of the working hours of regions
+var tzRegions = map[string]tzRange{
+	"us": {-480, -300}, "u.s.": {-480, -300}, "usa": {-480, -300}, "north america": {-480, -300},
+	"americas": {-480, -180}, "latam": {-360, -180},
+	"east coast": {-300, -300}, "west coast": {-480, -480},
+	"eu": {0, 120}, "europe": {0, 120}, "european": {0, 120}, "uk": {0, 0}, "emea": {0, 180},
+	"apac": {330, 600}, "asia": {330, 540},
+}
+
+// matchTimezone will return the range of UTC offsets a job requires overlap
+// with, from every offset, timezone and region hours mentioned in its text,
+// along with the matched terms and a confidence, lowest for region hours.
+// Returns false when the job does not mention any.
+func matchTimezone(text string) (tzRange, string, float64, bool) {
+	plain := jobPlainText(text)
+	var r tzRange
+	var terms []string
+	found := false
+	confidence := 0.9
+	add := func(lo, hi int, term string, c float64) {
+		if !found || lo < r.Min {
+			r.Min = lo
+		}
+		if !found || hi > r.Max {
+			r.Max = hi
+		}
+		found = true
+		if c < confidence {
+			confidence = c
+		}
+		if term = strconv.Quote(strings.TrimSpace(term)); getIndex(terms, term) == -1 {
+			terms = append(terms, term)
+		}
+	}
+
+	for _, m := range utcSpreadPattern.FindAllStringSubmatch(plain, -1) {
+		h, _ := strconv.Atoi(m[1])
+		if h <= 14 {
+			add(-h*60, h*60, m[0], 0.9)
+		}
+	}
+	for _, m := range utcOffsetPattern.FindAllStringSubmatch(plain, -1) {
+		offset, ok := parseUtcOffset(m[1] + m[2] + ":" + m[3])
+		if m[2] == "" {
+			offset, ok = 0, true
+		}
+		if ok {
+			add(offset, offset, m[0], 0.9)
+		}
+	}
+	for _, p := range []*regexp.Regexp{tzAbbrPattern, tzShortPattern, tzNamePattern} {
+		for _, m := range p.FindAllStringSubmatch(plain, -1) {
+			offset := tzAbbrOffsets[m[1]]
+			if p == tzNamePattern {
+				offset = tzAbbrOffsets[strings.ToLower(m[1])]
+			}
+			add(offset, offset, m[0], 0.8)
+		}
+	}
+	for _, m := range tzRegionPattern.FindAllStringSubmatch(plain, -1) {
+		region := strings.ToLower(m[1] + m[2])
+		if rr, ok := tzRegions[region]; ok {
+			add(rr.Min, rr.Max, m[0], 0.6)
+		}
+	}
+	return r, strings.Join(terms, ", "), confidence, found
+}
+
+// parseUtcOffset will parse an offset like "-5", "+5:30", "5.5" or "UTC+1"
+// into minutes. A leading space stands for the "+" of unescaped query params.
+func parseUtcOffset(v string) (int, bool) {
+	v = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(v)), "UTC")
+	v = strings.TrimSuffix(v, ":")
+	sign := 1
+	switch {
+	case strings.HasPrefix(v, "-"), strings.HasPrefix(v, "−"):
+		sign = -1
+		v = strings.TrimLeft(v, "-−")
+	case strings.HasPrefix(v, "+"):
+		v = v[1:]
+	}
+
+	hours, minutes, _ := strings.Cut(v, ":")
+	if h, frac, ok := strings.Cut(hours, "."); ok && minutes == "" {
+		hours = h
+		minutes = strconv.Itoa(int(float64(atoiOr(frac, 0)) * 6))
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h > 14 {
+		return 0, false
+	}
+	m := atoiOr(minutes, 0)
+	if m < 0 || m >= 60 {
+		return 0, false
+	}
+	return sign * (h*60 + m), true
+}
+
+// atoiOr will parse v as an int or return d
+func atoiOr(v string, d int) int {
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return d
+	}
+	return i
+}
+
+// formatUtcOffset will format an offset in minutes like "UTC-5" or "UTC+5:30"
+func formatUtcOffset(offset int) string {
+	if offset == 0 {
+		return "UTC"
+	}
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	if offset%60 == 0 {
+		return fmt.Sprintf("UTC%s%d", sign, offset/60)
+	}
+	return fmt.Sprintf("UTC%s%d:%02d", sign, offset/60, offset%60)
+}
+
+// String will format the range like "UTC±2" or "UTC-8 to UTC-5"
+func (r tzRange) String() string {
+	switch {
+	case r.Min == r.Max:
+		return formatUtcOffset(r.Min)
+	case r.Min == -r.Max && r.Max%60 == 0:
+		return fmt.Sprintf("UTC±%d", r.Max/60)
+	}
+	return formatUtcOffset(r.Min) + " to " + formatUtcOffset(r.Max)
+}
+
+// timezoneFilters are the UTC offsets in minutes offered as timezone filters
+var timezoneFilters = func() []int {
+	var offsets []int
+	for h := -12; h <= 14; h++ {
+		offsets = append(offsets, h*60)
+		if h == 5 {
+			offsets = append(offsets, 330)
+		}
+	}
+	return offsets
+}()
+
+// timezoneParam will format an offset in minutes as a tz param value like
+// "-5" or "5:30", without a "+" that would need escaping
+func timezoneParam(offset int) string {
+	if offset == 0 {
+		return "0"
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(formatUtcOffset(offset), "UTC"), "+")
+}
+
+// timezoneOption is a timezone filter offered on the reader
+type timezoneOption struct {
+	Param string
+	Label string
+}
+
+// timezoneOptions are the timezoneFilters as reader options
+var timezoneOptions = func() []timezoneOption {
+	options := make([]timezoneOption, len(timezoneFilters))
+	for i, offset := range timezoneFilters {
+		options[i] = timezoneOption{Param: timezoneParam(offset), Label: formatUtcOffset(offset)}
+	}
+	return options
+}()
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trendsTopTags is the number of most common tags listed for each month
+const trendsTopTags = 5
+
+func init() {
+	// the aggregates are refreshed after the data they summarize changes, so
+	// the trends page never computes them over every month
+	bus.Subscribe(eventSyncCompleted, func(e event) {
+		if err := RefreshStoryAggregate(e.StoryId); err != nil {
+			log.Println("failed to refresh story aggregate.", err)
+		}
+	})
+	bus.Subscribe(eventTagsChanged, func(event) {
+		if err := refreshStoryAggregates(true); err != nil {
+			log.Println("failed to refresh story aggregates.", err)
+		}
+	})
+}
+
+// StoryAggregate are the precomputed counts and salary percentiles of the
+// live, non duplicate jobs of a story
+type StoryAggregate struct {
+	StoryId uint64 `db:"story_id"`
+	Title   string
+	Time    uint64
+	Jobs    int
+	Remote  int
+	// Salaries is the number of jobs with a salary, which the percentiles are of
+	Salaries int
+	// SalaryP25 to SalaryP90 are percentiles of the yearly USD salaries, taking
+	// the middle of the range of each job
+	SalaryP25   uint64     `db:"salary_p25"`
+	SalaryP50   uint64     `db:"salary_p50"`
+	SalaryP75   uint64     `db:"salary_p75"`
+	SalaryP90   uint64     `db:"salary_p90"`
+	RefreshedAt int64      `db:"refreshed_at"`
+	TopTags     []TagCount `db:"-"`
+	// TagJobs are the jobs with the tag the trends are filtered by
+	TagJobs int `db:"-"`
+}
+
+// RemoteShare will return the percent of the jobs that are fully remote
+func (a StoryAggregate) RemoteShare() int {
+	return share(a.Remote, a.Jobs)
+}
+
+// TagShare will return the percent of the jobs with the filtered tag
+func (a StoryAggregate) TagShare() int {
+	return share(a.TagJobs, a.Jobs)
+}
+
+// SalaryK will return the p percentile of the salaries in thousands of USD,
+// like 150k, for p of 25, 50, 75 or 90
+func (a StoryAggregate) SalaryK(p int) string {
+	var usd uint64
+	switch p {
+	case 25:
+		usd = a.SalaryP25
+	case 50:
+		usd = a.SalaryP50
+	case 75:
+		usd = a.SalaryP75
+	case 90:
+		usd = a.SalaryP90
+	}
+	if usd == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dk", usd/1000)
+}
+
+// share will return n as a rounded percent of total
+func share(n, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return (200*n + total) / (2 * total)
+}
+
+// salaryPercentile will return the p percentile of sorted salaries, by nearest rank
+func salaryPercentile(sorted []uint64, p int) uint64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// RefreshStoryAggregate will compute the aggregates of the jobs of a story
+// again and replace the stored ones
+func RefreshStoryAggregate(hsId uint64) error {
+	a := StoryAggregate{StoryId: hsId, RefreshedAt: time.Now().Unix()}
+	sql := `SELECT COUNT(*) AS jobs,
+            COALESCE(SUM(instr(',' || remote || ',', ',' || ? || ',') > 0), 0) AS remote
+            FROM hiring_job_view
+            WHERE hiring_story_id=? and status=? and duplicate_of=0`
+	if err := db.Get(&a, sql, remoteFull, hsId, jobStatusOk); err != nil {
+		return err
+	}
+	var salaries []uint64
+	sql = `SELECT (CASE WHEN salary_min_usd > 0 THEN salary_min_usd ELSE salary_max_usd END + salary_max_usd) / 2
+            FROM hiring_job_view
+            WHERE hiring_story_id=? and status=? and duplicate_of=0 and salary_max_usd > 0`
+	if err := db.Select(&salaries, sql, hsId, jobStatusOk); err != nil {
+		return err
+	}
+	sort.Slice(salaries, func(i, j int) bool { return salaries[i] < salaries[j] })
+	a.Salaries = len(salaries)
+	a.SalaryP25 = salaryPercentile(salaries, 25)
+	a.SalaryP50 = salaryPercentile(salaries, 50)
+	a.SalaryP75 = salaryPercentile(salaries, 75)
+	a.SalaryP90 = salaryPercentile(salaries, 90)
+	tags, err := SelectStoryTagCounts(hsId)
+	if err != nil {
+		return err
+	}
+	terms, err := SelectStoryTermCounts(hsId)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	sql = `INSERT OR REPLACE INTO story_aggregate
+            (story_id, jobs, remote, salaries, salary_p25, salary_p50, salary_p75, salary_p90, refreshed_at)
+            VALUES (:story_id, :jobs, :remote, :salaries, :salary_p25, :salary_p50, :salary_p75, :salary_p90, :refreshed_at)`
+	if _, err := tx.NamedExec(sql, a); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM story_tag_aggregate WHERE story_id=?`, hsId); err != nil {
+		return err
+	}
+	for tag, jobs := range tags {
+		if _, err := tx.Exec(`INSERT INTO story_tag_aggregate (story_id, tag, jobs) VALUES (?, ?, ?)`, hsId, tag, jobs); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM story_term_aggregate WHERE story_id=?`, hsId); err != nil {
+		return err
+	}
+	for term, jobs := range terms {
+		if _, err := tx.Exec(`INSERT INTO story_term_aggregate (story_id, term, jobs) VALUES (?, ?, ?)`, hsId, term, jobs); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// refreshStoryAggregates will refresh the aggregates of every hiring story,
+// or with all unset, of the stories not aggregated yet
+func refreshStoryAggregates(all bool) error {
+	var ids []uint64
+	sql := `SELECT hn_id FROM hiring_story WHERE kind=?`
+	if !all {
+		sql += ` and hn_id NOT IN (SELECT story_id FROM story_aggregate)`
+	}
+	if err := db.Select(&ids, sql, storyKindHiring); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := RefreshStoryAggregate(id); err != nil {
+			return fmt.Errorf("story %d: %w", id, err)
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("refreshed the aggregates of %d stories", len(ids))
+	}
+	return nil
+}
+
+// SelectStoryAggregates will select the aggregates of the hiring stories,
+// newest first, with their most common tags and the jobs with tag when set
+func SelectStoryAggregates(tag string) ([]StoryAggregate, error) {
+	var aggregates []StoryAggregate
+	sql := `SELECT sa.story_id, hs.title, hs.time, sa.jobs, sa.remote, sa.salaries,
+            sa.salary_p25, sa.salary_p50, sa.salary_p75, sa.salary_p90, sa.refreshed_at
+            FROM story_aggregate sa
+            JOIN hiring_story hs ON hs.hn_id = sa.story_id
+            WHERE hs.kind=?
+            ORDER BY hs.time DESC`
+	if err := db.Select(&aggregates, sql, storyKindHiring); err != nil {
+		return nil, err
+	}
+
+	var tags []struct {
+		StoryId uint64 `db:"story_id"`
+		Tag     string
+		Jobs    int
+	}
+	sql = `SELECT story_id, tag, jobs FROM story_tag_aggregate ORDER BY story_id, jobs DESC, tag`
+	if err := db.Select(&tags, sql); err != nil {
+		return nil, err
+	}
+	byStory := map[uint64]int{}
+	for i, a := range aggregates {
+		byStory[a.StoryId] = i
+	}
+	for _, t := range tags {
+		i, ok := byStory[t.StoryId]
+		if !ok {
+			continue
+		}
+		if len(aggregates[i].TopTags) < trendsTopTags {
+			aggregates[i].TopTags = append(aggregates[i].TopTags, TagCount{Tag: t.Tag, Count: t.Jobs})
+		}
+		if t.Tag == tag {
+			aggregates[i].TagJobs = t.Jobs
+		}
+	}
+	return aggregates, nil
+}
+
+// trendsHandler will list the jobs, remote share, salary percentiles and most
+// common tags of every month, and the share of the jobs with the tag param.
+// The word cloud is of the month of the story param, the newest by default.
+func trendsHandler(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(r.URL.Query().Get("tag"))
+	if !isJobTag(tag) {
+		tag = ""
+	}
+	aggregates, err := SelectStoryAggregates(tag)
+	if err != nil {
+		log.Println("failed to select story aggregates.", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var cloud *StoryAggregate
+	storyId := paramValue(r.URL.Query().Get("story"), 0)
+	for i, a := range aggregates {
+		if a.StoryId == storyId || (storyId == 0 && i == 0) {
+			cloud = &aggregates[i]
+			break
+		}
+	}
+	var wordCloud template.HTML
+	if cloud != nil {
+		terms, err := SelectWordCloudTerms(*cloud)
+		if err != nil {
+			log.Println("failed to select word cloud terms.", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		wordCloud = renderWordCloud(cloud.StoryId, terms)
+	}
+
+	data := struct {
+		Tag       string
+		Months    []StoryAggregate
+		Cloud     *StoryAggregate
+		WordCloud template.HTML
+		Canonical string
+	}{
+		Tag:       tag,
+		Months:    aggregates,
+		Cloud:     cloud,
+		WordCloud: wordCloud,
+		Canonical: canonicalUrl("/trends"),
+	}
+	renderTemplate(w, "trends.html", data)
+}
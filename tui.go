@@ -0,0 +1,687 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	tuiListView = iota
+	tuiJobView
+)
+
+const (
+	tuiSearchPrompt = "Search"
+	tuiFilterPrompt = "Filters"
+)
+
+// terminal escape sequences the ui is drawn with
+const (
+	ansiClear   = "\x1b[H\x1b[2J"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiReverse = "\x1b[7m"
+	ansiReset   = "\x1b[0m"
+)
+
+// the job_mark columns of the read and favorite marks
+const (
+	jobMarkRead     = "read_at"
+	jobMarkFavorite = "favorite_at"
+)
+
+// jobMark tells when a job was read or favorited in the terminal ui, 0 when
+// it was not
+type jobMark struct {
+	HnId       uint64 `db:"hn_id"`
+	ReadAt     int64  `db:"read_at"`
+	FavoriteAt int64  `db:"favorite_at"`
+}
+
+// SelectJobMarks will select the read and favorite marks of the jobs, by job id
+func SelectJobMarks() (map[uint64]jobMark, error) {
+	var marks []jobMark
+	if err := db.Select(&marks, `SELECT hn_id, read_at, favorite_at FROM job_mark`); err != nil {
+		return nil, err
+	}
+	byId := make(map[uint64]jobMark, len(marks))
+	for _, m := range marks {
+		byId[m.HnId] = m
+	}
+	return byId, nil
+}
+
+// setJobMark will set the mark column of a job to now, or clear it. The
+// column is written into the sql, so it must be jobMarkRead or jobMarkFavorite.
+func setJobMark(hnId uint64, column string, on bool) (int64, error) {
+	if column != jobMarkRead && column != jobMarkFavorite {
+		return 0, fmt.Errorf("unknown job mark %q", column)
+	}
+	var at int64
+	if on {
+		at = time.Now().Unix()
+	}
+	sql := fmt.Sprintf(`INSERT INTO job_mark (hn_id, %[1]s) VALUES (?, ?)
+            ON CONFLICT (hn_id) DO UPDATE SET %[1]s=excluded.%[1]s`, column)
+	_, err := db.Exec(sql, hnId, at)
+	return at, err
+}
+
+// tuiKeys are the names of the keys sent as escape sequences or control characters
+var tuiKeys = map[string]string{
+	"\x1b[A": "up", "\x1bOA": "up",
+	"\x1b[B": "down", "\x1bOB": "down",
+	"\x1b[C": "right", "\x1bOC": "right",
+	"\x1b[D": "left", "\x1bOD": "left",
+	"\x1b[5~": "pgup", "\x1b[6~": "pgdown",
+	"\x1b[H": "home", "\x1b[1~": "home",
+	"\x1b[F": "end", "\x1b[4~": "end",
+	"\r": "enter", "\n": "enter",
+	"\x1b": "esc",
+	"\x7f": "backspace", "\x08": "backspace",
+	"\x03": "ctrl+c",
+	" ":    "space",
+}
+
+// tuiKey will return the name of the key read as raw, or raw for keys
+// typing text
+func tuiKey(raw string) string {
+	if k, ok := tuiKeys[raw]; ok {
+		return k
+	}
+	return raw
+}
+
+// stty will run stty on the terminal of stdin and return its output
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// terminal is the terminal the ui is drawn on, in raw mode until closed
+type terminal struct {
+	// state are the stty settings restored on close
+	state string
+}
+
+// openTerminal will switch the terminal of stdin to raw mode and to its
+// alternate screen, so the shell is left as it was once the ui quits
+func openTerminal() (*terminal, error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, errors.New("the tui runs in a terminal, failed to read its settings")
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	return &terminal{state: state}, nil
+}
+
+// close will restore the screen and settings of the terminal
+func (t *terminal) close() {
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+	stty(t.state)
+}
+
+// size will return the columns and rows of the terminal, read on every
+// frame so resizes are followed
+func (t *terminal) size() (int, int) {
+	rows, cols := 24, 80
+	if out, err := stty("size"); err == nil {
+		fmt.Sscan(out, &rows, &cols)
+	}
+	return cols, rows
+}
+
+// readKey will read a key press, along with the rest of its escape sequence
+func (t *terminal) readKey() (string, error) {
+	var buf [32]byte
+	n, err := os.Stdin.Read(buf[:])
+	return string(buf[:n]), err
+}
+
+// truncateLine will cut a line to width columns, ending it with an ellipsis
+func truncateLine(line string, width int) string {
+	if utf8.RuneCountInString(line) <= width {
+		return line
+	}
+	if width < 1 {
+		return ""
+	}
+	return string([]rune(line)[:width-1]) + "…"
+}
+
+// wrapLine will break a line into lines of up to width columns between
+// words, breaking words longer than a line
+func wrapLine(line string, width int) []string {
+	if width < 1 {
+		return []string{line}
+	}
+	var lines []string
+	var cur []rune
+	for _, word := range strings.Fields(line) {
+		w := []rune(word)
+		if len(cur) > 0 && len(cur)+1+len(w) > width {
+			lines, cur = append(lines, string(cur)), nil
+		}
+		for len(w) > width {
+			if len(cur) > 0 {
+				lines, cur = append(lines, string(cur)), nil
+			}
+			lines, w = append(lines, string(w[:width])), w[width:]
+		}
+		if len(cur) > 0 {
+			cur = append(cur, ' ')
+		}
+		cur = append(cur, w...)
+	}
+	if len(cur) > 0 || len(lines) == 0 {
+		lines = append(lines, string(cur))
+	}
+	return lines
+}
+
+// tuiModel is the state of the terminal ui. Like bubbletea models, keys
+// update it and every frame is drawn from it.
+type tuiModel struct {
+	stories    []HiringStorySummary
+	storyIndex int
+	filter     FilterState
+	// favoritesOnly lists the favorite jobs of every story
+	favoritesOnly bool
+
+	jobs  []HiringJobListItem
+	marks map[uint64]jobMark
+
+	view int
+	// cursor is the index of the selected job and top of the first job shown
+	cursor int
+	top    int
+	// scroll is the first line of the job shown in the job view
+	scroll int
+
+	// prompt is the name of the prompt the input is typed in, empty when
+	// keys browse the jobs
+	prompt  string
+	input   string
+	message string
+
+	width  int
+	height int
+}
+
+// newTuiModel will load the jobs of the story with id hsId, or of the
+// current story when 0, filtered by the encoded filter params
+func newTuiModel(hsId uint64, params string) (*tuiModel, error) {
+	stories, err := SelectHiringStories(storyKindHiring)
+	if err != nil {
+		return nil, err
+	}
+	if len(stories) == 0 {
+		return nil, errors.New("no stories stored yet, run the server to sync them first")
+	}
+	if hsId == 0 {
+		hs, err := GetCurrentHiringStory()
+		if err != nil {
+			return nil, err
+		}
+		hsId = hs.HnId
+	}
+	m := &tuiModel{stories: stories, storyIndex: -1, width: 80, height: 24}
+	for i, hs := range stories {
+		if hs.HnId == hsId {
+			m.storyIndex = i
+		}
+	}
+	if m.storyIndex < 0 {
+		return nil, fmt.Errorf("unknown story %d", hsId)
+	}
+	if m.filter, err = parseTuiFilter(params); err != nil {
+		return nil, err
+	}
+	if m.marks, err = SelectJobMarks(); err != nil {
+		return nil, err
+	}
+	return m, m.load()
+}
+
+// parseTuiFilter will parse encoded filter params, like remote=1&tag=go
+func parseTuiFilter(params string) (FilterState, error) {
+	q, err := url.ParseQuery(strings.TrimPrefix(params, "?"))
+	if err != nil {
+		return FilterState{}, errors.New("invalid filter params, expected params like remote=1&tag=go")
+	}
+	return parseFilterState(q)
+}
+
+// load will select the jobs of the story, or the favorite jobs, matching the filter
+func (m *tuiModel) load() error {
+	scope := jobScope{StoryId: m.stories[m.storyIndex].HnId}
+	if m.favoritesOnly {
+		scope = jobScope{}
+	}
+	res, err := searchJobs(scope, m.filter, 0)
+	if err != nil {
+		return err
+	}
+	m.jobs = nil
+	for _, hj := range res.Jobs {
+		if !m.favoritesOnly || m.marks[hj.HnId].FavoriteAt > 0 {
+			m.jobs = append(m.jobs, hj)
+		}
+	}
+	if len(res.Spellings) > 0 {
+		m.message = "Also matching " + strings.Join(res.Spellings, ", ")
+	}
+	m.moveCursor(0)
+	return nil
+}
+
+// reload will load the jobs again, showing the error of failed loads
+func (m *tuiModel) reload() {
+	if err := m.load(); err != nil {
+		m.message = "Failed to load the jobs: " + err.Error()
+	}
+}
+
+// listRows will return the number of jobs the list view shows
+func (m *tuiModel) listRows() int {
+	if rows := m.height - 5; rows > 1 {
+		return rows
+	}
+	return 1
+}
+
+// moveCursor will move the cursor by delta jobs, keeping it on a job and
+// scrolling the list to show it
+func (m *tuiModel) moveCursor(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.jobs) {
+		m.cursor = len(m.jobs) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	rows := m.listRows()
+	if m.cursor < m.top {
+		m.top = m.cursor
+	}
+	if m.cursor >= m.top+rows {
+		m.top = m.cursor - rows + 1
+	}
+}
+
+// selected will return the job under the cursor, false when no job is listed
+func (m *tuiModel) selected() (HiringJobListItem, bool) {
+	if m.cursor >= len(m.jobs) {
+		return HiringJobListItem{}, false
+	}
+	return m.jobs[m.cursor], true
+}
+
+// mark will set or clear the read or favorite mark of the selected job
+func (m *tuiModel) mark(column string, on bool) {
+	hj, ok := m.selected()
+	if !ok {
+		return
+	}
+	at, err := setJobMark(hj.HnId, column, on)
+	if err != nil {
+		m.message = "Failed to save the mark: " + err.Error()
+		return
+	}
+	mark := m.marks[hj.HnId]
+	mark.HnId = hj.HnId
+	if column == jobMarkRead {
+		mark.ReadAt = at
+	} else {
+		mark.FavoriteAt = at
+	}
+	m.marks[hj.HnId] = mark
+}
+
+// open will show the selected job, marking it read
+func (m *tuiModel) open() {
+	hj, ok := m.selected()
+	if !ok {
+		return
+	}
+	m.view, m.scroll = tuiJobView, 0
+	if m.marks[hj.HnId].ReadAt == 0 {
+		m.mark(jobMarkRead, true)
+	}
+}
+
+// nextSort will list the jobs in the sort after the current one
+func (m *tuiModel) nextSort() {
+	links := m.filter.sortLinks("")
+	for i, l := range links {
+		if l.Selected {
+			m.filter.Sort = links[(i+1)%len(links)].Sort
+			break
+		}
+	}
+	m.cursor = 0
+	m.reload()
+}
+
+// showStory will list the jobs of the story at index i, newer stories first
+func (m *tuiModel) showStory(i int) {
+	if i < 0 || i >= len(m.stories) || m.favoritesOnly {
+		return
+	}
+	m.storyIndex, m.cursor = i, 0
+	m.reload()
+}
+
+// update will change the model for a key, and return true to quit
+func (m *tuiModel) update(key string) bool {
+	if key == "ctrl+c" {
+		return true
+	}
+	if m.prompt != "" {
+		m.updatePrompt(key)
+		return false
+	}
+	m.message = ""
+	if m.view == tuiJobView {
+		return m.updateJob(key)
+	}
+	return m.updateList(key)
+}
+
+// updatePrompt will edit the input of the prompt, and apply it on enter
+func (m *tuiModel) updatePrompt(key string) {
+	switch key {
+	case "esc":
+		m.prompt = ""
+	case "enter":
+		m.applyPrompt()
+	case "backspace":
+		if _, size := utf8.DecodeLastRuneInString(m.input); size > 0 {
+			m.input = m.input[:len(m.input)-size]
+		}
+	case "space":
+		m.input += " "
+	default:
+		for _, r := range key {
+			if r < ' ' || r == 0x7f {
+				return
+			}
+		}
+		m.input += key
+	}
+}
+
+// applyPrompt will search or filter the jobs by the input of the prompt
+func (m *tuiModel) applyPrompt() {
+	prompt, input := m.prompt, strings.TrimSpace(m.input)
+	m.prompt = ""
+	switch prompt {
+	case tuiSearchPrompt:
+		m.filter.Query = input
+		if err := searchSyntaxError(input); input != "" && err != nil {
+			m.message = err.Error()
+		}
+	case tuiFilterPrompt:
+		f, err := parseTuiFilter(input)
+		if err != nil {
+			m.message = err.Error()
+			return
+		}
+		m.filter = f
+	}
+	m.cursor = 0
+	m.reload()
+}
+
+// updateList will handle the keys of the list of jobs
+func (m *tuiModel) updateList(key string) bool {
+	hj, ok := m.selected()
+	switch key {
+	case "q":
+		return true
+	case "j", "down":
+		m.moveCursor(1)
+	case "k", "up":
+		m.moveCursor(-1)
+	case "space", "pgdown":
+		m.moveCursor(m.listRows())
+	case "pgup":
+		m.moveCursor(-m.listRows())
+	case "g", "home":
+		m.moveCursor(-len(m.jobs))
+	case "G", "end":
+		m.moveCursor(len(m.jobs))
+	case "enter", "l", "right":
+		m.open()
+	case "/":
+		m.prompt, m.input = tuiSearchPrompt, m.filter.Query
+	case "f":
+		m.prompt, m.input = tuiFilterPrompt, m.filter.cursorParams()
+	case "esc":
+		m.filter, m.cursor = FilterState{}, 0
+		m.reload()
+	case "o":
+		m.nextSort()
+	case "r":
+		m.mark(jobMarkRead, ok && m.marks[hj.HnId].ReadAt == 0)
+	case "s":
+		m.mark(jobMarkFavorite, ok && m.marks[hj.HnId].FavoriteAt == 0)
+	case "v":
+		m.favoritesOnly, m.cursor = !m.favoritesOnly, 0
+		m.reload()
+	case "]":
+		m.showStory(m.storyIndex - 1)
+	case "[":
+		m.showStory(m.storyIndex + 1)
+	}
+	return false
+}
+
+// updateJob will handle the keys of the job view
+func (m *tuiModel) updateJob(key string) bool {
+	hj, ok := m.selected()
+	page := m.height - 4
+	switch key {
+	case "q", "esc", "h", "left", "backspace":
+		m.view = tuiListView
+	case "j", "down":
+		m.scroll++
+	case "k", "up":
+		m.scroll--
+	case "space", "pgdown":
+		m.scroll += page
+	case "pgup":
+		m.scroll -= page
+	case "n":
+		if m.cursor < len(m.jobs)-1 {
+			m.moveCursor(1)
+			m.open()
+		}
+	case "p":
+		if m.cursor > 0 {
+			m.moveCursor(-1)
+			m.open()
+		}
+	case "r":
+		m.mark(jobMarkRead, ok && m.marks[hj.HnId].ReadAt == 0)
+	case "s":
+		m.mark(jobMarkFavorite, ok && m.marks[hj.HnId].FavoriteAt == 0)
+	}
+	if m.scroll < 0 {
+		m.scroll = 0
+	}
+	return false
+}
+
+// styled will wrap a line truncated to width in an escape sequence
+func (m *tuiModel) styled(style, line string) string {
+	return style + truncateLine(line, m.width) + ansiReset
+}
+
+// render will draw a frame of the model
+func (m *tuiModel) render() string {
+	var lines []string
+	if m.view == tuiJobView {
+		lines = m.jobLines()
+	} else {
+		lines = m.listLines()
+	}
+	for len(lines) < m.height-2 {
+		lines = append(lines, "")
+	}
+	lines = lines[:m.height-2]
+
+	switch {
+	case m.prompt != "":
+		lines = append(lines, truncateLine(m.prompt+": "+m.input, m.width-1)+ansiReverse+" "+ansiReset)
+	case m.message != "":
+		lines = append(lines, m.styled(ansiBold, m.message))
+	default:
+		lines = append(lines, "")
+	}
+	help := "j/k move  enter open  / search  f filter  o sort  r read  s favorite  v favorites  [ ] month  esc clear  q quit"
+	if m.view == tuiJobView {
+		help = "j/k scroll  space page  n/p next/prev job  r read  s favorite  q back"
+	}
+	lines = append(lines, m.styled(ansiDim, help))
+	return ansiClear + strings.Join(lines, "\r\n")
+}
+
+// listLines will draw the lines of the list of jobs, but the footer
+func (m *tuiModel) listLines() []string {
+	title := m.stories[m.storyIndex].Title
+	if m.favoritesOnly {
+		title = "Favorites of every month"
+	}
+	unread := 0
+	for _, hj := range m.jobs {
+		if m.marks[hj.HnId].ReadAt == 0 {
+			unread++
+		}
+	}
+	lines := []string{
+		m.styled(ansiBold, fmt.Sprintf("%s · %s, %d unread · %s", title, countNoun(len(m.jobs), "job", "jobs"), unread, m.filter.SortName())),
+	}
+	if params := m.filter.cursorParams(); params != "" {
+		lines = append(lines, truncateLine("Filtered by "+params, m.width))
+	} else {
+		lines = append(lines, m.styled(ansiDim, "Every job, / to search and f to filter"))
+	}
+	lines = append(lines, "")
+
+	if len(m.jobs) == 0 {
+		return append(lines, "  No jobs found.")
+	}
+	end := m.top + m.listRows()
+	if end > len(m.jobs) {
+		end = len(m.jobs)
+	}
+	for i := m.top; i < end; i++ {
+		hj := m.jobs[i]
+		mark := m.marks[hj.HnId]
+		prefix, star, style := "  ", " ", ansiBold
+		if i == m.cursor {
+			prefix = "> "
+		}
+		if mark.FavoriteAt > 0 {
+			star = "*"
+		}
+		if mark.ReadAt > 0 {
+			style = ansiDim
+		}
+		if i == m.cursor {
+			style += ansiReverse
+		}
+		line := prefix + star + " " + plainJobTitle(hj.HiringJob)
+		if details := plainJobDetails(hj.HiringJob); details != "" {
+			line += " | " + details
+		}
+		if m.favoritesOnly {
+			line += " | " + hj.StoryMonth()
+		}
+		lines = append(lines, m.styled(style, line))
+	}
+	return lines
+}
+
+// jobLines will draw the lines of the selected job, but the footer
+func (m *tuiModel) jobLines() []string {
+	hj, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	mark := m.marks[hj.HnId]
+	status := []string{fmt.Sprintf("Job %d of %d", m.cursor+1, len(m.jobs)), hj.StoryMonth()}
+	if mark.ReadAt > 0 {
+		status = append(status, "read")
+	}
+	if mark.FavoriteAt > 0 {
+		status = append(status, "favorite")
+	}
+	lines := []string{m.styled(ansiBold, strings.Join(status, " · ")), ""}
+
+	var b strings.Builder
+	writePlainJob(&b, hj.HiringJob)
+	var body []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		body = append(body, wrapLine(line, m.width)...)
+	}
+	if last := len(body) - (m.height - 4); m.scroll > last {
+		m.scroll = last
+	}
+	if m.scroll < 0 {
+		m.scroll = 0
+	}
+	return append(lines, body[m.scroll:]...)
+}
+
+// tuiCommand will browse the jobs of the local database in the terminal,
+// without running the server: list, search and filter them, and mark them
+// read or favorite
+func tuiCommand(args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	story := fs.Uint64("story", 0, "id of the story to browse, the current story when 0")
+	params := fs.String("filter", "", "filter params to start with, like remote=1&tag=go")
+	fs.Parse(args)
+
+	if err := ensureSearchIndex(); err != nil {
+		return err
+	}
+	m, err := newTuiModel(*story, *params)
+	if err != nil {
+		return err
+	}
+	t, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+	for {
+		m.width, m.height = t.size()
+		m.moveCursor(0)
+		fmt.Print(m.render())
+		raw, err := t.readKey()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if m.update(tuiKey(raw)) {
+			return nil
+		}
+	}
+}
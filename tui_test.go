@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestSetJobMark(t *testing.T) {
+	useTestDB(t)
+	if _, err := setJobMark(10, jobMarkRead, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := setJobMark(10, jobMarkFavorite, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := setJobMark(11, jobMarkFavorite, true); err != nil {
+		t.Fatal(err)
+	}
+	marks, err := SelectJobMarks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := marks[10]; m.ReadAt == 0 || m.FavoriteAt == 0 {
+		t.Errorf("marks of job 10 = %+v, want read and favorite", m)
+	}
+	if m := marks[11]; m.ReadAt != 0 || m.FavoriteAt == 0 {
+		t.Errorf("marks of job 11 = %+v, want favorite only", m)
+	}
+
+	// clearing a mark keeps the other one
+	at, err := setJobMark(10, jobMarkRead, false)
+	if err != nil || at != 0 {
+		t.Fatalf("setJobMark(10, read, false) = %d, %v, want 0", at, err)
+	}
+	if marks, err = SelectJobMarks(); err != nil {
+		t.Fatal(err)
+	}
+	if m := marks[10]; m.ReadAt != 0 || m.FavoriteAt == 0 {
+		t.Errorf("marks of job 10 = %+v, want favorite only", m)
+	}
+	if _, err := setJobMark(11, jobMarkFavorite, false); err != nil {
+		t.Fatal(err)
+	}
+	if marks, err = SelectJobMarks(); err != nil {
+		t.Fatal(err)
+	}
+	if m := marks[11]; m.ReadAt != 0 || m.FavoriteAt != 0 {
+		t.Errorf("marks of job 11 = %+v, want none", m)
+	}
+}
+
+func TestSetJobMarkColumn(t *testing.T) {
+	useTestDB(t)
+	for _, column := range []string{"hn_id", "read_at=0, favorite_at", ""} {
+		if _, err := setJobMark(10, column, true); err == nil {
+			t.Errorf("setJobMark(10, %q) succeeded, want an unknown mark error", column)
+		}
+	}
+	marks, err := SelectJobMarks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(marks) != 0 {
+		t.Errorf("unknown marks saved %+v", marks)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	// typoFallbackBelow is the number of jobs under which typo tolerant
+	// searches are run again with the close spellings of their terms
+	typoFallbackBelow = 3
+	// typoSpellingsMax is the max number of close spellings a term matches
+	typoSpellingsMax = 3
+	// closeSpellingsHeader lists the close spellings an api search matched
+	closeSpellingsHeader = "X-Wih-Close-Spellings"
+)
+
+// typoVocabularyCache holds the words of the live jobs with the number of
+// jobs using each one, built on first use and after every sync
+type typoVocabularyCache struct {
+	mu    sync.Mutex
+	words map[string]int
+}
+
+var typoVocabulary = &typoVocabularyCache{}
+
+func init() {
+	bus.Subscribe(eventSyncCompleted, func(event) { typoVocabulary.purge() })
+}
+
+// get will return the number of live jobs using each word
+func (c *typoVocabularyCache) get() (map[string]int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.words != nil {
+		return c.words, nil
+	}
+	var texts []string
+	if err := db.Select(&texts, `SELECT text FROM hiring_job WHERE status=?`, jobStatusOk); err != nil {
+		return nil, err
+	}
+	words := map[string]int{}
+	for _, text := range texts {
+		seen := map[string]bool{}
+		for _, w := range searchWords(searchIndexText(text)) {
+			if !seen[w] {
+				seen[w] = true
+				words[w]++
+			}
+		}
+	}
+	c.words = words
+	return words, nil
+}
+
+func (c *typoVocabularyCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.words = nil
+}
+
+// closeSpellings will return the words within maxEdits of a term, closest and
+// most used first. Known words have no close spellings.
+func closeSpellings(term string, words map[string]int) []string {
+	edits := maxEdits(term)
+	if edits == 0 || words[term] > 0 {
+		return nil
+	}
+	type spelling struct {
+		word  string
+		dist  int
+		count int
+	}
+	var found []spelling
+	n := len([]rune(term))
+	for w, count := range words {
+		if diff := len([]rune(w)) - n; diff > edits || diff < -edits {
+			continue
+		}
+		if d := editDistance(term, w); d <= edits {
+			found = append(found, spelling{w, d, count})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].dist != found[j].dist {
+			return found[i].dist < found[j].dist
+		}
+		if found[i].count != found[j].count {
+			return found[i].count > found[j].count
+		}
+		return found[i].word < found[j].word
+	})
+	var spellings []string
+	for i := 0; i < len(found) && i < typoSpellingsMax; i++ {
+		spellings = append(spellings, found[i].word)
+	}
+	return spellings
+}
+
+// withSpellings will return the search with every term also matching its
+// close spellings, along with the spellings added. Phrases and excluded
+// terms are left as they are.
+func (n *searchNode) withSpellings(words map[string]int) (*searchNode, []string) {
+	switch n.Op {
+	case tokenTerm:
+		terms := searchWords(n.Text)
+		if len(terms) != 1 {
+			return n, nil
+		}
+		spellings := closeSpellings(terms[0], words)
+		expanded := n
+		for _, s := range spellings {
+			expanded = joinSearch(tokenOr, expanded, &searchNode{Op: tokenTerm, Text: s})
+		}
+		return expanded, spellings
+	case tokenAnd, tokenOr, tokenNot:
+		left, spellings := n.Left.withSpellings(words)
+		right := n.Right
+		if n.Op != tokenNot {
+			var more []string
+			right, more = n.Right.withSpellings(words)
+			spellings = append(spellings, more...)
+		}
+		return &searchNode{Op: n.Op, Left: left, Right: right}, spellings
+	}
+	return n, nil
+}
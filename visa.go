@@ -0,0 +1,47 @@
+package main
+
+import (
+	"regexp"
+)
+
+const (
+	visaYes = "yes"
+	visaNo  = "no"
+)
+
+var visaOptions = []string{visaYes, visaNo}
+
+// visaNames are the display names of the visa sponsorship values
+var visaNames = map[string]string{
+	visaYes: "Visa sponsorship",
+	visaNo:  "No visa sponsorship",
+}
+
+// visaNoPattern matches posts ruling sponsorship out or requiring the right
+// to work. It is matched first, as these posts mention sponsorship too.
+var visaNoPattern = regexp.MustCompile(`(?i)\b(no|not|cannot|can't|unable to|don't|won't|doesn't)\b[^.;!\n]{0,30}\b(sponsor|sponsors|sponsorship|sponsoring|visas?)\b` +
+	`|\bwithout (visa )?sponsorship\b` +
+	`|\bmust (already )?(be|have) (legally )?(authorized|eligible|the right) to work\b` +
+	`|\b(us|u\.s\.) citizens? only\b|\bcitizenship (is )?required\b`)
+
+// visaYesPattern matches posts offering visa sponsorship
+var visaYesPattern = regexp.MustCompile(`(?i)\b(visa sponsorship|sponsor(s|ing)? (your |work |h-?1b )?visas?` +
+	`|visas? (support|sponsored|assistance)|(will|can|we|happy to) sponsor|h-?1b (sponsorship|transfers?)` +
+	`|relocation and visa|visa and relocation|eu blue card)\b`)
+
+func isVisaOption(v string) bool {
+	return getIndex(visaOptions, v) != -1
+}
+
+// jobVisa will return whether a job sponsors visas, yes or no, along with the
+// matched terms. Posts not mentioning sponsorship return empty.
+func jobVisa(text string) (string, string) {
+	plain := jobPlainText(text)
+	if visaNoPattern.MatchString(plain) {
+		return visaNo, matchedTerms(visaNoPattern, plain)
+	}
+	if visaYesPattern.MatchString(plain) {
+		return visaYes, matchedTerms(visaYesPattern, plain)
+	}
+	return "", ""
+}
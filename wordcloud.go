@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The word cloud of a month shows the terms its posts mention more than the
+// posts of the months before it, weighted by tf-idf: the share of the jobs of
+// the month mentioning a term, times the rarity of the term across the prior
+// months. Term counts are aggregated with the other story aggregates.
+
+const (
+	// wordCloudTerms is the number of terms shown in a word cloud
+	wordCloudTerms = 40
+	// wordCloudMinJobs is the number of jobs of a month a term must be in to
+	// be aggregated, which keeps typos and names out
+	wordCloudMinJobs = 2
+
+	wordCloudWidth   = 800
+	wordCloudHeight  = 320
+	wordCloudMinFont = 12
+	wordCloudMaxFont = 40
+)
+
+// wordCloudColors are the fill colors of the terms, from the most distinctive
+var wordCloudColors = []string{"#fcd34d", "#6ee7b7", "#7dd3fc", "#c4b5fd", "#f9a8d4", "#e2e8f0"}
+
+// termPattern matches the terms of a lowercased job text, keeping the
+// punctuation of names like node.js, c++ or c#
+var termPattern = regexp.MustCompile(`[a-z][a-z0-9]*(?:[.\-][a-z0-9]+)*[+#]*`)
+
+// termDomainPattern matches the domains left in job texts, like acme.com
+var termDomainPattern = regexp.MustCompile(`\.(com|io|org|net|co|ai|dev|app|tech|xyz|me)$`)
+
+// termStopwords are common english words, and words of every post, that say
+// nothing about a month
+var termStopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a about after all also an and any are as at be been but by can could
+		do does each etc for from get has have how if in into is it its just like more most must new no
+		not of on or other our out over per so some such than that the their them there these they this
+		those through to up us use using very was we well were what when where which while who whom why
+		will with within would you your yours we're you'll you're it's i me my
+		http https www com job jobs role roles position positions hiring hire apply team company work
+		working looking join years year experience full time`) {
+		termStopwords[w] = true
+	}
+}
+
+// jobTerms will return the distinct terms of a job text, leaving out
+// stopwords, numbers and domains
+func jobTerms(text string) map[string]bool {
+	terms := map[string]bool{}
+	for _, t := range termPattern.FindAllString(strings.ToLower(jobPlainText(text)), -1) {
+		t = strings.TrimRight(t, ".-")
+		if len(t) < 2 || termStopwords[t] || termDomainPattern.MatchString(t) {
+			continue
+		}
+		terms[t] = true
+	}
+	return terms
+}
+
+// SelectStoryTermCounts will count the live, non duplicate jobs of a story
+// mentioning each term, leaving out the terms of fewer than wordCloudMinJobs
+func SelectStoryTermCounts(hsId uint64) (map[string]int, error) {
+	var texts []string
+	sql := `SELECT text FROM hiring_job_view WHERE hiring_story_id=? and status=? and duplicate_of=0`
+	if err := db.Select(&texts, sql, hsId, jobStatusOk); err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, text := range texts {
+		for t := range jobTerms(text) {
+			counts[t]++
+		}
+	}
+	for t, n := range counts {
+		if n < wordCloudMinJobs {
+			delete(counts, t)
+		}
+	}
+	return counts, nil
+}
+
+// wordCloudTerm is a term of a month weighted by how distinctive it is
+type wordCloudTerm struct {
+	Term   string
+	Jobs   int
+	Weight float64
+}
+
+// SelectWordCloudTerms will select the wordCloudTerms most distinctive terms
+// of a story against the hiring stories posted before it, the heaviest first
+func SelectWordCloudTerms(a StoryAggregate) ([]wordCloudTerm, error) {
+	if a.Jobs == 0 {
+		return nil, nil
+	}
+	var counts []struct {
+		Term string
+		Jobs int
+	}
+	sql := `SELECT term, jobs FROM story_term_aggregate WHERE story_id=?`
+	if err := db.Select(&counts, sql, a.StoryId); err != nil {
+		return nil, err
+	}
+	var months int
+	sql = `SELECT COUNT(*) FROM story_aggregate sa
+            JOIN hiring_story hs ON hs.hn_id = sa.story_id
+            WHERE hs.kind=? and hs.time < ?`
+	if err := db.Get(&months, sql, storyKindHiring, a.Time); err != nil {
+		return nil, err
+	}
+	var prior []struct {
+		Term   string
+		Months int
+	}
+	sql = `SELECT sta.term, COUNT(*) AS months FROM story_term_aggregate sta
+            JOIN hiring_story hs ON hs.hn_id = sta.story_id
+            WHERE hs.kind=? and hs.time < ?
+            GROUP BY sta.term`
+	if err := db.Select(&prior, sql, storyKindHiring, a.Time); err != nil {
+		return nil, err
+	}
+	seen := map[string]int{}
+	for _, p := range prior {
+		seen[p.Term] = p.Months
+	}
+
+	terms := make([]wordCloudTerm, 0, len(counts))
+	for _, c := range counts {
+		// smoothed, so the first month is weighted by frequency alone
+		idf := math.Log(float64(months+1)/float64(seen[c.Term]+1)) + 1
+		tf := float64(c.Jobs) / float64(a.Jobs)
+		terms = append(terms, wordCloudTerm{Term: c.Term, Jobs: c.Jobs, Weight: tf * idf})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Weight != terms[j].Weight {
+			return terms[i].Weight > terms[j].Weight
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	if len(terms) > wordCloudTerms {
+		terms = terms[:wordCloudTerms]
+	}
+	return terms, nil
+}
+
+// wordCloudBox is the area taken by a placed term
+type wordCloudBox struct {
+	x, y, w, h float64
+}
+
+func (b wordCloudBox) overlaps(o wordCloudBox) bool {
+	return b.x < o.x+o.w && o.x < b.x+b.w && b.y < o.y+o.h && o.y < b.y+b.h
+}
+
+// renderWordCloud will lay terms out as an svg, the heaviest first from the
+// center along a spiral, each linked to the search of its term in the story.
+// Text widths are estimated, so terms are given some room. Terms not fitting
+// are left out.
+func renderWordCloud(hsId uint64, terms []wordCloudTerm) template.HTML {
+	if len(terms) == 0 {
+		return ""
+	}
+	heaviest, lightest := terms[0].Weight, terms[len(terms)-1].Weight
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="w-full" role="img" aria-label="most distinctive terms">`, wordCloudWidth, wordCloudHeight)
+	var placed []wordCloudBox
+	for i, t := range terms {
+		size := float64(wordCloudMinFont)
+		if heaviest > lightest {
+			size += (wordCloudMaxFont - wordCloudMinFont) * (t.Weight - lightest) / (heaviest - lightest)
+		}
+		box := wordCloudBox{w: 0.6 * size * float64(len(t.Term)), h: size}
+		fits := false
+		for step := 0; step < 2000 && !fits; step++ {
+			angle := 0.1 * float64(step)
+			radius := 1.5 * angle
+			box.x = wordCloudWidth/2 + 2*radius*math.Cos(angle) - box.w/2
+			box.y = wordCloudHeight/2 + radius*math.Sin(angle) - box.h/2
+			if box.x < 0 || box.y < 0 || box.x+box.w > wordCloudWidth || box.y+box.h > wordCloudHeight {
+				continue
+			}
+			fits = true
+			for _, p := range placed {
+				if box.overlaps(p) {
+					fits = false
+					break
+				}
+			}
+		}
+		if !fits {
+			continue
+		}
+		placed = append(placed, box)
+		color := wordCloudColors[i*len(wordCloudColors)/len(terms)]
+		// the baseline of the text sits about a fifth of its size above the bottom
+		fmt.Fprintf(&b, `<a href="/search?q=%s&amp;story=%d"><text x="%.1f" y="%.1f" font-size="%.1f" fill="%s" font-family="sans-serif"><title>%s, in %s</title>%s</text></a>`,
+			template.URLQueryEscaper(t.Term), hsId, box.x, box.y+0.8*box.h, size, color,
+			template.HTMLEscapeString(t.Term), countNoun(t.Jobs, "job", "jobs"), template.HTMLEscapeString(t.Term))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}